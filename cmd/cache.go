@@ -0,0 +1,13 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	RootCmd.AddCommand(CacheCmd)
+}
+
+var CacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the resized tile cache",
+	Long:  "Manage the resized tile cache",
+}
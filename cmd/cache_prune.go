@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cachePruneMaxSize int
+	cachePruneAll     bool
+)
+
+func init() {
+	addLocalIntFlag(&cachePruneMaxSize, "max-size", "", -1, "Size in megabytes to prune the cache to, -1 uses the cache size", CachePruneCmd)
+	addLocalBoolFlag(&cachePruneAll, "all", "", false, "Remove every tile from the cache", CachePruneCmd)
+	CacheCmd.AddCommand(CachePruneCmd)
+}
+
+var CachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the least recently used tiles from the tile cache",
+	Long:  "Remove the least recently used tiles from the tile cache",
+	Run: func(c *cobra.Command, args []string) {
+		if cachePruneAll && cachePruneMaxSize >= 0 {
+			Env.Fatalln("all cannot be used with max-size")
+		}
+
+		maxSize := int64(-1)
+		if cachePruneAll {
+			maxSize = 0
+		} else if cachePruneMaxSize >= 0 {
+			maxSize = int64(cachePruneMaxSize) << 20
+		}
+
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.CachePrune(Env, maxSize)
+	},
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CacheCmd.AddCommand(CacheStatsCmd)
+}
+
+var CacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size of the tile cache",
+	Long:  "Show the size of the tile cache",
+	Run: func(c *cobra.Command, args []string) {
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.CacheStats(Env)
+	},
+}
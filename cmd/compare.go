@@ -7,10 +7,12 @@ import (
 
 var (
 	compareMacroId int
+	compareKeepTop int
 )
 
 func init() {
 	addLocalIntFlag(&compareMacroId, "macro-id", "", 0, "Id of macro for comparison", CompareCmd)
+	addLocalIntFlag(&compareKeepTop, "keep-top", "", 0, "Number of closest comparisons to keep for each macro partial, 0 keeps all", CompareCmd)
 	RootCmd.AddCommand(CompareCmd)
 }
 
@@ -24,12 +26,16 @@ var CompareCmd = &cobra.Command{
 			Env.Fatalln("Macro id is required")
 		}
 
+		if compareKeepTop < 0 {
+			Env.Fatalln("keep-top cannot be negative")
+		}
+
 		err := Env.Init()
 		if err != nil {
 			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
 		}
 		defer Env.Close()
 
-		controller.Compare(Env, int64(compareMacroId))
+		controller.Compare(Env, int64(compareMacroId), compareKeepTop)
 	},
 }
@@ -0,0 +1,13 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	RootCmd.AddCommand(CoverCmd)
+}
+
+var CoverCmd = &cobra.Command{
+	Use:   "cover",
+	Short: "Manage covers",
+	Long:  "Manage covers",
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +14,7 @@ var (
 	coverAspectHeight int
 	coverAspect       string
 	coverAspectSize   int
+	coverAspectLayout string
 )
 
 func init() {
@@ -20,6 +22,7 @@ func init() {
 	addLocalIntFlag(&coverAspectHeight, "height", "", 0, "Pixel height of cover", CoverAspectCmd)
 	addLocalStrFlag(&coverAspect, "aspect", "a", "1x1", "Aspect of cover partials (CxR)", CoverAspectCmd)
 	addLocalIntFlag(&coverAspectSize, "size", "s", 0, "Number of partials in smallest dimension", CoverAspectCmd)
+	addLocalStrFlag(&coverAspectLayout, "layout", "l", "grid", "Layout of cover partials, one of 'grid', 'brick', 'random' or 'herringbone'", CoverAspectCmd)
 	RootCmd.AddCommand(CoverAspectCmd)
 }
 
@@ -78,12 +81,16 @@ var CoverAspectCmd = &cobra.Command{
 			Env.Fatalln("num must be greater than zero")
 		}
 
+		if !util.SliceContainsString(controller.CoverAspectLayouts, coverAspectLayout) {
+			Env.Fatalln("Invalid layout")
+		}
+
 		err = Env.Init()
 		if err != nil {
 			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
 		}
 		defer Env.Close()
 
-		controller.CoverAspect(Env, coverAspectWidth, coverAspectHeight, aw, ah, coverAspectSize)
+		controller.CoverAspect(Env, coverAspectWidth, coverAspectHeight, aw, ah, coverAspectSize, coverAspectLayout)
 	},
 }
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	coverExportCoverId int
+	coverExportFormat  string
+)
+
+func init() {
+	addLocalIntFlag(&coverExportCoverId, "cover-id", "c", 0, "Id of cover to export", CoverExportCmd)
+	addLocalStrFlag(&coverExportFormat, "format", "f", "json", "Format of export, either 'json' or 'svg'", CoverExportCmd)
+	CoverCmd.AddCommand(CoverExportCmd)
+}
+
+var CoverExportCmd = &cobra.Command{
+	Use:   "export [OUTFILE]",
+	Short: "Export cover layout to OUTFILE, or stdout",
+	Long:  "Export cover layout to OUTFILE, or stdout",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) > 1 {
+			Env.Fatalln("Only one out file is allowed")
+		}
+
+		if coverExportCoverId == 0 {
+			Env.Fatalln("Cover id is required")
+		}
+
+		if !util.SliceContainsString(controller.CoverExportFormats, coverExportFormat) {
+			Env.Fatalln("Invalid format")
+		}
+
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		var w io.Writer = os.Stdout
+		if len(args) == 1 && args[0] != "" {
+			f, err := os.Create(args[0])
+			if err != nil {
+				Env.Fatalf("Unable to create out file: %s\n", err.Error())
+			}
+			defer f.Close()
+			w = f
+		}
+
+		err = controller.CoverExport(Env, int64(coverExportCoverId), coverExportFormat, w)
+		if err != nil {
+			Env.Printf("Error exporting cover: %s\n", err.Error())
+		}
+	},
+}
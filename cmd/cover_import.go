@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CoverCmd.AddCommand(CoverImportCmd)
+}
+
+var CoverImportCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Import cover layout from json or svg FILE",
+	Long:  "Import cover layout from json or svg FILE",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 1 {
+			Env.Fatalln("Cover layout file is required")
+		}
+
+		if args[0] == "" {
+			Env.Fatalln("Cover layout file is required")
+		}
+
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.CoverImport(Env, args[0])
+	},
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+var (
+	coverMixedWidth   int
+	coverMixedHeight  int
+	coverMixedAspects string
+	coverMixedSize    int
+)
+
+func init() {
+	addLocalIntFlag(&coverMixedWidth, "width", "w", 0, "Pixel width of cover", CoverMixedCmd)
+	addLocalIntFlag(&coverMixedHeight, "height", "", 0, "Pixel height of cover", CoverMixedCmd)
+	addLocalStrFlag(&coverMixedAspects, "aspects", "a", "2x3,3x2", "Comma separated aspects of cover partials (CxR,CxR)", CoverMixedCmd)
+	addLocalIntFlag(&coverMixedSize, "size", "s", 0, "Approximate number of partials in smallest dimension", CoverMixedCmd)
+	RootCmd.AddCommand(CoverMixedCmd)
+}
+
+var CoverMixedCmd = &cobra.Command{
+	Use:    "cover_mixed",
+	Short:  "Create a mixed aspect cover",
+	Long:   "Create a mixed aspect cover",
+	Hidden: true,
+	Run: func(c *cobra.Command, args []string) {
+		if coverMixedWidth == 0 {
+			Env.Fatalln("width is required")
+		} else if coverMixedWidth < 0 {
+			Env.Fatalln("width must be greater than zero")
+		}
+
+		if coverMixedHeight == 0 {
+			Env.Fatalln("height is required")
+		} else if coverMixedHeight < 0 {
+			Env.Fatalln("height must be greater than zero")
+		}
+
+		aspects, err := controller.ParseAspects(coverMixedAspects)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		if coverMixedSize < 0 {
+			Env.Fatalln("size must be greater than zero")
+		}
+
+		err = Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.CoverMixed(Env, coverMixedWidth, coverMixedHeight, aspects, coverMixedSize)
+	},
+}
@@ -0,0 +1,13 @@
+package cmd
+
+import "github.com/spf13/cobra"
+
+func init() {
+	RootCmd.AddCommand(DbCmd)
+}
+
+var DbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the database",
+	Long:  "Manage the database",
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbConvertBatchSize int
+)
+
+func init() {
+	addLocalIntFlag(&dbConvertBatchSize, "batch-size", "b", 10000, "Number of rows to copy at a time", DbConvertCmd)
+	DbCmd.AddCommand(DbConvertCmd)
+}
+
+var DbConvertCmd = &cobra.Command{
+	Use:   "convert DSN",
+	Short: "Copy the database to another backend",
+	Long:  "Copy every row of the database at --dsn to the empty database at DSN, which can use another backend, like sqlite3://path or bolt://path",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 1 {
+			Env.Fatalln("DSN is required")
+		}
+
+		if dbConvertBatchSize <= 0 {
+			Env.Fatalln("batch-size must be greater than zero")
+		}
+
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.DbConvert(Env, args[0], dbConvertBatchSize)
+	},
+}
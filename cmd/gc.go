@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcBatchSize int
+	gcDryRun    bool
+)
+
+func init() {
+	addLocalIntFlag(&gcBatchSize, "batch-size", "b", 10000, "Number of rows to delete at a time", GcCmd)
+	addLocalBoolFlag(&gcDryRun, "dry-run", "", false, "Report what would be deleted without deleting it", GcCmd)
+	RootCmd.AddCommand(GcCmd)
+}
+
+var GcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete data not used by any project or mosaic, and compact the database",
+	Long:  "Delete covers, macros, aspects, index partials and comparisons not used by any project or mosaic, then compact the database",
+	Run: func(c *cobra.Command, args []string) {
+		if gcBatchSize <= 0 {
+			Env.Fatalln("batch-size must be greater than zero")
+		}
+
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.Gc(Env, gcBatchSize, gcDryRun)
+	},
+}
@@ -8,10 +8,14 @@ import (
 var (
 	macroCoverId int
 	macroOutfile string
+	macroGrout   int
+	macroBleed   int
 )
 
 func init() {
 	addLocalIntFlag(&macroCoverId, "cover-id", "c", 0, "Id of cover to use for macro", MacroCmd)
+	addLocalIntFlag(&macroGrout, "grout", "", 0, "Pixel width of gap between tiles", MacroCmd)
+	addLocalIntFlag(&macroBleed, "bleed", "", 0, "Pixel width of bleed around macro", MacroCmd)
 	addLocalStrFlag(&macroOutfile, "out", "o", "", "Outfile for resized macro image", MacroCmd)
 	RootCmd.AddCommand(MacroCmd)
 }
@@ -34,12 +38,20 @@ var MacroCmd = &cobra.Command{
 			Env.Fatalln("Cover id is required")
 		}
 
+		if macroGrout < 0 {
+			Env.Fatalln("grout cannot be negative")
+		}
+
+		if macroBleed < 0 {
+			Env.Fatalln("bleed cannot be negative")
+		}
+
 		err := Env.Init()
 		if err != nil {
 			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
 		}
 		defer Env.Close()
 
-		controller.Macro(Env, args[0], int64(macroCoverId), macroOutfile)
+		controller.Macro(Env, args[0], int64(macroCoverId), macroGrout, macroBleed, macroOutfile)
 	},
 }
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +14,9 @@ var (
 	macroAspectHeight       int
 	macroAspect             string
 	macroAspectSize         int
+	macroAspectLayout       string
+	macroAspectGrout        int
+	macroAspectBleed        int
 	macroAspectCoverOutfile string
 	macroAspectMacroOutfile string
 )
@@ -22,6 +26,9 @@ func init() {
 	addLocalIntFlag(&macroAspectHeight, "height", "", 0, "Pixel height of cover, 0 maintains aspect from width", MacroAspectCmd)
 	addLocalStrFlag(&macroAspect, "aspect", "a", "1x1", "Aspect of cover partials (CxR)", MacroAspectCmd)
 	addLocalIntFlag(&macroAspectSize, "size", "s", 0, "Number of partials in smallest dimension", MacroAspectCmd)
+	addLocalStrFlag(&macroAspectLayout, "layout", "l", "grid", "Layout of cover partials, one of 'grid', 'brick', 'random' or 'herringbone'", MacroAspectCmd)
+	addLocalIntFlag(&macroAspectGrout, "grout", "", 0, "Pixel width of gap between tiles", MacroAspectCmd)
+	addLocalIntFlag(&macroAspectBleed, "bleed", "", 0, "Pixel width of bleed around macro", MacroAspectCmd)
 	addLocalStrFlag(&macroAspectCoverOutfile, "cover-out", "", "", "File to write resized macro image", MacroAspectCmd)
 	addLocalStrFlag(&macroAspectMacroOutfile, "out", "o", "", "File to write resized macro image", MacroAspectCmd)
 	RootCmd.AddCommand(MacroAspectCmd)
@@ -86,12 +93,24 @@ var MacroAspectCmd = &cobra.Command{
 			Env.Fatalln("num must be greater than zero")
 		}
 
+		if !util.SliceContainsString(controller.CoverAspectLayouts, macroAspectLayout) {
+			Env.Fatalln("Invalid layout")
+		}
+
+		if macroAspectGrout < 0 {
+			Env.Fatalln("grout cannot be negative")
+		}
+
+		if macroAspectBleed < 0 {
+			Env.Fatalln("bleed cannot be negative")
+		}
+
 		err = Env.Init()
 		if err != nil {
 			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
 		}
 		defer Env.Close()
 
-		controller.MacroAspect(Env, args[0], macroAspectWidth, macroAspectHeight, aw, ah, macroAspectSize, macroAspectCoverOutfile, macroAspectMacroOutfile)
+		controller.MacroAspect(Env, args[0], macroAspectWidth, macroAspectHeight, aw, ah, macroAspectSize, macroAspectGrout, macroAspectBleed, macroAspectLayout, macroAspectCoverOutfile, macroAspectMacroOutfile)
 	},
 }
@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
 
 	"github.com/spf13/cobra"
 )
 
 var (
+	macroQuadMode         string
 	macroQuadWidth        int
 	macroQuadHeight       int
 	macroQuadSize         int
@@ -14,11 +16,14 @@ var (
 	macroQuadMaxDepth     int
 	macroQuadMinArea      int
 	macroQuadMaxArea      int
+	macroQuadGrout        int
+	macroQuadBleed        int
 	macroQuadCoverOutfile string
 	macroQuadMacroOutfile string
 )
 
 func init() {
+	addLocalStrFlag(&macroQuadMode, "mode", "m", "quad", "How to split partials, one of 'quad', 'binary' or 'guillotine'", MacroQuadCmd)
 	addLocalIntFlag(&macroQuadWidth, "width", "w", 0, "Pixel width of cover, 0 maintains aspect from height", MacroQuadCmd)
 	addLocalIntFlag(&macroQuadHeight, "height", "", 0, "Pixel height of cover, 0 maintains aspect from width", MacroQuadCmd)
 	addLocalIntFlag(&macroQuadSize, "size", "s", -1, "Number of times to subdivide the image into quads", MacroQuadCmd)
@@ -26,6 +31,8 @@ func init() {
 	addLocalIntFlag(&macroQuadMaxDepth, "max-depth", "", -1, "Maximum depth of quad subdivisions", MacroQuadCmd)
 	addLocalIntFlag(&macroQuadMinArea, "min-area", "", -1, "Minimum area of quad subdivisions", MacroQuadCmd)
 	addLocalIntFlag(&macroQuadMinArea, "max-area", "", -1, "Maxumum area of quad subdivisions", MacroQuadCmd)
+	addLocalIntFlag(&macroQuadGrout, "grout", "", 0, "Pixel width of gap between tiles", MacroQuadCmd)
+	addLocalIntFlag(&macroQuadBleed, "bleed", "", 0, "Pixel width of bleed around macro", MacroQuadCmd)
 	addLocalStrFlag(&macroQuadCoverOutfile, "cover-out", "", "", "File to write cover image", MacroQuadCmd)
 	addLocalStrFlag(&macroQuadMacroOutfile, "out", "o", "", "File to write resized macro image", MacroQuadCmd)
 	RootCmd.AddCommand(MacroQuadCmd)
@@ -53,6 +60,10 @@ var MacroQuadCmd = &cobra.Command{
 			Env.Fatalln("height must be greater than zero")
 		}
 
+		if !util.SliceContainsString(controller.MacroSplitModes, macroQuadMode) {
+			Env.Fatalln("Invalid mode")
+		}
+
 		if macroQuadSize == 0 &&
 			macroQuadMinDepth == 0 &&
 			macroQuadMaxDepth == 0 &&
@@ -61,15 +72,24 @@ var MacroQuadCmd = &cobra.Command{
 			Env.Fatalln("Add least one of size, min-depth, max-depth, min-area, or max-area must be non-zero.")
 		}
 
+		if macroQuadGrout < 0 {
+			Env.Fatalln("grout cannot be negative")
+		}
+
+		if macroQuadBleed < 0 {
+			Env.Fatalln("bleed cannot be negative")
+		}
+
 		err := Env.Init()
 		if err != nil {
 			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
 		}
 		defer Env.Close()
 
-		controller.MacroQuad(
+		controller.MacroSplit(
 			Env,
 			args[0],
+			macroQuadMode,
 			macroQuadWidth,
 			macroQuadHeight,
 			macroQuadSize,
@@ -77,6 +97,8 @@ var MacroQuadCmd = &cobra.Command{
 			macroQuadMaxDepth,
 			macroQuadMinArea,
 			macroQuadMaxArea,
+			macroQuadGrout,
+			macroQuadBleed,
 			macroQuadCoverOutfile,
 			macroQuadMacroOutfile,
 		)
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mosaicAnimateMosaicId   int
+	mosaicAnimateOut        string
+	mosaicAnimateOrder      string
+	mosaicAnimateZoom       bool
+	mosaicAnimateFrames     int
+	mosaicAnimateSize       int
+	mosaicAnimateDelay      int
+	mosaicAnimateGrout      int
+	mosaicAnimateGroutColor string
+)
+
+func init() {
+	addLocalIntFlag(&mosaicAnimateMosaicId, "mosaic-id", "", 0, "Id of mosaic to animate", MosaicAnimateCmd)
+	addLocalStrFlag(&mosaicAnimateOut, "out", "o", "", "Gif file to write, or directory to write png frames to", MosaicAnimateCmd)
+	addLocalStrFlag(&mosaicAnimateOrder, "order", "", "fill", "Order tiles appear in, either 'fill' or 'dist'", MosaicAnimateCmd)
+	addLocalBoolFlag(&mosaicAnimateZoom, "zoom", "", false, "Zoom out from the center tile to the whole mosaic", MosaicAnimateCmd)
+	addLocalIntFlag(&mosaicAnimateFrames, "frames", "", 30, "Number of frames", MosaicAnimateCmd)
+	addLocalIntFlag(&mosaicAnimateSize, "size", "", 480, "Largest pixel width or height of frames", MosaicAnimateCmd)
+	addLocalIntFlag(&mosaicAnimateDelay, "delay", "", 100, "Milliseconds between frames", MosaicAnimateCmd)
+	addLocalIntFlag(&mosaicAnimateGrout, "grout", "", -1, "Pixel width of gap between tiles, -1 uses the grout of the macro", MosaicAnimateCmd)
+	addLocalStrFlag(&mosaicAnimateGroutColor, "grout-color", "", "#ffffff", "Color of grout", MosaicAnimateCmd)
+	MosaicCmd.AddCommand(MosaicAnimateCmd)
+}
+
+var MosaicAnimateCmd = &cobra.Command{
+	Use:   "animate",
+	Short: "Write an animation of a mosaic forming",
+	Long:  "Write an animation of a mosaic forming, as an animated gif or a directory of png frames",
+	Run: func(c *cobra.Command, args []string) {
+		if mosaicAnimateMosaicId == 0 {
+			Env.Fatalln("Mosaic id is required")
+		}
+
+		if mosaicAnimateOut == "" {
+			Env.Fatalln("Out is required")
+		}
+
+		if !util.SliceContainsString(controller.MosaicAnimateOrders, mosaicAnimateOrder) {
+			Env.Fatalln("Invalid order")
+		}
+
+		if mosaicAnimateFrames <= 0 {
+			Env.Fatalln("frames must be greater than zero")
+		}
+
+		if mosaicAnimateSize <= 0 {
+			Env.Fatalln("size must be greater than zero")
+		}
+
+		if mosaicAnimateDelay < 0 {
+			Env.Fatalln("delay cannot be negative")
+		}
+
+		groutColor, err := util.ParseHexColor(mosaicAnimateGroutColor)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		animOpts := controller.MosaicAnimateOptions{
+			Order:  mosaicAnimateOrder,
+			Zoom:   mosaicAnimateZoom,
+			Frames: mosaicAnimateFrames,
+			Size:   mosaicAnimateSize,
+			Delay:  mosaicAnimateDelay,
+		}
+
+		opts := controller.MosaicDrawOptions{
+			Grout:      mosaicAnimateGrout,
+			GroutColor: groutColor,
+		}
+
+		err = Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.MosaicAnimate(Env, int64(mosaicAnimateMosaicId), mosaicAnimateOut, animOpts, opts)
+	},
+}
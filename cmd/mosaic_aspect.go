@@ -5,39 +5,50 @@ import (
 	"strings"
 
 	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
 	"github.com/spf13/cobra"
 )
 
 var (
 	mosaicAspectName          string
+	mosaicAspectOnExists      string
 	mosaicAspectFillType      string
 	mosaicAspectCoverWidth    int
 	mosaicAspectCoverHeight   int
 	mosaicAspectPartialAspect string
 	mosaicAspectSize          int
 	mosaicAspectMaxRepeats    int
+	mosaicAspectKeepTop       int
 	mosaicAspectThreashold    float64
+	mosaicAspectLayout        string
 	mosaicAspectOutfile       string
 	mosaicAspectCoverOutfile  string
 	mosaicAspectMacroOutfile  string
 	mosaicAspectCleanup       bool
 	mosaicAspectDestructive   bool
+	mosaicAspectDraw          = &mosaicDrawFlags{}
+	mosaicAspectPrint         = &mosaicPrintFlags{}
 )
 
 func init() {
 	addLocalStrFlag(&mosaicAspectName, "name", "n", "", "Name of mosaic", MosaicAspectCmd)
+	addOnExistsFlag(&mosaicAspectOnExists, MosaicAspectCmd)
 	addLocalStrFlag(&mosaicAspectFillType, "fill-type", "f", "random", "Mosaic fill to use, either 'random' or 'best'", MosaicAspectCmd)
 	addLocalIntFlag(&mosaicAspectCoverWidth, "width", "w", 0, "Pixel width of mosaic, 0 maintains aspect from image height", MosaicAspectCmd)
 	addLocalIntFlag(&mosaicAspectCoverHeight, "height", "", 0, "Pixel height of mosaic, 0 maintains aspect from width", MosaicAspectCmd)
 	addLocalStrFlag(&mosaicAspectPartialAspect, "aspect", "a", "", "Aspect of mosaic partials (CxR)", MosaicAspectCmd)
 	addLocalIntFlag(&mosaicAspectSize, "size", "s", 0, "Number of mosaic partials in smallest dimension, 0 auto-calculates", MosaicAspectCmd)
 	addLocalIntFlag(&mosaicAspectMaxRepeats, "max-repeats", "", -1, "Number of times an index image can be repeated, 0 is unlimited, -1 is the minimun number", MosaicAspectCmd)
+	addLocalIntFlag(&mosaicAspectKeepTop, "keep-top", "", 0, "Number of closest index images to keep compared with each mosaic partial, 0 keeps all", MosaicAspectCmd)
 	addLocalFloatFlag(&mosaicAspectThreashold, "threashold", "t", -1.0, "How similar aspect ratios must be", MosaicAspectCmd)
-	addLocalStrFlag(&mosaicAspectOutfile, "out", "", "", "File to write final mosaic image", MosaicAspectCmd)
+	addLocalStrFlag(&mosaicAspectLayout, "layout", "l", "grid", "Layout of mosaic partials, one of 'grid', 'brick', 'random' or 'herringbone'", MosaicAspectCmd)
+	addLocalStrFlag(&mosaicAspectOutfile, "out", "", "", "File to write final mosaic image, as .jpg, .png or .tif, with tiff written uncompressed in strips", MosaicAspectCmd)
 	addLocalStrFlag(&mosaicAspectCoverOutfile, "cover-out", "", "", "File to write cover partial pattern image", MosaicAspectCmd)
 	addLocalStrFlag(&mosaicAspectMacroOutfile, "macro-out", "", "", "File to write resized macro image", MosaicAspectCmd)
 	addLocalBoolFlag(&mosaicAspectCleanup, "cleanup", "", false, "Delete mosaic metadata after completion", MosaicAspectCmd)
 	addLocalBoolFlag(&mosaicAspectDestructive, "destructive", "d", false, "Delete mosaic metadata during creation", MosaicAspectCmd)
+	addMosaicDrawFlags(mosaicAspectDraw, 0, "Pixel width of gap between tiles", MosaicAspectCmd)
+	addMosaicPrintFlags(mosaicAspectPrint, MosaicAspectCmd)
 	MosaicCmd.AddCommand(MosaicAspectCmd)
 }
 
@@ -95,31 +106,67 @@ var MosaicAspectCmd = &cobra.Command{
 			}
 		}
 
+		if mosaicAspectKeepTop < 0 {
+			Env.Fatalln("keep-top cannot be negative")
+		}
+
 		if mosaicAspectFillType != "best" && mosaicAspectFillType != "random" {
 			Env.Fatalln("Invalid fill-type")
 		}
 
+		if !util.SliceContainsString(controller.CoverAspectLayouts, mosaicAspectLayout) {
+			Env.Fatalln("Invalid layout")
+		}
+
+		drawOpts, err := mosaicAspectDraw.options()
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		if drawOpts.Grout < 0 {
+			Env.Fatalln("grout cannot be negative")
+		}
+
+		coverWidth, coverHeight, err := mosaicAspectPrint.coverSize(mosaicAspectCoverWidth, mosaicAspectCoverHeight, &drawOpts)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		onExists, err := onExistsAction(mosaicAspectOnExists)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
 		err = Env.Init()
 		if err != nil {
 			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
 		}
 		defer Env.Close()
 
+		onExists, err = askOnExists(args[0], mosaicAspectName, onExists)
+		if err != nil {
+			Env.Fatalf("Error getting project: %s\n", err.Error())
+		}
+
 		controller.MosaicAspect(
 			Env,
 			args[0],
 			mosaicAspectName,
+			onExists,
 			mosaicAspectFillType,
-			mosaicAspectCoverWidth,
-			mosaicAspectCoverHeight,
+			coverWidth,
+			coverHeight,
 			aw,
 			ah,
 			mosaicAspectSize,
 			mosaicAspectMaxRepeats,
+			mosaicAspectKeepTop,
 			mosaicAspectThreashold,
+			mosaicAspectLayout,
 			mosaicAspectCoverOutfile,
 			mosaicAspectMacroOutfile,
 			mosaicAspectOutfile,
+			drawOpts,
 			mosaicAspectCleanup,
 			mosaicAspectDestructive,
 		)
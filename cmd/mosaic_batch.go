@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mosaicBatchLayout       string
+	mosaicBatchOnExists     string
+	mosaicBatchOutDir       string
+	mosaicBatchFillType     string
+	mosaicBatchCoverWidth   int
+	mosaicBatchCoverHeight  int
+	mosaicBatchAspect       string
+	mosaicBatchAspects      string
+	mosaicBatchSize         int
+	mosaicBatchAspectLayout string
+	mosaicBatchMode         string
+	mosaicBatchMinDepth     int
+	mosaicBatchMaxDepth     int
+	mosaicBatchMinArea      int
+	mosaicBatchMaxArea      int
+	mosaicBatchMaxRepeats   int
+	mosaicBatchKeepTop      int
+	mosaicBatchThreashold   float64
+	mosaicBatchCleanup      bool
+	mosaicBatchDestructive  bool
+	mosaicBatchDraw         = &mosaicDrawFlags{}
+	mosaicBatchPrint        = &mosaicPrintFlags{}
+)
+
+func init() {
+	addLocalStrFlag(&mosaicBatchLayout, "layout", "l", "aspect", "Mosaic to create for each image, one of 'aspect', 'mixed', 'quad' or 'split'", MosaicBatchCmd)
+	addOnExistsFlag(&mosaicBatchOnExists, MosaicBatchCmd)
+	addLocalStrFlag(&mosaicBatchOutDir, "out-dir", "o", "", "Directory to write cover, macro and mosaic images, defaults to the directory of each image", MosaicBatchCmd)
+	addLocalStrFlag(&mosaicBatchFillType, "fill-type", "f", "random", "Mosaic fill to use, either 'random' or 'best'", MosaicBatchCmd)
+	addLocalIntFlag(&mosaicBatchCoverWidth, "width", "w", 0, "Pixel width of mosaic, 0 maintains aspect from image height", MosaicBatchCmd)
+	addLocalIntFlag(&mosaicBatchCoverHeight, "height", "", 0, "Pixel height of mosaic, 0 maintains aspect from width", MosaicBatchCmd)
+	addLocalStrFlag(&mosaicBatchAspect, "aspect", "a", "", "Aspect of aspect mosaic partials (CxR)", MosaicBatchCmd)
+	addLocalStrFlag(&mosaicBatchAspects, "aspects", "", "2x3,3x2", "Comma separated aspects of mixed mosaic partials (CxR,CxR)", MosaicBatchCmd)
+	addLocalIntFlag(&mosaicBatchSize, "size", "s", -1, "Size flag of the mosaic sub-command of the layout, -1 uses its default", MosaicBatchCmd)
+	addLocalStrFlag(&mosaicBatchAspectLayout, "aspect-layout", "", "grid", "Layout of aspect mosaic partials, one of 'grid', 'brick', 'random' or 'herringbone'", MosaicBatchCmd)
+	addLocalStrFlag(&mosaicBatchMode, "mode", "m", "binary", "How to split split mosaic partials, one of 'quad', 'binary' or 'guillotine'", MosaicBatchCmd)
+	addLocalIntFlag(&mosaicBatchMinDepth, "min-depth", "", -1, "Minimum number of times all quad or split partials will be split", MosaicBatchCmd)
+	addLocalIntFlag(&mosaicBatchMaxDepth, "max-depth", "", -1, "Number of times a quad or split partial can be split", MosaicBatchCmd)
+	addLocalIntFlag(&mosaicBatchMinArea, "min-area", "", -1, "The smallest a quad or split partial can get before it can't be split", MosaicBatchCmd)
+	addLocalIntFlag(&mosaicBatchMaxArea, "max-area", "", -1, "The largest a quad or split partial can be", MosaicBatchCmd)
+	addLocalIntFlag(&mosaicBatchMaxRepeats, "max-repeats", "", -1, "Number of times an index image can be repeated, 0 is unlimited, -1 is the minimun number", MosaicBatchCmd)
+	addLocalIntFlag(&mosaicBatchKeepTop, "keep-top", "", 0, "Number of closest index images to keep compared with each mosaic partial, 0 keeps all", MosaicBatchCmd)
+	addLocalFloatFlag(&mosaicBatchThreashold, "threashold", "t", -1.0, "How similar aspect ratios must be", MosaicBatchCmd)
+	addLocalBoolFlag(&mosaicBatchCleanup, "cleanup", "", false, "Delete mosaic metadata after completion", MosaicBatchCmd)
+	addLocalBoolFlag(&mosaicBatchDestructive, "destructive", "d", false, "Delete mosaic metadata during creation", MosaicBatchCmd)
+	addMosaicDrawFlags(mosaicBatchDraw, 0, "Pixel width of gap between tiles", MosaicBatchCmd)
+	addMosaicPrintFlags(mosaicBatchPrint, MosaicBatchCmd)
+	MosaicCmd.AddCommand(MosaicBatchCmd)
+}
+
+var MosaicBatchCmd = &cobra.Command{
+	Use:   "batch DIR_OR_LIST",
+	Short: "Create a mosaic from each image in DIR_OR_LIST",
+	Long:  "Create a mosaic from each image in DIR_OR_LIST, which is either a directory of images, or a file listing one image path per line. Each image gets its own project, named after the image, and partials of the index are shared between them. Images that are skipped or fail are reported at the end.",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			Env.Fatalln("Directory or list of images is required")
+		}
+
+		if !util.SliceContainsString(controller.RunJobTypes, mosaicBatchLayout) {
+			Env.Fatalln("Invalid layout")
+		}
+
+		paths, err := controller.MosaicBatchPaths(args[0])
+		if err != nil {
+			Env.Fatalf("Error finding images: %s\n", err.Error())
+		}
+
+		if len(paths) == 0 {
+			Env.Fatalf("No images found in %s\n", args[0])
+		}
+
+		if mosaicBatchOutDir != "" {
+			mosaicBatchOutDir, err = filepath.Abs(mosaicBatchOutDir)
+			if err != nil {
+				Env.Fatalf("Error getting out-dir path: %s\n", err.Error())
+			}
+
+			err = os.MkdirAll(mosaicBatchOutDir, 0755)
+			if err != nil {
+				Env.Fatalf("Error creating out-dir: %s\n", err.Error())
+			}
+		}
+
+		jobs := controller.MosaicBatchJobs(newMosaicBatchTemplate(), paths, mosaicBatchOutDir)
+		for _, job := range jobs {
+			err = job.Prepare()
+			if err != nil {
+				Env.Fatalln(err.Error())
+			}
+		}
+
+		onExists, err := onExistsAction(mosaicBatchOnExists)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		err = Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		for _, job := range jobs {
+			if job.OnExists == "" {
+				job.OnExists, err = askOnExists(job.Path, job.Name, onExists)
+				if err != nil {
+					Env.Fatalf("Error getting project: %s\n", err.Error())
+				}
+			}
+		}
+
+		results, err := controller.MosaicBatch(Env, jobs, onExists)
+		if err != nil {
+			Env.Fatalf("Error creating mosaics: %s\n", err.Error())
+		}
+
+		for _, result := range results {
+			fmt.Println(result)
+		}
+	},
+}
+
+// newMosaicBatchTemplate returns the job copied for each image of the batch,
+// set from the batch flags
+func newMosaicBatchTemplate() *controller.RunJob {
+	job := controller.NewRunJob()
+	job.Type = mosaicBatchLayout
+	job.FillType = mosaicBatchFillType
+	job.Width = mosaicBatchCoverWidth
+	job.Height = mosaicBatchCoverHeight
+	job.Aspect = mosaicBatchAspect
+	job.Aspects = mosaicBatchAspects
+	if mosaicBatchSize != -1 {
+		size := mosaicBatchSize
+		job.Size = &size
+	}
+	job.Layout = mosaicBatchAspectLayout
+	job.Mode = mosaicBatchMode
+	job.MinDepth = mosaicBatchMinDepth
+	job.MaxDepth = mosaicBatchMaxDepth
+	job.MinArea = mosaicBatchMinArea
+	job.MaxArea = mosaicBatchMaxArea
+	job.MaxRepeats = mosaicBatchMaxRepeats
+	job.KeepTop = mosaicBatchKeepTop
+	job.Threashold = mosaicBatchThreashold
+	job.Cleanup = mosaicBatchCleanup
+	job.Destructive = mosaicBatchDestructive
+
+	job.Grout = mosaicBatchDraw.grout
+	job.GroutColor = mosaicBatchDraw.groutColor
+	job.TileRadius = mosaicBatchDraw.tileRadius
+	job.OuterBorder = mosaicBatchDraw.outerBorder
+	job.DeepZoom = mosaicBatchDraw.deepZoom
+	job.DeepZoomFormat = mosaicBatchDraw.deepZoomFmt
+	job.DeepZoomScale = mosaicBatchDraw.deepZoomScl
+	job.Metadata = mosaicBatchDraw.metadata
+	job.DPI = mosaicBatchDraw.dpi
+	job.CropMarks = mosaicBatchDraw.cropMarks
+	job.PrintSize = mosaicBatchPrint.printSize
+	job.Bleed = mosaicBatchPrint.bleed
+
+	return job
+}
@@ -2,15 +2,74 @@ package cmd
 
 import (
 	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
 	"github.com/spf13/cobra"
 )
 
 var (
 	mosaicDrawMosaicId int
+	mosaicDrawDraw     = &mosaicDrawFlags{}
 )
 
+// mosaicDrawFlags are the flags shared by commands that draw a mosaic
+type mosaicDrawFlags struct {
+	grout       int
+	groutColor  string
+	tileRadius  int
+	outerBorder int
+	deepZoom    string
+	deepZoomFmt string
+	deepZoomScl int
+	metadata    string
+	dpi         int
+	cropMarks   bool
+}
+
+func addMosaicDrawFlags(f *mosaicDrawFlags, groutDefault int, groutDesc string, cmd *cobra.Command) {
+	addLocalIntFlag(&f.grout, "grout", "", groutDefault, groutDesc, cmd)
+	addLocalStrFlag(&f.groutColor, "grout-color", "", "#ffffff", "Color of grout, tile corners and outer border", cmd)
+	addLocalIntFlag(&f.tileRadius, "tile-radius", "", 0, "Pixel radius of rounded tile corners", cmd)
+	addLocalIntFlag(&f.outerBorder, "outer-border", "", 0, "Pixel width of border around mosaic", cmd)
+	addLocalStrFlag(&f.deepZoom, "deepzoom", "", "", "Directory to write deep zoom tile pyramid", cmd)
+	addLocalStrFlag(&f.deepZoomFmt, "deepzoom-format", "", "dzi", "Format of deep zoom tile pyramid, either 'dzi' or 'xyz'", cmd)
+	addLocalIntFlag(&f.deepZoomScl, "deepzoom-scale", "", 0, "Scale of deepest zoom level relative to mosaic, 0 auto-calculates", cmd)
+	addLocalIntFlag(&f.dpi, "dpi", "", util.DefaultDPI, "Print resolution of mosaic in dots per inch", cmd)
+	addLocalBoolFlag(&f.cropMarks, "crop-marks", "", false, "Draw crop marks in the bleed", cmd)
+	addLocalStrFlag(&f.metadata, "metadata", "", "safe", "Metadata to copy from input image, one of 'all', 'safe' or 'none'", cmd)
+}
+
+func (f *mosaicDrawFlags) options() (controller.MosaicDrawOptions, error) {
+	opts := controller.MosaicDrawOptions{
+		Grout:       f.grout,
+		TileRadius:  f.tileRadius,
+		OuterBorder: f.outerBorder,
+
+		DeepZoom:       f.deepZoom,
+		DeepZoomFormat: f.deepZoomFmt,
+		DeepZoomScale:  f.deepZoomScl,
+
+		Metadata:  f.metadata,
+		DPI:       f.dpi,
+		CropMarks: f.cropMarks,
+	}
+
+	err := opts.Validate()
+	if err != nil {
+		return opts, err
+	}
+
+	groutColor, err := util.ParseHexColor(f.groutColor)
+	if err != nil {
+		return opts, err
+	}
+	opts.GroutColor = groutColor
+
+	return opts, nil
+}
+
 func init() {
 	addLocalIntFlag(&mosaicDrawMosaicId, "mosaic-id", "", 0, "Id of mosaic to draw", MosaicDrawCmd)
+	addMosaicDrawFlags(mosaicDrawDraw, -1, "Pixel width of gap between tiles, -1 uses the grout of the macro", MosaicDrawCmd)
 	RootCmd.AddCommand(MosaicDrawCmd)
 }
 
@@ -32,12 +91,17 @@ var MosaicDrawCmd = &cobra.Command{
 			Env.Fatalln("Mosaic id is required")
 		}
 
-		err := Env.Init()
+		opts, err := mosaicDrawDraw.options()
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		err = Env.Init()
 		if err != nil {
 			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
 		}
 		defer Env.Close()
 
-		controller.MosaicDraw(Env, int64(mosaicDrawMosaicId), args[0])
+		controller.MosaicDraw(Env, int64(mosaicDrawMosaicId), args[0], opts)
 	},
 }
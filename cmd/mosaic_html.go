@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mosaicHtmlMosaicId int
+	mosaicHtmlDraw     = &mosaicDrawFlags{}
+)
+
+func init() {
+	addLocalIntFlag(&mosaicHtmlMosaicId, "mosaic-id", "", 0, "Id of mosaic to view", MosaicHtmlCmd)
+	addMosaicDrawFlags(mosaicHtmlDraw, -1, "Pixel width of gap between tiles, -1 uses the grout of the macro", MosaicHtmlCmd)
+	MosaicCmd.AddCommand(MosaicHtmlCmd)
+}
+
+var MosaicHtmlCmd = &cobra.Command{
+	Use:   "html OUTDIR",
+	Short: "Write an html viewer of a mosaic to OUTDIR",
+	Long:  "Write an html viewer of a mosaic to OUTDIR, which shows the index image of each tile",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			Env.Fatalln("Out directory is required")
+		}
+
+		if mosaicHtmlMosaicId == 0 {
+			Env.Fatalln("Mosaic id is required")
+		}
+
+		opts, err := mosaicHtmlDraw.options()
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		err = Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.MosaicHtml(Env, int64(mosaicHtmlMosaicId), args[0], opts)
+	},
+}
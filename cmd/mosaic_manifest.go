@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mosaicManifestMosaicId int
+	mosaicManifestFormat   string
+)
+
+func init() {
+	addLocalIntFlag(&mosaicManifestMosaicId, "mosaic-id", "", 0, "Id of mosaic to export", MosaicManifestCmd)
+	addLocalStrFlag(&mosaicManifestFormat, "format", "f", "csv", "Format of manifest, either 'csv' or 'json'", MosaicManifestCmd)
+	MosaicCmd.AddCommand(MosaicManifestCmd)
+}
+
+var MosaicManifestCmd = &cobra.Command{
+	Use:   "manifest [OUTFILE]",
+	Short: "Export the index image of every mosaic tile to OUTFILE, or stdout",
+	Long:  "Export the index image of every mosaic tile to OUTFILE, or stdout",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) > 1 {
+			Env.Fatalln("Only one out file is allowed")
+		}
+
+		if mosaicManifestMosaicId == 0 {
+			Env.Fatalln("Mosaic id is required")
+		}
+
+		if !util.SliceContainsString(controller.MosaicManifestFormats, mosaicManifestFormat) {
+			Env.Fatalln("Invalid format")
+		}
+
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		var w io.Writer = os.Stdout
+		if len(args) == 1 && args[0] != "" {
+			f, err := os.Create(args[0])
+			if err != nil {
+				Env.Fatalf("Unable to create out file: %s\n", err.Error())
+			}
+			defer f.Close()
+			w = f
+		}
+
+		err = controller.MosaicManifest(Env, int64(mosaicManifestMosaicId), mosaicManifestFormat, w)
+		if err != nil {
+			Env.Printf("Error exporting mosaic manifest: %s\n", err.Error())
+		}
+	},
+}
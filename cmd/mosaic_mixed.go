@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mosaicMixedName         string
+	mosaicMixedOnExists     string
+	mosaicMixedFillType     string
+	mosaicMixedCoverWidth   int
+	mosaicMixedCoverHeight  int
+	mosaicMixedAspects      string
+	mosaicMixedSize         int
+	mosaicMixedMaxRepeats   int
+	mosaicMixedKeepTop      int
+	mosaicMixedThreashold   float64
+	mosaicMixedOutfile      string
+	mosaicMixedCoverOutfile string
+	mosaicMixedMacroOutfile string
+	mosaicMixedCleanup      bool
+	mosaicMixedDestructive  bool
+	mosaicMixedDraw         = &mosaicDrawFlags{}
+	mosaicMixedPrint        = &mosaicPrintFlags{}
+)
+
+func init() {
+	addLocalStrFlag(&mosaicMixedName, "name", "n", "", "Name of mosaic", MosaicMixedCmd)
+	addOnExistsFlag(&mosaicMixedOnExists, MosaicMixedCmd)
+	addLocalStrFlag(&mosaicMixedFillType, "fill-type", "f", "random", "Mosaic fill to use, either 'random' or 'best'", MosaicMixedCmd)
+	addLocalIntFlag(&mosaicMixedCoverWidth, "width", "w", 0, "Pixel width of mosaic, 0 maintains aspect from image height", MosaicMixedCmd)
+	addLocalIntFlag(&mosaicMixedCoverHeight, "height", "", 0, "Pixel height of mosaic, 0 maintains aspect from width", MosaicMixedCmd)
+	addLocalStrFlag(&mosaicMixedAspects, "aspects", "a", "2x3,3x2", "Comma separated aspects of mosaic partials (CxR,CxR)", MosaicMixedCmd)
+	addLocalIntFlag(&mosaicMixedSize, "size", "s", 0, "Approximate number of mosaic partials in smallest dimension, 0 auto-calculates", MosaicMixedCmd)
+	addLocalIntFlag(&mosaicMixedMaxRepeats, "max-repeats", "", -1, "Number of times an index image can be repeated, 0 is unlimited, -1 is the minimun number", MosaicMixedCmd)
+	addLocalIntFlag(&mosaicMixedKeepTop, "keep-top", "", 0, "Number of closest index images to keep compared with each mosaic partial, 0 keeps all", MosaicMixedCmd)
+	addLocalFloatFlag(&mosaicMixedThreashold, "threashold", "t", -1.0, "How similar aspect ratios must be", MosaicMixedCmd)
+	addLocalStrFlag(&mosaicMixedOutfile, "out", "", "", "File to write final mosaic image, as .jpg, .png or .tif, with tiff written uncompressed in strips", MosaicMixedCmd)
+	addLocalStrFlag(&mosaicMixedCoverOutfile, "cover-out", "", "", "File to write cover partial pattern image", MosaicMixedCmd)
+	addLocalStrFlag(&mosaicMixedMacroOutfile, "macro-out", "", "", "File to write resized macro image", MosaicMixedCmd)
+	addLocalBoolFlag(&mosaicMixedCleanup, "cleanup", "", false, "Delete mosaic metadata after completion", MosaicMixedCmd)
+	addLocalBoolFlag(&mosaicMixedDestructive, "destructive", "d", false, "Delete mosaic metadata during creation", MosaicMixedCmd)
+	addMosaicDrawFlags(mosaicMixedDraw, 0, "Pixel width of gap between tiles", MosaicMixedCmd)
+	addMosaicPrintFlags(mosaicMixedPrint, MosaicMixedCmd)
+	MosaicCmd.AddCommand(MosaicMixedCmd)
+}
+
+var MosaicMixedCmd = &cobra.Command{
+	Use:   "mixed PATH",
+	Short: "Create a mixed portrait and landscape mosaic from image at PATH",
+	Long:  "Create a mixed portrait and landscape mosaic from image at PATH",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 1 {
+			Env.Fatalln("Mosaic path is required")
+		}
+
+		if args[0] == "" {
+			Env.Fatalln("Mosaic path is required")
+		}
+
+		if mosaicMixedCoverWidth < 0 {
+			Env.Fatalln("width must be greater than zero")
+		}
+
+		if mosaicMixedCoverHeight < 0 {
+			Env.Fatalln("height must be greater than zero")
+		}
+
+		aspects, err := controller.ParseAspects(mosaicMixedAspects)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		if mosaicMixedKeepTop < 0 {
+			Env.Fatalln("keep-top cannot be negative")
+		}
+
+		if mosaicMixedFillType != "best" && mosaicMixedFillType != "random" {
+			Env.Fatalln("Invalid fill-type")
+		}
+
+		drawOpts, err := mosaicMixedDraw.options()
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		if drawOpts.Grout < 0 {
+			Env.Fatalln("grout cannot be negative")
+		}
+
+		coverWidth, coverHeight, err := mosaicMixedPrint.coverSize(mosaicMixedCoverWidth, mosaicMixedCoverHeight, &drawOpts)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		onExists, err := onExistsAction(mosaicMixedOnExists)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		err = Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		onExists, err = askOnExists(args[0], mosaicMixedName, onExists)
+		if err != nil {
+			Env.Fatalf("Error getting project: %s\n", err.Error())
+		}
+
+		controller.MosaicMixed(
+			Env,
+			args[0],
+			mosaicMixedName,
+			onExists,
+			mosaicMixedFillType,
+			coverWidth,
+			coverHeight,
+			aspects,
+			mosaicMixedSize,
+			mosaicMixedMaxRepeats,
+			mosaicMixedKeepTop,
+			mosaicMixedThreashold,
+			mosaicMixedCoverOutfile,
+			mosaicMixedMacroOutfile,
+			mosaicMixedOutfile,
+			drawOpts,
+			mosaicMixedCleanup,
+			mosaicMixedDestructive,
+		)
+	},
+}
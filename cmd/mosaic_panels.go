@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mosaicPanelsMosaicId int
+	mosaicPanelsGrid     string
+	mosaicPanelsOverlap  string
+	mosaicPanelsFormat   string
+	mosaicPanelsMarks    bool
+	mosaicPanelsLabels   bool
+	mosaicPanelsDraw     = &mosaicDrawFlags{}
+)
+
+func init() {
+	addLocalIntFlag(&mosaicPanelsMosaicId, "mosaic-id", "", 0, "Id of mosaic to split into panels", MosaicPanelsCmd)
+	addLocalStrFlag(&mosaicPanelsGrid, "grid", "", "2x2", "Grid of panels as COLSxROWS", MosaicPanelsCmd)
+	addLocalStrFlag(&mosaicPanelsOverlap, "overlap", "", "0", "Physical width of overlap between panels, with unit 'in', 'cm' or 'mm'", MosaicPanelsCmd)
+	addLocalStrFlag(&mosaicPanelsFormat, "format", "f", "pdf", "Format of panels, either 'pdf' or 'jpg'", MosaicPanelsCmd)
+	addLocalBoolFlag(&mosaicPanelsMarks, "marks", "", false, "Draw alignment marks in the overlap between panels", MosaicPanelsCmd)
+	addLocalBoolFlag(&mosaicPanelsLabels, "labels", "", false, "Add a label below each panel with its position", MosaicPanelsCmd)
+	addMosaicDrawFlags(mosaicPanelsDraw, -1, "Pixel width of gap between tiles, -1 uses the grout of the macro", MosaicPanelsCmd)
+	MosaicCmd.AddCommand(MosaicPanelsCmd)
+}
+
+var MosaicPanelsCmd = &cobra.Command{
+	Use:   "panels OUT",
+	Short: "Split a mosaic into printable panels",
+	Long:  "Split a mosaic into a grid of printable panels, written to OUT as a pdf file with a page per panel, or a directory of jpg images",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			Env.Fatalln("Out file is required")
+		}
+
+		if mosaicPanelsMosaicId == 0 {
+			Env.Fatalln("Mosaic id is required")
+		}
+
+		if !util.SliceContainsString(controller.MosaicPanelFormats, mosaicPanelsFormat) {
+			Env.Fatalln("Invalid format")
+		}
+
+		opts, err := mosaicPanelsDraw.options()
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		cols, rows, err := util.ParseGrid(mosaicPanelsGrid)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		overlap, err := util.ParseLength(mosaicPanelsOverlap)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		panelOpts := controller.MosaicPanelOptions{
+			Cols:    cols,
+			Rows:    rows,
+			Overlap: util.InchesToPixels(overlap, opts.DPI),
+			Format:  mosaicPanelsFormat,
+			Marks:   mosaicPanelsMarks,
+			Labels:  mosaicPanelsLabels,
+		}
+
+		err = Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.MosaicPanels(Env, int64(mosaicPanelsMosaicId), args[0], panelOpts, opts)
+	},
+}
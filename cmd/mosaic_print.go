@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+// mosaicPrintFlags are the flags shared by commands that size a mosaic for print
+type mosaicPrintFlags struct {
+	printSize string
+	bleed     string
+}
+
+func addMosaicPrintFlags(f *mosaicPrintFlags, cmd *cobra.Command) {
+	addLocalStrFlag(&f.printSize, "print-size", "", "", "Physical size of mosaic as WxH with unit 'in', 'cm' or 'mm', or a preset name", cmd)
+	addLocalStrFlag(&f.bleed, "bleed", "", "", "Physical width of bleed around mosaic, with unit 'in', 'cm' or 'mm'", cmd)
+}
+
+// coverSize returns the pixel size of the cover, from either the physical
+// print size or the width and height, with the bleed added around it.
+// It sets the bleed of opts, and uses its dpi.
+func (f *mosaicPrintFlags) coverSize(width, height int, opts *controller.MosaicDrawOptions) (int, int, error) {
+	return controller.PrintCoverSize(width, height, f.printSize, f.bleed, opts)
+}
@@ -7,6 +7,7 @@ import (
 
 var (
 	mosaicQuadName         string
+	mosaicQuadOnExists     string
 	mosaicQuadFillType     string
 	mosaicQuadCoverWidth   int
 	mosaicQuadCoverHeight  int
@@ -16,16 +17,20 @@ var (
 	mosaicQuadMinArea      int
 	mosaicQuadMaxArea      int
 	mosaicQuadMaxRepeats   int
+	mosaicQuadKeepTop      int
 	mosaicQuadThreashold   float64
 	mosaicQuadOutfile      string
 	mosaicQuadCoverOutfile string
 	mosaicQuadMacroOutfile string
 	mosaicQuadCleanup      bool
 	mosaicQuadDestructive  bool
+	mosaicQuadDraw         = &mosaicDrawFlags{}
+	mosaicQuadPrint        = &mosaicPrintFlags{}
 )
 
 func init() {
 	addLocalStrFlag(&mosaicQuadName, "name", "n", "", "Name of mosaic", MosaicQuadCmd)
+	addOnExistsFlag(&mosaicQuadOnExists, MosaicQuadCmd)
 	addLocalStrFlag(&mosaicQuadFillType, "fill-type", "f", "random", "Mosaic fill to use, either 'random' or 'best'", MosaicQuadCmd)
 	addLocalIntFlag(&mosaicQuadCoverWidth, "width", "w", 0, "Pixel width of mosaic, 0 maintains aspect from image height", MosaicQuadCmd)
 	addLocalIntFlag(&mosaicQuadCoverHeight, "height", "", 0, "Pixel height of mosaic, 0 maintains aspect from width", MosaicQuadCmd)
@@ -35,12 +40,15 @@ func init() {
 	addLocalIntFlag(&mosaicQuadMinArea, "min-area", "", -1, "The smallest a partial can get before it can't be split", MosaicQuadCmd)
 	addLocalIntFlag(&mosaicQuadMaxArea, "max-area", "", -1, "The largest a partial can be", MosaicQuadCmd)
 	addLocalIntFlag(&mosaicQuadMaxRepeats, "max-repeats", "", -1, "Number of times an index image can be repeated, 0 is unlimited, -1 is the minimun number", MosaicQuadCmd)
+	addLocalIntFlag(&mosaicQuadKeepTop, "keep-top", "", 0, "Number of closest index images to keep compared with each mosaic partial, 0 keeps all", MosaicQuadCmd)
 	addLocalFloatFlag(&mosaicQuadThreashold, "threashold", "t", -1.0, "How similar aspect ratios must be", MosaicQuadCmd)
-	addLocalStrFlag(&mosaicQuadOutfile, "out", "o", "", "File to write final mosaic image", MosaicQuadCmd)
+	addLocalStrFlag(&mosaicQuadOutfile, "out", "o", "", "File to write final mosaic image, as .jpg, .png or .tif, with tiff written uncompressed in strips", MosaicQuadCmd)
 	addLocalStrFlag(&mosaicQuadCoverOutfile, "cover-out", "", "", "File to write cover partial pattern image", MosaicQuadCmd)
 	addLocalStrFlag(&mosaicQuadMacroOutfile, "macro-out", "", "", "File to write resized macro image", MosaicQuadCmd)
 	addLocalBoolFlag(&mosaicQuadCleanup, "cleanup", "", false, "Delete mosaic metadata after completion", MosaicQuadCmd)
 	addLocalBoolFlag(&mosaicQuadDestructive, "destructive", "d", false, "Delete mosaic metadata during creation", MosaicQuadCmd)
+	addMosaicDrawFlags(mosaicQuadDraw, 0, "Pixel width of gap between tiles", MosaicQuadCmd)
+	addMosaicPrintFlags(mosaicQuadPrint, MosaicQuadCmd)
 	MosaicCmd.AddCommand(MosaicQuadCmd)
 }
 
@@ -65,6 +73,10 @@ var MosaicQuadCmd = &cobra.Command{
 			Env.Fatalln("height must be greater than zero")
 		}
 
+		if mosaicQuadKeepTop < 0 {
+			Env.Fatalln("keep-top cannot be negative")
+		}
+
 		if mosaicQuadFillType != "best" && mosaicAspectFillType != "random" {
 			Env.Fatalln("Invalid fill-type")
 		}
@@ -77,29 +89,56 @@ var MosaicQuadCmd = &cobra.Command{
 			Env.Fatalln("Add least one of size, min-depth, max-depth, min-area, or max-area must be non-zero.")
 		}
 
-		err := Env.Init()
+		drawOpts, err := mosaicQuadDraw.options()
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		if drawOpts.Grout < 0 {
+			Env.Fatalln("grout cannot be negative")
+		}
+
+		coverWidth, coverHeight, err := mosaicQuadPrint.coverSize(mosaicQuadCoverWidth, mosaicQuadCoverHeight, &drawOpts)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		onExists, err := onExistsAction(mosaicQuadOnExists)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		err = Env.Init()
 		if err != nil {
 			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
 		}
 		defer Env.Close()
 
+		onExists, err = askOnExists(args[0], mosaicQuadName, onExists)
+		if err != nil {
+			Env.Fatalf("Error getting project: %s\n", err.Error())
+		}
+
 		controller.MosaicQuad(
 			Env,
 			args[0],
 			mosaicQuadName,
+			onExists,
 			mosaicQuadFillType,
-			mosaicQuadCoverWidth,
-			mosaicQuadCoverHeight,
+			coverWidth,
+			coverHeight,
 			mosaicQuadSize,
 			mosaicQuadMinDepth,
 			mosaicQuadMaxDepth,
 			mosaicQuadMinArea,
 			mosaicQuadMaxArea,
 			mosaicQuadMaxRepeats,
+			mosaicQuadKeepTop,
 			mosaicQuadThreashold,
 			mosaicQuadCoverOutfile,
 			mosaicQuadMacroOutfile,
 			mosaicQuadOutfile,
+			drawOpts,
 			mosaicQuadCleanup,
 			mosaicQuadDestructive,
 		)
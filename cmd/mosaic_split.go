@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mosaicSplitName         string
+	mosaicSplitOnExists     string
+	mosaicSplitFillType     string
+	mosaicSplitMode         string
+	mosaicSplitCoverWidth   int
+	mosaicSplitCoverHeight  int
+	mosaicSplitSize         int
+	mosaicSplitMinDepth     int
+	mosaicSplitMaxDepth     int
+	mosaicSplitMinArea      int
+	mosaicSplitMaxArea      int
+	mosaicSplitMaxRepeats   int
+	mosaicSplitKeepTop      int
+	mosaicSplitThreashold   float64
+	mosaicSplitOutfile      string
+	mosaicSplitCoverOutfile string
+	mosaicSplitMacroOutfile string
+	mosaicSplitCleanup      bool
+	mosaicSplitDestructive  bool
+	mosaicSplitDraw         = &mosaicDrawFlags{}
+	mosaicSplitPrint        = &mosaicPrintFlags{}
+)
+
+func init() {
+	addLocalStrFlag(&mosaicSplitName, "name", "n", "", "Name of mosaic", MosaicSplitCmd)
+	addOnExistsFlag(&mosaicSplitOnExists, MosaicSplitCmd)
+	addLocalStrFlag(&mosaicSplitFillType, "fill-type", "f", "random", "Mosaic fill to use, either 'random' or 'best'", MosaicSplitCmd)
+	addLocalStrFlag(&mosaicSplitMode, "mode", "m", "binary", "How to split partials, one of 'quad', 'binary' or 'guillotine'", MosaicSplitCmd)
+	addLocalIntFlag(&mosaicSplitCoverWidth, "width", "w", 0, "Pixel width of mosaic, 0 maintains aspect from image height", MosaicSplitCmd)
+	addLocalIntFlag(&mosaicSplitCoverHeight, "height", "", 0, "Pixel height of mosaic, 0 maintains aspect from width", MosaicSplitCmd)
+	addLocalIntFlag(&mosaicSplitSize, "size", "s", -1, "Number of times to split partials", MosaicSplitCmd)
+	addLocalIntFlag(&mosaicSplitMinDepth, "min-depth", "", -1, "Minimum number of times all partials will be split", MosaicSplitCmd)
+	addLocalIntFlag(&mosaicSplitMaxDepth, "max-depth", "", -1, "Number of times a partial can be split", MosaicSplitCmd)
+	addLocalIntFlag(&mosaicSplitMinArea, "min-area", "", -1, "The smallest a partial can get before it can't be split", MosaicSplitCmd)
+	addLocalIntFlag(&mosaicSplitMaxArea, "max-area", "", -1, "The largest a partial can be", MosaicSplitCmd)
+	addLocalIntFlag(&mosaicSplitMaxRepeats, "max-repeats", "", -1, "Number of times an index image can be repeated, 0 is unlimited, -1 is the minimun number", MosaicSplitCmd)
+	addLocalIntFlag(&mosaicSplitKeepTop, "keep-top", "", 0, "Number of closest index images to keep compared with each mosaic partial, 0 keeps all", MosaicSplitCmd)
+	addLocalFloatFlag(&mosaicSplitThreashold, "threashold", "t", -1.0, "How similar aspect ratios must be", MosaicSplitCmd)
+	addLocalStrFlag(&mosaicSplitOutfile, "out", "o", "", "File to write final mosaic image, as .jpg, .png or .tif, with tiff written uncompressed in strips", MosaicSplitCmd)
+	addLocalStrFlag(&mosaicSplitCoverOutfile, "cover-out", "", "", "File to write cover partial pattern image", MosaicSplitCmd)
+	addLocalStrFlag(&mosaicSplitMacroOutfile, "macro-out", "", "", "File to write resized macro image", MosaicSplitCmd)
+	addLocalBoolFlag(&mosaicSplitCleanup, "cleanup", "", false, "Delete mosaic metadata after completion", MosaicSplitCmd)
+	addLocalBoolFlag(&mosaicSplitDestructive, "destructive", "d", false, "Delete mosaic metadata during creation", MosaicSplitCmd)
+	addMosaicDrawFlags(mosaicSplitDraw, 0, "Pixel width of gap between tiles", MosaicSplitCmd)
+	addMosaicPrintFlags(mosaicSplitPrint, MosaicSplitCmd)
+	MosaicCmd.AddCommand(MosaicSplitCmd)
+}
+
+var MosaicSplitCmd = &cobra.Command{
+	Use:   "split PATH",
+	Short: "Create binary or guillotine split mosaic from image at PATH",
+	Long:  "Create binary or guillotine split mosaic from image at PATH",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 1 {
+			Env.Fatalln("Mosaic path is required")
+		}
+
+		if args[0] == "" {
+			Env.Fatalln("Mosaic path is required")
+		}
+
+		if mosaicSplitCoverWidth < 0 {
+			Env.Fatalln("width must be greater than zero")
+		}
+
+		if mosaicSplitCoverHeight < 0 {
+			Env.Fatalln("height must be greater than zero")
+		}
+
+		if mosaicSplitKeepTop < 0 {
+			Env.Fatalln("keep-top cannot be negative")
+		}
+
+		if mosaicSplitFillType != "best" && mosaicSplitFillType != "random" {
+			Env.Fatalln("Invalid fill-type")
+		}
+
+		if !util.SliceContainsString(controller.MacroSplitModes, mosaicSplitMode) {
+			Env.Fatalln("Invalid mode")
+		}
+
+		if mosaicSplitSize == 0 &&
+			mosaicSplitMinDepth == 0 &&
+			mosaicSplitMaxDepth == 0 &&
+			mosaicSplitMinArea == 0 &&
+			mosaicSplitMaxArea == 0 {
+			Env.Fatalln("Add least one of size, min-depth, max-depth, min-area, or max-area must be non-zero.")
+		}
+
+		drawOpts, err := mosaicSplitDraw.options()
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		if drawOpts.Grout < 0 {
+			Env.Fatalln("grout cannot be negative")
+		}
+
+		coverWidth, coverHeight, err := mosaicSplitPrint.coverSize(mosaicSplitCoverWidth, mosaicSplitCoverHeight, &drawOpts)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		onExists, err := onExistsAction(mosaicSplitOnExists)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		err = Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		onExists, err = askOnExists(args[0], mosaicSplitName, onExists)
+		if err != nil {
+			Env.Fatalf("Error getting project: %s\n", err.Error())
+		}
+
+		controller.MosaicSplit(
+			Env,
+			args[0],
+			mosaicSplitName,
+			onExists,
+			mosaicSplitFillType,
+			mosaicSplitMode,
+			coverWidth,
+			coverHeight,
+			mosaicSplitSize,
+			mosaicSplitMinDepth,
+			mosaicSplitMaxDepth,
+			mosaicSplitMinArea,
+			mosaicSplitMaxArea,
+			mosaicSplitMaxRepeats,
+			mosaicSplitKeepTop,
+			mosaicSplitThreashold,
+			mosaicSplitCoverOutfile,
+			mosaicSplitMacroOutfile,
+			mosaicSplitOutfile,
+			drawOpts,
+			mosaicSplitCleanup,
+			mosaicSplitDestructive,
+		)
+	},
+}
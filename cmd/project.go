@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/atongen/gosaic/controller"
+	"github.com/atongen/gosaic/util"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func init() {
+	RootCmd.AddCommand(ProjectCmd)
+}
+
+var ProjectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Manage mosaic projects",
+	Long:  "Manage mosaic projects",
+}
+
+func addOnExistsFlag(onExists *string, cmd *cobra.Command) {
+	addLocalStrFlag(onExists, "on-exists", "", "", "Action when a project with the name already exists, one of 'resume', 'rebuild', 'abort' or 'new', empty asks, or aborts when stdin is not a terminal", cmd)
+}
+
+// onExistsAction validates the on-exists flag, and returns the action
+// to take, which is abort when it is empty and there is no one to ask
+func onExistsAction(onExists string) (string, error) {
+	if onExists == "" {
+		if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+			return "abort", nil
+		}
+		return "", nil
+	}
+
+	if !util.SliceContainsString(controller.ProjectOnExists, onExists) {
+		return "", errors.New("Invalid on-exists")
+	}
+
+	return onExists, nil
+}
+
+// askOnExists resolves an empty on-exists action for a mosaic of the image
+// at path named name, by asking whether to continue the project when one
+// already exists. Declining aborts.
+func askOnExists(path, name, onExists string) (string, error) {
+	if onExists != "" {
+		return onExists, nil
+	}
+
+	project, err := controller.ExistingProject(Env, path, name)
+	if err != nil || project == nil {
+		return onExists, err
+	}
+
+	var status string
+	if project.IsComplete {
+		status = "Complete"
+		onExists = "rebuild"
+	} else {
+		status = "Incomplete"
+		onExists = "resume"
+	}
+	fmt.Printf("%s project with name '%s' already exists. Type 'Y' to %s, or anything else to abort [Y,n]: ", status, project.Name, onExists)
+	var confirm string
+	fmt.Scanln(&confirm)
+	if confirm != "Y" {
+		Env.Printf("Not attempting to %s project.\n", onExists)
+		return "abort", nil
+	}
+
+	return onExists, nil
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	ProjectCmd.AddCommand(ProjectListCmd)
+}
+
+var ProjectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List projects, newest first",
+	Long:  "List projects, newest first",
+	Run: func(c *cobra.Command, args []string) {
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.ProjectList(Env, os.Stdout)
+	},
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	ProjectCmd.AddCommand(ProjectRenameCmd)
+}
+
+var ProjectRenameCmd = &cobra.Command{
+	Use:   "rename NAME NEW_NAME",
+	Short: "Rename project NAME, or id, to NEW_NAME",
+	Long:  "Rename project NAME, or id, to NEW_NAME",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 2 || args[0] == "" || args[1] == "" {
+			Env.Fatalln("Project name and new name are required")
+		}
+
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.ProjectRename(Env, args[0], args[1])
+	},
+}
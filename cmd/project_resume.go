@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+var (
+	projectResumeFillType    string
+	projectResumeMaxRepeats  int
+	projectResumeKeepTop     int
+	projectResumeThreashold  float64
+	projectResumeDestructive bool
+	projectResumeDraw        = &mosaicDrawFlags{}
+)
+
+func init() {
+	addLocalStrFlag(&projectResumeFillType, "fill-type", "f", "random", "Mosaic fill to use, either 'random' or 'best', defaults to that of the project", ProjectResumeCmd)
+	addLocalIntFlag(&projectResumeMaxRepeats, "max-repeats", "", -1, "Number of times an index image can be repeated, 0 is unlimited, -1 is the minimun number, defaults to that of the project", ProjectResumeCmd)
+	addLocalIntFlag(&projectResumeKeepTop, "keep-top", "", 0, "Number of closest index images to keep compared with each mosaic partial, 0 keeps all, defaults to that of the project", ProjectResumeCmd)
+	addLocalFloatFlag(&projectResumeThreashold, "threashold", "t", -1.0, "How similar aspect ratios must be, defaults to that of the project", ProjectResumeCmd)
+	addLocalBoolFlag(&projectResumeDestructive, "destructive", "d", false, "Delete mosaic metadata during creation, defaults to that of the project", ProjectResumeCmd)
+	addMosaicDrawFlags(projectResumeDraw, -1, "Pixel width of gap between tiles, -1 uses the grout of the macro", ProjectResumeCmd)
+	ProjectCmd.AddCommand(ProjectResumeCmd)
+}
+
+var ProjectResumeCmd = &cobra.Command{
+	Use:   "resume NAME",
+	Short: "Resume building project NAME, or id, from its first unfinished stage",
+	Long:  "Resume building project NAME, or id, from its first unfinished stage, with the parameters it was built with, overridden by the flags that are set",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			Env.Fatalln("Project name is required")
+		}
+
+		if projectResumeKeepTop < 0 {
+			Env.Fatalln("keep-top cannot be negative")
+		}
+
+		if projectResumeFillType != "best" && projectResumeFillType != "random" {
+			Env.Fatalln("Invalid fill-type")
+		}
+
+		drawOpts, err := projectResumeDraw.options()
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		opts := controller.ProjectResumeOptions{}
+		flags := c.Flags()
+		if flags.Changed("fill-type") {
+			opts.FillType = &projectResumeFillType
+		}
+		if flags.Changed("max-repeats") {
+			opts.MaxRepeats = &projectResumeMaxRepeats
+		}
+		if flags.Changed("keep-top") {
+			opts.KeepTop = &projectResumeKeepTop
+		}
+		if flags.Changed("threashold") {
+			opts.Threashold = &projectResumeThreashold
+		}
+		if flags.Changed("engine") {
+			engine := Env.Engine()
+			opts.Engine = &engine
+		}
+		if flags.Changed("destructive") {
+			opts.Destructive = &projectResumeDestructive
+		}
+
+		err = Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.ProjectResume(Env, args[0], opts, drawOpts)
+	},
+}
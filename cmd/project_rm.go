@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	ProjectCmd.AddCommand(ProjectRmCmd)
+}
+
+var ProjectRmCmd = &cobra.Command{
+	Use:   "rm NAMES...",
+	Short: "Remove projects, and the cover, macro and mosaic entries only they use",
+	Long:  "Remove projects, and the cover, macro and mosaic entries only they use. Image files are not removed.",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) == 0 {
+			Env.Fatalln("Project name is required")
+		}
+
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		for _, name := range args {
+			err = controller.ProjectRm(Env, name)
+			if err != nil {
+				return
+			}
+		}
+	},
+}
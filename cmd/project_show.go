@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	ProjectCmd.AddCommand(ProjectShowCmd)
+}
+
+var ProjectShowCmd = &cobra.Command{
+	Use:   "show NAME",
+	Short: "Show the state of each stage of project NAME, or id",
+	Long:  "Show the state of each stage of project NAME, or id",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			Env.Fatalln("Project name is required")
+		}
+
+		err := Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		controller.ProjectShow(Env, args[0], os.Stdout)
+	},
+}
@@ -6,7 +6,9 @@ import (
 	"path"
 	"runtime"
 
+	"github.com/atongen/gosaic/controller"
 	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/util"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -14,8 +16,12 @@ import (
 
 var (
 	// global flags
-	dsn     string
-	workers int
+	dsn       string
+	workers   int
+	cacheDir  string
+	cacheSize int
+	engine    string
+	memLimit  int
 )
 
 var (
@@ -38,6 +44,10 @@ func init() {
 
 	addGlobalStrFlag(&dsn, "dsn", "", defaultDsn, "Database connection string")
 	addGlobalIntFlag(&workers, "workers", "", runtime.NumCPU(), "Number of workers to use")
+	addGlobalStrFlag(&cacheDir, "cache-dir", "", path.Join(home, ".gosaic_cache"), "Directory to cache resized tiles in")
+	addGlobalIntFlag(&cacheSize, "cache-size", "", 1024, "Size of tile cache in megabytes, 0 disables the cache")
+	addGlobalStrFlag(&engine, "engine", "", "db", "Engine to compare and fill mosaics with, either 'db' or 'memory'")
+	addGlobalIntFlag(&memLimit, "memory-limit", "", 2048, "Megabytes of memory the memory engine can use, 0 is unlimited")
 
 	cobra.OnInitialize(setEnv)
 }
@@ -52,6 +62,24 @@ func setEnv() {
 		fmt.Printf("Unable to create environment: %s\n", err.Error())
 		os.Exit(1)
 	}
+
+	Env.SetTileCache(util.NewTileCache(
+		viper.GetString("cache-dir"),
+		int64(viper.GetInt("cache-size"))<<20,
+	))
+
+	if !util.SliceContainsString(controller.Engines, viper.GetString("engine")) {
+		fmt.Printf("Invalid engine: %s\n", viper.GetString("engine"))
+		os.Exit(1)
+	}
+
+	if viper.GetInt("memory-limit") < 0 {
+		fmt.Println("memory-limit cannot be negative")
+		os.Exit(1)
+	}
+
+	Env.SetEngine(viper.GetString("engine"))
+	Env.SetMemoryLimit(int64(viper.GetInt("memory-limit")) << 20)
 }
 
 func addGlobalStrFlag(myVar *string, longName, shortName, defVal, desc string) {
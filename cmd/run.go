@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"github.com/atongen/gosaic/controller"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runOnExists  string
+	runKeepGoing bool
+)
+
+func init() {
+	addOnExistsFlag(&runOnExists, RunCmd)
+	addLocalBoolFlag(&runKeepGoing, "keep-going", "k", false, "Run the remaining jobs after a job fails", RunCmd)
+	RootCmd.AddCommand(RunCmd)
+}
+
+var RunCmd = &cobra.Command{
+	Use:   "run JOBFILE",
+	Short: "Create the mosaics described in yaml JOBFILE",
+	Long:  "Create the mosaics described in yaml JOBFILE, one after another. Each job sets the flags of a mosaic sub-command, and jobs share the flags set in defaults.",
+	Run: func(c *cobra.Command, args []string) {
+		if len(args) != 1 || args[0] == "" {
+			Env.Fatalln("Job file is required")
+		}
+
+		jobs, err := controller.LoadRunJobs(args[0])
+		if err != nil {
+			Env.Fatalf("Error reading job file: %s\n", err.Error())
+		}
+
+		for i, job := range jobs {
+			err = job.Prepare()
+			if err != nil {
+				Env.Fatalf("Invalid job %d (%s): %s\n", i+1, job.Describe(), err.Error())
+			}
+		}
+
+		onExists, err := onExistsAction(runOnExists)
+		if err != nil {
+			Env.Fatalln(err.Error())
+		}
+
+		err = Env.Init()
+		if err != nil {
+			Env.Fatalf("Unable to initialize environment: %s\n", err.Error())
+		}
+		defer Env.Close()
+
+		failed := 0
+		for i, job := range jobs {
+			if Env.Cancel() {
+				break
+			}
+
+			if job.OnExists == "" {
+				job.OnExists, err = askOnExists(job.Path, job.Name, onExists)
+				if err != nil {
+					Env.Fatalf("Error getting project: %s\n", err.Error())
+				}
+			}
+
+			Env.Printf("Running job %d of %d (%s)...\n", i+1, len(jobs), job.Describe())
+			err = job.Run(Env, onExists)
+			if err != nil {
+				failed++
+				Env.Printf("Job %d (%s) failed\n", i+1, job.Describe())
+				if !runKeepGoing {
+					break
+				}
+			}
+		}
+
+		if failed > 0 {
+			Env.Printf("%d of %d jobs failed\n", failed, len(jobs))
+		}
+	},
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+)
+
+// CacheStats prints the number of tiles in the tile cache, and their size
+func CacheStats(env environment.Environment) error {
+	cache := env.TileCache()
+	if cache == nil {
+		env.Println("Tile cache is disabled")
+		return nil
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		env.Printf("Error reading tile cache: %s\n", err.Error())
+		return err
+	}
+
+	env.Printf("Tile cache: %s\n", cache.Dir)
+	env.Printf("%d tiles, %s of %s\n", stats.Tiles, formatBytes(stats.Size), formatBytes(cache.MaxSize))
+
+	return nil
+}
+
+// CachePrune removes the least recently used tiles from the tile cache,
+// until it holds at most maxSize bytes. A negative maxSize prunes the
+// cache to its own size cap.
+func CachePrune(env environment.Environment, maxSize int64) error {
+	cache := env.TileCache()
+	if cache == nil {
+		err := errors.New("Tile cache is disabled")
+		env.Println(err.Error())
+		return err
+	}
+
+	if maxSize < 0 {
+		maxSize = cache.MaxSize
+	}
+
+	removed, err := cache.Prune(maxSize)
+	if err != nil {
+		env.Printf("Error pruning tile cache: %s\n", err.Error())
+		return err
+	}
+
+	env.Printf("Pruned %d tiles, %s\n", removed.Tiles, formatBytes(removed.Size))
+
+	return nil
+}
+
+// formatBytes formats a number of bytes in the largest whole unit
+func formatBytes(n int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	f := float64(n)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+
+	if i == 0 {
+		return fmt.Sprintf("%d %s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f %s", f, units[i])
+}
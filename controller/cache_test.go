@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"bytes"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atongen/gosaic/util"
+)
+
+func TestCache(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_cache")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for cache test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	env.SetTileCache(util.NewTileCache(filepath.Join(dir, "cache"), 1<<30))
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	mosaic := MosaicBuild(env, "best", macro.Id, 0, false)
+	if mosaic == nil {
+		t.Fatal("Failed to build mosaic")
+	}
+
+	views, err := findMosaicPartialViews(env, mosaic)
+	if err != nil {
+		t.Fatalf("Error finding mosaic partial views: %s\n", err.Error())
+	}
+
+	// all of the tiles are the same size
+	md5sums := make(map[string]bool)
+	for _, view := range views {
+		md5sums[view.Gidx.Md5sum] = true
+	}
+
+	var draws [][]byte
+	for _, name := range []string{"first.png", "second.png"} {
+		path := filepath.Join(dir, name)
+		err = MosaicDraw(env, mosaic.Id, path, MosaicDrawOptions{})
+		if err != nil {
+			t.Fatalf("Error drawing mosaic: %s\n", err.Error())
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Error reading mosaic: %s\n", err.Error())
+		}
+		draws = append(draws, b)
+
+		stats, err := env.TileCache().Stats()
+		if err != nil {
+			t.Fatalf("Error getting tile cache stats: %s\n", err.Error())
+		}
+
+		if stats.Tiles != len(md5sums) {
+			t.Errorf("Expected %d cached tiles, got %d\n", len(md5sums), stats.Tiles)
+		}
+	}
+
+	if !bytes.Equal(draws[0], draws[1]) {
+		t.Errorf("Expected mosaic drawn from cached tiles to be the same\n")
+	}
+
+	err = CacheStats(env)
+	if err != nil {
+		t.Fatalf("Error showing cache stats: %s\n", err.Error())
+	}
+
+	err = CachePrune(env, 0)
+	if err != nil {
+		t.Fatalf("Error pruning cache: %s\n", err.Error())
+	}
+
+	stats, err := env.TileCache().Stats()
+	if err != nil || stats.Tiles != 0 {
+		t.Errorf("Expected pruned tile cache to be empty, got %+v, %v\n", stats, err)
+	}
+
+	expect := []string{
+		"Tile cache: " + filepath.Join(dir, "cache"),
+		"Pruned ",
+	}
+
+	testResultExpect(t, out.String(), expect)
+}
+
+func TestShrinkMosaicTileSource(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+
+	for _, tt := range []struct {
+		sizes []image.Point
+		want  image.Rectangle
+	}{
+		{[]image.Point{image.Pt(50, 50), image.Pt(100, 20)}, image.Rect(0, 0, 100, 50)},
+		{[]image.Point{image.Pt(10, 100)}, image.Rect(0, 0, 200, 100)},
+		{[]image.Point{image.Pt(800, 10)}, image.Rect(0, 0, 400, 200)},
+	} {
+		got := shrinkMosaicTileSource(img, tt.sizes).Bounds()
+		if got != tt.want {
+			t.Errorf("shrinkMosaicTileSource(%v) => %v, want %v\n", tt.sizes, got, tt.want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for _, tt := range []struct {
+		n    int64
+		want string
+	}{
+		{512, "512 B"},
+		{1536, "1.5 KB"},
+		{3 << 30, "3.0 GB"},
+	} {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) => %s, want %s\n", tt.n, got, tt.want)
+		}
+	}
+}
@@ -11,7 +11,10 @@ import (
 	"gopkg.in/cheggaaa/pb.v1"
 )
 
-func Compare(env environment.Environment, macroId int64) error {
+// Compare compares each macro partial of a macro with each index partial
+// of the same aspect. When keepTop is greater than zero, the comparisons of
+// each macro partial are pruned to the keepTop closest once it is compared.
+func Compare(env environment.Environment, macroId int64, keepTop int) error {
 	macroService := env.ServiceFactory().MustMacroService()
 
 	macro, err := macroService.Get(macroId)
@@ -20,16 +23,24 @@ func Compare(env environment.Environment, macroId int64) error {
 		return err
 	}
 
-	err = createMissingComparisons(env, macro)
+	err = createMissingComparisons(env, macro, keepTop)
 	if err != nil {
 		env.Printf("Error creating comparisons: %s\n", err.Error())
 		return err
 	}
 
+	if keepTop > 0 {
+		err = keepTopComparisons(env, macro, keepTop)
+		if err != nil {
+			env.Printf("Error pruning comparisons: %s\n", err.Error())
+			return err
+		}
+	}
+
 	return nil
 }
 
-func createMissingComparisons(env environment.Environment, macro *model.Macro) error {
+func createMissingComparisons(env environment.Environment, macro *model.Macro, keepTop int) error {
 	partialComparisonService := env.ServiceFactory().MustPartialComparisonService()
 
 	batchSize := 500
@@ -45,6 +56,10 @@ func createMissingComparisons(env environment.Environment, macro *model.Macro) e
 	env.Printf("Building %d partial image comparisons...\n", numTotal)
 	bar := pb.StartNew(int(numTotal))
 
+	// macro partials that are being compared, which are
+	// pruned as soon as all of their comparisons are made
+	pending := make([]*model.MacroPartial, 0)
+
 	for {
 		if env.Cancel() {
 			return errors.New("Cancelled")
@@ -69,12 +84,62 @@ func createMissingComparisons(env environment.Environment, macro *model.Macro) e
 
 			bar.Add(int(numCreated))
 		}
+
+		if keepTop > 0 {
+			for _, view := range views {
+				if len(pending) == 0 || pending[len(pending)-1].Id != view.MacroPartial.Id {
+					pending = append(pending, view.MacroPartial)
+				}
+			}
+
+			// missing comparisons are found in order of macro partial,
+			// so every macro partial before the last is complete
+			for len(pending) > 1 {
+				_, err = partialComparisonService.KeepTop(pending[0], keepTop)
+				if err != nil {
+					return err
+				}
+				pending = pending[1:]
+			}
+		}
 	}
 
 	bar.Finish()
 	return nil
 }
 
+// keepTopComparisons prunes the comparisons of each macro partial
+// of macro to the keepTop closest
+func keepTopComparisons(env environment.Environment, macro *model.Macro, keepTop int) error {
+	macroPartialService := env.ServiceFactory().MustMacroPartialService()
+	partialComparisonService := env.ServiceFactory().MustPartialComparisonService()
+
+	batchSize := 1000
+	for offset := 0; ; offset += batchSize {
+		if env.Cancel() {
+			return errors.New("Cancelled")
+		}
+
+		macroPartials, err := macroPartialService.FindAll("macro_partials.id asc", batchSize, offset, "macro_partials.macro_id = ?", macro.Id)
+		if err != nil {
+			return err
+		}
+
+		for _, macroPartial := range macroPartials {
+			_, err = partialComparisonService.KeepTop(macroPartial, keepTop)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(macroPartials) < batchSize {
+			break
+		}
+	}
+
+	return nil
+}
+
 func buildPartialComparisons(l *log.Logger, macroGidxViews []*model.MacroGidxView) []*model.PartialComparison {
 	var wg sync.WaitGroup
 	wg.Add(len(macroGidxViews))
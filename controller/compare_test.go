@@ -1,6 +1,10 @@
 package controller
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+)
 
 func TestCompare(t *testing.T) {
 	env, out, err := setupControllerTest()
@@ -14,7 +18,7 @@ func TestCompare(t *testing.T) {
 		t.Fatalf("Error indexing images: %s\n", err.Error())
 	}
 
-	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, "", "")
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, 0, 0, "grid", "", "")
 	if cover == nil || macro == nil {
 		t.Fatal("Failed to create cover or macro")
 	}
@@ -24,7 +28,7 @@ func TestCompare(t *testing.T) {
 		t.Fatalf("Error building partial aspects: %s\n", err.Error())
 	}
 
-	err = Compare(env, macro.Id)
+	err = Compare(env, macro.Id, 0)
 	if err != nil {
 		t.Fatalf("Comparing images: %s\n", err.Error())
 	}
@@ -35,3 +39,87 @@ func TestCompare(t *testing.T) {
 
 	testResultExpect(t, out.String(), expect)
 }
+
+func TestCompareKeepTopReindex(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 1)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	err = env.ServiceFactory().MustProjectService().Insert(&model.Project{Name: "bunny", CoverId: cover.Id, MacroId: macro.Id})
+	if err != nil {
+		t.Fatalf("Error inserting project: %s\n", err.Error())
+	}
+
+	// remove the image with the last index partial, and add it back
+	gidxPartialService := env.ServiceFactory().MustGidxPartialService()
+	last, err := gidxPartialService.FindAll("id desc", 1, 0, "id > ?", 0)
+	if err != nil || len(last) != 1 {
+		t.Fatalf("Error finding last index partial: %v\n", err)
+	}
+
+	gidx, err := env.ServiceFactory().MustGidxService().Get(last[0].GidxId)
+	if err != nil {
+		t.Fatalf("Error getting index image: %s\n", err.Error())
+	}
+
+	err = IndexRm(env, []string{gidx.Path})
+	if err != nil {
+		t.Fatalf("Error removing index image: %s\n", err.Error())
+	}
+
+	err = Gc(env, 100, false)
+	if err != nil {
+		t.Fatalf("Error running gc: %s\n", err.Error())
+	}
+
+	err = Index(env, []string{gidx.Path})
+	if err != nil {
+		t.Fatalf("Error indexing image: %s\n", err.Error())
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	next, err := gidxPartialService.FindAll("id desc", 1, 0, "id > ?", 0)
+	if err != nil || len(next) != 1 {
+		t.Fatalf("Error finding last index partial: %v\n", err)
+	}
+
+	if next[0].Id <= last[0].Id {
+		t.Fatalf("Expected new index partial id to be greater than %d, got %d\n", last[0].Id, next[0].Id)
+	}
+
+	// the new index partial is compared with every macro partial,
+	// although all of them were pruned through the deleted one
+	out.Reset()
+	err = Compare(env, macro.Id, 1)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	testResultExpect(t, out.String(), []string{"Building 150 partial image comparisons..."})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/atongen/gosaic/util"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -85,7 +86,22 @@ func calculateDimensionsFromAspect(aspect *model.Aspect, width, height, baseWidt
 	return cWidth, cHeight
 }
 
-func findOrCreateProject(env environment.Environment, inPath, name, coverOutfile, macroOutfile, mosaicOutfile string) (*model.Project, error) {
+// ProjectOnExists are the actions taken when a mosaic is created with the
+// name of an existing project. Resume continues the project, building
+// again only the stages that changed parameters invalidate, rebuild also
+// builds the mosaic again, abort stops, and new creates a project with
+// the next free name. An empty action aborts.
+var ProjectOnExists = []string{"resume", "rebuild", "abort", "new"}
+
+// Engines compare macro partials with index partials and fill mosaics.
+// The db engine stores every comparison in the database, while the memory
+// engine compares in memory and only stores the filled mosaic partials.
+var Engines = []string{"db", "memory"}
+
+// findOrCreateProject finds the project with name, or creates it.
+// The stages of an existing project that params invalidate are
+// reset, and params are stored with the project.
+func findOrCreateProject(env environment.Environment, inPath, name, onExists string, params *projectParams, coverOutfile, macroOutfile, mosaicOutfile string) (*model.Project, error) {
 	if inPath == "" {
 		return nil, errors.New("Error: path is empty")
 	}
@@ -99,6 +115,12 @@ func findOrCreateProject(env environment.Environment, inPath, name, coverOutfile
 		return nil, fmt.Errorf("Error getting image directory: %s\n", err.Error())
 	}
 
+	params.Path, err = filepath.Abs(inPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting image path: %s\n", err.Error())
+	}
+	params.Engine = env.Engine()
+
 	fName := filepath.Base(inPath)
 	ext := filepath.Ext(fName)
 	extL := strings.ToLower(ext)
@@ -106,12 +128,14 @@ func findOrCreateProject(env environment.Environment, inPath, name, coverOutfile
 		return nil, errors.New("Error: only jpg images can be processed")
 	}
 
-	basename := fName[:len(fName)-len(ext)]
-	if name == "" {
-		name = basename
+	if mosaicOutfile != "" {
+		outExt := strings.ToLower(filepath.Ext(mosaicOutfile))
+		if _, ok := mosaicStreamedFormats[outExt]; !ok {
+			return nil, fmt.Errorf("Error: unsupported mosaic format '%s', use .jpg, .png or .tif", outExt)
+		}
 	}
 
-	baseFilename := util.CleanStr(name)
+	name = projectName(inPath, name)
 
 	projectService := env.ServiceFactory().MustProjectService()
 	project, err := projectService.GetOneBy("name = ?", name)
@@ -119,26 +143,45 @@ func findOrCreateProject(env environment.Environment, inPath, name, coverOutfile
 		return nil, fmt.Errorf("Error getting project name: %s\n", err.Error())
 	}
 
+	if project != nil {
+		switch onExists {
+		case "resume", "rebuild":
+			err = resetChangedProjectStages(env, project, params, onExists == "rebuild")
+			if err != nil {
+				return nil, fmt.Errorf("Error resetting project: %s\n", err.Error())
+			}
+		case "abort", "":
+			return nil, fmt.Errorf("Project with name '%s' already exists, aborting.", name)
+		case "new":
+			name, err = nextProjectName(env, name)
+			if err != nil {
+				return nil, fmt.Errorf("Error getting project name: %s\n", err.Error())
+			}
+			project = nil
+		default:
+			return nil, fmt.Errorf("Invalid on-exists action: %s", onExists)
+		}
+	}
+
 	if project == nil {
 		project = &model.Project{Name: name}
-	} else {
-		var status, action string
-		if project.IsComplete {
-			status = "Complete"
-			action = "rebuild"
-		} else {
-			status = "Incomplete"
-			action = "resume"
-		}
-		fmt.Printf("%s project with name '%s' already exists. Type 'Y' to %s, or anything else to abort [Y,n]: ", status, name, action)
-		var confirm string
-		fmt.Scanln(&confirm)
-		if confirm != "Y" {
-			return nil, fmt.Errorf("Not attempting to %s project.", action)
-		}
 	}
 	project.Path = inPath
 
+	baseFilename := util.CleanStr(name)
+
+	// an existing project writes to the same files again
+	// unless it is given new ones
+	if coverOutfile == "" {
+		coverOutfile = project.CoverPath
+	}
+	if macroOutfile == "" {
+		macroOutfile = project.MacroPath
+	}
+	if mosaicOutfile == "" {
+		mosaicOutfile = project.MosaicPath
+	}
+
 	if coverOutfile == "" {
 		coverOutfile, err = util.NextAvailableFilename(filepath.Join(dir, baseFilename+"-cover.png"))
 		if err != nil {
@@ -163,6 +206,11 @@ func findOrCreateProject(env environment.Environment, inPath, name, coverOutfile
 	}
 	project.MosaicPath = mosaicOutfile
 
+	err = setProjectParams(project, params)
+	if err != nil {
+		return nil, fmt.Errorf("Error storing project parameters: %s\n", err.Error())
+	}
+
 	if project.Id == int64(0) {
 		err = projectService.Insert(project)
 	} else {
@@ -176,6 +224,55 @@ func findOrCreateProject(env environment.Environment, inPath, name, coverOutfile
 	return project, nil
 }
 
+// projectName is name, or the name of the image at inPath when it is empty
+func projectName(inPath, name string) string {
+	if name != "" {
+		return name
+	}
+	fName := filepath.Base(inPath)
+	return fName[:len(fName)-len(filepath.Ext(fName))]
+}
+
+// ExistingProject returns the project that a mosaic of the image at inPath
+// named name would continue, or nil when there is none. An empty name is
+// the name of the image.
+func ExistingProject(env environment.Environment, inPath, name string) (*model.Project, error) {
+	return env.ServiceFactory().MustProjectService().GetOneBy("name = ?", projectName(inPath, name))
+}
+
+// resetChangedProjectStages resets the stages of project that params
+// invalidate. Rebuild also resets the mosaic when nothing has changed.
+// Projects built before their parameters were stored are reset entirely
+// when rebuilt, and resumed as they are.
+func resetChangedProjectStages(env environment.Environment, project *model.Project, params *projectParams, rebuild bool) error {
+	prev, err := getProjectParams(project)
+	if err != nil {
+		return err
+	}
+
+	stage := projectStageNone
+	if prev == nil {
+		if rebuild {
+			stage = projectStageCover
+		}
+	} else {
+		changes := params.changes(prev)
+		if len(changes) > 0 {
+			env.Println(describeProjectChanges(changes))
+			stage = invalidatedProjectStage(changes)
+		}
+		if rebuild && stage > projectStageMosaic {
+			stage = projectStageMosaic
+		}
+	}
+
+	if stage == projectStageNone {
+		return nil
+	}
+
+	return resetProjectStages(env, project, stage)
+}
+
 func envProject(env environment.Environment) (*model.Project, error) {
 	if env.ProjectId() == int64(0) {
 		return nil, nil
@@ -292,9 +389,20 @@ func projectComplete(env environment.Environment, project *model.Project) error
 		return nil
 	}
 
+	// the stages of the project have set their ids on
+	// a newer copy of it, which must not be overwritten
 	projectService := env.ServiceFactory().MustProjectService()
+	current, err := projectService.Get(project.Id)
+	if err != nil {
+		return err
+	}
+
+	if current != nil {
+		*project = *current
+	}
+
 	project.IsComplete = true
-	_, err := projectService.Update(project)
+	_, err = projectService.Update(project)
 	return err
 }
 
@@ -303,3 +411,36 @@ func projectCleanup(env environment.Environment, macro *model.Macro) error {
 	partialComparisonService := env.ServiceFactory().MustPartialComparisonService()
 	return partialComparisonService.DeleteFrom(macro)
 }
+
+// ParseAspects parses a comma separated list of aspects in CxR format.
+// Aspects are not reduced, so 2x3 remains 2x3.
+func ParseAspects(str string) ([]*model.Aspect, error) {
+	aspects := []*model.Aspect{}
+	for _, aspectStr := range strings.Split(str, ",") {
+		aspectStrings := strings.Split(strings.TrimSpace(aspectStr), "x")
+		if len(aspectStrings) != 2 {
+			return nil, fmt.Errorf("aspect format must be CxR: %s", aspectStr)
+		}
+
+		aw, err := strconv.Atoi(aspectStrings[0])
+		if err != nil {
+			return nil, fmt.Errorf("Error converting aspect columns: %s", err.Error())
+		}
+
+		if aw <= 0 {
+			return nil, fmt.Errorf("aspect columns must be greater than zero: %s", aspectStr)
+		}
+
+		ah, err := strconv.Atoi(aspectStrings[1])
+		if err != nil {
+			return nil, fmt.Errorf("Error converting aspect rows: %s", err.Error())
+		}
+
+		if ah <= 0 {
+			return nil, fmt.Errorf("aspect rows must be greater than zero: %s", aspectStr)
+		}
+
+		aspects = append(aspects, &model.Aspect{Columns: aw, Rows: ah})
+	}
+	return aspects, nil
+}
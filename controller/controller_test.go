@@ -2,11 +2,26 @@ package controller
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/atongen/gosaic/environment"
+	"os"
 	"strings"
 	"testing"
 )
 
+// TestMain stops before any test when the test environment cannot be
+// created, such as with a sqlite3 dsn when built without cgo
+func TestMain(m *testing.M) {
+	env, _, err := setupControllerTest()
+	if err != nil {
+		fmt.Printf("Error getting test environment with %s: %s\n", environment.TestDsn(), err.Error())
+		os.Exit(1)
+	}
+	env.Close()
+
+	os.Exit(m.Run())
+}
+
 func setupControllerTest() (environment.Environment, *bytes.Buffer, error) {
 	var out bytes.Buffer
 	env, err := environment.GetTestEnv(&out)
@@ -6,11 +6,24 @@ import (
 	"github.com/atongen/gosaic/model"
 	"github.com/atongen/gosaic/util"
 	"math"
+	"math/rand"
 
 	"gopkg.in/cheggaaa/pb.v1"
 )
 
-func CoverAspect(env environment.Environment, coverWidth, coverHeight, partialWidth, partialHeight, size int) *model.Cover {
+// CoverAspectLayouts are the valid arrangements of aspect cover partials.
+// grid is an exact grid, brick offsets every other row by half a partial,
+// random offsets each row by a random quarter of a partial, and herringbone
+// staggers rows back and forth by a third of a partial.
+var CoverAspectLayouts = []string{"grid", "brick", "random", "herringbone"}
+
+// coverAspectRandomSteps is the number of offsets a row of the random
+// layout can have. Keeping it small keeps the number of widths that
+// partials cropped at the cover edges have, and so the number of aspects
+// that index images must be compared with, small.
+const coverAspectRandomSteps = 4
+
+func CoverAspect(env environment.Environment, coverWidth, coverHeight, partialWidth, partialHeight, size int, layout string) *model.Cover {
 	coverService := env.ServiceFactory().MustCoverService()
 	aspectService := env.ServiceFactory().MustAspectService()
 
@@ -20,6 +33,11 @@ func CoverAspect(env environment.Environment, coverWidth, coverHeight, partialWi
 		return nil
 	}
 
+	if !util.SliceContainsString(CoverAspectLayouts, layout) {
+		env.Printf("Invalid cover layout: %s\n", layout)
+		return nil
+	}
+
 	coverPartialAspect, err := aspectService.FindOrCreate(partialWidth, partialHeight)
 	if err != nil {
 		env.Printf("Error getting cover partial aspect: %s\n", err.Error())
@@ -44,7 +62,7 @@ func CoverAspect(env environment.Environment, coverWidth, coverHeight, partialWi
 		cSize = size
 	}
 
-	err = addCoverAspectPartials(env, cover, coverPartialAspect, cSize)
+	err = addCoverAspectPartials(env, cover, coverPartialAspect, cSize, layout)
 	if err != nil {
 		env.Printf("Error adding cover partials: %s\n", err.Error())
 		// attempt to delete cover
@@ -96,41 +114,120 @@ func getCoverAspectDims(coverWidth, coverHeight, partialAspectWidth, partialAspe
 	return
 }
 
-func addCoverAspectPartials(env environment.Environment, cover *model.Cover, coverPartialAspect *model.Aspect, size int) error {
+// getCoverAspectRowOffset returns how far to the right row j is shifted
+// for the given layout, where width is the width of a single partial.
+func getCoverAspectRowOffset(layout string, j, width int) int {
+	switch layout {
+	case "brick":
+		return (j % 2) * width / 2
+	case "random":
+		return rand.Intn(coverAspectRandomSteps) * width / coverAspectRandomSteps
+	case "herringbone":
+		// zig-zag: 0, 1/3, 2/3, 1/3, 0, ...
+		steps := []int{0, 1, 2, 1}
+		return steps[j%len(steps)] * width / 3
+	default:
+		return 0
+	}
+}
+
+// getCoverAspectRects builds the rows of cover partial rectangles for
+// the layout. The grid layout is centered on the cover and may overhang
+// its edges. All other layouts crop partials at the cover boundary, so
+// partials on the edges can be narrower or shorter than the others.
+func getCoverAspectRects(coverWidth, coverHeight, width, height, columns, rows int, layout string) [][]*model.CoverPartial {
+	xOffset := int(math.Floor(float64(coverWidth-width*columns) / float64(2.0)))
+	yOffset := int(math.Floor(float64(coverHeight-height*rows) / float64(2.0)))
+
+	rects := make([][]*model.CoverPartial, rows)
+
+	for j := 0; j < rows; j++ {
+		y1 := j*height + yOffset
+		y2 := (j+1)*height + yOffset
+
+		if layout == "grid" {
+			row := make([]*model.CoverPartial, columns)
+			for i := 0; i < columns; i++ {
+				row[i] = &model.CoverPartial{
+					X1: i*width + xOffset,
+					Y1: y1,
+					X2: (i+1)*width + xOffset,
+					Y2: y2,
+				}
+			}
+			rects[j] = row
+			continue
+		}
+
+		y1 = util.MaxInt(y1, 0)
+		y2 = util.MinInt(y2, coverHeight)
+		if y2 <= y1 {
+			continue
+		}
+
+		x := xOffset + getCoverAspectRowOffset(layout, j, width)
+		for x > 0 {
+			x -= width
+		}
+
+		row := []*model.CoverPartial{}
+		for ; x < coverWidth; x += width {
+			x1 := util.MaxInt(x, 0)
+			x2 := util.MinInt(x+width, coverWidth)
+			if x2 <= x1 {
+				continue
+			}
+			row = append(row, &model.CoverPartial{
+				X1: x1,
+				Y1: y1,
+				X2: x2,
+				Y2: y2,
+			})
+		}
+		rects[j] = row
+	}
+
+	return rects
+}
+
+func addCoverAspectPartials(env environment.Environment, cover *model.Cover, coverPartialAspect *model.Aspect, size int, layout string) error {
+	aspectService := env.ServiceFactory().MustAspectService()
 	coverPartialService := env.ServiceFactory().MustCoverPartialService()
 
 	width, height, columns, rows := getCoverAspectDims(cover.Width, cover.Height, coverPartialAspect.Columns, coverPartialAspect.Rows, size)
+	rects := getCoverAspectRects(cover.Width, cover.Height, width, height, columns, rows, layout)
 
-	xOffset := int(math.Floor(float64(cover.Width-width*columns) / float64(2.0)))
-	yOffset := int(math.Floor(float64(cover.Height-height*rows) / float64(2.0)))
-
-	count := columns * rows
+	count := 0
+	for _, row := range rects {
+		count += len(row)
+	}
 	env.Printf("Building %d cover partials...\n", count)
 
 	bar := pb.StartNew(count)
 
-	for i := 0; i < columns; i++ {
-		var coverPartials []*model.CoverPartial = make([]*model.CoverPartial, rows)
-		for j := 0; j < rows; j++ {
+	for _, coverPartials := range rects {
+		if len(coverPartials) == 0 {
+			continue
+		}
+
+		for _, coverPartial := range coverPartials {
 			if env.Cancel() {
 				return errors.New("Cancelled")
 			}
 
-			x1 := i*width + xOffset
-			y1 := j*height + yOffset
-			x2 := (i+1)*width + xOffset
-			y2 := (j+1)*height + yOffset
-
-			var coverPartial model.CoverPartial = model.CoverPartial{
-				CoverId:  cover.Id,
-				AspectId: coverPartialAspect.Id,
-				X1:       x1,
-				Y1:       y1,
-				X2:       x2,
-				Y2:       y2,
+			coverPartial.CoverId = cover.Id
+			if coverPartial.Width() == width && coverPartial.Height() == height {
+				coverPartial.AspectId = coverPartialAspect.Id
+			} else {
+				// cropped at the cover boundary
+				aspect, err := aspectService.FindOrCreate(coverPartial.Width(), coverPartial.Height())
+				if err != nil {
+					return err
+				}
+				coverPartial.AspectId = aspect.Id
 			}
-			coverPartials[j] = &coverPartial
 		}
+
 		num, err := coverPartialService.BulkInsert(coverPartials)
 		if err != nil {
 			return err
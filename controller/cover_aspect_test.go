@@ -9,7 +9,7 @@ func TestCoverAspect(t *testing.T) {
 	}
 	defer env.Close()
 
-	cover := CoverAspect(env, 1, 1, 1, 1, 1)
+	cover := CoverAspect(env, 1, 1, 1, 1, 1, "grid")
 	if cover == nil {
 		t.Fatal("Failed to create cover")
 	}
@@ -20,3 +20,97 @@ func TestCoverAspect(t *testing.T) {
 
 	testResultExpect(t, out.String(), expect)
 }
+
+func TestCoverAspectLayoutBrick(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	cover := CoverAspect(env, 100, 100, 1, 1, 10, "brick")
+	if cover == nil {
+		t.Fatal("Failed to create cover")
+	}
+
+	expect := []string{
+		"Building 105 cover partials...",
+	}
+
+	testResultExpect(t, out.String(), expect)
+}
+
+func TestGetCoverAspectRects(t *testing.T) {
+	for _, layout := range CoverAspectLayouts {
+		rects := getCoverAspectRects(95, 60, 10, 10, 10, 6, layout)
+		if len(rects) != 6 {
+			t.Fatalf("%s layout has %d rows, want 6", layout, len(rects))
+		}
+
+		area := 0
+		for j, row := range rects {
+			for _, cp := range row {
+				if cp.Y1 != j*10 || cp.Y2 != (j+1)*10 {
+					t.Errorf("%s layout row %d has partial %+v outside of row", layout, j, cp)
+				}
+				if cp.Width() <= 0 || cp.Width() > 10 {
+					t.Errorf("%s layout row %d has partial %+v with invalid width", layout, j, cp)
+				}
+				if layout != "grid" && (cp.X1 < 0 || cp.X2 > 95) {
+					t.Errorf("%s layout row %d has partial %+v outside of cover", layout, j, cp)
+				}
+				area += cp.Area()
+			}
+		}
+
+		if layout == "grid" {
+			if area != 100*60 {
+				t.Errorf("grid layout area is %d, want %d", area, 100*60)
+			}
+		} else if area != 95*60 {
+			t.Errorf("%s layout area is %d, want %d", layout, area, 95*60)
+		}
+	}
+}
+
+func TestGetCoverAspectRectsBrick(t *testing.T) {
+	rects := getCoverAspectRects(100, 20, 10, 10, 10, 2, "brick")
+
+	if len(rects[0]) != 10 {
+		t.Errorf("brick layout first row has %d partials, want 10", len(rects[0]))
+	}
+
+	if len(rects[1]) != 11 {
+		t.Fatalf("brick layout second row has %d partials, want 11", len(rects[1]))
+	}
+
+	first := rects[1][0]
+	if first.X1 != 0 || first.X2 != 5 {
+		t.Errorf("brick layout second row starts with %+v, want half partial", first)
+	}
+
+	last := rects[1][10]
+	if last.X1 != 95 || last.X2 != 100 {
+		t.Errorf("brick layout second row ends with %+v, want half partial", last)
+	}
+}
+
+func TestGetCoverAspectRectsRandom(t *testing.T) {
+	// each row has a full partial width, and one of the steps as the
+	// width of its first and of its last partial
+	maxAspects := 1 + 2*coverAspectRandomSteps
+
+	aspects := make(map[[2]int]bool)
+	for i := 0; i < 100; i++ {
+		rects := getCoverAspectRects(1000, 230, 37, 23, 28, 10, "random")
+		for _, row := range rects {
+			for _, cp := range row {
+				aspects[[2]int{cp.Width(), cp.Height()}] = true
+			}
+		}
+	}
+
+	if len(aspects) > maxAspects {
+		t.Errorf("random layout has %d distinct partial sizes, want at most %d", len(aspects), maxAspects)
+	}
+}
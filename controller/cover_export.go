@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"io"
+)
+
+// CoverExportFormats are the formats a cover can be exported to and imported from
+var CoverExportFormats = []string{"json", "svg"}
+
+// coverLayout is the exported representation of a cover and its partials
+type coverLayout struct {
+	Width    int                  `json:"width"`
+	Height   int                  `json:"height"`
+	Partials []coverLayoutPartial `json:"partials"`
+}
+
+type coverLayoutPartial struct {
+	X1 int `json:"x1"`
+	Y1 int `json:"y1"`
+	X2 int `json:"x2"`
+	Y2 int `json:"y2"`
+}
+
+func CoverExport(env environment.Environment, coverId int64, format string, w io.Writer) error {
+	coverService := env.ServiceFactory().MustCoverService()
+	coverPartialService := env.ServiceFactory().MustCoverPartialService()
+
+	cover, err := coverService.Get(coverId)
+	if err != nil {
+		return err
+	} else if cover == nil {
+		return errors.New("Cover not found")
+	}
+
+	coverPartials, err := coverPartialService.FindAll(cover.Id, "id ASC")
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		return coverExportJson(w, cover, coverPartials)
+	case "svg":
+		return coverExportSvg(w, cover, coverPartials)
+	default:
+		return fmt.Errorf("Invalid cover export format: %s", format)
+	}
+}
+
+func coverExportJson(w io.Writer, cover *model.Cover, coverPartials []*model.CoverPartial) error {
+	layout := coverLayout{
+		Width:    cover.Width,
+		Height:   cover.Height,
+		Partials: make([]coverLayoutPartial, len(coverPartials)),
+	}
+
+	for i, cp := range coverPartials {
+		layout.Partials[i] = coverLayoutPartial{cp.X1, cp.Y1, cp.X2, cp.Y2}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(layout)
+}
+
+func coverExportSvg(w io.Writer, cover *model.Cover, coverPartials []*model.CoverPartial) error {
+	_, err := fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		cover.Width, cover.Height, cover.Width, cover.Height)
+	if err != nil {
+		return err
+	}
+
+	for _, cp := range coverPartials {
+		_, err = fmt.Fprintf(w, "  <rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"none\" stroke=\"red\" stroke-width=\"1\"/>\n",
+			cp.X1, cp.Y1, cp.Width(), cp.Height())
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(w, "</svg>")
+	return err
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"bytes"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCoverExportImport(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_cover_export")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for cover export test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	coverPartialService := env.ServiceFactory().MustCoverPartialService()
+
+	cover := CoverAspect(env, 100, 100, 1, 1, 10, "brick")
+	if cover == nil {
+		t.Fatal("Failed to create cover")
+	}
+
+	for _, format := range CoverExportFormats {
+		var buf bytes.Buffer
+		err = CoverExport(env, cover.Id, format, &buf)
+		if err != nil {
+			t.Fatalf("Error exporting cover as %s: %s\n", format, err.Error())
+		}
+
+		path := filepath.Join(dir, "cover."+format)
+		err = ioutil.WriteFile(path, buf.Bytes(), 0644)
+		if err != nil {
+			t.Fatalf("Error writing cover export: %s\n", err.Error())
+		}
+
+		imported := CoverImport(env, path)
+		if imported == nil {
+			t.Fatalf("Failed to import %s cover: %s\n", format, out.String())
+		}
+
+		if imported.Id == cover.Id || imported.Width != cover.Width || imported.Height != cover.Height {
+			t.Fatalf("Imported %s cover %+v does not match %+v\n", format, imported, cover)
+		}
+
+		expected, err := coverPartialService.FindAll(cover.Id, "id ASC")
+		if err != nil {
+			t.Fatalf("Error finding cover partials: %s\n", err.Error())
+		}
+
+		coverPartials, err := coverPartialService.FindAll(imported.Id, "id ASC")
+		if err != nil {
+			t.Fatalf("Error finding imported cover partials: %s\n", err.Error())
+		}
+
+		if len(coverPartials) != len(expected) {
+			t.Fatalf("Imported %d %s cover partials, want %d\n", len(coverPartials), format, len(expected))
+		}
+
+		// bulk insert does not preserve order
+		aspects := make(map[image.Rectangle]int64)
+		for _, cp := range expected {
+			aspects[cp.Rectangle()] = cp.AspectId
+		}
+
+		for _, cp := range coverPartials {
+			if aspectId, ok := aspects[cp.Rectangle()]; !ok || aspectId != cp.AspectId {
+				t.Fatalf("Imported unexpected %s cover partial %+v\n", format, cp)
+			}
+		}
+	}
+
+	expect := []string{
+		"Importing 105 cover partials...",
+	}
+
+	testResultExpect(t, out.String(), expect)
+}
+
+func TestCoverImportInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		data string
+		err  string
+	}{
+		{`{"width": 0, "height": 10, "partials": [{"x1": 0, "y1": 0, "x2": 1, "y2": 1}]}`, "must be greater than zero"},
+		{`{"width": 10, "height": 10, "partials": []}`, "no partials"},
+		{`{"width": 10, "height": 10, "partials": [{"x1": 5, "y1": 0, "x2": 5, "y2": 10}]}`, "is empty"},
+		{`{"width": 10, "height": 10, "partials": [{"x1": -1, "y1": 0, "x2": 5, "y2": 10}]}`, "outside of the 10x10 cover"},
+		{`{"width": 10, "height": 10, "partials": [{"x1": 0, "y1": 0, "x2": 5, "y2": 11}]}`, "outside of the 10x10 cover"},
+		{`<svg width="10" height="10"><rect x="0" y="0" width="5" height="5"/><rect x="0" y="0" width="5" height="5"/></svg>`, "is a duplicate"},
+	} {
+		layout, err := parseCoverLayout([]byte(tt.data), "")
+		if err != nil {
+			t.Fatalf("Error parsing cover layout %s: %s\n", tt.data, err.Error())
+		}
+
+		err = validateCoverLayout(layout)
+		if err == nil {
+			t.Errorf("validateCoverLayout(%s) => nil, want %s", tt.data, tt.err)
+		} else if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("validateCoverLayout(%s) => %s, want %s", tt.data, err.Error(), tt.err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/cheggaaa/pb.v1"
+)
+
+// CoverImport creates a new cover from a layout file previously written
+// by CoverExport, or crafted by hand. JSON and SVG layouts are supported.
+// SVG layouts are read from the width and height of the root svg element,
+// and the rect elements directly below it.
+func CoverImport(env environment.Environment, path string) *model.Cover {
+	coverService := env.ServiceFactory().MustCoverService()
+	aspectService := env.ServiceFactory().MustAspectService()
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		env.Printf("Error reading cover layout: %s\n", err.Error())
+		return nil
+	}
+
+	layout, err := parseCoverLayout(data, filepath.Ext(path))
+	if err != nil {
+		env.Printf("Error parsing cover layout: %s\n", err.Error())
+		return nil
+	}
+
+	err = validateCoverLayout(layout)
+	if err != nil {
+		env.Printf("Invalid cover layout: %s\n", err.Error())
+		return nil
+	}
+
+	aspect, err := aspectService.FindOrCreate(layout.Width, layout.Height)
+	if err != nil {
+		env.Printf("Error getting cover aspect: %s\n", err.Error())
+		return nil
+	}
+
+	cover := &model.Cover{
+		AspectId: aspect.Id,
+		Width:    layout.Width,
+		Height:   layout.Height,
+	}
+	err = coverService.Insert(cover)
+	if err != nil {
+		env.Printf("Error creating cover: %s\n", err.Error())
+		return nil
+	}
+
+	err = addCoverImportPartials(env, cover, layout)
+	if err != nil {
+		env.Printf("Error adding cover partials: %s\n", err.Error())
+		coverService.Delete(cover)
+		return nil
+	}
+
+	env.Printf("Imported cover id %d\n", cover.Id)
+
+	return cover
+}
+
+func parseCoverLayout(data []byte, ext string) (*coverLayout, error) {
+	if strings.ToLower(ext) == ".svg" || bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return parseCoverLayoutSvg(data)
+	}
+
+	layout := &coverLayout{}
+	err := json.Unmarshal(data, layout)
+	if err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+type coverLayoutSvg struct {
+	XMLName xml.Name             `xml:"svg"`
+	Width   string               `xml:"width,attr"`
+	Height  string               `xml:"height,attr"`
+	Rects   []coverLayoutSvgRect `xml:"rect"`
+}
+
+type coverLayoutSvgRect struct {
+	X      string `xml:"x,attr"`
+	Y      string `xml:"y,attr"`
+	Width  string `xml:"width,attr"`
+	Height string `xml:"height,attr"`
+}
+
+func parseCoverLayoutSvg(data []byte) (*coverLayout, error) {
+	svg := coverLayoutSvg{}
+	err := xml.Unmarshal(data, &svg)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &coverLayout{}
+
+	layout.Width, err = parseSvgLength(svg.Width)
+	if err != nil {
+		return nil, err
+	}
+
+	layout.Height, err = parseSvgLength(svg.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rect := range svg.Rects {
+		var x, y, w, h int
+		for _, v := range []struct {
+			dst *int
+			src string
+		}{{&x, rect.X}, {&y, rect.Y}, {&w, rect.Width}, {&h, rect.Height}} {
+			*v.dst, err = parseSvgLength(v.src)
+			if err != nil {
+				return nil, err
+			}
+		}
+		layout.Partials = append(layout.Partials, coverLayoutPartial{x, y, x + w, y + h})
+	}
+
+	return layout, nil
+}
+
+// parseSvgLength parses a pixel length, which may have a px suffix.
+// A missing length is zero.
+func parseSvgLength(s string) (int, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "px")
+	if s == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid length: %s", s)
+	}
+
+	return util.Round(f), nil
+}
+
+func validateCoverLayout(layout *coverLayout) error {
+	if layout.Width <= 0 || layout.Height <= 0 {
+		return fmt.Errorf("cover dimensions %dx%d must be greater than zero", layout.Width, layout.Height)
+	}
+
+	if len(layout.Partials) == 0 {
+		return errors.New("cover has no partials")
+	}
+
+	seen := make(map[coverLayoutPartial]bool)
+	for i, p := range layout.Partials {
+		if p.X1 >= p.X2 || p.Y1 >= p.Y2 {
+			return fmt.Errorf("partial %d (%d, %d, %d, %d) is empty", i, p.X1, p.Y1, p.X2, p.Y2)
+		}
+
+		if p.X1 < 0 || p.Y1 < 0 || p.X2 > layout.Width || p.Y2 > layout.Height {
+			return fmt.Errorf("partial %d (%d, %d, %d, %d) is outside of the %dx%d cover",
+				i, p.X1, p.Y1, p.X2, p.Y2, layout.Width, layout.Height)
+		}
+
+		if seen[p] {
+			return fmt.Errorf("partial %d (%d, %d, %d, %d) is a duplicate", i, p.X1, p.Y1, p.X2, p.Y2)
+		}
+		seen[p] = true
+	}
+
+	return nil
+}
+
+func addCoverImportPartials(env environment.Environment, cover *model.Cover, layout *coverLayout) error {
+	aspectService := env.ServiceFactory().MustAspectService()
+	coverPartialService := env.ServiceFactory().MustCoverPartialService()
+
+	count := len(layout.Partials)
+	env.Printf("Importing %d cover partials...\n", count)
+
+	bar := pb.StartNew(count)
+
+	batchSize := 100
+	coverPartials := []*model.CoverPartial{}
+
+	for i, p := range layout.Partials {
+		if env.Cancel() {
+			return errors.New("Cancelled")
+		}
+
+		coverPartial := &model.CoverPartial{
+			CoverId: cover.Id,
+			X1:      p.X1,
+			Y1:      p.Y1,
+			X2:      p.X2,
+			Y2:      p.Y2,
+		}
+
+		aspect, err := aspectService.FindOrCreate(coverPartial.Width(), coverPartial.Height())
+		if err != nil {
+			return err
+		}
+		coverPartial.AspectId = aspect.Id
+
+		coverPartials = append(coverPartials, coverPartial)
+
+		if len(coverPartials) == batchSize || i == count-1 {
+			num, err := coverPartialService.BulkInsert(coverPartials)
+			if err != nil {
+				return err
+			}
+			bar.Add(int(num))
+			coverPartials = []*model.CoverPartial{}
+		}
+	}
+
+	bar.Finish()
+	return nil
+}
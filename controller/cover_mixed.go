@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+	"image"
+	"math"
+	"math/rand"
+	"strings"
+
+	"gopkg.in/cheggaaa/pb.v1"
+)
+
+// CoverMixed creates a cover packed with partials of several aspects,
+// for example both portrait 2x3 and landscape 3x2 partials.
+// An aspect of CxR covers C by R units of the cover, so aspects should
+// be given at the scale they are meant to be combined, ie. 2x3 and 3x2,
+// rather than 1x1 and 3x2. The share of partials of each aspect follows
+// the distribution of aspects in the index.
+func CoverMixed(env environment.Environment, coverWidth, coverHeight int, aspects []*model.Aspect, size int) *model.Cover {
+	coverService := env.ServiceFactory().MustCoverService()
+	aspectService := env.ServiceFactory().MustAspectService()
+
+	if len(aspects) == 0 {
+		env.Println("At least one partial aspect is required")
+		return nil
+	}
+
+	coverAspect, err := aspectService.FindOrCreate(coverWidth, coverHeight)
+	if err != nil {
+		env.Printf("Error getting cover aspect: %s\n", err.Error())
+		return nil
+	}
+
+	weights, err := coverMixedWeights(env, aspects)
+	if err != nil {
+		env.Printf("Error getting index aspect distribution: %s\n", err.Error())
+		return nil
+	}
+
+	cover := &model.Cover{
+		AspectId: coverAspect.Id,
+		Width:    coverWidth,
+		Height:   coverHeight,
+	}
+	err = coverService.Insert(cover)
+	if err != nil {
+		env.Printf("Error creating cover: %s\n", err.Error())
+		return nil
+	}
+
+	var cSize int
+	if size <= 0 {
+		cSize = coverAspectCalculateSize(coverWidth, coverHeight)
+	} else {
+		cSize = size
+	}
+
+	err = addCoverMixedPartials(env, cover, aspects, weights, cSize)
+	if err != nil {
+		env.Printf("Error adding cover partials: %s\n", err.Error())
+		coverService.Delete(cover)
+		return nil
+	}
+
+	return cover
+}
+
+// coverMixedWeights counts the index images closest in ratio to each aspect.
+// If the index is empty, each aspect is weighted equally.
+func coverMixedWeights(env environment.Environment, aspects []*model.Aspect) ([]int, error) {
+	gidxService := env.ServiceFactory().MustGidxService()
+
+	weights := make([]int, len(aspects))
+	batchSize := 1000
+	total := 0
+
+	for i := 0; ; i++ {
+		gidxs, err := gidxService.FindAll("gidx.id asc", batchSize, batchSize*i)
+		if err != nil {
+			return nil, err
+		}
+		if len(gidxs) == 0 {
+			break
+		}
+
+		for _, gidx := range gidxs {
+			weights[coverMixedClosestAspect(gidx, aspects)]++
+			total++
+		}
+	}
+
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+
+	return weights, nil
+}
+
+func coverMixedClosestAspect(gidx *model.Gidx, aspects []*model.Aspect) int {
+	gRatio := float64(gidx.Width) / float64(gidx.Height)
+
+	idx := 0
+	best := math.MaxFloat64
+	for i, aspect := range aspects {
+		d := math.Abs(gRatio - aspect.Ratio())
+		if d < best {
+			best = d
+			idx = i
+		}
+	}
+
+	return idx
+}
+
+// getCoverMixedUnit returns the pixel length of a single cover unit so
+// that there are approximately size partials in the smallest dimension.
+func getCoverMixedUnit(coverWidth, coverHeight int, aspects []*model.Aspect, weights []int, size int) int {
+	var sum, total float64
+	for i, aspect := range aspects {
+		w := float64(weights[i])
+		if coverWidth < coverHeight {
+			sum += w * float64(aspect.Columns)
+		} else {
+			sum += w * float64(aspect.Rows)
+		}
+		total += w
+	}
+
+	if total == 0 {
+		return 1
+	}
+
+	minDim := float64(util.MinInt(coverWidth, coverHeight))
+	unit := int(math.Ceil(minDim / (float64(size) * sum / total)))
+	return util.MaxInt(unit, 1)
+}
+
+// getCoverMixedRects packs a grid of columns by rows units with aspect
+// shaped rectangles, returned in units. Rectangles are placed from the
+// top-left, and may overhang the right and bottom edges of the grid.
+// The aspect of each rectangle is chosen at random from those that fit,
+// in proportion to weights. Gaps that no aspect fits into are filled
+// with the largest rectangle that does.
+func getCoverMixedRects(columns, rows int, aspects []*model.Aspect, weights []int, r *rand.Rand) []image.Rectangle {
+	occupied := make([][]bool, rows)
+	for y := 0; y < rows; y++ {
+		occupied[y] = make([]bool, columns)
+	}
+
+	minRows := aspects[0].Rows
+	for _, aspect := range aspects {
+		minRows = util.MinInt(minRows, aspect.Rows)
+	}
+
+	free := func(rect image.Rectangle) bool {
+		for y := rect.Min.Y; y < rect.Max.Y && y < rows; y++ {
+			for x := rect.Min.X; x < rect.Max.X && x < columns; x++ {
+				if occupied[y][x] {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	rects := []image.Rectangle{}
+
+	for y := 0; y < rows; y++ {
+		for x := 0; x < columns; x++ {
+			if occupied[y][x] {
+				continue
+			}
+
+			fits := []int{}
+			sum := 0
+			for i, aspect := range aspects {
+				if free(image.Rect(x, y, x+aspect.Columns, y+aspect.Rows)) {
+					fits = append(fits, i)
+					sum += weights[i]
+				}
+			}
+
+			var rect image.Rectangle
+			if len(fits) > 0 {
+				choice := fits[0]
+				if sum > 0 {
+					n := r.Intn(sum)
+					for _, i := range fits {
+						if n < weights[i] {
+							choice = i
+							break
+						}
+						n -= weights[i]
+					}
+				}
+				rect = image.Rect(x, y, x+aspects[choice].Columns, y+aspects[choice].Rows)
+			} else {
+				// fill the gap
+				w := 1
+				for x+w < columns && !occupied[y][x+w] {
+					w++
+				}
+				h := 1
+				for h < minRows && free(image.Rect(x, y+h, x+w, y+h+1)) {
+					h++
+				}
+				rect = image.Rect(x, y, x+w, y+h)
+			}
+
+			for ry := rect.Min.Y; ry < rect.Max.Y && ry < rows; ry++ {
+				for rx := rect.Min.X; rx < rect.Max.X && rx < columns; rx++ {
+					occupied[ry][rx] = true
+				}
+			}
+			rects = append(rects, rect)
+		}
+	}
+
+	return rects
+}
+
+func addCoverMixedPartials(env environment.Environment, cover *model.Cover, aspects []*model.Aspect, weights []int, size int) error {
+	aspectService := env.ServiceFactory().MustAspectService()
+	coverPartialService := env.ServiceFactory().MustCoverPartialService()
+
+	unit := getCoverMixedUnit(cover.Width, cover.Height, aspects, weights, size)
+	columns := int(math.Ceil(float64(cover.Width) / float64(unit)))
+	rows := int(math.Ceil(float64(cover.Height) / float64(unit)))
+
+	rects := getCoverMixedRects(columns, rows, aspects, weights, rand.New(rand.NewSource(rand.Int63())))
+
+	msgSlice := make([]string, len(aspects))
+	for i, aspect := range aspects {
+		msgSlice[i] = fmt.Sprintf("%dx%d (%d)", aspect.Columns, aspect.Rows, weights[i])
+	}
+	env.Printf("Mixing partial aspects %s\n", strings.Join(msgSlice, ", "))
+
+	count := len(rects)
+	env.Printf("Building %d cover partials...\n", count)
+
+	bar := pb.StartNew(count)
+
+	batchSize := 100
+	coverPartials := []*model.CoverPartial{}
+
+	for i, rect := range rects {
+		if env.Cancel() {
+			return errors.New("Cancelled")
+		}
+
+		coverPartial := &model.CoverPartial{
+			CoverId: cover.Id,
+			X1:      rect.Min.X * unit,
+			Y1:      rect.Min.Y * unit,
+			X2:      util.MinInt(rect.Max.X*unit, cover.Width),
+			Y2:      util.MinInt(rect.Max.Y*unit, cover.Height),
+		}
+
+		aspect, err := aspectService.FindOrCreate(coverPartial.Width(), coverPartial.Height())
+		if err != nil {
+			return err
+		}
+		coverPartial.AspectId = aspect.Id
+
+		coverPartials = append(coverPartials, coverPartial)
+
+		if len(coverPartials) == batchSize || i == count-1 {
+			num, err := coverPartialService.BulkInsert(coverPartials)
+			if err != nil {
+				return err
+			}
+			bar.Add(int(num))
+			coverPartials = []*model.CoverPartial{}
+		}
+	}
+
+	bar.Finish()
+	return nil
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+)
+
+func TestCoverMixed(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	coverPartialService := env.ServiceFactory().MustCoverPartialService()
+
+	aspects := []*model.Aspect{
+		&model.Aspect{Columns: 2, Rows: 3},
+		&model.Aspect{Columns: 3, Rows: 2},
+	}
+
+	cover := CoverMixed(env, 600, 400, aspects, 10)
+	if cover == nil {
+		t.Fatal("Failed to create cover")
+	}
+
+	expect := []string{
+		"Mixing partial aspects 2x3 (1), 3x2 (1)",
+	}
+
+	testResultExpect(t, out.String(), expect)
+
+	coverPartials, err := coverPartialService.FindAll(cover.Id, "id ASC")
+	if err != nil {
+		t.Fatalf("Error finding cover partials: %s\n", err.Error())
+	}
+
+	area := 0
+	for _, cp := range coverPartials {
+		if cp.X1 < 0 || cp.Y1 < 0 || cp.X2 > 600 || cp.Y2 > 400 {
+			t.Errorf("Cover partial %+v is outside of cover", cp)
+		}
+		area += cp.Area()
+	}
+
+	if area != 600*400 {
+		t.Errorf("Cover partials cover %d pixels, want %d", area, 600*400)
+	}
+}
+
+func TestGetCoverMixedRects(t *testing.T) {
+	aspects := []*model.Aspect{
+		&model.Aspect{Columns: 2, Rows: 3},
+		&model.Aspect{Columns: 3, Rows: 2},
+	}
+
+	for _, weights := range [][]int{{1, 1}, {3, 1}, {1, 0}, {0, 1}} {
+		r := rand.New(rand.NewSource(1))
+		rects := getCoverMixedRects(31, 23, aspects, weights, r)
+
+		covered := make([][]int, 23)
+		for y := range covered {
+			covered[y] = make([]int, 31)
+		}
+
+		for _, rect := range rects {
+			for y := rect.Min.Y; y < rect.Max.Y && y < 23; y++ {
+				for x := rect.Min.X; x < rect.Max.X && x < 31; x++ {
+					covered[y][x]++
+				}
+			}
+		}
+
+		for y := range covered {
+			for x := range covered[y] {
+				if covered[y][x] != 1 {
+					t.Fatalf("weights %v: unit (%d, %d) covered %d times, want 1", weights, x, y, covered[y][x])
+				}
+			}
+		}
+	}
+}
+
+func TestGetCoverMixedRectsWeights(t *testing.T) {
+	aspects := []*model.Aspect{
+		&model.Aspect{Columns: 2, Rows: 3},
+		&model.Aspect{Columns: 3, Rows: 2},
+	}
+
+	r := rand.New(rand.NewSource(1))
+	rects := getCoverMixedRects(30, 30, aspects, []int{1, 0}, r)
+
+	for _, rect := range rects {
+		if rect.Dx() == 3 && rect.Dy() == 2 {
+			t.Fatalf("Found 3x2 rectangle %v with zero weight", rect)
+		}
+	}
+}
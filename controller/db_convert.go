@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/service"
+	"gopkg.in/cheggaaa/pb.v1"
+)
+
+// DbConvert copies every row of the database of env, with its id, to the
+// empty database at dsn, batchSize rows at a time. This moves a database
+// between backends, such as from sqlite3 to bolt.
+func DbConvert(env environment.Environment, dsn string, batchSize int) error {
+	dst, err := service.NewServiceFactory(dsn)
+	if err != nil {
+		env.Printf("Error opening %s: %s\n", dsn, err.Error())
+		return err
+	}
+	defer dst.Close()
+
+	return dbConvert(env, dst, batchSize)
+}
+
+func dbConvert(env environment.Environment, dst service.ServiceFactory, batchSize int) error {
+	exportService := env.ServiceFactory().MustExportService()
+
+	if batchSize <= 0 {
+		err := errors.New("Batch size must be greater than zero")
+		env.Println(err.Error())
+		return err
+	}
+
+	dstExportService, err := dst.ExportService()
+	if err != nil {
+		env.Printf("Error getting export service: %s\n", err.Error())
+		return err
+	}
+
+	tables := exportService.Tables()
+	counts := make([]int64, len(tables))
+	var total int64
+	for i, table := range tables {
+		counts[i], err = exportService.Count(table)
+		if err != nil {
+			env.Printf("Error counting %s: %s\n", table, err.Error())
+			return err
+		}
+		total += counts[i]
+
+		// rows keep their ids, so the destination must be empty
+		num, err := dstExportService.Count(table)
+		if err != nil {
+			env.Printf("Error counting %s of destination: %s\n", table, err.Error())
+			return err
+		}
+		if num > 0 {
+			err = fmt.Errorf("Destination is not empty, %s has %d rows", table, num)
+			env.Println(err.Error())
+			return err
+		}
+	}
+
+	env.Printf("Copying %d rows...\n", total)
+	bar := pb.StartNew(int(total))
+
+	for i, table := range tables {
+		if counts[i] == 0 {
+			continue
+		}
+
+		err = exportService.Export(table, batchSize, func(rows []interface{}) error {
+			if env.Cancel() {
+				return errors.New("Cancelled")
+			}
+
+			num, err := dstExportService.Import(table, rows)
+			if err != nil {
+				return err
+			}
+
+			bar.Add(int(num))
+			return nil
+		})
+		if err != nil {
+			bar.Finish()
+			env.Printf("Error copying %s: %s\n", table, err.Error())
+			return err
+		}
+	}
+
+	bar.Finish()
+
+	for i, table := range tables {
+		env.Printf("%s: %d rows\n", table, counts[i])
+	}
+
+	return nil
+}
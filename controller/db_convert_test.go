@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atongen/gosaic/service"
+)
+
+func TestDbConvert(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building index partials: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Error building comparisons: %s\n", err.Error())
+	}
+
+	dir, err := ioutil.TempDir("", "gosaic_db_convert_test")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	dsn := "bolt://" + filepath.Join(dir, "gosaic.db")
+
+	out.Reset()
+	err = DbConvert(env, dsn, 10)
+	if err != nil {
+		t.Fatalf("Error converting database: %s\n", err.Error())
+	}
+
+	testResultExpect(t, out.String(), []string{
+		"gidx: 4 rows",
+		"macros: 1 rows",
+	})
+
+	dst, err := service.NewServiceFactory(dsn)
+	if err != nil {
+		t.Fatalf("Error opening converted database: %s\n", err.Error())
+	}
+
+	for _, table := range env.ServiceFactory().MustExportService().Tables() {
+		expected, err := env.ServiceFactory().MustExportService().Count(table)
+		if err != nil {
+			t.Fatalf("Error counting %s: %s\n", table, err.Error())
+		}
+
+		num, err := dst.MustExportService().Count(table)
+		if err != nil {
+			t.Fatalf("Error counting converted %s: %s\n", table, err.Error())
+		}
+
+		if num != expected {
+			t.Fatalf("Expected %d converted %s, got %d\n", expected, table, num)
+		}
+	}
+
+	m, err := dst.MustMacroService().Get(macro.Id)
+	if err != nil || m == nil || m.Md5sum != macro.Md5sum {
+		t.Fatalf("Expected converted macro %+v, got %+v\n", macro, m)
+	}
+
+	expected, err := env.ServiceFactory().MustPartialComparisonService().CountMissing(macro)
+	if err != nil {
+		t.Fatalf("Error counting missing comparisons: %s\n", err.Error())
+	}
+	num, err := dst.MustPartialComparisonService().CountMissing(macro)
+	if err != nil || num != expected {
+		t.Fatalf("Expected %d missing converted comparisons, got %d\n", expected, num)
+	}
+
+	dst.Close()
+
+	// rows keep their ids, so the destination must be empty
+	err = DbConvert(env, dsn, 10)
+	if err == nil {
+		t.Fatal("Expected converting to a database that is not empty to fail")
+	}
+}
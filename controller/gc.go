@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"errors"
+	"github.com/atongen/gosaic/environment"
+)
+
+// gcTable is the state of a table with rows that can be orphaned
+type gcTable struct {
+	name    string
+	orphans int64
+	rows    int64
+	// bytes is -1 when the size of the table is unknown
+	bytes int64
+}
+
+// Gc deletes the rows of the database that are not reachable from any
+// project or mosaic, batchSize rows at a time, then compacts the database
+// and reports the space reclaimed by each table. A dry run only reports
+// what would be deleted.
+func Gc(env environment.Environment, batchSize int, dryRun bool) error {
+	gcService := env.ServiceFactory().MustGcService()
+
+	if batchSize <= 0 {
+		err := errors.New("Batch size must be greater than zero")
+		env.Println(err.Error())
+		return err
+	}
+
+	fileBefore, err := gcService.FileSize()
+	if err != nil {
+		env.Printf("Error getting database size: %s\n", err.Error())
+		return err
+	}
+
+	tables := make([]*gcTable, 0)
+	for _, name := range gcService.Tables() {
+		table := &gcTable{name: name}
+
+		table.orphans, err = gcService.CountOrphans(name)
+		if err != nil {
+			env.Printf("Error counting orphaned %s: %s\n", name, err.Error())
+			return err
+		}
+
+		table.rows, table.bytes, err = gcService.TableSize(name)
+		if err != nil {
+			env.Printf("Error getting size of %s: %s\n", name, err.Error())
+			return err
+		}
+
+		tables = append(tables, table)
+	}
+
+	if dryRun {
+		for _, table := range tables {
+			if table.bytes >= 0 && table.rows > 0 {
+				env.Printf("%s: would delete %d of %d rows, about %s\n", table.name, table.orphans, table.rows,
+					formatBytes(table.bytes*table.orphans/table.rows))
+			} else {
+				env.Printf("%s: would delete %d of %d rows\n", table.name, table.orphans, table.rows)
+			}
+		}
+		env.Printf("Database: %s\n", formatBytes(fileBefore))
+		return nil
+	}
+
+	for _, table := range tables {
+		if table.orphans == int64(0) {
+			continue
+		}
+
+		env.Printf("Deleting %d orphaned rows from %s...\n", table.orphans, table.name)
+
+		for {
+			if env.Cancel() {
+				return errors.New("Cancelled")
+			}
+
+			deleted, err := gcService.DeleteOrphans(table.name, batchSize)
+			if err != nil {
+				env.Printf("Error deleting orphaned %s: %s\n", table.name, err.Error())
+				return err
+			}
+
+			if deleted < int64(batchSize) {
+				break
+			}
+		}
+	}
+
+	env.Println("Compacting database...")
+
+	err = gcService.Vacuum()
+	if err != nil {
+		env.Printf("Error compacting database: %s\n", err.Error())
+		return err
+	}
+
+	err = gcService.Analyze()
+	if err != nil {
+		env.Printf("Error analyzing database: %s\n", err.Error())
+		return err
+	}
+
+	for _, table := range tables {
+		rows, bytes, err := gcService.TableSize(table.name)
+		if err != nil {
+			env.Printf("Error getting size of %s: %s\n", table.name, err.Error())
+			return err
+		}
+
+		if table.bytes >= 0 && bytes >= 0 {
+			env.Printf("%s: deleted %d rows, reclaimed %s\n", table.name, table.rows-rows, formatBytes(table.bytes-bytes))
+		} else {
+			env.Printf("%s: deleted %d rows\n", table.name, table.rows-rows)
+		}
+	}
+
+	fileAfter, err := gcService.FileSize()
+	if err != nil {
+		env.Printf("Error getting database size: %s\n", err.Error())
+		return err
+	}
+
+	env.Printf("Database: reclaimed %s, %s remaining\n", formatBytes(fileBefore-fileAfter), formatBytes(fileAfter))
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+)
+
+func TestGc(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building index partials: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Error building comparisons: %s\n", err.Error())
+	}
+
+	out.Reset()
+	err = Gc(env, 2, true)
+	if err != nil {
+		t.Fatalf("Error running dry run gc: %s\n", err.Error())
+	}
+
+	testResultExpect(t, out.String(), []string{
+		"macros: would delete 1 of 1 rows",
+		"covers: would delete 1 of 1 rows",
+		"gidx_partials: would delete",
+	})
+
+	if m, err := env.ServiceFactory().MustMacroService().Get(macro.Id); err != nil || m == nil {
+		t.Fatalf("Dry run deleted macro")
+	}
+
+	// a second macro, used by a project, is kept
+	cover2, macro2 := MacroAspect(env, "testdata/jumping_bunny.jpg", 100, 100, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover2 == nil || macro2 == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = env.ServiceFactory().MustProjectService().Insert(&model.Project{Name: "bunny", CoverId: cover2.Id, MacroId: macro2.Id})
+	if err != nil {
+		t.Fatalf("Error inserting project: %s\n", err.Error())
+	}
+
+	out.Reset()
+	err = Gc(env, 2, false)
+	if err != nil {
+		t.Fatalf("Error running gc: %s\n", err.Error())
+	}
+
+	testResultExpect(t, out.String(), []string{
+		"macros: deleted 1 rows",
+		"covers: deleted 1 rows",
+		"Database: reclaimed",
+	})
+
+	if m, err := env.ServiceFactory().MustMacroService().Get(macro.Id); err != nil || m != nil {
+		t.Fatalf("Orphaned macro not deleted")
+	}
+
+	if c, err := env.ServiceFactory().MustCoverService().Get(cover.Id); err != nil || c != nil {
+		t.Fatalf("Orphaned cover not deleted")
+	}
+
+	if m, err := env.ServiceFactory().MustMacroService().Get(macro2.Id); err != nil || m == nil {
+		t.Fatalf("Macro of project deleted")
+	}
+
+	num, err := env.ServiceFactory().MustPartialComparisonService().Count()
+	if err != nil || num != int64(0) {
+		t.Fatalf("Expected comparisons of orphaned macro to be deleted, %d remain\n", num)
+	}
+}
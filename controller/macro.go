@@ -2,6 +2,7 @@ package controller
 
 import (
 	"errors"
+	"fmt"
 	"github.com/atongen/gosaic/environment"
 	"github.com/atongen/gosaic/model"
 	"github.com/atongen/gosaic/util"
@@ -12,7 +13,7 @@ import (
 	"gopkg.in/cheggaaa/pb.v1"
 )
 
-func Macro(env environment.Environment, path string, coverId int64, outfile string) *model.Macro {
+func Macro(env environment.Environment, path string, coverId int64, grout, bleed int, outfile string) *model.Macro {
 	coverService := env.ServiceFactory().MustCoverService()
 
 	cover, err := coverService.Get(coverId)
@@ -24,7 +25,7 @@ func Macro(env environment.Environment, path string, coverId int64, outfile stri
 		return nil
 	}
 
-	macro, img, err := findOrCreateMacro(env, cover, path, outfile)
+	macro, img, err := findOrCreateMacro(env, cover, path, grout, bleed, outfile)
 	if err != nil {
 		env.Printf("Error creating macro: %s\n", err.Error())
 		return nil
@@ -39,7 +40,13 @@ func Macro(env environment.Environment, path string, coverId int64, outfile stri
 	return macro
 }
 
-func findOrCreateMacro(env environment.Environment, cover *model.Cover, path, outfile string) (*model.Macro, *image.Image, error) {
+// findOrCreateMacro finds or creates the macro for the image at path,
+// which is distinct for each cover, grout and bleed.
+// Macro partials are sampled from the area of each cover partial
+// that remains visible once grout is drawn between the tiles.
+// When bleed is positive, the image is fit within the cover inset by bleed,
+// and the bleed around it is filled by mirroring the edges of the image.
+func findOrCreateMacro(env environment.Environment, cover *model.Cover, path string, grout, bleed int, outfile string) (*model.Macro, *image.Image, error) {
 	macroService := env.ServiceFactory().MustMacroService()
 	aspectService := env.ServiceFactory().MustAspectService()
 
@@ -64,8 +71,15 @@ func findOrCreateMacro(env environment.Environment, cover *model.Cover, path, ou
 	}
 	bounds := (*img).Bounds()
 
+	if bleed < 0 || 2*bleed >= cover.Width || 2*bleed >= cover.Height {
+		return nil, nil, fmt.Errorf("Invalid bleed %d for cover %dx%d", bleed, cover.Width, cover.Height)
+	}
+
 	var imgCov image.Image
-	imgCov = imaging.Fill(*img, cover.Width, cover.Height, imaging.Center, imaging.Lanczos)
+	imgCov = imaging.Fill(*img, cover.Width-2*bleed, cover.Height-2*bleed, imaging.Center, imaging.Lanczos)
+	if bleed > 0 {
+		imgCov = padMacroBleed(imgCov, bleed)
+	}
 
 	if outfile != "" {
 		err = imaging.Save(imgCov, outfile)
@@ -75,7 +89,7 @@ func findOrCreateMacro(env environment.Environment, cover *model.Cover, path, ou
 		env.Printf("Wrote macro image: %s\n", outfile)
 	}
 
-	macro, err := macroService.GetOneBy("cover_id = ? AND md5sum = ?", cover.Id, md5sum)
+	macro, err := macroService.GetOneBy("cover_id = ? AND md5sum = ? AND grout = ? AND bleed = ?", cover.Id, md5sum, grout, bleed)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -94,6 +108,8 @@ func findOrCreateMacro(env environment.Environment, cover *model.Cover, path, ou
 			Width:       bounds.Max.X,
 			Height:      bounds.Max.Y,
 			Orientation: orientation,
+			Grout:       grout,
+			Bleed:       bleed,
 		}
 		err = macroService.Insert(macro)
 		if err != nil {
@@ -104,6 +120,36 @@ func findOrCreateMacro(env environment.Environment, cover *model.Cover, path, ou
 	return macro, &imgCov, nil
 }
 
+// padMacroBleed returns img with a border of bleed pixels,
+// which mirrors the pixels along each edge of img
+func padMacroBleed(img image.Image, bleed int) *image.NRGBA {
+	src := imaging.Clone(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w+2*bleed, h+2*bleed))
+
+	for y := 0; y < h+2*bleed; y++ {
+		sy := mirrorIndex(y-bleed, h)
+		for x := 0; x < w+2*bleed; x++ {
+			sx := mirrorIndex(x-bleed, w)
+			dst.SetNRGBA(x, y, src.NRGBAAt(sx, sy))
+		}
+	}
+
+	return dst
+}
+
+// mirrorIndex reflects i back within [0, n)
+func mirrorIndex(i, n int) int {
+	for i < 0 || i >= n {
+		if i < 0 {
+			i = -i - 1
+		} else {
+			i = 2*n - i - 1
+		}
+	}
+	return i
+}
+
 func buildMacroPartials(env environment.Environment, img *image.Image, macro *model.Macro, workers int) error {
 	macroPartialService := env.ServiceFactory().MustMacroPartialService()
 
@@ -181,7 +227,7 @@ func storeMacroPartial(img *image.Image, macro *model.Macro, coverPartial *model
 		AspectId:       coverPartial.AspectId,
 	}
 
-	pixels := util.GetImgPartialLab(img, coverPartial)
+	pixels := util.GetImgPartialLab(img, coverPartial.Inset(macro.Grout))
 	macroPartial.Pixels = pixels
 
 	add <- &macroPartial
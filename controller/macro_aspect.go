@@ -5,7 +5,7 @@ import (
 	"github.com/atongen/gosaic/model"
 )
 
-func MacroAspect(env environment.Environment, path string, coverWidth, coverHeight, partialWidth, partialHeight, size int, coverOutfile, macroOutfile string) (*model.Cover, *model.Macro) {
+func MacroAspect(env environment.Environment, path string, coverWidth, coverHeight, partialWidth, partialHeight, size, grout, bleed int, layout, coverOutfile, macroOutfile string) (*model.Cover, *model.Macro) {
 	aspectService := env.ServiceFactory().MustAspectService()
 
 	aspect, width, height, err := getImageDimensions(aspectService, path)
@@ -31,7 +31,7 @@ func MacroAspect(env environment.Environment, path string, coverWidth, coverHeig
 	}
 
 	if cover == nil {
-		cover = CoverAspect(env, myCoverWidth, myCoverHeight, myPartialWidth, myPartialHeight, size)
+		cover = CoverAspect(env, myCoverWidth, myCoverHeight, myPartialWidth, myPartialHeight, size, layout)
 		if cover == nil {
 			env.Println("Failed to create cover")
 			return nil, nil
@@ -59,7 +59,7 @@ func MacroAspect(env environment.Environment, path string, coverWidth, coverHeig
 	}
 
 	if macro == nil {
-		macro = Macro(env, path, cover.Id, macroOutfile)
+		macro = Macro(env, path, cover.Id, grout, bleed, macroOutfile)
 		if macro == nil {
 			env.Println("Failed to create macro")
 			return cover, nil
@@ -9,7 +9,7 @@ func TestMacroAspect(t *testing.T) {
 	}
 	defer env.Close()
 
-	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, "", "")
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, 0, 0, "grid", "", "")
 	if cover == nil || macro == nil {
 		t.Fatal("Failed to create cover or macro")
 	}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+)
+
+func MacroMixed(env environment.Environment, path string, coverWidth, coverHeight int, aspects []*model.Aspect, size, grout, bleed int, coverOutfile, macroOutfile string) (*model.Cover, *model.Macro) {
+	aspectService := env.ServiceFactory().MustAspectService()
+
+	aspect, width, height, err := getImageDimensions(aspectService, path)
+	if err != nil {
+		env.Printf("Error getting image aspect: %s\n", err.Error())
+		return nil, nil
+	}
+
+	myCoverWidth, myCoverHeight := calculateDimensionsFromAspect(aspect, coverWidth, coverHeight, width, height)
+
+	cover, err := envCover(env)
+	if err != nil {
+		env.Printf("Error getting cover from project environment: %s\n", err.Error())
+		return nil, nil
+	}
+
+	if cover == nil {
+		cover = CoverMixed(env, myCoverWidth, myCoverHeight, aspects, size)
+		if cover == nil {
+			env.Println("Failed to create cover")
+			return nil, nil
+		}
+	}
+
+	err = setEnvCover(env, cover)
+	if err != nil {
+		env.Printf("Error setting cover in project environment: %s\n", err.Error())
+		return nil, nil
+	}
+
+	if coverOutfile != "" {
+		err = CoverDraw(env, cover.Id, coverOutfile)
+		if err != nil {
+			env.Printf("Error drawing cover: %s\n", err.Error())
+			return cover, nil
+		}
+	}
+
+	macro, err := envMacro(env)
+	if err != nil {
+		env.Printf("Error getting macro from project environment: %s\n", err.Error())
+		return cover, nil
+	}
+
+	if macro == nil {
+		macro = Macro(env, path, cover.Id, grout, bleed, macroOutfile)
+		if macro == nil {
+			env.Println("Failed to create macro")
+			return cover, nil
+		}
+	}
+
+	err = orientMacro(env, macro)
+	if err != nil {
+		env.Printf("Error orienting macro: %s\n", err.Error())
+		return cover, nil
+	}
+
+	err = setEnvMacro(env, macro)
+	if err != nil {
+		env.Printf("Error setting macro in project environment: %s\n", err.Error())
+		return cover, nil
+	}
+
+	return cover, macro
+}
+
+// orientMacro marks macro as oriented, so that each of its partials is
+// only compared with, and filled by, index images of the same orientation.
+// Comparisons made before it was oriented are deleted to be made again.
+func orientMacro(env environment.Environment, macro *model.Macro) error {
+	if macro.Oriented {
+		return nil
+	}
+
+	macro.Oriented = true
+	err := env.ServiceFactory().MustMacroService().Update(macro)
+	if err != nil {
+		return err
+	}
+
+	return env.ServiceFactory().MustPartialComparisonService().DeleteFrom(macro)
+}
@@ -17,73 +17,10 @@ func MacroQuad(env environment.Environment,
 	path string,
 	coverWidth, coverHeight, size, minDepth, maxDepth, minArea, maxArea int,
 	coverOutfile, macroOutfile string) (*model.Cover, *model.Macro) {
-
-	aspectService := env.ServiceFactory().MustAspectService()
-	coverService := env.ServiceFactory().MustCoverService()
-
-	myCoverWidth, myCoverHeight, err := calculateDimensions(aspectService, path, coverWidth, coverHeight)
-	if err != nil {
-		env.Printf("Error getting cover dimensions: %s\n", err.Error())
-		return nil, nil
-	}
-
-	size, minDepth, maxDepth, minArea, maxArea, err = macroQuadFixArgs(myCoverWidth, myCoverHeight, size, minDepth, maxDepth, minArea, maxArea)
-	if err != nil {
-		env.Printf("Error calculating quad arguments: %s\n", err.Error())
-		return nil, nil
-	}
-
-	cover, err := envCover(env)
-	if err != nil {
-		env.Printf("Error getting cover from project environment: %s\n", err.Error())
-		return nil, nil
-	}
-
-	if cover == nil {
-		cover, err = macroQuadCreateCover(env, myCoverWidth, myCoverHeight)
-		if err != nil {
-			env.Printf("Error building cover: %s\n", err.Error())
-			return nil, nil
-		}
-	}
-
-	err = setEnvCover(env, cover)
-	if err != nil {
-		env.Printf("Error setting cover in project environment: %s\n", err.Error())
-		return nil, nil
-	}
-
-	macro, img, err := findOrCreateMacro(env, cover, path, macroOutfile)
-	if err != nil {
-		env.Printf("Error building macro: %s\n", err.Error())
-		coverService.Delete(cover)
-		return cover, nil
-	}
-
-	err = setEnvMacro(env, macro)
-	if err != nil {
-		env.Printf("Error setting macro in project environment: %s\n", err.Error())
-		return cover, nil
-	}
-
-	err = macroQuadBuildPartials(env, cover, macro, img, size, minDepth, maxDepth, minArea, maxArea)
-	if err != nil {
-		env.Printf("Error building quad partials: %s\n", err.Error())
-		return cover, nil
-	}
-
-	if coverOutfile != "" {
-		err = CoverDraw(env, cover.Id, coverOutfile)
-		if err != nil {
-			env.Printf("Error drawing cover: %s\n", err.Error())
-			return cover, nil
-		}
-	}
-
-	return cover, macro
+	return MacroSplit(env, path, "quad", coverWidth, coverHeight, size, minDepth, maxDepth, minArea, maxArea, 0, 0, coverOutfile, macroOutfile)
 }
 
-func macroQuadBuildPartials(env environment.Environment, cover *model.Cover, macro *model.Macro, img *image.Image, size, minDepth, maxDepth, minArea, maxArea int) error {
+func macroQuadBuildPartials(env environment.Environment, mode string, cover *model.Cover, macro *model.Macro, img *image.Image, size, minDepth, maxDepth, minArea, maxArea int) error {
 	coverPartialService := env.ServiceFactory().MustCoverPartialService()
 	quadDistService := env.ServiceFactory().MustQuadDistService()
 
@@ -95,13 +32,16 @@ func macroQuadBuildPartials(env environment.Environment, cover *model.Cover, mac
 	}
 	current := int(count)
 
+	// each split replaces one partial with its children
+	added := macroSplitChildren(mode) - 1
+
 	var total, remain int
 	if size > 0 {
-		total = macroQuadSplitSize(size)
+		total = macroQuadSplitSize(mode, size)
 		remain = total - current
 	} else {
 		total = -1
-		remain = 3
+		remain = added
 	}
 
 	msgSlice := []string{}
@@ -123,7 +63,7 @@ func macroQuadBuildPartials(env environment.Environment, cover *model.Cover, mac
 	if maxArea > 0 {
 		msgSlice = append(msgSlice, fmt.Sprintf("max area %d", maxArea))
 	}
-	env.Println(fmt.Sprintf("Building macro quad with %s...", strings.Join(msgSlice, ", ")))
+	env.Println(fmt.Sprintf("Building macro %s with %s...", macroSplitLabel(mode), strings.Join(msgSlice, ", ")))
 
 	bar := pb.StartNew(remain)
 
@@ -180,14 +120,14 @@ func macroQuadBuildPartials(env environment.Environment, cover *model.Cover, mac
 			}
 		}
 
-		err = macroQuadSplit(env, macro, coverPartialQuadView, img)
+		err = macroQuadSplit(env, mode, macro, coverPartialQuadView, img)
 		if err != nil {
 			return err
 		}
-		current += 3
+		current += added
 
 		if total == -1 {
-			remain += 3
+			remain += added
 			bar.Total = int64(remain)
 		}
 		bar.Set(current)
@@ -232,8 +172,8 @@ func macroQuadCreateCover(env environment.Environment, width, height int) (*mode
 	return cover, nil
 }
 
-func macroQuadSplit(env environment.Environment, macro *model.Macro, coverPartialQuadView *model.CoverPartialQuadView, img *image.Image) error {
-	coverPartials, err := macroQuadBuildCoverPartials(env, coverPartialQuadView)
+func macroQuadSplit(env environment.Environment, mode string, macro *model.Macro, coverPartialQuadView *model.CoverPartialQuadView, img *image.Image) error {
+	coverPartials, err := macroQuadBuildCoverPartials(env, mode, coverPartialQuadView, img)
 	if err != nil {
 		return err
 	}
@@ -246,26 +186,14 @@ func macroQuadSplit(env environment.Environment, macro *model.Macro, coverPartia
 	return macroQuadBuildQuadDist(env, coverPartials, macroPartials, coverPartialQuadView.QuadDist, img)
 }
 
-func macroQuadBuildCoverPartials(env environment.Environment, coverPartialQuadView *model.CoverPartialQuadView) ([]*model.CoverPartial, error) {
+func macroQuadBuildCoverPartials(env environment.Environment, mode string, coverPartialQuadView *model.CoverPartialQuadView, img *image.Image) ([]*model.CoverPartial, error) {
 	aspectService := env.ServiceFactory().MustAspectService()
 	coverPartialService := env.ServiceFactory().MustCoverPartialService()
 
-	x1 := coverPartialQuadView.CoverPartial.X1
-	y1 := coverPartialQuadView.CoverPartial.Y1
-	x2 := coverPartialQuadView.CoverPartial.X2
-	y2 := coverPartialQuadView.CoverPartial.Y2
+	pts := macroSplitPoints(mode, coverPartialQuadView.CoverPartial, img)
+	coverPartials := make([]*model.CoverPartial, len(pts))
 
-	midX := ((x2 - x1) / 2) + x1
-	midY := ((y2 - y1) / 2) + y1
-
-	coverPartials := make([]*model.CoverPartial, 4)
-
-	for i, pt := range [][]int{
-		[]int{x1, y1, midX, midY},
-		[]int{midX, y1, x2, midY},
-		[]int{x1, midY, midX, y2},
-		[]int{midX, midY, x2, y2},
-	} {
+	for i, pt := range pts {
 		cp := &model.CoverPartial{
 			CoverId: coverPartialQuadView.CoverPartial.CoverId,
 			X1:      pt[0],
@@ -295,7 +223,7 @@ func macroQuadBuildCoverPartials(env environment.Environment, coverPartialQuadVi
 func macroQuadBuildMacroPartials(env environment.Environment, macro *model.Macro, coverPartials []*model.CoverPartial, img *image.Image) ([]*model.MacroPartial, error) {
 	macroPartialService := env.ServiceFactory().MustMacroPartialService()
 
-	macroPartials := make([]*model.MacroPartial, 4)
+	macroPartials := make([]*model.MacroPartial, len(coverPartials))
 	sem := make(chan bool, 4)
 
 	for idx, coverPartial := range coverPartials {
@@ -307,7 +235,7 @@ func macroQuadBuildMacroPartials(env environment.Environment, macro *model.Macro
 				AspectId:       cp.AspectId,
 			}
 
-			macroPartial.Pixels = util.GetImgPartialLab(img, cp)
+			macroPartial.Pixels = util.GetImgPartialLab(img, cp.Inset(macro.Grout))
 			err := macroPartialService.Insert(&macroPartial)
 			if err != nil {
 				macroPartials[i] = nil
@@ -341,7 +269,7 @@ func macroQuadBuildQuadDist(env environment.Environment, coverPartials []*model.
 	sem := make(chan bool, 4)
 	errs := false
 
-	for idx := 0; idx < 4; idx++ {
+	for idx := 0; idx < len(coverPartials); idx++ {
 		sem <- true
 		go func(i int) {
 			quadDist := &model.QuadDist{
@@ -373,11 +301,12 @@ func macroQuadBuildQuadDist(env environment.Environment, coverPartials []*model.
 
 // macroQuadSplitSize returns the total number of cover partials produced
 // from splitting the rectangle n times
-func macroQuadSplitSize(n int) int {
-	return 4 + 3*n
+func macroQuadSplitSize(mode string, n int) int {
+	children := macroSplitChildren(mode)
+	return children + (children-1)*n
 }
 
-func macroQuadNewMinDepthSplits(minDepth int, cache map[int]int) (int, map[int]int) {
+func macroQuadNewMinDepthSplits(children, minDepth int, cache map[int]int) (int, map[int]int) {
 	if minDepth <= 0 {
 		return 0, cache
 	} else if minDepth == 1 {
@@ -385,19 +314,21 @@ func macroQuadNewMinDepthSplits(minDepth int, cache map[int]int) (int, map[int]i
 	} else if splits, ok := cache[minDepth]; ok {
 		return splits, cache
 	} else {
-		rSplits, cache := macroQuadNewMinDepthSplits(minDepth-1, cache)
-		splits = rSplits * 4
+		rSplits, cache := macroQuadNewMinDepthSplits(children, minDepth-1, cache)
+		splits = rSplits * children
 		cache[minDepth] = splits
 		return splits, cache
 	}
 }
 
-func macroQuadMinDepthSplits(minDepth int) int {
+// macroQuadMinDepthSplits returns the number of splits required for every
+// partial to reach minDepth, when each split produces children partials
+func macroQuadMinDepthSplits(children, minDepth int) int {
 	sum := 0
 	cache := make(map[int]int)
 	for i := 0; i <= minDepth; i++ {
 		var v int
-		v, cache = macroQuadNewMinDepthSplits(i, cache)
+		v, cache = macroQuadNewMinDepthSplits(children, i, cache)
 		sum += v
 	}
 	return sum
@@ -408,9 +339,13 @@ func macroQuadMinDepthSplits(minDepth int) int {
 // val == 0 is unrestricted
 // val > 0 sets explicitly
 // val == -1 (<0) calculates optimal
-func macroQuadFixArgs(width, height, size, minDepth, maxDepth, minArea, maxArea int) (int, int, int, int, int, error) {
+// depth values are doubled for modes that split in two, since it takes
+// two binary splits to halve both dimensions of a partial
+func macroQuadFixArgs(mode string, width, height, size, minDepth, maxDepth, minArea, maxArea int) (int, int, int, int, int, error) {
 	var cSize, cMinDepth, cMaxDepth, cMinArea, cMaxArea int
 
+	children := macroSplitChildren(mode)
+
 	area := width * height
 	normalDim := math.Sqrt(float64(area))
 
@@ -454,13 +389,13 @@ func macroQuadFixArgs(width, height, size, minDepth, maxDepth, minArea, maxArea
 		// do not restrict minDepth is size is not restricted
 		if cSize > 0 {
 			// target a minDepth that produces approx 1/60 the total number of cover partials
-			totalPartials := macroQuadSplitSize(cSize)
+			totalPartials := macroQuadSplitSize(mode, cSize)
 			minDepthSizeTarget := util.Round(float64(totalPartials) / 60.0)
 
 			// increment cMinDepth until the highest value where it doesn't exceed minDepthSizeTarget
 			splits := 0
 			for depth := 0; splits <= minDepthSizeTarget; depth += 1 {
-				splits = macroQuadMinDepthSplits(depth)
+				splits = macroQuadMinDepthSplits(children, depth)
 				if splits <= minDepthSizeTarget {
 					cMinDepth = depth
 				}
@@ -472,7 +407,7 @@ func macroQuadFixArgs(width, height, size, minDepth, maxDepth, minArea, maxArea
 
 	if cMinDepth > 0 {
 		var splits int
-		splits = macroQuadMinDepthSplits(cMinDepth)
+		splits = macroQuadMinDepthSplits(children, cMinDepth)
 		if splits > cSize {
 			return 0, 0, 0, 0, 0, fmt.Errorf("min-depth %d too large for size %d", cMinDepth, cSize)
 		}
@@ -490,6 +425,9 @@ func macroQuadFixArgs(width, height, size, minDepth, maxDepth, minArea, maxArea
 			minLength = math.Max(normalDim/float64(85), float64(35))
 		}
 		cMaxDepth = util.Round(math.Sqrt(normalDim / minLength))
+		if children == 2 {
+			cMaxDepth *= 2
+		}
 
 		if cMinDepth > 0 && cMinDepth >= cMaxDepth {
 			// fall back to basing this off cMinDepth
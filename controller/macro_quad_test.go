@@ -44,7 +44,7 @@ func TestMacroQuadFixArgs(t *testing.T) {
 			argTestOut{700, 3, 9, 1794, 360000, ""},
 		},
 	} {
-		size, minDepth, maxDepth, minArea, maxArea, err := macroQuadFixArgs(
+		size, minDepth, maxDepth, minArea, maxArea, err := macroQuadFixArgs("quad",
 			tt.t.width, tt.t.height, tt.t.size, tt.t.minDepth, tt.t.maxDepth, tt.t.minArea, tt.t.maxArea)
 		var errStr string
 		if err != nil {
@@ -98,7 +98,7 @@ func TestMacroQuadMinDepthSplits(t *testing.T) {
 		{4, 85},
 		{5, 341},
 	} {
-		r := macroQuadMinDepthSplits(tt.a)
+		r := macroQuadMinDepthSplits(4, tt.a)
 		if r != tt.r {
 			t.Errorf("macroQuadMinDepthSplits(%d) => %d, want %d", tt.a, r, tt.r)
 		}
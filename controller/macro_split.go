@@ -0,0 +1,225 @@
+package controller
+
+import (
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+	"image"
+)
+
+// MacroSplitModes are the ways a partial can be subdivided.
+// Quad splits a partial into four equal quadrants. Binary splits a partial
+// in half along its longer axis. Guillotine splits a partial in two at the
+// position that best reduces the colour variance of the two halves.
+var MacroSplitModes = []string{"quad", "binary", "guillotine"}
+
+// macroGuillotineSampleSize is the size of the grid that a partial is
+// sampled into when searching for the best guillotine cut
+const macroGuillotineSampleSize = 16
+
+// MacroSplit builds a cover and macro by repeatedly splitting
+// the partial with the worst quad dist using mode.
+func MacroSplit(env environment.Environment,
+	path, mode string,
+	coverWidth, coverHeight, size, minDepth, maxDepth, minArea, maxArea, grout, bleed int,
+	coverOutfile, macroOutfile string) (*model.Cover, *model.Macro) {
+
+	aspectService := env.ServiceFactory().MustAspectService()
+	coverService := env.ServiceFactory().MustCoverService()
+
+	if !util.SliceContainsString(MacroSplitModes, mode) {
+		env.Printf("Invalid split mode: %s\n", mode)
+		return nil, nil
+	}
+
+	myCoverWidth, myCoverHeight, err := calculateDimensions(aspectService, path, coverWidth, coverHeight)
+	if err != nil {
+		env.Printf("Error getting cover dimensions: %s\n", err.Error())
+		return nil, nil
+	}
+
+	size, minDepth, maxDepth, minArea, maxArea, err = macroQuadFixArgs(mode, myCoverWidth, myCoverHeight, size, minDepth, maxDepth, minArea, maxArea)
+	if err != nil {
+		env.Printf("Error calculating %s arguments: %s\n", mode, err.Error())
+		return nil, nil
+	}
+
+	cover, err := envCover(env)
+	if err != nil {
+		env.Printf("Error getting cover from project environment: %s\n", err.Error())
+		return nil, nil
+	}
+
+	if cover == nil {
+		cover, err = macroQuadCreateCover(env, myCoverWidth, myCoverHeight)
+		if err != nil {
+			env.Printf("Error building cover: %s\n", err.Error())
+			return nil, nil
+		}
+	}
+
+	err = setEnvCover(env, cover)
+	if err != nil {
+		env.Printf("Error setting cover in project environment: %s\n", err.Error())
+		return nil, nil
+	}
+
+	macro, img, err := findOrCreateMacro(env, cover, path, grout, bleed, macroOutfile)
+	if err != nil {
+		env.Printf("Error building macro: %s\n", err.Error())
+		coverService.Delete(cover)
+		return cover, nil
+	}
+
+	err = setEnvMacro(env, macro)
+	if err != nil {
+		env.Printf("Error setting macro in project environment: %s\n", err.Error())
+		return cover, nil
+	}
+
+	err = macroQuadBuildPartials(env, mode, cover, macro, img, size, minDepth, maxDepth, minArea, maxArea)
+	if err != nil {
+		env.Printf("Error building %s partials: %s\n", mode, err.Error())
+		return cover, nil
+	}
+
+	if coverOutfile != "" {
+		err = CoverDraw(env, cover.Id, coverOutfile)
+		if err != nil {
+			env.Printf("Error drawing cover: %s\n", err.Error())
+			return cover, nil
+		}
+	}
+
+	return cover, macro
+}
+
+// macroSplitChildren returns the number of partials produced by a single split
+func macroSplitChildren(mode string) int {
+	if mode == "quad" {
+		return 4
+	}
+	return 2
+}
+
+func macroSplitLabel(mode string) string {
+	if mode == "quad" {
+		return "quad"
+	}
+	return mode + " split"
+}
+
+// macroSplitPoints returns the x1, y1, x2, y2 points of the
+// partials produced by splitting coverPartial using mode
+func macroSplitPoints(mode string, coverPartial *model.CoverPartial, img *image.Image) [][]int {
+	x1 := coverPartial.X1
+	y1 := coverPartial.Y1
+	x2 := coverPartial.X2
+	y2 := coverPartial.Y2
+
+	switch mode {
+	case "binary":
+		if coverPartial.Width() >= coverPartial.Height() {
+			midX := ((x2 - x1) / 2) + x1
+			return [][]int{
+				[]int{x1, y1, midX, y2},
+				[]int{midX, y1, x2, y2},
+			}
+		}
+		midY := ((y2 - y1) / 2) + y1
+		return [][]int{
+			[]int{x1, y1, x2, midY},
+			[]int{x1, midY, x2, y2},
+		}
+	case "guillotine":
+		labs := util.GetImgPartialLabSize(img, coverPartial, macroGuillotineSampleSize)
+		vertical, k := macroGuillotineCut(labs, macroGuillotineSampleSize, coverPartial.Width(), coverPartial.Height())
+		if vertical {
+			cutX := x1 + coverPartial.Width()*k/macroGuillotineSampleSize
+			return [][]int{
+				[]int{x1, y1, cutX, y2},
+				[]int{cutX, y1, x2, y2},
+			}
+		}
+		cutY := y1 + coverPartial.Height()*k/macroGuillotineSampleSize
+		return [][]int{
+			[]int{x1, y1, x2, cutY},
+			[]int{x1, cutY, x2, y2},
+		}
+	default:
+		midX := ((x2 - x1) / 2) + x1
+		midY := ((y2 - y1) / 2) + y1
+		return [][]int{
+			[]int{x1, y1, midX, midY},
+			[]int{midX, y1, x2, midY},
+			[]int{x1, midY, midX, y2},
+			[]int{midX, midY, x2, y2},
+		}
+	}
+}
+
+// macroGuillotineCut finds the cut of a size by size grid of labs that
+// minimizes the total distance of each side from its average.
+// It returns whether the cut is vertical, and the grid position of the cut.
+// Cuts are kept within the middle half of the partial to avoid slivers,
+// and an axis is only cut if it is at least half as long as the other.
+// Ties favor the longer axis, and the position closest to the middle.
+func macroGuillotineCut(labs []*model.Lab, size, width, height int) (bool, int) {
+	vertical := width >= height
+	best := -1.0
+	bestK := size / 2
+
+	for _, v := range []bool{vertical, !vertical} {
+		if v && width*2 < height || !v && height*2 < width {
+			continue
+		}
+
+		for _, k := range macroGuillotineCandidates(size) {
+			var a, b []*model.Lab
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					lab := labs[y*size+x]
+					if v && x < k || !v && y < k {
+						a = append(a, lab)
+					} else {
+						b = append(b, lab)
+					}
+				}
+			}
+
+			dist := macroGuillotineDist(a) + macroGuillotineDist(b)
+			if best < 0 || dist < best {
+				best = dist
+				vertical = v
+				bestK = k
+			}
+		}
+	}
+
+	return vertical, bestK
+}
+
+// macroGuillotineCandidates returns cut positions ordered
+// from the middle of the grid outwards
+func macroGuillotineCandidates(size int) []int {
+	mid := size / 2
+	candidates := []int{mid}
+	for d := 1; mid-d >= size/4 || mid+d <= size-size/4; d++ {
+		if mid-d >= size/4 {
+			candidates = append(candidates, mid-d)
+		}
+		if mid+d <= size-size/4 {
+			candidates = append(candidates, mid+d)
+		}
+	}
+	return candidates
+}
+
+func macroGuillotineDist(labs []*model.Lab) float64 {
+	avg := util.LabAvg(labs)
+	dist := 0.0
+	for _, lab := range labs {
+		dist += lab.Dist(avg)
+	}
+	return dist
+}
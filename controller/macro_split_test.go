@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+)
+
+func TestMacroSplitBinary(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	cover, macro := MacroSplit(env, "testdata/jumping_bunny.jpg", "binary", 200, 200, 10, -1, 4, 50, -1, 0, 0, "", "")
+	if cover == nil || macro == nil {
+		fmt.Println(out.String())
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	expect := []string{
+		"Building macro binary split with 10 splits, 12 partials, max depth 4, min area 50...",
+	}
+
+	testResultExpect(t, out.String(), expect)
+
+	coverPartialService := env.ServiceFactory().MustCoverPartialService()
+	num, err := coverPartialService.Count(cover)
+	if err != nil {
+		t.Fatalf("Error counting cover partials: %s\n", err.Error())
+	}
+
+	if num != 12 {
+		t.Fatalf("Expected 12 cover partials, got %d\n", num)
+	}
+}
+
+func TestMacroSplitGuillotine(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	cover, macro := MacroSplit(env, "testdata/jumping_bunny.jpg", "guillotine", 200, 200, 10, -1, 4, 50, -1, 0, 0, "", "")
+	if cover == nil || macro == nil {
+		fmt.Println(out.String())
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	expect := []string{
+		"Building macro guillotine split with 10 splits, 12 partials, max depth 4, min area 50...",
+	}
+
+	testResultExpect(t, out.String(), expect)
+
+	coverPartialService := env.ServiceFactory().MustCoverPartialService()
+	coverPartials, err := coverPartialService.FindAll(cover.Id, "id ASC")
+	if err != nil {
+		t.Fatalf("Error finding cover partials: %s\n", err.Error())
+	}
+
+	area := 0
+	for _, cp := range coverPartials {
+		area += cp.Area()
+	}
+
+	if area != 200*200 {
+		t.Fatalf("Cover partials cover %d pixels, want %d", area, 200*200)
+	}
+}
+
+func TestMacroSplitPointsBinary(t *testing.T) {
+	for _, tt := range []struct {
+		cp     *model.CoverPartial
+		expect [][]int
+	}{
+		{
+			&model.CoverPartial{X1: 0, Y1: 0, X2: 100, Y2: 50},
+			[][]int{{0, 0, 50, 50}, {50, 0, 100, 50}},
+		},
+		{
+			&model.CoverPartial{X1: 10, Y1: 20, X2: 60, Y2: 120},
+			[][]int{{10, 20, 60, 70}, {10, 70, 60, 120}},
+		},
+	} {
+		pts := macroSplitPoints("binary", tt.cp, nil)
+		if fmt.Sprint(pts) != fmt.Sprint(tt.expect) {
+			t.Errorf("macroSplitPoints(binary, %v) => %v, want %v", tt.cp, pts, tt.expect)
+		}
+	}
+}
+
+func TestMacroGuillotineCut(t *testing.T) {
+	size := 16
+	dark := &model.Lab{L: 10}
+	light := &model.Lab{L: 90}
+
+	// left 5 columns are dark, the rest are light
+	labs := make([]*model.Lab, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if x < 5 {
+				labs[y*size+x] = dark
+			} else {
+				labs[y*size+x] = light
+			}
+		}
+	}
+
+	vertical, k := macroGuillotineCut(labs, size, 100, 100)
+	if !vertical || k != 5 {
+		t.Errorf("macroGuillotineCut => (%t, %d), want (true, 5)", vertical, k)
+	}
+
+	// a tall partial cannot be cut vertically
+	vertical, k = macroGuillotineCut(labs, size, 40, 100)
+	if vertical || k != 8 {
+		t.Errorf("macroGuillotineCut => (%t, %d), want (false, 8)", vertical, k)
+	}
+}
+
+func TestMacroQuadFixArgsBinary(t *testing.T) {
+	size, minDepth, maxDepth, minArea, maxArea, err := macroQuadFixArgs("binary", 600, 600, -1, -1, -1, -1, -1)
+	if err != nil {
+		t.Fatalf("Error fixing binary args: %s\n", err.Error())
+	}
+
+	if size != 167 || minDepth != 2 || maxDepth != 8 || minArea != 1225 || maxArea != 0 {
+		t.Errorf("macroQuadFixArgs(binary, 600, 600, -1, -1, -1, -1, -1) => (%d, %d, %d, %d, %d), want (167, 2, 8, 1225, 0)",
+			size, minDepth, maxDepth, minArea, maxArea)
+	}
+}
+
+func TestMacroQuadMinDepthSplitsBinary(t *testing.T) {
+	for _, tt := range []struct {
+		a int
+		r int
+	}{
+		{0, 0},
+		{1, 1},
+		{2, 3},
+		{3, 7},
+		{4, 15},
+	} {
+		r := macroQuadMinDepthSplits(2, tt.a)
+		if r != tt.r {
+			t.Errorf("macroQuadMinDepthSplits(2, %d) => %d, want %d", tt.a, r, tt.r)
+		}
+	}
+}
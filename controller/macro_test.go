@@ -1,6 +1,10 @@
 package controller
 
-import "testing"
+import (
+	"image"
+	"image/color"
+	"testing"
+)
 
 func TestMacro(t *testing.T) {
 	env, out, err := setupControllerTest()
@@ -12,11 +16,11 @@ func TestMacro(t *testing.T) {
 	macroPartialService := env.ServiceFactory().MustMacroPartialService()
 
 	// build a test cover
-	cover := CoverAspect(env, 594, 554, 2, 3, 10)
+	cover := CoverAspect(env, 594, 554, 2, 3, 10, "grid")
 	if cover == nil {
 		t.Fatal("Failed to create cover")
 	}
-	macro := Macro(env, "testdata/jumping_bunny.jpg", cover.Id, "")
+	macro := Macro(env, "testdata/jumping_bunny.jpg", cover.Id, 0, 0, "")
 	if macro == nil {
 		t.Fatal("Failed to create macro")
 	}
@@ -51,3 +55,77 @@ func TestMacro(t *testing.T) {
 		}
 	}
 }
+
+func TestPadMacroBleed(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+
+	dst := padMacroBleed(src, 2)
+	if dst.Bounds() != image.Rect(0, 0, 7, 6) {
+		t.Fatalf("Expected padded bounds 7x6, got %v\n", dst.Bounds())
+	}
+
+	for _, tt := range []struct {
+		pt   image.Point
+		x, y uint8
+	}{
+		{image.Pt(2, 2), 0, 0},
+		{image.Pt(4, 3), 2, 1},
+		{image.Pt(1, 2), 0, 0},
+		{image.Pt(0, 2), 1, 0},
+		{image.Pt(6, 5), 1, 0},
+		{image.Pt(5, 0), 2, 1},
+	} {
+		c := dst.NRGBAAt(tt.pt.X, tt.pt.Y)
+		if c.R != tt.x || c.G != tt.y {
+			t.Errorf("padMacroBleed pixel at %v => %v, want source pixel %d,%d\n", tt.pt, c, tt.x, tt.y)
+		}
+	}
+}
+
+func TestMacroBleed(t *testing.T) {
+	env, _, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	cover := CoverAspect(env, 200, 200, 1, 1, 4, "grid")
+	if cover == nil {
+		t.Fatal("Failed to create cover")
+	}
+
+	macro := Macro(env, "testdata/jumping_bunny.jpg", cover.Id, 0, 10, "")
+	if macro == nil {
+		t.Fatal("Failed to create macro")
+	}
+
+	if macro.Bleed != 10 {
+		t.Fatalf("Expected macro bleed 10, got %d\n", macro.Bleed)
+	}
+
+	found := Macro(env, "testdata/jumping_bunny.jpg", cover.Id, 0, 10, "")
+	if found == nil || found.Id != macro.Id {
+		t.Fatalf("Expected to find macro %d, got %+v\n", macro.Id, found)
+	}
+
+	// each grout and bleed has its own macro
+	for _, gb := range [][]int{{0, 20}, {4, 10}} {
+		other := Macro(env, "testdata/jumping_bunny.jpg", cover.Id, gb[0], gb[1], "")
+		if other == nil {
+			t.Fatalf("Failed to create macro with grout %d and bleed %d", gb[0], gb[1])
+		}
+
+		if other.Id == macro.Id || other.Grout != gb[0] || other.Bleed != gb[1] {
+			t.Fatalf("Expected a new macro with grout %d and bleed %d, got %+v\n", gb[0], gb[1], other)
+		}
+	}
+
+	if Macro(env, "testdata/jumping_bunny.jpg", cover.Id, 0, 100, "") != nil {
+		t.Fatal("Expected error creating macro with bleed larger than cover")
+	}
+}
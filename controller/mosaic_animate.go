@@ -0,0 +1,383 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/cheggaaa/pb.v1"
+
+	"github.com/disintegration/imaging"
+)
+
+// MosaicAnimateOrders are the orders in which tiles appear in an animation.
+// Fill is the order the mosaic was built in, and dist places the closest
+// matches first.
+var MosaicAnimateOrders = []string{"fill", "dist"}
+
+// mosaicAnimateHold is how long the last frame is shown, in hundredths of a second
+const mosaicAnimateHold = 200
+
+// MosaicAnimateOptions control an animation of a mosaic forming
+type MosaicAnimateOptions struct {
+	// Order is one of MosaicAnimateOrders, defaults to fill
+	Order string
+	// Zoom zooms out from the center tile to the whole mosaic,
+	// instead of placing the tiles one after another
+	Zoom bool
+	// Frames is the number of frames in the animation
+	Frames int
+	// Size is the largest pixel width or height of a frame
+	Size int
+	// Delay is the time between frames, in milliseconds
+	Delay int
+}
+
+// mosaicPartialViewsById sorts mosaic partial views in the order they were inserted
+type mosaicPartialViewsById []*model.MosaicPartialView
+
+func (v mosaicPartialViewsById) Len() int      { return len(v) }
+func (v mosaicPartialViewsById) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v mosaicPartialViewsById) Less(i, j int) bool {
+	return v[i].MosaicPartialId < v[j].MosaicPartialId
+}
+
+// mosaicPartialViewsByDist sorts mosaic partial views from the closest match
+// to the furthest, with views that have lost their comparison last
+type mosaicPartialViewsByDist []*model.MosaicPartialView
+
+func (v mosaicPartialViewsByDist) Len() int      { return len(v) }
+func (v mosaicPartialViewsByDist) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v mosaicPartialViewsByDist) Less(i, j int) bool {
+	if v[i].Dist < 0 || v[j].Dist < 0 {
+		return v[j].Dist < 0 && v[i].Dist >= 0
+	}
+	return v[i].Dist < v[j].Dist
+}
+
+// MosaicAnimate writes an animation of the mosaic forming to out. If out has
+// a .gif extension it is written as an animated gif, otherwise out is a
+// directory that a png image of each frame is written to.
+func MosaicAnimate(env environment.Environment, mosaicId int64, out string, animOpts MosaicAnimateOptions, opts MosaicDrawOptions) error {
+	mosaic, macro, cover, err := findMosaicMacroCover(env, mosaicId)
+	if err != nil {
+		env.Println(err.Error())
+		return err
+	}
+
+	if animOpts.Order == "" {
+		animOpts.Order = "fill"
+	}
+	if !util.SliceContainsString(MosaicAnimateOrders, animOpts.Order) {
+		err = fmt.Errorf("Invalid animation order: %s", animOpts.Order)
+		env.Println(err.Error())
+		return err
+	}
+
+	if animOpts.Frames <= 0 || animOpts.Size <= 0 || animOpts.Delay < 0 {
+		err = errors.New("Animation frames and size must be greater than zero, and delay cannot be negative")
+		env.Println(err.Error())
+		return err
+	}
+
+	if opts.Grout < 0 {
+		opts.Grout = macro.Grout
+	}
+
+	views, err := findMosaicPartialViews(env, mosaic)
+	if err != nil {
+		env.Printf("Error finding mosaic partials: %s\n", err.Error())
+		return err
+	}
+
+	if len(views) == 0 {
+		env.Println("This mosaic has 0 partials")
+		return nil
+	}
+
+	if animOpts.Order == "dist" {
+		sort.Stable(mosaicPartialViewsByDist(views))
+	} else {
+		sort.Stable(mosaicPartialViewsById(views))
+	}
+
+	frames := &mosaicAnimateFrames{
+		env:   env,
+		views: views,
+		cover: cover,
+		opts:  opts,
+		anim:  animOpts,
+	}
+	frames.loader = frames.newLoader()
+
+	env.Printf("Drawing %d animation frames...\n", animOpts.Frames)
+	bar := pb.StartNew(animOpts.Frames)
+
+	if strings.ToLower(filepath.Ext(out)) == ".gif" {
+		err = frames.writeGif(out, bar)
+	} else {
+		err = frames.writePngs(out, bar)
+	}
+	if err != nil {
+		env.Printf("Error drawing mosaic animation: %s\n", err.Error())
+		return err
+	}
+
+	bar.Finish()
+	env.Printf("Wrote mosaic animation: %s\n", out)
+
+	return nil
+}
+
+// mosaicAnimateFrames draws the frames of a mosaic animation
+type mosaicAnimateFrames struct {
+	env   environment.Environment
+	views []*model.MosaicPartialView
+	cover *model.Cover
+	opts  MosaicDrawOptions
+	anim  MosaicAnimateOptions
+	// loader loads the tiles of every frame
+	loader *mosaicTileLoader
+	// the frame being assembled, and the number of tiles drawn on it
+	canvas *image.NRGBA
+	placed int
+}
+
+// newLoader returns a loader of the tiles of every frame, so that each index
+// image is decoded once for the whole animation. Tiles that are placed one
+// after another are drawn once each, and are read from and written to the
+// tile cache. Zoomed tiles are drawn at a new size in each frame, which
+// would only fill the tile cache, so they are not.
+func (f *mosaicAnimateFrames) newLoader() *mosaicTileLoader {
+	width, height := f.size()
+	bounds := image.Rect(0, 0, width, height)
+
+	tiles := make([]*model.MosaicPartialView, 0)
+	add := func(viewport mosaicAnimateViewport) {
+		for _, view := range f.views {
+			rect, ok := f.tileRect(view, viewport, bounds)
+			if !ok {
+				continue
+			}
+			tiles = append(tiles, &model.MosaicPartialView{
+				Gidx: view.Gidx,
+				CoverPartial: &model.CoverPartial{
+					X1: rect.Min.X,
+					Y1: rect.Min.Y,
+					X2: rect.Max.X,
+					Y2: rect.Max.Y,
+				},
+			})
+		}
+	}
+
+	var cache *util.TileCache
+	if f.anim.Zoom {
+		for i := 1; i <= f.anim.Frames; i++ {
+			add(f.zoomViewport(i, width, height))
+		}
+	} else {
+		cache = f.env.TileCache()
+		add(f.placeViewport(width))
+	}
+
+	return newMosaicTileLoader(cache, tiles, func(view *model.MosaicPartialView) *model.CoverPartial {
+		return view.CoverPartial
+	})
+}
+
+// size returns the pixel size of the frames, which fit the whole mosaic
+// within the size of the animation
+func (f *mosaicAnimateFrames) size() (int, int) {
+	scale := float64(f.anim.Size) / float64(util.MaxInt(f.cover.Width, f.cover.Height))
+	return util.MaxInt(util.Round(float64(f.cover.Width)*scale), 1), util.MaxInt(util.Round(float64(f.cover.Height)*scale), 1)
+}
+
+func (f *mosaicAnimateFrames) bg() color.Color {
+	if f.opts.Grout > 0 {
+		return f.opts.groutColor()
+	}
+	return color.Black
+}
+
+// frame draws frame i of the animation, counting from 1
+func (f *mosaicAnimateFrames) frame(i int) (*image.NRGBA, error) {
+	width, height := f.size()
+
+	if f.anim.Zoom {
+		viewport := f.zoomViewport(i, width, height)
+		canvas := imaging.New(width, height, f.bg())
+		err := f.drawTiles(canvas, f.views, viewport)
+		return canvas, err
+	}
+
+	if f.canvas == nil {
+		f.canvas = imaging.New(width, height, f.bg())
+	}
+
+	// tiles are spread evenly across the frames,
+	// so that the last frame is the whole mosaic
+	n := (i*len(f.views) + f.anim.Frames - 1) / f.anim.Frames
+	err := f.drawTiles(f.canvas, f.views[f.placed:n], f.placeViewport(width))
+	if err != nil {
+		return nil, err
+	}
+	f.placed = n
+
+	return f.canvas, nil
+}
+
+// mosaicAnimateViewport is the area of the cover shown in a frame,
+// as its top left corner, and the scale from cover to frame pixels
+type mosaicAnimateViewport struct {
+	x, y  float64
+	scale float64
+}
+
+// placeViewport returns the viewport of the frames that tiles are placed
+// on one after another, which shows the whole mosaic in a frame of width
+func (f *mosaicAnimateFrames) placeViewport(width int) mosaicAnimateViewport {
+	return mosaicAnimateViewport{scale: float64(width) / float64(f.cover.Width)}
+}
+
+// zoomViewport returns the viewport of zoom frame i, which shrinks
+// geometrically from the whole mosaic in the last frame to the tile
+// at the center of the mosaic in the first frame
+func (f *mosaicAnimateFrames) zoomViewport(i, width, height int) mosaicAnimateViewport {
+	center := image.Pt(f.cover.Width/2, f.cover.Height/2)
+	tile := f.views[0].CoverPartial
+	for _, view := range f.views {
+		if center.In(view.CoverPartial.Rectangle()) {
+			tile = view.CoverPartial
+			break
+		}
+	}
+
+	// the smallest viewport fits the tile within the frame
+	end := float64(width) / float64(f.cover.Width)
+	start := math.Min(float64(width)/float64(tile.Width()), float64(height)/float64(tile.Height()))
+
+	t := 1.0
+	if f.anim.Frames > 1 {
+		t = float64(i-1) / float64(f.anim.Frames-1)
+	}
+	scale := start * math.Pow(end/start, t)
+
+	// the center moves from the tile to the mosaic at the same rate
+	// as the viewport grows, so that the tile stays in view
+	progress := 1.0
+	if start != end {
+		progress = (1/scale - 1/start) / (1/end - 1/start)
+	}
+	tx := float64(tile.X1+tile.X2) / 2
+	ty := float64(tile.Y1+tile.Y2) / 2
+	cx := tx + (float64(f.cover.Width)/2-tx)*progress
+	cy := ty + (float64(f.cover.Height)/2-ty)*progress
+
+	return mosaicAnimateViewport{
+		x:     cx - float64(width)/scale/2,
+		y:     cy - float64(height)/scale/2,
+		scale: scale,
+	}
+}
+
+// tileRect returns the area of a frame with bounds that the tile of view
+// fills in viewport, or false if it is not within the frame
+func (f *mosaicAnimateFrames) tileRect(view *model.MosaicPartialView, viewport mosaicAnimateViewport, bounds image.Rectangle) (image.Rectangle, bool) {
+	cp := view.CoverPartial.Inset(f.opts.Grout)
+	rect := image.Rect(
+		util.Round((float64(cp.X1)-viewport.x)*viewport.scale),
+		util.Round((float64(cp.Y1)-viewport.y)*viewport.scale),
+		util.Round((float64(cp.X2)-viewport.x)*viewport.scale),
+		util.Round((float64(cp.Y2)-viewport.y)*viewport.scale),
+	)
+	return rect, !rect.Empty() && rect.Overlaps(bounds)
+}
+
+// drawTiles draws each of views that is within the viewport onto canvas
+func (f *mosaicAnimateFrames) drawTiles(canvas *image.NRGBA, views []*model.MosaicPartialView, viewport mosaicAnimateViewport) error {
+	for _, view := range views {
+		if f.env.Cancel() {
+			return errors.New("Cancelled")
+		}
+
+		rect, ok := f.tileRect(view, viewport, canvas.Bounds())
+		if !ok {
+			continue
+		}
+
+		tile, err := f.loader.load(view.Gidx, rect.Dx(), rect.Dy())
+		if err != nil {
+			return err
+		}
+
+		draw.Draw(canvas, rect, tile, tile.Bounds().Min, draw.Src)
+	}
+
+	return nil
+}
+
+func (f *mosaicAnimateFrames) writeGif(out string, bar *pb.ProgressBar) error {
+	anim := &gif.GIF{}
+
+	for i := 1; i <= f.anim.Frames; i++ {
+		frame, err := f.frame(i)
+		if err != nil {
+			return err
+		}
+
+		paletted := image.NewPaletted(frame.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, frame.Bounds(), frame, frame.Bounds().Min)
+
+		delay := f.anim.Delay / 10
+		if i == f.anim.Frames {
+			delay = util.MaxInt(delay, mosaicAnimateHold)
+		}
+
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+		bar.Increment()
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return gif.EncodeAll(file, anim)
+}
+
+func (f *mosaicAnimateFrames) writePngs(out string, bar *pb.ProgressBar) error {
+	err := os.MkdirAll(out, 0755)
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i <= f.anim.Frames; i++ {
+		frame, err := f.frame(i)
+		if err != nil {
+			return err
+		}
+
+		err = imaging.Save(frame, filepath.Join(out, fmt.Sprintf("frame_%04d.png", i)))
+		if err != nil {
+			return err
+		}
+		bar.Increment()
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+	"github.com/disintegration/imaging"
+)
+
+func TestMosaicPartialViewsByDist(t *testing.T) {
+	views := []*model.MosaicPartialView{
+		{MosaicPartialId: 1, Dist: 0.5},
+		{MosaicPartialId: 2, Dist: -1},
+		{MosaicPartialId: 3, Dist: 0.1},
+		{MosaicPartialId: 4, Dist: 0.3},
+	}
+
+	sort.Stable(mosaicPartialViewsByDist(views))
+
+	for i, id := range []int64{3, 4, 1, 2} {
+		if views[i].MosaicPartialId != id {
+			t.Errorf("Expected view %d to have id %d, got %d\n", i, id, views[i].MosaicPartialId)
+		}
+	}
+}
+
+func TestMosaicAnimate(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_animate")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic animate test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 100, 2, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	mosaic := MosaicBuild(env, "best", macro.Id, 0, false)
+	if mosaic == nil {
+		t.Fatal("Failed to build mosaic")
+	}
+
+	animOpts := MosaicAnimateOptions{Order: "dist", Frames: 5, Size: 100, Delay: 100}
+
+	gifPath := filepath.Join(dir, "mosaic.gif")
+	err = MosaicAnimate(env, mosaic.Id, gifPath, animOpts, MosaicDrawOptions{Grout: -1})
+	if err != nil {
+		t.Fatalf("Error animating mosaic: %s\n", err.Error())
+	}
+
+	f, err := os.Open(gifPath)
+	if err != nil {
+		t.Fatalf("Error opening gif: %s\n", err.Error())
+	}
+	defer f.Close()
+
+	anim, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatalf("Error decoding gif: %s\n", err.Error())
+	}
+
+	if len(anim.Image) != 5 {
+		t.Fatalf("Expected 5 gif frames, got %d\n", len(anim.Image))
+	}
+
+	for i, frame := range anim.Image {
+		if frame.Bounds() != image.Rect(0, 0, 100, 50) {
+			t.Errorf("Expected gif frame %d to be 100x50, got %v\n", i, frame.Bounds())
+		}
+	}
+
+	if anim.Delay[0] != 10 || anim.Delay[4] != mosaicAnimateHold {
+		t.Errorf("Expected gif delays 10 and %d, got %v\n", mosaicAnimateHold, anim.Delay)
+	}
+
+	animOpts.Zoom = true
+	pngDir := filepath.Join(dir, "frames")
+	err = MosaicAnimate(env, mosaic.Id, pngDir, animOpts, MosaicDrawOptions{Grout: -1})
+	if err != nil {
+		t.Fatalf("Error animating mosaic zoom: %s\n", err.Error())
+	}
+
+	for i := 1; i <= 5; i++ {
+		img, err := imaging.Open(filepath.Join(pngDir, fmt.Sprintf("frame_%04d.png", i)))
+		if err != nil {
+			t.Fatalf("Error opening png frame %d: %s\n", i, err.Error())
+		}
+
+		if img.Bounds() != image.Rect(0, 0, 100, 50) {
+			t.Errorf("Expected png frame %d to be 100x50, got %v\n", i, img.Bounds())
+		}
+	}
+
+	animOpts.Order = "random"
+	err = MosaicAnimate(env, mosaic.Id, gifPath, animOpts, MosaicDrawOptions{Grout: -1})
+	if err == nil {
+		t.Errorf("Expected error animating mosaic in random order\n")
+	}
+
+	expect := []string{
+		"Drawing 5 animation frames...",
+		"Wrote mosaic animation: " + gifPath,
+		"Wrote mosaic animation: " + pngDir,
+		"Invalid animation order: random",
+	}
+
+	testResultExpect(t, out.String(), expect)
+}
+
+func TestMosaicAnimateLoader(t *testing.T) {
+	env, _, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	gidx := &model.Gidx{Id: 1, Md5sum: "394c43174e42e043e7b9049e1bb10a39", Width: 478, Height: 340}
+	views := []*model.MosaicPartialView{
+		{MosaicPartialId: 1, Gidx: gidx, CoverPartial: &model.CoverPartial{X1: 100, Y1: 50, X2: 200, Y2: 150}},
+		{MosaicPartialId: 2, Gidx: gidx, CoverPartial: &model.CoverPartial{X1: 200, Y1: 50, X2: 300, Y2: 150}},
+	}
+
+	frames := &mosaicAnimateFrames{
+		env:   env,
+		views: views,
+		cover: &model.Cover{Width: 400, Height: 200},
+		anim:  MosaicAnimateOptions{Zoom: true, Frames: 10, Size: 100},
+	}
+
+	// the index image is decoded once for every size of every frame
+	loader := frames.newLoader()
+	if loader.cache != nil {
+		t.Error("Expected zoom tiles not to use the tile cache")
+	}
+
+	if len(loader.sources) != 1 || len(loader.sources[gidx.Id].sizes) < 2 {
+		t.Fatalf("Expected one index image source with several sizes, got %+v\n", loader.sources)
+	}
+
+	frames.anim.Zoom = false
+	loader = frames.newLoader()
+	if loader.cache != env.TileCache() {
+		t.Error("Expected placed tiles to use the tile cache")
+	}
+
+	if len(loader.tiles) != 1 || loader.tiles[mosaicTileKey{gidx.Md5sum, 25, 25}] == nil {
+		t.Fatalf("Expected one 25x25 tile, got %+v\n", loader.tiles)
+	}
+}
@@ -6,20 +6,39 @@ import (
 )
 
 func MosaicAspect(env environment.Environment,
-	inPath, name, fillType string,
-	coverWidth, coverHeight, partialWidth, partialHeight, size, maxRepeats int,
+	inPath, name, onExists, fillType string,
+	coverWidth, coverHeight, partialWidth, partialHeight, size, maxRepeats, keepTop int,
 	threashold float64,
+	layout string,
 	coverOutfile, macroOutfile, mosaicOutfile string,
+	drawOpts MosaicDrawOptions,
 	cleanup, destructive bool) *model.Mosaic {
 
-	project, err := findOrCreateProject(env, inPath, name, coverOutfile, macroOutfile, mosaicOutfile)
+	params := &projectParams{
+		Type:          "aspect",
+		Width:         coverWidth,
+		Height:        coverHeight,
+		PartialWidth:  partialWidth,
+		PartialHeight: partialHeight,
+		Size:          size,
+		Layout:        layout,
+		Grout:         drawOpts.Grout,
+		Bleed:         drawOpts.Bleed,
+		Threashold:    threashold,
+		FillType:      fillType,
+		MaxRepeats:    maxRepeats,
+		KeepTop:       keepTop,
+		Destructive:   destructive,
+	}
+
+	project, err := findOrCreateProject(env, inPath, name, onExists, params, coverOutfile, macroOutfile, mosaicOutfile)
 	if err != nil {
 		env.Println(err.Error())
 		return nil
 	}
 	env.SetProjectId(project.Id)
 
-	cover, macro := MacroAspect(env, project.Path, coverWidth, coverHeight, partialWidth, partialHeight, size, project.CoverPath, project.MacroPath)
+	cover, macro := MacroAspect(env, project.Path, coverWidth, coverHeight, partialWidth, partialHeight, size, drawOpts.Grout, drawOpts.Bleed, layout, project.CoverPath, project.MacroPath)
 	if cover == nil || macro == nil {
 		return nil
 	}
@@ -29,17 +48,12 @@ func MosaicAspect(env environment.Environment,
 		return nil
 	}
 
-	err = Compare(env, macro.Id)
-	if err != nil {
-		return nil
-	}
-
-	mosaic := MosaicBuild(env, fillType, macro.Id, maxRepeats, destructive)
+	mosaic := mosaicCompareBuild(env, fillType, macro.Id, maxRepeats, keepTop, destructive)
 	if mosaic == nil {
 		return nil
 	}
 
-	err = MosaicDraw(env, mosaic.Id, project.MosaicPath)
+	err = MosaicDraw(env, mosaic.Id, project.MosaicPath, drawOpts)
 	if err != nil {
 		return nil
 	}
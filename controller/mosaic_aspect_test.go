@@ -26,12 +26,15 @@ func TestMosaicAspect(t *testing.T) {
 		env,
 		"testdata/jumping_bunny.jpg",
 		"Jumping Bunny",
+		"abort",
 		"best",
-		1000, 1000, 3, 2, 10, -1,
+		1000, 1000, 3, 2, 10, -1, 0,
 		-1.0,
+		"grid",
 		filepath.Join(dir, "jumping_bunny_cover.png"),
 		filepath.Join(dir, "jumping_bunny_macro.jpg"),
 		filepath.Join(dir, "jumping_bunny_mosaic.jpg"),
+		MosaicDrawOptions{},
 		true,
 		false,
 	)
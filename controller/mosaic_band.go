@@ -0,0 +1,364 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/cheggaaa/pb.v1"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/tiff"
+)
+
+// mosaicBandHeight is the pixel height of the bands that a mosaic is drawn in.
+// It is a multiple of the 16 pixel rows that jpeg encodes at a time.
+const mosaicBandHeight = 256
+
+// mosaicPartialViewsByY sorts mosaic partial views by the top of their cover partial
+type mosaicPartialViewsByY []*model.MosaicPartialView
+
+func (v mosaicPartialViewsByY) Len() int      { return len(v) }
+func (v mosaicPartialViewsByY) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+func (v mosaicPartialViewsByY) Less(i, j int) bool {
+	return v[i].CoverPartial.Y1 < v[j].CoverPartial.Y1
+}
+
+// findMosaicPartialViews returns all of the partial views of mosaic,
+// sorted from top to bottom
+func findMosaicPartialViews(env environment.Environment, mosaic *model.Mosaic) ([]*model.MosaicPartialView, error) {
+	mosaicPartialService := env.ServiceFactory().MustMosaicPartialService()
+
+	batchSize := 1000
+	views := []*model.MosaicPartialView{}
+	for {
+		if env.Cancel() {
+			return nil, errors.New("Cancelled")
+		}
+
+		batch, err := mosaicPartialService.FindAllPartialViews(mosaic, "mosaic_partials.id asc", batchSize, len(views))
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		views = append(views, batch...)
+	}
+	sort.Stable(mosaicPartialViewsByY(views))
+
+	return views, nil
+}
+
+// mosaicBandRenderer draws the tiles of a mosaic that intersect a band,
+// so that only a band of the mosaic needs to be held in memory.
+// Tiles are drawn at scale times the size of their cover partials.
+// Index images are loaded by workers goroutines, while the tiles
+// are pasted into the band on a single goroutine.
+type mosaicBandRenderer struct {
+	env     environment.Environment
+	views   []*model.MosaicPartialView
+	scale   int
+	height  int
+	offset  image.Point
+	workers int
+	// full bounds of the mosaic, and the area left once the bleed is trimmed
+	bounds image.Rectangle
+	trim   image.Rectangle
+	opts   MosaicDrawOptions
+	bg     color.Color
+	bar    *pb.ProgressBar
+	// centers of alignment marks, drawn over the tiles
+	marks    []image.Point
+	markSize int
+	// resized index images of tiles that extend below the last band drawn
+	cache  map[int]image.Image
+	loader *mosaicTileLoader
+}
+
+// mosaicBandTile is the resized index image of the view at index,
+// prepared by a worker
+type mosaicBandTile struct {
+	index int
+	img   image.Image
+	err   error
+}
+
+func newMosaicBandRenderer(env environment.Environment, views []*model.MosaicPartialView, cover *model.Cover, scale int, opts MosaicDrawOptions, bg color.Color, bar *pb.ProgressBar) *mosaicBandRenderer {
+	border := util.MaxInt(opts.OuterBorder, 0) * scale
+	bleed := util.MaxInt(opts.Bleed, 0) * scale
+	bounds := image.Rect(0, 0, cover.Width*scale+2*border, cover.Height*scale+2*border)
+	r := &mosaicBandRenderer{
+		env:     env,
+		views:   views,
+		scale:   scale,
+		height:  bounds.Dy(),
+		offset:  image.Pt(border, border),
+		workers: util.MaxInt(env.Workers(), 1),
+		bounds:  bounds,
+		trim:    bounds.Inset(border + bleed),
+		opts:    opts,
+		bg:      bg,
+		bar:     bar,
+		cache:   make(map[int]image.Image),
+	}
+	r.loader = newMosaicTileLoader(env.TileCache(), views, r.tile)
+	return r
+}
+
+func (r *mosaicBandRenderer) tile(view *model.MosaicPartialView) *model.CoverPartial {
+	scaled := &model.CoverPartial{
+		X1: view.CoverPartial.X1 * r.scale,
+		Y1: view.CoverPartial.Y1 * r.scale,
+		X2: view.CoverPartial.X2 * r.scale,
+		Y2: view.CoverPartial.Y2 * r.scale,
+	}
+	return scaled.Inset(r.opts.Grout * r.scale)
+}
+
+// draw fills band with the background, and draws every tile that intersects it.
+// Tiles are pasted in the order of the views, whatever order the workers
+// finish them in, so that the band is the same for any number of workers.
+func (r *mosaicBandRenderer) draw(band *image.NRGBA) error {
+	bounds := band.Bounds()
+	draw.Draw(band, bounds, image.NewUniform(r.bg), image.Point{}, draw.Src)
+
+	needed := []int{}
+	for i, view := range r.views {
+		if r.tile(view).Rectangle().Add(r.offset).Overlaps(bounds) {
+			needed = append(needed, i)
+		}
+	}
+
+	tiles := r.prepare(needed)
+	pending := make(map[int]image.Image)
+	next := 0
+
+	var err error
+	for t := range tiles {
+		if t.err != nil {
+			if err == nil {
+				err = t.err
+			}
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		pending[t.index] = t.img
+		for ; next < len(needed); next++ {
+			img, ok := pending[needed[next]]
+			if !ok {
+				break
+			}
+			delete(pending, needed[next])
+			r.paste(band, needed[next], img)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.opts.CropMarks && r.opts.Bleed > 0 {
+		drawCropMarks(band, r.bounds, r.trim, cropMarkWidth(r.opts.DPI)*r.scale, color.Black)
+	}
+
+	for _, mark := range r.marks {
+		drawAlignmentMark(band, mark, r.markSize, cropMarkWidth(r.opts.DPI)*r.scale)
+	}
+
+	return nil
+}
+
+// prepare sends the resized index image of each of the views at indexes
+// to the returned channel, which is closed once they have all been sent.
+// Cached images are sent as they are, and the others are prepared by
+// up to r.workers goroutines at a time.
+func (r *mosaicBandRenderer) prepare(indexes []int) <-chan mosaicBandTile {
+	tiles := make(chan mosaicBandTile)
+
+	cached := make(map[int]image.Image)
+	for _, i := range indexes {
+		if img, ok := r.cache[i]; ok {
+			cached[i] = img
+		}
+	}
+
+	go func() {
+		sem := make(chan bool, r.workers)
+
+		for _, i := range indexes {
+			if img, ok := cached[i]; ok {
+				tiles <- mosaicBandTile{index: i, img: img}
+				continue
+			}
+
+			if r.env.Cancel() {
+				tiles <- mosaicBandTile{index: i, err: errors.New("Cancelled")}
+				break
+			}
+
+			sem <- true
+			go func(i int) {
+				tile := r.tile(r.views[i])
+				img, err := r.loader.load(r.views[i].Gidx, tile.Width(), tile.Height())
+				tiles <- mosaicBandTile{index: i, img: img, err: err}
+				<-sem
+			}(i)
+		}
+
+		// wait for the last workers to finish
+		for i := 0; i < cap(sem); i++ {
+			sem <- true
+		}
+
+		close(tiles)
+	}()
+
+	return tiles
+}
+
+// paste draws the tile of the view at index i onto band, and caches its
+// image if the tile extends below band, or counts it as drawn if not
+func (r *mosaicBandRenderer) paste(band *image.NRGBA, i int, img image.Image) {
+	bounds := band.Bounds()
+	rect := r.tile(r.views[i]).Rectangle().Add(r.offset)
+
+	draw.Draw(band, rect, img, img.Bounds().Min, draw.Src)
+	if r.opts.TileRadius > 0 {
+		drawTileCorners(band, rect, r.opts.TileRadius*r.scale, r.bg)
+	}
+
+	if rect.Max.Y <= bounds.Max.Y || bounds.Max.Y >= r.height {
+		delete(r.cache, i)
+		if r.bar != nil {
+			r.bar.Increment()
+		}
+	} else {
+		r.cache[i] = img
+	}
+}
+
+// mosaicBandImage is an image that draws itself one band at a time as it
+// is read. Image encoders read pixels from top to bottom, so each band is
+// only drawn once, and memory is bounded by the size of a single band.
+// Any error while drawing is kept in err, and the background is returned.
+type mosaicBandImage struct {
+	env      environment.Environment
+	renderer *mosaicBandRenderer
+	rect     image.Rectangle
+	band     *image.NRGBA
+	err      error
+}
+
+func (m *mosaicBandImage) ColorModel() color.Model {
+	return color.NRGBAModel
+}
+
+func (m *mosaicBandImage) Bounds() image.Rectangle {
+	return m.rect
+}
+
+// Opaque lets encoders skip scanning the image for transparency
+func (m *mosaicBandImage) Opaque() bool {
+	_, _, _, a := m.renderer.bg.RGBA()
+	return a == 0xffff
+}
+
+func (m *mosaicBandImage) At(x, y int) color.Color {
+	pt := image.Pt(x, y)
+	if !pt.In(m.rect) {
+		return color.NRGBA{}
+	}
+
+	if m.band == nil || !pt.In(m.band.Rect) {
+		m.drawBand(y)
+	}
+
+	if m.band == nil {
+		return m.renderer.bg
+	}
+
+	return m.band.NRGBAAt(x, y)
+}
+
+func (m *mosaicBandImage) drawBand(y int) {
+	if m.err != nil {
+		m.band = nil
+		return
+	}
+
+	if m.env.Cancel() {
+		m.err = errors.New("Cancelled")
+		m.band = nil
+		return
+	}
+
+	y0 := m.rect.Min.Y + (y-m.rect.Min.Y)/mosaicBandHeight*mosaicBandHeight
+	y1 := util.MinInt(y0+mosaicBandHeight, m.rect.Max.Y)
+	bandRect := image.Rect(m.rect.Min.X, y0, m.rect.Max.X, y1)
+
+	if m.band != nil && m.band.Rect.Size() == bandRect.Size() {
+		m.band.Rect = bandRect
+	} else {
+		m.band = image.NewNRGBA(bandRect)
+	}
+
+	err := m.renderer.draw(m.band)
+	if err != nil {
+		m.err = err
+		m.band = nil
+	}
+}
+
+// mosaicStreamedFormats are the formats that a mosaic can be written in
+// by extension. Their encoders read the mosaic once, from top to bottom,
+// so that only a band of it is held in memory. Bmp is written bottom up,
+// and gif reads the whole image to build its palette, so neither is one.
+var mosaicStreamedFormats = map[string]imaging.Format{
+	".jpg":  imaging.JPEG,
+	".jpeg": imaging.JPEG,
+	".png":  imaging.PNG,
+	".tif":  imaging.TIFF,
+	".tiff": imaging.TIFF,
+}
+
+// saveStreamed encodes img to path, using the format of its extension,
+// with meta added. Tiff is written uncompressed in strips, rather than
+// tiles, since compressed tiff is buffered in memory.
+func saveStreamed(img image.Image, path string, meta *util.Metadata) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	format, ok := mosaicStreamedFormats[ext]
+	if !ok {
+		return fmt.Errorf("Unsupported mosaic format '%s', use .jpg, .png or .tif", ext)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case imaging.JPEG:
+		return imaging.Encode(util.NewJpegMetadataWriter(f, meta), img, format)
+	case imaging.PNG:
+		return imaging.Encode(util.NewPngMetadataWriter(f, meta), img, format)
+	default:
+		err = tiff.Encode(f, img, nil)
+		if err != nil {
+			return err
+		}
+		return util.WriteTiffMetadata(f, meta)
+	}
+}
@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atongen/gosaic/util"
+	"github.com/disintegration/imaging"
+)
+
+func TestMosaicDrawBands(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_band")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic band test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	// taller than several bands, with tiles that span band edges
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 300, 700, 1, 1, 3, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	mosaic := MosaicBuild(env, "best", macro.Id, 0, false)
+	if mosaic == nil {
+		t.Fatal("Failed to build mosaic")
+	}
+
+	views, err := findMosaicPartialViews(env, mosaic)
+	if err != nil {
+		t.Fatalf("Error finding mosaic partial views: %s\n", err.Error())
+	}
+
+	for i := 1; i < len(views); i++ {
+		if views[i].CoverPartial.Y1 < views[i-1].CoverPartial.Y1 {
+			t.Fatalf("Expected mosaic partial views sorted from top to bottom\n")
+		}
+	}
+
+	for _, name := range []string{"jumping_bunny_mosaic.tif", "jumping_bunny_mosaic.png"} {
+		outfile := filepath.Join(dir, name)
+		err = MosaicDraw(env, mosaic.Id, outfile, MosaicDrawOptions{})
+		if err != nil {
+			t.Fatalf("Error drawing mosaic %s: %s\n", name, err.Error())
+		}
+
+		img, err := imaging.Open(outfile)
+		if err != nil {
+			t.Fatalf("Error opening mosaic %s: %s\n", name, err.Error())
+		}
+
+		if img.Bounds() != image.Rect(0, 0, cover.Width, cover.Height) {
+			t.Fatalf("Expected mosaic %s bounds %dx%d, got %v\n", name, cover.Width, cover.Height, img.Bounds())
+		}
+
+		// pixels on either side of each tile's vertical middle,
+		// which fall in different bands for some tiles
+		for _, view := range views {
+			tile, err := util.GetImageCoverPartial(view.Gidx, view.CoverPartial)
+			if err != nil {
+				t.Fatalf("Error getting tile image: %s\n", err.Error())
+			}
+
+			cp := view.CoverPartial
+			for _, pt := range []image.Point{
+				image.Pt((cp.X1+cp.X2)/2, cp.Y1),
+				image.Pt((cp.X1+cp.X2)/2, cp.Y2-1),
+			} {
+				if !pt.In(img.Bounds()) {
+					continue
+				}
+
+				got := color.NRGBAModel.Convert(img.At(pt.X, pt.Y))
+				want := color.NRGBAModel.Convert((*tile).At(pt.X-cp.X1, pt.Y-cp.Y1))
+				if got != want {
+					t.Errorf("Expected mosaic %s color %v at %v, got %v\n", name, want, pt, got)
+				}
+			}
+		}
+	}
+
+	// bands drawn by any number of workers are the same
+	var bands []*image.NRGBA
+	for _, workers := range []int{1, 4} {
+		renderer := newMosaicBandRenderer(env, views, cover, 1, MosaicDrawOptions{TileRadius: 5}, color.White, nil)
+		renderer.workers = workers
+
+		band := image.NewNRGBA(image.Rect(0, 100, cover.Width, 100+mosaicBandHeight))
+		err = renderer.draw(band)
+		if err != nil {
+			t.Fatalf("Error drawing band with %d workers: %s\n", workers, err.Error())
+		}
+		bands = append(bands, band)
+	}
+
+	if !bytes.Equal(bands[0].Pix, bands[1].Pix) {
+		t.Errorf("Expected bands drawn with 1 and 4 workers to be the same\n")
+	}
+
+	expect := []string{
+		"Drawing 21 mosaic partials...",
+	}
+
+	testResultExpect(t, out.String(), expect)
+}
+
+func TestSaveStreamedFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_band")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic band test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	meta := &util.Metadata{DPI: 300}
+
+	for _, name := range []string{"mosaic.jpg", "mosaic.png", "mosaic.tif"} {
+		err = saveStreamed(img, filepath.Join(dir, name), meta)
+		if err != nil {
+			t.Errorf("Error saving %s: %s\n", name, err.Error())
+		}
+	}
+
+	// bmp and gif encoders do not read the mosaic from top to bottom
+	for _, name := range []string{"mosaic.bmp", "mosaic.gif"} {
+		path := filepath.Join(dir, name)
+		err = saveStreamed(img, path, meta)
+		if err == nil {
+			t.Errorf("Expected error saving %s\n", name)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be written\n", name)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/util"
+)
+
+// MosaicBatchResult is the outcome of a mosaic of a batch. Status is one
+// of "ok", "skipped" or "failed", and MosaicPath is only set when ok.
+type MosaicBatchResult struct {
+	Status     string
+	Path       string
+	MosaicPath string
+}
+
+func (r MosaicBatchResult) String() string {
+	return fmt.Sprintf("%s\t%s\t%s", r.Status, r.Path, r.MosaicPath)
+}
+
+// MosaicBatchPaths returns the absolute paths of the images in the directory
+// at path, or listed in the file at path. Relative paths in a list are
+// relative to its directory, and blank lines and lines starting with '#'
+// are skipped.
+func MosaicBatchPaths(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0)
+
+	if info.IsDir() {
+		files, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range files {
+			ext := strings.ToLower(filepath.Ext(f.Name()))
+			if f.Mode().IsRegular() && util.SliceContainsString([]string{".jpg", ".jpeg", ".png"}, ext) {
+				paths = append(paths, filepath.Join(path, f.Name()))
+			}
+		}
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		dir := filepath.Dir(path)
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !filepath.IsAbs(line) {
+				line = filepath.Join(dir, line)
+			}
+			paths = append(paths, line)
+		}
+
+		err = scanner.Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, p := range paths {
+		paths[i], err = filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return paths, nil
+}
+
+// MosaicBatchJobs returns a job for each of paths, copied from template.
+// Each project is named after its image, with a number added when an
+// earlier image of the batch has the same name. When outDir is set,
+// the cover, macro and mosaic images are written to it, and when
+// the deepzoom of template is set, it is the parent directory of
+// the deepzoom of each job.
+func MosaicBatchJobs(template *RunJob, paths []string, outDir string) []*RunJob {
+	jobs := make([]*RunJob, len(paths))
+	names := make(map[string]int)
+	for i, path := range paths {
+		job := *template
+		job.Path = path
+
+		ext := filepath.Ext(path)
+		name := strings.TrimSuffix(filepath.Base(path), ext)
+		names[name]++
+		if names[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, names[name])
+		}
+		job.Name = name
+
+		if outDir != "" {
+			base := filepath.Join(outDir, util.CleanStr(name))
+			job.CoverOut = base + "-cover.png"
+			job.MacroOut = base + "-macro" + ext
+			job.Out = base + "-mosaic" + ext
+		}
+
+		if template.DeepZoom != "" {
+			job.DeepZoom = filepath.Join(template.DeepZoom, util.CleanStr(name))
+		}
+
+		if template.Size != nil {
+			size := *template.Size
+			job.Size = &size
+		}
+
+		jobs[i] = &job
+	}
+	return jobs
+}
+
+// MosaicBatch runs each of the prepared jobs, and returns their results.
+// Images written by a project are skipped, since a batch run twice over
+// the same directory finds the images written by the first run in it.
+func MosaicBatch(env environment.Environment, jobs []*RunJob, onExists string) ([]MosaicBatchResult, error) {
+	projectService := env.ServiceFactory().MustProjectService()
+
+	results := make([]MosaicBatchResult, 0, len(jobs))
+	created, skipped, failed := 0, 0, 0
+	for i, job := range jobs {
+		if env.Cancel() {
+			break
+		}
+
+		output, err := projectService.ExistsBy("cover_path = ? OR macro_path = ? OR mosaic_path = ?", job.Path, job.Path, job.Path)
+		if err != nil {
+			return nil, err
+		}
+		if output {
+			skipped++
+			env.Printf("Skipping mosaic %d of %d (%s), it was written by a project\n", i+1, len(jobs), job.Path)
+			results = append(results, MosaicBatchResult{Status: "skipped", Path: job.Path})
+			continue
+		}
+
+		env.Printf("Creating mosaic %d of %d (%s)...\n", i+1, len(jobs), job.Path)
+		env.SetProjectId(int64(0))
+		err = job.Run(env, onExists)
+		if err == nil {
+			project, err := projectService.Get(env.ProjectId())
+			if err == nil && project != nil {
+				created++
+				results = append(results, MosaicBatchResult{Status: "ok", Path: job.Path, MosaicPath: project.MosaicPath})
+				continue
+			}
+		}
+
+		failed++
+		env.Printf("Mosaic %d (%s) failed\n", i+1, job.Path)
+		results = append(results, MosaicBatchResult{Status: "failed", Path: job.Path})
+	}
+
+	env.Printf("%d of %d mosaics created, %d skipped, %d failed\n", created, len(results), skipped, failed)
+
+	return results, nil
+}
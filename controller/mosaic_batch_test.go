@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+)
+
+func TestMosaicBatchPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_batch")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic batch test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	images := filepath.Join(dir, "images")
+	err = os.MkdirAll(filepath.Join(images, "sub"), 0755)
+	if err != nil {
+		t.Fatalf("Error creating images dir: %s\n", err.Error())
+	}
+
+	for _, name := range []string{"a.jpg", "B.PNG", "c.jpeg", "notes.txt", "sub/d.jpg"} {
+		err = ioutil.WriteFile(filepath.Join(images, name), []byte{}, 0644)
+		if err != nil {
+			t.Fatalf("Error writing %s: %s\n", name, err.Error())
+		}
+	}
+
+	list := filepath.Join(dir, "list.txt")
+	err = ioutil.WriteFile(list, []byte("# images\nimages/a.jpg\n\n  /tmp/e.jpg  \n#images/c.jpeg\nimages/sub/d.jpg\n"), 0644)
+	if err != nil {
+		t.Fatalf("Error writing list: %s\n", err.Error())
+	}
+
+	for _, tt := range []struct {
+		path string
+		want []string
+	}{
+		{images, []string{
+			filepath.Join(images, "B.PNG"),
+			filepath.Join(images, "a.jpg"),
+			filepath.Join(images, "c.jpeg"),
+		}},
+		{list, []string{
+			filepath.Join(images, "a.jpg"),
+			"/tmp/e.jpg",
+			filepath.Join(images, "sub", "d.jpg"),
+		}},
+	} {
+		got, err := MosaicBatchPaths(tt.path)
+		if err != nil {
+			t.Fatalf("Error finding batch paths in %s: %s\n", tt.path, err.Error())
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MosaicBatchPaths(%s) => %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	_, err = MosaicBatchPaths(filepath.Join(dir, "missing"))
+	if err == nil {
+		t.Error("Expected error finding batch paths in missing dir")
+	}
+}
+
+func TestMosaicBatchJobs(t *testing.T) {
+	size := 4
+	template := NewRunJob()
+	template.Type = "mixed"
+	template.Width = 300
+	template.Size = &size
+	template.DeepZoom = "/tmp/dz"
+
+	jobs := MosaicBatchJobs(template, []string{
+		"/a/cat.jpg",
+		"/b/cat.jpg",
+		"/a/dog.png",
+		"/c/cat.png",
+	}, "/out")
+
+	for i, tt := range []struct {
+		name, out, macroOut, coverOut, deepZoom string
+	}{
+		{"cat", "/out/cat-mosaic.jpg", "/out/cat-macro.jpg", "/out/cat-cover.png", "/tmp/dz/cat"},
+		{"cat-2", "/out/cat-2-mosaic.jpg", "/out/cat-2-macro.jpg", "/out/cat-2-cover.png", "/tmp/dz/cat-2"},
+		{"dog", "/out/dog-mosaic.png", "/out/dog-macro.png", "/out/dog-cover.png", "/tmp/dz/dog"},
+		{"cat-3", "/out/cat-3-mosaic.png", "/out/cat-3-macro.png", "/out/cat-3-cover.png", "/tmp/dz/cat-3"},
+	} {
+		job := jobs[i]
+		if job.Name != tt.name {
+			t.Errorf("%d: expected name %s, got %s", i, tt.name, job.Name)
+		}
+		if job.Out != tt.out || job.MacroOut != tt.macroOut || job.CoverOut != tt.coverOut {
+			t.Errorf("%d: expected outs %s %s %s, got %s %s %s", i,
+				tt.out, tt.macroOut, tt.coverOut, job.Out, job.MacroOut, job.CoverOut)
+		}
+		if job.DeepZoom != tt.deepZoom {
+			t.Errorf("%d: expected deepzoom %s, got %s", i, tt.deepZoom, job.DeepZoom)
+		}
+		if job.Type != "mixed" || job.Width != 300 {
+			t.Errorf("%d: expected mixed job of width 300, got %s job of width %d", i, job.Type, job.Width)
+		}
+		if job.Size == nil || *job.Size != 4 || job.Size == template.Size {
+			t.Errorf("%d: expected a copy of the template size", i)
+		}
+	}
+
+	jobs = MosaicBatchJobs(NewRunJob(), []string{"/a/cat.jpg"}, "")
+	if jobs[0].Out != "" || jobs[0].DeepZoom != "" || jobs[0].Size != nil {
+		t.Errorf("Expected job without out-dir to keep default outs, got %+v", jobs[0])
+	}
+}
+
+func TestMosaicBatchSkipped(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	path, err := filepath.Abs("testdata/jumping_bunny.jpg")
+	if err != nil {
+		t.Fatalf("Error getting image path: %s\n", err.Error())
+	}
+
+	err = env.ServiceFactory().MustProjectService().Insert(&model.Project{Name: "bunny", Path: "other.jpg", MosaicPath: path})
+	if err != nil {
+		t.Fatalf("Error inserting project: %s\n", err.Error())
+	}
+
+	job := NewRunJob()
+	job.Path = path
+	err = job.Prepare()
+	if err != nil {
+		t.Fatalf("Error preparing job: %s\n", err.Error())
+	}
+
+	results, err := MosaicBatch(env, []*RunJob{job}, "")
+	if err != nil {
+		t.Fatalf("Error running mosaic batch: %s\n", err.Error())
+	}
+
+	want := []MosaicBatchResult{{Status: "skipped", Path: path}}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Expected results %v, got %v", want, results)
+	}
+
+	for _, e := range []string{
+		"Skipping mosaic 1 of 1",
+		"0 of 1 mosaics created, 1 skipped, 0 failed",
+	} {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("Expected output to contain '%s', but it did not:\n%s\n", e, out.String())
+		}
+	}
+}
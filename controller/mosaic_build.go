@@ -11,6 +11,39 @@ import (
 )
 
 func MosaicBuild(env environment.Environment, fillType string, macroId int64, maxRepeats int, destructive bool) *model.Mosaic {
+	mosaic, maxRepeats := mosaicBuildSetup(env, macroId, maxRepeats)
+	if mosaic == nil {
+		return nil
+	}
+
+	err := doMosaicBuild(env, mosaic, fillType, maxRepeats, destructive)
+	if err != nil {
+		env.Printf("Error building mosaic: %s\n", err.Error())
+		return nil
+	}
+
+	return mosaic
+}
+
+// mosaicCompareBuild compares the partials of a macro, and builds its
+// mosaic, with the engine of env
+func mosaicCompareBuild(env environment.Environment, fillType string, macroId int64, maxRepeats, keepTop int, destructive bool) *model.Mosaic {
+	if env.Engine() == "memory" {
+		return MosaicBuildMemory(env, fillType, macroId, maxRepeats, keepTop)
+	}
+
+	err := Compare(env, macroId, keepTop)
+	if err != nil {
+		return nil
+	}
+
+	return MosaicBuild(env, fillType, macroId, maxRepeats, destructive)
+}
+
+// mosaicBuildSetup finds or creates the mosaic of the project environment
+// for a macro, and calculates the max repeats to fill it with.
+// It returns a nil mosaic when it fails.
+func mosaicBuildSetup(env environment.Environment, macroId int64, maxRepeats int) (*model.Mosaic, int) {
 	gidxPartialService := env.ServiceFactory().MustGidxPartialService()
 	macroService := env.ServiceFactory().MustMacroService()
 	macroPartialService := env.ServiceFactory().MustMacroPartialService()
@@ -19,24 +52,24 @@ func MosaicBuild(env environment.Environment, fillType string, macroId int64, ma
 	macro, err := macroService.Get(macroId)
 	if err != nil {
 		env.Printf("Error getting macro: %s\n", err.Error())
-		return nil
+		return nil, 0
 	}
 
 	if macro == nil {
 		env.Printf("Macro %d not found\n", macroId)
-		return nil
+		return nil, 0
 	}
 
 	numMacroPartials, err := macroPartialService.Count(macro)
 	if err != nil {
 		env.Printf("Error counting macro partials: %s\n", err.Error())
-		return nil
+		return nil, 0
 	}
 
 	numGidxs, err := gidxPartialService.CountForMacro(macro)
 	if err != nil {
 		env.Printf("Error counting index images: %s\n", err.Error())
-		return nil
+		return nil, 0
 	}
 
 	// maxRepeats == 0 is unrestricted
@@ -51,14 +84,14 @@ func MosaicBuild(env environment.Environment, fillType string, macroId int64, ma
 	} else if maxRepeats > 0 {
 		if numGidxs*int64(maxRepeats) < numMacroPartials {
 			env.Printf("Not enough index images (%d) to fill mosaic (%d) with max repeats set to %d", numGidxs, numMacroPartials, maxRepeats)
-			return nil
+			return nil, 0
 		}
 	}
 
 	mosaic, err := envMosaic(env)
 	if err != nil {
 		env.Printf("Error getting mosaic from project environment: %s\n", err.Error())
-		return nil
+		return nil, 0
 	}
 
 	if mosaic == nil {
@@ -68,23 +101,17 @@ func MosaicBuild(env environment.Environment, fillType string, macroId int64, ma
 		err = mosaicService.Insert(mosaic)
 		if err != nil {
 			env.Printf("Error creating mosaic: %s\n", err.Error())
-			return nil
+			return nil, 0
 		}
 	}
 
 	err = setEnvMosaic(env, mosaic)
 	if err != nil {
 		env.Printf("Error setting mosaic in project environment: %s\n", err.Error())
-		return nil
-	}
-
-	err = doMosaicBuild(env, mosaic, fillType, maxRepeats, destructive)
-	if err != nil {
-		env.Printf("Error building mosaic: %s\n", err.Error())
-		return nil
+		return nil, 0
 	}
 
-	return mosaic
+	return mosaic, maxRepeats
 }
 
 func doMosaicBuild(env environment.Environment, mosaic *model.Mosaic, fillType string, maxRepeats int, destructive bool) error {
@@ -135,23 +162,37 @@ func createMosaicPartialsRandom(env environment.Environment, mosaic *model.Mosai
 		}
 
 		var gidxPartialId int64
-		if maxRepeats == 0 || destructive {
-			gidxPartialId, err = partialComparisonService.GetClosest(macroPartial)
-		} else {
-			gidxPartialId, err = partialComparisonService.GetClosestMax(macroPartial, mosaic, maxRepeats)
-		}
-		if err != nil {
-			return err
-		}
+		for restore := true; ; restore = false {
+			if maxRepeats == 0 || destructive {
+				gidxPartialId, err = partialComparisonService.GetClosest(macroPartial)
+			} else {
+				gidxPartialId, err = partialComparisonService.GetClosestMax(macroPartial, mosaic, maxRepeats)
+			}
+			if err != nil {
+				return err
+			}
+
+			if gidxPartialId != int64(0) {
+				break
+			}
+
+			if !restore {
+				return fmt.Errorf("Error: Invalid closest gidx partial found")
+			}
 
-		if gidxPartialId == int64(0) {
-			return fmt.Errorf("Error: Invalid closest gidx partial found")
+			// every comparison kept for the macro partial
+			// has been used as many times as it can be
+			err = restorePrunedComparisons(env, mosaic, macroPartial, maxRepeats, destructive)
+			if err != nil {
+				return err
+			}
 		}
 
 		mosaicPartial := model.MosaicPartial{
 			MosaicId:       mosaic.Id,
 			MacroPartialId: macroPartial.Id,
 			GidxPartialId:  gidxPartialId,
+			Dist:           -1.0,
 		}
 
 		err = mosaicPartialService.Insert(&mosaicPartial)
@@ -189,6 +230,7 @@ func createMosaicPartialsBest(env environment.Environment, mosaic *model.Mosaic,
 	env.Printf("Building %d mosaic partials...\n", numMissing)
 	bar := pb.StartNew(int(numMissing))
 
+	var restoredId int64
 	for {
 		if env.Cancel() {
 			return errors.New("Cancelled")
@@ -204,13 +246,29 @@ func createMosaicPartialsBest(env environment.Environment, mosaic *model.Mosaic,
 		if err != nil {
 			return err
 		} else if partialComparison == nil {
-			break
+			// the comparisons kept for the remaining macro partials
+			// have all been used as many times as they can be
+			macroPartial, err := mosaicPartialService.GetMissing(mosaic)
+			if err != nil {
+				return err
+			} else if macroPartial == nil || macroPartial.Id == restoredId {
+				break
+			}
+
+			err = restorePrunedComparisons(env, mosaic, macroPartial, maxRepeats, destructive)
+			if err != nil {
+				return err
+			}
+			restoredId = macroPartial.Id
+
+			continue
 		}
 
 		mosaicPartial := model.MosaicPartial{
 			MosaicId:       mosaic.Id,
 			MacroPartialId: partialComparison.MacroPartialId,
 			GidxPartialId:  partialComparison.GidxPartialId,
+			Dist:           partialComparison.Dist,
 		}
 
 		err = mosaicPartialService.Insert(&mosaicPartial)
@@ -232,6 +290,42 @@ func createMosaicPartialsBest(env environment.Environment, mosaic *model.Mosaic,
 	return nil
 }
 
+// restorePrunedComparisons compares macroPartial again with
+// the index partials that were pruned from its comparisons
+func restorePrunedComparisons(env environment.Environment, mosaic *model.Mosaic, macroPartial *model.MacroPartial, maxRepeats int, destructive bool) error {
+	partialComparisonService := env.ServiceFactory().MustPartialComparisonService()
+
+	views, err := partialComparisonService.FindPruned(macroPartial)
+	if err != nil {
+		return err
+	}
+
+	if len(views) == 0 {
+		return nil
+	}
+
+	batchSize := 500
+	for i := 0; i < len(views); i += batchSize {
+		j := i + batchSize
+		if j > len(views) {
+			j = len(views)
+		}
+
+		_, err = partialComparisonService.BulkInsert(buildPartialComparisons(env.Log(), views[i:j]))
+		if err != nil {
+			return err
+		}
+	}
+
+	// restored comparisons of index images that have been
+	// used too many times are deleted again
+	if destructive && maxRepeats > 0 {
+		return mosaicBuildDeleteGidxDuplicates(env, mosaic, maxRepeats)
+	}
+
+	return nil
+}
+
 func mosaicBuildDestruct(env environment.Environment, mosaic *model.Mosaic, maxRepeats int, macroPartialId int64) error {
 	if maxRepeats > 0 {
 		err := mosaicBuildDeleteGidxDuplicates(env, mosaic, maxRepeats)
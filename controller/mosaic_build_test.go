@@ -14,7 +14,7 @@ func TestMosaicBuildRandom(t *testing.T) {
 		t.Fatalf("Error indexing images: %s\n", err.Error())
 	}
 
-	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, "", "")
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, 0, 0, "grid", "", "")
 	if cover == nil || macro == nil {
 		t.Fatal("Failed to create cover or macro")
 	}
@@ -24,7 +24,7 @@ func TestMosaicBuildRandom(t *testing.T) {
 		t.Fatalf("Error building partial aspects: %s\n", err.Error())
 	}
 
-	err = Compare(env, macro.Id)
+	err = Compare(env, macro.Id, 0)
 	if err != nil {
 		t.Fatalf("Comparing images: %s\n", err.Error())
 	}
@@ -53,7 +53,7 @@ func TestMosaicBuildBest(t *testing.T) {
 		t.Fatalf("Error indexing images: %s\n", err.Error())
 	}
 
-	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, "", "")
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, 0, 0, "grid", "", "")
 	if cover == nil || macro == nil {
 		t.Fatal("Failed to create cover or macro")
 	}
@@ -63,7 +63,7 @@ func TestMosaicBuildBest(t *testing.T) {
 		t.Fatalf("Error building partial aspects: %s\n", err.Error())
 	}
 
-	err = Compare(env, macro.Id)
+	err = Compare(env, macro.Id, 0)
 	if err != nil {
 		t.Fatalf("Comparing images: %s\n", err.Error())
 	}
@@ -92,7 +92,7 @@ func TestMosaicBuildRandomDestructive(t *testing.T) {
 		t.Fatalf("Error indexing images: %s\n", err.Error())
 	}
 
-	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, "", "")
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, 0, 0, "grid", "", "")
 	if cover == nil || macro == nil {
 		t.Fatal("Failed to create cover or macro")
 	}
@@ -102,7 +102,7 @@ func TestMosaicBuildRandomDestructive(t *testing.T) {
 		t.Fatalf("Error building partial aspects: %s\n", err.Error())
 	}
 
-	err = Compare(env, macro.Id)
+	err = Compare(env, macro.Id, 0)
 	if err != nil {
 		t.Fatalf("Comparing images: %s\n", err.Error())
 	}
@@ -118,3 +118,61 @@ func TestMosaicBuildRandomDestructive(t *testing.T) {
 
 	testResultExpect(t, out.String(), expect)
 }
+
+func TestMosaicBuildKeepTop(t *testing.T) {
+	for _, fillType := range []string{"random", "best"} {
+		env, out, err := setupControllerTest()
+		if err != nil {
+			t.Fatalf("Error getting test environment: %s\n", err.Error())
+		}
+
+		err = Index(env, []string{"testdata", "../service/testdata"})
+		if err != nil {
+			t.Fatalf("Error indexing images: %s\n", err.Error())
+		}
+
+		cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, 0, 0, "grid", "", "")
+		if cover == nil || macro == nil {
+			t.Fatal("Failed to create cover or macro")
+		}
+
+		err = PartialAspect(env, macro.Id, -1.0)
+		if err != nil {
+			t.Fatalf("Error building partial aspects: %s\n", err.Error())
+		}
+
+		err = Compare(env, macro.Id, 1)
+		if err != nil {
+			t.Fatalf("Comparing images: %s\n", err.Error())
+		}
+
+		partialComparisonService := env.ServiceFactory().MustPartialComparisonService()
+		num, err := partialComparisonService.Count()
+		if err != nil {
+			t.Fatalf("Error counting comparisons: %s\n", err.Error())
+		}
+
+		if num != int64(150) {
+			t.Fatalf("Expected 1 comparison to be kept for each of 150 macro partials, got %d\n", num)
+		}
+
+		// each index image can only be used 38 times, so the
+		// closest comparison of most macro partials runs out
+		mosaic := MosaicBuild(env, fillType, macro.Id, -1, false)
+		if mosaic == nil {
+			t.Fatalf("Failed to build %s mosaic:\n%s\n", fillType, out.String())
+		}
+
+		missing, err := env.ServiceFactory().MustMosaicPartialService().CountMissing(mosaic)
+		if err != nil {
+			t.Fatalf("Error counting missing mosaic partials: %s\n", err.Error())
+		}
+
+		if missing != int64(0) {
+			t.Fatalf("Expected %s mosaic to be filled, %d partials missing\n", fillType, missing)
+		}
+
+		testResultExpect(t, out.String(), []string{"Building 150 mosaic partials..."})
+		env.Close()
+	}
+}
@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"gopkg.in/cheggaaa/pb.v1"
+
+	"github.com/disintegration/imaging"
+)
+
+// DeepZoomFormats are the supported tile pyramid layouts.
+// Dzi writes a Deep Zoom Image descriptor, with tiles in a _files directory.
+// Xyz writes square tiles to z/x/y.jpg, as used by slippy map viewers.
+var DeepZoomFormats = []string{"dzi", "xyz"}
+
+const (
+	deepZoomTileSize = 256
+	deepZoomMaxScale = 16
+)
+
+// deepZoomLevel is a single level of a tile pyramid
+type deepZoomLevel struct {
+	Level  int
+	Width  int
+	Height int
+	Cols   int
+	Rows   int
+}
+
+// getDeepZoomLevels returns the levels of the tile pyramid for an image
+// of width by height pixels, from the largest level to the smallest.
+// Each level is half the size of the previous level, rounded up.
+// Dzi pyramids continue down to a single pixel, and xyz pyramids
+// down to a single tile.
+func getDeepZoomLevels(format string, width, height int) []deepZoomLevel {
+	maxDim := float64(util.MaxInt(width, height))
+
+	var maxLevel int
+	if format == "xyz" {
+		maxLevel = int(math.Ceil(math.Log2(maxDim / float64(deepZoomTileSize))))
+	} else {
+		maxLevel = int(math.Ceil(math.Log2(maxDim)))
+	}
+	maxLevel = util.MaxInt(maxLevel, 0)
+
+	levels := make([]deepZoomLevel, maxLevel+1)
+	w, h := width, height
+	for i := 0; i <= maxLevel; i++ {
+		levels[i] = deepZoomLevel{
+			Level:  maxLevel - i,
+			Width:  w,
+			Height: h,
+			Cols:   (w + deepZoomTileSize - 1) / deepZoomTileSize,
+			Rows:   (h + deepZoomTileSize - 1) / deepZoomTileSize,
+		}
+		w = (w + 1) / 2
+		h = (h + 1) / 2
+	}
+
+	return levels
+}
+
+func deepZoomTilePath(dir, name, format string, level, col, row int) string {
+	if format == "xyz" {
+		return filepath.Join(dir, strconv.Itoa(level), strconv.Itoa(col), strconv.Itoa(row)+".jpg")
+	}
+	return filepath.Join(dir, name+"_files", strconv.Itoa(level), fmt.Sprintf("%d_%d.jpg", col, row))
+}
+
+// getDeepZoomAutoScale returns the power of two by which the mosaic must be
+// scaled for the median tile to be drawn at the full resolution of its
+// index image.
+func getDeepZoomAutoScale(views []*model.MosaicPartialView, grout int) int {
+	if len(views) == 0 {
+		return 1
+	}
+
+	ratios := make([]float64, len(views))
+	for i, view := range views {
+		tile := view.CoverPartial.Inset(grout)
+		ratios[i] = math.Min(
+			float64(view.Gidx.Width)/float64(tile.Width()),
+			float64(view.Gidx.Height)/float64(tile.Height()),
+		)
+	}
+	sort.Float64s(ratios)
+	median := ratios[len(ratios)/2]
+
+	scale := 1
+	for float64(scale) < median && scale < deepZoomMaxScale {
+		scale *= 2
+	}
+
+	return scale
+}
+
+// drawMosaicDeepZoom writes a tile pyramid of the mosaic to opts.DeepZoom.
+// The largest level is drawn directly from the index images, scaled up from
+// the mosaic by opts.DeepZoomScale, so that zooming in reveals the detail
+// of each photo. It is drawn one row of tiles at a time to bound memory.
+// Each smaller level is then built from the tiles of the level above it.
+func drawMosaicDeepZoom(env environment.Environment, mosaic *model.Mosaic, cover *model.Cover, name string, opts MosaicDrawOptions) error {
+	format := opts.DeepZoomFormat
+	if format == "" {
+		format = "dzi"
+	}
+	if !util.SliceContainsString(DeepZoomFormats, format) {
+		return fmt.Errorf("Invalid deep zoom format: %s", format)
+	}
+
+	views, err := findMosaicPartialViews(env, mosaic)
+	if err != nil {
+		return err
+	}
+
+	scale := opts.DeepZoomScale
+	if scale <= 0 {
+		scale = getDeepZoomAutoScale(views, opts.Grout)
+	}
+
+	border := util.MaxInt(opts.OuterBorder, 0)
+	width := (cover.Width + 2*border) * scale
+	height := (cover.Height + 2*border) * scale
+
+	levels := getDeepZoomLevels(format, width, height)
+
+	total := 0
+	for _, level := range levels {
+		total += level.Cols * level.Rows
+	}
+
+	env.Printf("Drawing %d deep zoom tiles at %dx scale...\n", total, scale)
+	bar := pb.StartNew(total)
+
+	err = os.MkdirAll(opts.DeepZoom, 0755)
+	if err != nil {
+		return err
+	}
+
+	bg := opts.groutColor()
+	if !opts.decorated() {
+		bg = color.Black
+	}
+
+	renderer := newMosaicBandRenderer(env, views, cover, scale, opts, bg, nil)
+	err = drawDeepZoomBase(env, renderer, levels[0], name, format, bg, opts, bar)
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i < len(levels); i++ {
+		err = drawDeepZoomLevel(env, levels[i], levels[i-1], name, format, bg, opts, bar)
+		if err != nil {
+			return err
+		}
+	}
+
+	if format == "dzi" {
+		err = writeDeepZoomDescriptor(filepath.Join(opts.DeepZoom, name+".dzi"), width, height)
+		if err != nil {
+			return err
+		}
+	}
+
+	bar.Finish()
+	return nil
+}
+
+func drawDeepZoomBase(env environment.Environment, renderer *mosaicBandRenderer, level deepZoomLevel, name, format string, bg color.Color, opts MosaicDrawOptions, bar *pb.ProgressBar) error {
+	band := image.NewNRGBA(image.Rect(0, 0, level.Width, deepZoomTileSize))
+
+	for row := 0; row < level.Rows; row++ {
+		if env.Cancel() {
+			return errors.New("Cancelled")
+		}
+
+		y0 := row * deepZoomTileSize
+		y1 := util.MinInt(y0+deepZoomTileSize, level.Height)
+		band.Rect = image.Rect(0, y0, level.Width, y1)
+
+		err := renderer.draw(band)
+		if err != nil {
+			return err
+		}
+
+		for col := 0; col < level.Cols; col++ {
+			x0 := col * deepZoomTileSize
+			x1 := util.MinInt(x0+deepZoomTileSize, level.Width)
+			tileImg := band.SubImage(image.Rect(x0, y0, x1, y1))
+
+			err := saveDeepZoomTile(tileImg, deepZoomTilePath(opts.DeepZoom, name, format, level.Level, col, row), format, bg)
+			if err != nil {
+				return err
+			}
+			bar.Increment()
+		}
+	}
+
+	return nil
+}
+
+// drawDeepZoomLevel builds each tile of level by downsampling
+// the four tiles of the larger level above it
+func drawDeepZoomLevel(env environment.Environment, level, above deepZoomLevel, name, format string, bg color.Color, opts MosaicDrawOptions, bar *pb.ProgressBar) error {
+	for row := 0; row < level.Rows; row++ {
+		for col := 0; col < level.Cols; col++ {
+			if env.Cancel() {
+				return errors.New("Cancelled")
+			}
+
+			canvas := imaging.New(2*deepZoomTileSize, 2*deepZoomTileSize, bg)
+			for dy := 0; dy < 2; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					c := 2*col + dx
+					r := 2*row + dy
+					if c >= above.Cols || r >= above.Rows {
+						continue
+					}
+
+					child, err := imaging.Open(deepZoomTilePath(opts.DeepZoom, name, format, above.Level, c, r))
+					if err != nil {
+						return err
+					}
+					draw.Draw(canvas, child.Bounds().Add(image.Pt(dx*deepZoomTileSize, dy*deepZoomTileSize)), child, child.Bounds().Min, draw.Src)
+				}
+			}
+
+			w := util.MinInt(2*deepZoomTileSize, above.Width-2*col*deepZoomTileSize)
+			h := util.MinInt(2*deepZoomTileSize, above.Height-2*row*deepZoomTileSize)
+			tw := util.MinInt(deepZoomTileSize, level.Width-col*deepZoomTileSize)
+			th := util.MinInt(deepZoomTileSize, level.Height-row*deepZoomTileSize)
+
+			tileImg := imaging.Resize(canvas.SubImage(image.Rect(0, 0, w, h)), tw, th, imaging.Lanczos)
+
+			err := saveDeepZoomTile(tileImg, deepZoomTilePath(opts.DeepZoom, name, format, level.Level, col, row), format, bg)
+			if err != nil {
+				return err
+			}
+			bar.Increment()
+		}
+	}
+
+	return nil
+}
+
+// saveDeepZoomTile writes img to path. Xyz tiles are always square,
+// so partial tiles at the edges are padded with bg.
+func saveDeepZoomTile(img image.Image, path, format string, bg color.Color) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	bounds := img.Bounds()
+	if format == "xyz" && (bounds.Dx() < deepZoomTileSize || bounds.Dy() < deepZoomTileSize) {
+		padded := imaging.New(deepZoomTileSize, deepZoomTileSize, bg)
+		draw.Draw(padded, image.Rect(0, 0, bounds.Dx(), bounds.Dy()), img, bounds.Min, draw.Src)
+		img = padded
+	}
+
+	return imaging.Save(img, path)
+}
+
+func writeDeepZoomDescriptor(path string, width, height int) error {
+	dzi := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Image xmlns="http://schemas.microsoft.com/deepzoom/2008" Format="jpg" Overlap="0" TileSize="%d">
+  <Size Width="%d" Height="%d"/>
+</Image>
+`, deepZoomTileSize, width, height)
+
+	return ioutil.WriteFile(path, []byte(dzi), 0644)
+}
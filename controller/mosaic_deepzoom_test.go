@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestGetDeepZoomLevels(t *testing.T) {
+	for _, tt := range []struct {
+		format        string
+		width, height int
+		levels        []deepZoomLevel
+	}{
+		{"dzi", 1, 1, []deepZoomLevel{{0, 1, 1, 1, 1}}},
+		{"dzi", 600, 300, []deepZoomLevel{
+			{10, 600, 300, 3, 2},
+			{9, 300, 150, 2, 1},
+			{8, 150, 75, 1, 1},
+			{7, 75, 38, 1, 1},
+			{6, 38, 19, 1, 1},
+			{5, 19, 10, 1, 1},
+			{4, 10, 5, 1, 1},
+			{3, 5, 3, 1, 1},
+			{2, 3, 2, 1, 1},
+			{1, 2, 1, 1, 1},
+			{0, 1, 1, 1, 1},
+		}},
+		{"xyz", 200, 100, []deepZoomLevel{{0, 200, 100, 1, 1}}},
+		{"xyz", 600, 300, []deepZoomLevel{
+			{2, 600, 300, 3, 2},
+			{1, 300, 150, 2, 1},
+			{0, 150, 75, 1, 1},
+		}},
+	} {
+		levels := getDeepZoomLevels(tt.format, tt.width, tt.height)
+		if len(levels) != len(tt.levels) {
+			t.Errorf("getDeepZoomLevels(%s, %d, %d) => %v, want %v", tt.format, tt.width, tt.height, levels, tt.levels)
+			continue
+		}
+		for i := range levels {
+			if levels[i] != tt.levels[i] {
+				t.Errorf("getDeepZoomLevels(%s, %d, %d) => %v, want %v", tt.format, tt.width, tt.height, levels, tt.levels)
+				break
+			}
+		}
+	}
+}
+
+func TestMosaicDrawDeepZoom(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_deepzoom")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic deep zoom test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	mosaic := MosaicBuild(env, "best", macro.Id, 0, false)
+	if mosaic == nil {
+		t.Fatal("Failed to build mosaic")
+	}
+
+	dziDir := filepath.Join(dir, "dzi")
+	err = MosaicDraw(env, mosaic.Id, filepath.Join(dir, "bunny.jpg"), MosaicDrawOptions{
+		DeepZoom:      dziDir,
+		DeepZoomScale: 2,
+	})
+	if err != nil {
+		t.Fatalf("Error drawing dzi mosaic: %s\n", err.Error())
+	}
+
+	xyzDir := filepath.Join(dir, "xyz")
+	err = MosaicDraw(env, mosaic.Id, filepath.Join(dir, "bunny.jpg"), MosaicDrawOptions{
+		DeepZoom:       xyzDir,
+		DeepZoomFormat: "xyz",
+		DeepZoomScale:  2,
+	})
+	if err != nil {
+		t.Fatalf("Error drawing xyz mosaic: %s\n", err.Error())
+	}
+
+	expect := []string{
+		"Drawing 13 deep zoom tiles at 2x scale...",
+		"Drawing 5 deep zoom tiles at 2x scale...",
+		"Wrote deep zoom tiles: " + dziDir,
+		"Wrote deep zoom tiles: " + xyzDir,
+	}
+
+	testResultExpect(t, out.String(), expect)
+
+	if _, err := os.Stat(filepath.Join(dziDir, "bunny.dzi")); err != nil {
+		t.Errorf("Expected dzi descriptor: %s\n", err.Error())
+	}
+
+	for _, tt := range []struct {
+		path string
+		size image.Point
+	}{
+		{filepath.Join(dziDir, "bunny_files", "9", "0_0.jpg"), image.Pt(256, 256)},
+		{filepath.Join(dziDir, "bunny_files", "9", "1_1.jpg"), image.Pt(144, 144)},
+		{filepath.Join(dziDir, "bunny_files", "8", "0_0.jpg"), image.Pt(200, 200)},
+		{filepath.Join(dziDir, "bunny_files", "0", "0_0.jpg"), image.Pt(1, 1)},
+		{filepath.Join(xyzDir, "1", "1", "1.jpg"), image.Pt(256, 256)},
+		{filepath.Join(xyzDir, "0", "0", "0.jpg"), image.Pt(256, 256)},
+	} {
+		img, err := imaging.Open(tt.path)
+		if err != nil {
+			t.Errorf("Error opening deep zoom tile: %s\n", err.Error())
+			continue
+		}
+		if img.Bounds().Size() != tt.size {
+			t.Errorf("Deep zoom tile %s is %v, want %v", tt.path, img.Bounds().Size(), tt.size)
+		}
+	}
+}
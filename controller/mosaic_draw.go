@@ -6,67 +6,214 @@ import (
 	"github.com/atongen/gosaic/environment"
 	"github.com/atongen/gosaic/model"
 	"github.com/atongen/gosaic/util"
+	"image"
 	"image/color"
+	"image/draw"
+	"path/filepath"
+	"strings"
 
 	"gopkg.in/cheggaaa/pb.v1"
-
-	"github.com/disintegration/imaging"
 )
 
-func MosaicDraw(env environment.Environment, mosaicId int64, outfile string) error {
+// MosaicDrawOptions control the decoration drawn around mosaic tiles.
+// The zero value draws tiles edge to edge, without any decoration.
+// They also control the metadata written to the mosaic image.
+type MosaicDrawOptions struct {
+	// Grout is the pixel width of the gap between tiles.
+	// A negative value uses the grout that the macro was sampled with.
+	Grout int
+	// GroutColor fills the gaps between tiles, the rounded corners
+	// and the outer border. Defaults to white.
+	GroutColor color.Color
+	// TileRadius is the pixel radius of rounded tile corners.
+	TileRadius int
+	// OuterBorder is the pixel width of the border added around the mosaic.
+	OuterBorder int
+	// Bleed is the pixel width of the edge of the mosaic that is trimmed
+	// off after printing. The macro image is padded to fill it, so
+	// MosaicDraw always uses the bleed of the macro.
+	Bleed int
+	// CropMarks draws marks in the bleed, in line with the trim edges.
+	CropMarks bool
+	// DPI is the print resolution recorded in the mosaic image.
+	// Defaults to util.DefaultDPI.
+	DPI int
+	// DeepZoom is a directory to write a tile pyramid of the mosaic to.
+	// No pyramid is written if it is empty.
+	DeepZoom string
+	// DeepZoomFormat is one of DeepZoomFormats, defaults to dzi.
+	DeepZoomFormat string
+	// DeepZoomScale is how many times larger than the mosaic the largest
+	// level of the pyramid is. Zero calculates it from the index images.
+	DeepZoomScale int
+	// Metadata is one of util.MetadataPolicies, and controls which exif
+	// metadata is copied from the macro image. Defaults to safe.
+	Metadata string
+}
+
+// Validate returns an error naming the first option that is out of range
+func (opts MosaicDrawOptions) Validate() error {
+	if opts.TileRadius < 0 {
+		return errors.New("tile-radius cannot be negative")
+	}
+
+	if opts.OuterBorder < 0 {
+		return errors.New("outer-border cannot be negative")
+	}
+
+	if !util.SliceContainsString(DeepZoomFormats, opts.DeepZoomFormat) {
+		return errors.New("Invalid deepzoom-format")
+	}
+
+	if opts.DeepZoomScale < 0 {
+		return errors.New("deepzoom-scale cannot be negative")
+	}
+
+	if opts.DPI <= 0 {
+		return errors.New("dpi must be greater than zero")
+	}
+
+	if !util.SliceContainsString(util.MetadataPolicies, opts.Metadata) {
+		return errors.New("Invalid metadata")
+	}
+
+	return nil
+}
+
+// PrintCoverSize returns the pixel size of a cover, from either the physical
+// printSize or the width and height, with the physical bleed added around it.
+// It sets the bleed of opts, and uses its dpi.
+func PrintCoverSize(width, height int, printSize, bleed string, opts *MosaicDrawOptions) (int, int, error) {
+	if printSize != "" {
+		if width != 0 || height != 0 {
+			return 0, 0, errors.New("print-size cannot be used with width or height")
+		}
+
+		w, h, err := util.ParsePrintSize(printSize)
+		if err != nil {
+			return 0, 0, err
+		}
+		width = util.InchesToPixels(w, opts.DPI)
+		height = util.InchesToPixels(h, opts.DPI)
+	}
+
+	if bleed != "" {
+		b, err := util.ParseLength(bleed)
+		if err != nil {
+			return 0, 0, err
+		}
+		opts.Bleed = util.InchesToPixels(b, opts.DPI)
+	}
+
+	if opts.Bleed > 0 {
+		if width == 0 || height == 0 {
+			return 0, 0, errors.New("bleed requires print-size, or both width and height")
+		}
+		width += 2 * opts.Bleed
+		height += 2 * opts.Bleed
+	}
+
+	if opts.CropMarks && opts.Bleed == 0 {
+		return 0, 0, errors.New("crop-marks requires bleed")
+	}
+
+	return width, height, nil
+}
+
+func (opts MosaicDrawOptions) decorated() bool {
+	return opts.Grout > 0 || opts.TileRadius > 0 || opts.OuterBorder > 0
+}
+
+func (opts MosaicDrawOptions) groutColor() color.Color {
+	if opts.GroutColor == nil {
+		return color.White
+	}
+	return opts.GroutColor
+}
+
+func MosaicDraw(env environment.Environment, mosaicId int64, outfile string, opts MosaicDrawOptions) error {
+	mosaic, macro, cover, err := findMosaicMacroCover(env, mosaicId)
+	if err != nil {
+		env.Println(err.Error())
+		return err
+	}
+
+	if opts.Grout < 0 {
+		opts.Grout = macro.Grout
+	}
+	opts.Bleed = macro.Bleed
+
+	meta, err := util.NewMetadata(macro.Path, opts.Metadata, opts.DPI)
+	if err != nil {
+		env.Printf("Error reading metadata: %s\n", err.Error())
+		return err
+	}
+
+	err = drawMosaic(env, mosaic, cover, outfile, meta, opts)
+	if err != nil {
+		env.Printf("Error drawing mosaic: %s\n", err.Error())
+		return err
+	}
+	env.Printf("Wrote mosaic image: %s\n", outfile)
+
+	if opts.DeepZoom != "" {
+		name := strings.TrimSuffix(filepath.Base(outfile), filepath.Ext(outfile))
+		err = drawMosaicDeepZoom(env, mosaic, cover, name, opts)
+		if err != nil {
+			env.Printf("Error drawing deep zoom tiles: %s\n", err.Error())
+			return err
+		}
+		env.Printf("Wrote deep zoom tiles: %s\n", opts.DeepZoom)
+	}
+
+	if cache := env.TileCache(); cache != nil {
+		_, err = cache.Prune(cache.MaxSize)
+		if err != nil {
+			env.Printf("Error pruning tile cache: %s\n", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// findMosaicMacroCover returns the mosaic with mosaicId,
+// along with the macro and cover that it was built from
+func findMosaicMacroCover(env environment.Environment, mosaicId int64) (*model.Mosaic, *model.Macro, *model.Cover, error) {
 	macroService := env.ServiceFactory().MustMacroService()
 	coverService := env.ServiceFactory().MustCoverService()
 	mosaicService := env.ServiceFactory().MustMosaicService()
 
 	mosaic, err := mosaicService.Get(mosaicId)
 	if err != nil {
-		env.Printf("Error getting mosaic id %d: %s\n", mosaicId, err.Error())
-		return err
+		return nil, nil, nil, fmt.Errorf("Error getting mosaic id %d: %s", mosaicId, err.Error())
 	}
 
 	if mosaic == nil {
-		msg := fmt.Sprintf("Mosaic id %d does not exist\n", mosaicId)
-		env.Println(msg)
-		return errors.New(msg)
+		return nil, nil, nil, fmt.Errorf("Mosaic id %d does not exist", mosaicId)
 	}
 
 	macro, err := macroService.Get(mosaic.MacroId)
 	if err != nil {
-		env.Printf("Error getting macro: %s\n", err.Error())
-		return err
+		return nil, nil, nil, fmt.Errorf("Error getting macro: %s", err.Error())
 	}
 
 	if macro == nil {
-		msg := fmt.Sprintf("Macro id %d does not exist\n", mosaic.MacroId)
-		env.Println(msg)
-		return errors.New(msg)
+		return nil, nil, nil, fmt.Errorf("Macro id %d does not exist", mosaic.MacroId)
 	}
 
 	cover, err := coverService.Get(macro.CoverId)
 	if err != nil {
-		env.Printf("Error getting cover: %s\n", err.Error())
-		return err
+		return nil, nil, nil, fmt.Errorf("Error getting cover: %s", err.Error())
 	}
 
 	if cover == nil {
-		msg := fmt.Sprintf("Cover id %d does not exist\n", macro.CoverId)
-		env.Println(msg)
-		return errors.New(msg)
+		return nil, nil, nil, fmt.Errorf("Cover id %d does not exist", macro.CoverId)
 	}
 
-	err = drawMosaic(env, mosaic, cover, outfile)
-	if err != nil {
-		env.Printf("Error drawing mosaic: %s\n", err.Error())
-		return err
-	}
-	env.Printf("Wrote mosaic image: %s\n", outfile)
-
-	writeExif("", macro.Path, outfile)
-
-	return nil
+	return mosaic, macro, cover, nil
 }
 
-func drawMosaic(env environment.Environment, mosaic *model.Mosaic, cover *model.Cover, outfile string) error {
+func drawMosaic(env environment.Environment, mosaic *model.Mosaic, cover *model.Cover, outfile string, meta *util.Metadata, opts MosaicDrawOptions) error {
 	mosaicPartialService := env.ServiceFactory().MustMosaicPartialService()
 
 	numPartials, err := mosaicPartialService.Count(mosaic)
@@ -79,58 +226,99 @@ func drawMosaic(env environment.Environment, mosaic *model.Mosaic, cover *model.
 		return nil
 	}
 
-	dst := imaging.New(int(cover.Width), int(cover.Height), color.NRGBA{0, 0, 0, 0})
+	views, err := findMosaicPartialViews(env, mosaic)
+	if err != nil {
+		return err
+	}
 
-	batchSize := 100
-	numCreated := 0
+	// the mosaic is streamed to the encoder, so it must be opaque
+	var bg color.Color = color.Black
+	if opts.decorated() {
+		bg = opts.groutColor()
+	}
 
 	env.Printf("Drawing %d mosaic partials...\n", numPartials)
 	bar := pb.StartNew(int(numPartials))
 
-	for {
-		if env.Cancel() {
-			return errors.New("Cancelled")
-		}
+	border := util.MaxInt(opts.OuterBorder, 0)
+	img := &mosaicBandImage{
+		env:      env,
+		renderer: newMosaicBandRenderer(env, views, cover, 1, opts, bg, bar),
+		rect:     image.Rect(0, 0, cover.Width+2*border, cover.Height+2*border),
+	}
 
-		mosaicPartialViews, err := mosaicPartialService.FindAllPartialViews(mosaic, "mosaic_partials.id asc", batchSize, numCreated)
-		if err != nil {
-			return err
-		}
+	err = saveStreamed(img, outfile, meta)
+	if img.err != nil {
+		return img.err
+	}
+	if err != nil {
+		return err
+	}
 
-		num := len(mosaicPartialViews)
-		if num == 0 {
-			break
-		}
+	bar.Finish()
 
-		for _, view := range mosaicPartialViews {
-			img, err := util.GetImageCoverPartial(view.Gidx, view.CoverPartial)
-			if err != nil {
-				return err
-			}
-			dst = imaging.Paste(dst, *img, view.CoverPartial.Pt())
-			bar.Increment()
-		}
+	return nil
+}
 
-		numCreated += num
+// cropMarkWidth is the pixel width of crop marks, a hundredth of an inch
+func cropMarkWidth(dpi int) int {
+	if dpi <= 0 {
+		dpi = util.DefaultDPI
 	}
+	return util.MaxInt(dpi/100, 1)
+}
 
-	bar.Finish()
+// drawCropMarks draws lines of width w in c, from the edges of bounds to
+// each corner of trim, in line with the edges of trim. The lines fall
+// entirely outside of trim, so they are cut off when the print is trimmed.
+func drawCropMarks(dst *image.NRGBA, bounds, trim image.Rectangle, w int, c color.Color) {
+	src := image.NewUniform(c)
+	for _, x := range []int{trim.Min.X, trim.Max.X} {
+		for _, y := range []int{trim.Min.Y, trim.Max.Y} {
+			// the outer edges of bounds beyond this corner
+			ex, ey := bounds.Min.X, bounds.Min.Y
+			if x == trim.Max.X {
+				ex = bounds.Max.X
+			}
+			if y == trim.Max.Y {
+				ey = bounds.Max.Y
+			}
 
-	err = imaging.Save(dst, outfile)
-	if err != nil {
-		return err
+			horizontal := image.Rect(ex, y-w/2, x, y-w/2+w)
+			vertical := image.Rect(x-w/2, ey, x-w/2+w, y)
+			for _, rect := range []image.Rectangle{horizontal, vertical} {
+				draw.Draw(dst, rect.Intersect(dst.Bounds()), src, image.Point{}, draw.Src)
+			}
+		}
 	}
-
-	return nil
 }
 
-func writeExif(toolPath, src, dst string) error {
-	tp, err := util.ExiftoolPath(toolPath)
-	if err != nil {
-		return err
-	} else if tp == "" {
-		return nil
+// drawTileCorners rounds the corners of the tile at rect by filling
+// the pixels outside a circle of radius at each corner with c
+func drawTileCorners(dst *image.NRGBA, rect image.Rectangle, radius int, c color.Color) {
+	radius = util.MinInt(radius, util.MinInt(rect.Dx(), rect.Dy())/2)
+	if radius <= 0 {
+		return
+	}
+
+	r := float64(radius)
+	for y := 0; y < radius; y++ {
+		for x := 0; x < radius; x++ {
+			// distance from the pixel center to the corner circle center
+			dx := r - float64(x) - 0.5
+			dy := r - float64(y) - 0.5
+			if dx*dx+dy*dy <= r*r {
+				continue
+			}
+
+			for _, pt := range []image.Point{
+				image.Pt(rect.Min.X+x, rect.Min.Y+y),
+				image.Pt(rect.Max.X-1-x, rect.Min.Y+y),
+				image.Pt(rect.Min.X+x, rect.Max.Y-1-y),
+				image.Pt(rect.Max.X-1-x, rect.Max.Y-1-y),
+			} {
+				dst.Set(pt.X, pt.Y, c)
+			}
+		}
 	}
-	_, err = util.ExifCp(tp, src, dst)
-	return err
 }
@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"image"
+	"image/color"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/disintegration/imaging"
 )
 
 func TestMosaicDraw(t *testing.T) {
@@ -25,7 +29,7 @@ func TestMosaicDraw(t *testing.T) {
 		t.Fatalf("Error indexing images: %s\n", err.Error())
 	}
 
-	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, "", "")
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, 0, 0, "grid", "", "")
 	if cover == nil || macro == nil {
 		t.Fatal("Failed to create cover or macro")
 	}
@@ -35,7 +39,7 @@ func TestMosaicDraw(t *testing.T) {
 		t.Fatalf("Error building partial aspects: %s\n", err.Error())
 	}
 
-	err = Compare(env, macro.Id)
+	err = Compare(env, macro.Id, 0)
 	if err != nil {
 		t.Fatalf("Comparing images: %s\n", err.Error())
 	}
@@ -45,7 +49,7 @@ func TestMosaicDraw(t *testing.T) {
 		t.Fatal("Failed to build mosaic")
 	}
 
-	err = MosaicDraw(env, mosaic.Id, filepath.Join(dir, "jumping_bunny_mosaic.jpg"))
+	err = MosaicDraw(env, mosaic.Id, filepath.Join(dir, "jumping_bunny_mosaic.jpg"), MosaicDrawOptions{})
 	if err != nil {
 		t.Fatalf("Error drawing mosaic: %s\n", err.Error())
 	}
@@ -56,3 +60,204 @@ func TestMosaicDraw(t *testing.T) {
 
 	testResultExpect(t, out.String(), expect)
 }
+
+func TestMosaicDrawGrout(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_draw_grout")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic draw test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 200, 1, 1, 2, 4, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	if macro.Grout != 4 {
+		t.Fatalf("Expected macro grout 4, got %d\n", macro.Grout)
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	mosaic := MosaicBuild(env, "best", macro.Id, 0, false)
+	if mosaic == nil {
+		t.Fatal("Failed to build mosaic")
+	}
+
+	outfile := filepath.Join(dir, "jumping_bunny_mosaic.png")
+	red := color.NRGBA{255, 0, 0, 255}
+	err = MosaicDraw(env, mosaic.Id, outfile, MosaicDrawOptions{
+		Grout:       -1,
+		GroutColor:  red,
+		TileRadius:  5,
+		OuterBorder: 10,
+	})
+	if err != nil {
+		t.Fatalf("Error drawing mosaic: %s\n", err.Error())
+	}
+
+	expect := []string{
+		"Drawing 4 mosaic partials...",
+	}
+
+	testResultExpect(t, out.String(), expect)
+
+	img, err := imaging.Open(outfile)
+	if err != nil {
+		t.Fatalf("Error opening mosaic: %s\n", err.Error())
+	}
+
+	if img.Bounds() != image.Rect(0, 0, 220, 220) {
+		t.Fatalf("Expected mosaic bounds 220x220, got %v\n", img.Bounds())
+	}
+
+	// outer border, grout between tiles, and a rounded tile corner
+	for _, pt := range []image.Point{
+		image.Pt(5, 5),
+		image.Pt(110, 50),
+		image.Pt(50, 110),
+		image.Pt(12, 12),
+	} {
+		c := color.NRGBAModel.Convert(img.At(pt.X, pt.Y))
+		if c != red {
+			t.Errorf("Expected grout color at %v, got %v", pt, c)
+		}
+	}
+}
+
+func TestMosaicDrawCropMarks(t *testing.T) {
+	env, _, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_draw_crop_marks")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic draw test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 200, 1, 1, 2, 0, 10, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	mosaic := MosaicBuild(env, "best", macro.Id, 0, false)
+	if mosaic == nil {
+		t.Fatal("Failed to build mosaic")
+	}
+
+	outfile := filepath.Join(dir, "jumping_bunny_mosaic.png")
+	err = MosaicDraw(env, mosaic.Id, outfile, MosaicDrawOptions{CropMarks: true, DPI: 300})
+	if err != nil {
+		t.Fatalf("Error drawing mosaic: %s\n", err.Error())
+	}
+
+	img, err := imaging.Open(outfile)
+	if err != nil {
+		t.Fatalf("Error opening mosaic: %s\n", err.Error())
+	}
+
+	// marks are 3 pixels wide at 300 dpi, centered on the trim edges at 10 and 190
+	black := color.NRGBA{0, 0, 0, 255}
+	for _, pt := range []image.Point{
+		image.Pt(0, 10),
+		image.Pt(9, 11),
+		image.Pt(10, 0),
+		image.Pt(199, 190),
+		image.Pt(190, 199),
+		image.Pt(10, 199),
+	} {
+		c := color.NRGBAModel.Convert(img.At(pt.X, pt.Y))
+		if c != black {
+			t.Errorf("Expected crop mark at %v, got %v", pt, c)
+		}
+	}
+}
+
+func TestDrawCropMarks(t *testing.T) {
+	white := color.NRGBA{255, 255, 255, 255}
+	black := color.NRGBA{0, 0, 0, 255}
+	dst := imaging.New(20, 20, white)
+	drawCropMarks(dst, dst.Bounds(), image.Rect(5, 5, 15, 15), 1, black)
+
+	for _, tt := range []struct {
+		pt image.Point
+		c  color.NRGBA
+	}{
+		{image.Pt(0, 5), black},
+		{image.Pt(4, 5), black},
+		{image.Pt(5, 0), black},
+		{image.Pt(5, 4), black},
+		{image.Pt(19, 15), black},
+		{image.Pt(15, 19), black},
+		{image.Pt(5, 5), white},
+		{image.Pt(10, 10), white},
+		{image.Pt(0, 0), white},
+		{image.Pt(10, 0), white},
+	} {
+		c := dst.NRGBAAt(tt.pt.X, tt.pt.Y)
+		if c != tt.c {
+			t.Errorf("drawCropMarks pixel at %v => %v, want %v", tt.pt, c, tt.c)
+		}
+	}
+}
+
+func TestDrawTileCorners(t *testing.T) {
+	dst := imaging.New(10, 10, color.NRGBA{0, 0, 0, 255})
+	white := color.NRGBA{255, 255, 255, 255}
+	drawTileCorners(dst, dst.Bounds(), 3, white)
+
+	for _, tt := range []struct {
+		pt image.Point
+		c  color.NRGBA
+	}{
+		{image.Pt(0, 0), white},
+		{image.Pt(9, 0), white},
+		{image.Pt(0, 9), white},
+		{image.Pt(9, 9), white},
+		{image.Pt(2, 2), color.NRGBA{0, 0, 0, 255}},
+		{image.Pt(5, 0), color.NRGBA{0, 0, 0, 255}},
+		{image.Pt(5, 5), color.NRGBA{0, 0, 0, 255}},
+	} {
+		c := dst.NRGBAAt(tt.pt.X, tt.pt.Y)
+		if c != tt.c {
+			t.Errorf("drawTileCorners pixel at %v => %v, want %v", tt.pt, c, tt.c)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+	"html/template"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/cheggaaa/pb.v1"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	mosaicHtmlImage     = "mosaic.jpg"
+	mosaicHtmlIndex     = "index.html"
+	mosaicHtmlThumbDir  = "thumbs"
+	mosaicHtmlThumbSize = 200
+)
+
+// mosaicHtmlTile is a tile of the mosaic viewer,
+// positioned in pixels of the mosaic image
+type mosaicHtmlTile struct {
+	X     int     `json:"x"`
+	Y     int     `json:"y"`
+	W     int     `json:"w"`
+	H     int     `json:"h"`
+	Path  string  `json:"path"`
+	Thumb string  `json:"thumb"`
+	Dist  float64 `json:"dist"`
+}
+
+type mosaicHtmlPage struct {
+	Title  string
+	Image  string
+	Width  int
+	Height int
+	Tiles  []mosaicHtmlTile
+}
+
+// MosaicHtml writes an html viewer of a mosaic to outdir. It draws the mosaic
+// image, a thumbnail of each index image it uses, and an index.html page that
+// shows the path, thumbnail and match distance of the tile under the pointer.
+func MosaicHtml(env environment.Environment, mosaicId int64, outdir string, opts MosaicDrawOptions) error {
+	mosaic, macro, cover, err := findMosaicMacroCover(env, mosaicId)
+	if err != nil {
+		env.Println(err.Error())
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Join(outdir, mosaicHtmlThumbDir), 0755)
+	if err != nil {
+		env.Printf("Error creating html directory: %s\n", err.Error())
+		return err
+	}
+
+	err = MosaicDraw(env, mosaic.Id, filepath.Join(outdir, mosaicHtmlImage), opts)
+	if err != nil {
+		return err
+	}
+
+	views, err := findMosaicPartialViews(env, mosaic)
+	if err != nil {
+		env.Printf("Error finding mosaic partials: %s\n", err.Error())
+		return err
+	}
+
+	thumbs, err := writeMosaicHtmlThumbs(env, views, outdir)
+	if err != nil {
+		env.Printf("Error writing thumbnails: %s\n", err.Error())
+		return err
+	}
+
+	if opts.Grout < 0 {
+		opts.Grout = macro.Grout
+	}
+	border := util.MaxInt(opts.OuterBorder, 0)
+
+	page := mosaicHtmlPage{
+		Title:  filepath.Base(macro.Path),
+		Image:  mosaicHtmlImage,
+		Width:  cover.Width + 2*border,
+		Height: cover.Height + 2*border,
+		Tiles:  make([]mosaicHtmlTile, len(views)),
+	}
+
+	for i, view := range views {
+		rect := view.CoverPartial.Inset(opts.Grout).Rectangle()
+		page.Tiles[i] = mosaicHtmlTile{
+			X:     rect.Min.X + border,
+			Y:     rect.Min.Y + border,
+			W:     rect.Dx(),
+			H:     rect.Dy(),
+			Path:  view.Gidx.Path,
+			Thumb: thumbs[view.Gidx.Id],
+			Dist:  view.Dist,
+		}
+	}
+
+	outfile := filepath.Join(outdir, mosaicHtmlIndex)
+	f, err := os.Create(outfile)
+	if err != nil {
+		env.Printf("Error creating html page: %s\n", err.Error())
+		return err
+	}
+	defer f.Close()
+
+	err = mosaicHtmlTemplate.Execute(f, page)
+	if err != nil {
+		env.Printf("Error writing html page: %s\n", err.Error())
+		return err
+	}
+
+	env.Printf("Wrote mosaic html: %s\n", outfile)
+
+	return nil
+}
+
+// writeMosaicHtmlThumbs writes a thumbnail of each index image used by views,
+// and returns their paths relative to outdir, by gidx id
+func writeMosaicHtmlThumbs(env environment.Environment, views []*model.MosaicPartialView, outdir string) (map[int64]string, error) {
+	gidxs := []*model.Gidx{}
+	thumbs := make(map[int64]string)
+	for _, view := range views {
+		if _, ok := thumbs[view.Gidx.Id]; ok {
+			continue
+		}
+		thumbs[view.Gidx.Id] = filepath.ToSlash(filepath.Join(mosaicHtmlThumbDir, fmt.Sprintf("%d.jpg", view.Gidx.Id)))
+		gidxs = append(gidxs, view.Gidx)
+	}
+
+	env.Printf("Writing %d thumbnails...\n", len(gidxs))
+	bar := pb.StartNew(len(gidxs))
+
+	for _, gidx := range gidxs {
+		if env.Cancel() {
+			return nil, errors.New("Cancelled")
+		}
+
+		img, err := util.OpenImg(gidx)
+		if err != nil {
+			return nil, err
+		}
+
+		err = util.FixOrientation(img, gidx.Orientation)
+		if err != nil {
+			return nil, err
+		}
+
+		thumb := imaging.Fit(*img, mosaicHtmlThumbSize, mosaicHtmlThumbSize, imaging.Lanczos)
+		err = imaging.Save(thumb, filepath.Join(outdir, filepath.FromSlash(thumbs[gidx.Id])))
+		if err != nil {
+			return nil, err
+		}
+		bar.Increment()
+	}
+
+	bar.Finish()
+
+	return thumbs, nil
+}
+
+var mosaicHtmlTemplate = template.Must(template.New("mosaic").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+  body { margin: 0; font-family: sans-serif; background: #222; color: #eee; }
+  #mosaic { position: relative; display: inline-block; }
+  #mosaic img { display: block; max-width: 100vw; height: auto; }
+  #highlight { position: absolute; border: 2px solid #ff0; box-sizing: border-box; pointer-events: none; display: none; }
+  #info { position: fixed; top: 10px; right: 10px; width: 220px; padding: 10px; background: rgba(0, 0, 0, 0.8); display: none; word-wrap: break-word; }
+  #info img { display: block; max-width: 200px; max-height: 200px; margin-bottom: 8px; }
+  #info.pinned { border: 1px solid #ff0; }
+</style>
+</head>
+<body>
+<div id="mosaic">
+  <img id="image" src="{{.Image}}" width="{{.Width}}" height="{{.Height}}" alt="{{.Title}}">
+  <div id="highlight"></div>
+</div>
+<div id="info">
+  <img id="thumb" alt="">
+  <div id="path"></div>
+  <div id="dist"></div>
+</div>
+<script>
+(function() {
+  var width = {{.Width}};
+  var tiles = {{.Tiles}};
+  var image = document.getElementById("image");
+  var highlight = document.getElementById("highlight");
+  var info = document.getElementById("info");
+  var pinned = null;
+
+  function tileAt(e) {
+    var rect = image.getBoundingClientRect();
+    var scale = width / rect.width;
+    var x = (e.clientX - rect.left) * scale;
+    var y = (e.clientY - rect.top) * scale;
+    for (var i = 0; i < tiles.length; i++) {
+      var t = tiles[i];
+      if (x >= t.x && x < t.x + t.w && y >= t.y && y < t.y + t.h) {
+        return t;
+      }
+    }
+    return null;
+  }
+
+  function show(t) {
+    if (!t) {
+      highlight.style.display = "none";
+      info.style.display = "none";
+      return;
+    }
+    var scale = image.getBoundingClientRect().width / width;
+    highlight.style.left = (t.x * scale) + "px";
+    highlight.style.top = (t.y * scale) + "px";
+    highlight.style.width = (t.w * scale) + "px";
+    highlight.style.height = (t.h * scale) + "px";
+    highlight.style.display = "block";
+    document.getElementById("thumb").src = t.thumb;
+    document.getElementById("path").textContent = t.path;
+    document.getElementById("dist").textContent = t.dist < 0 ? "" : "Distance: " + t.dist.toFixed(2);
+    info.style.display = "block";
+  }
+
+  image.addEventListener("mousemove", function(e) {
+    if (!pinned) {
+      show(tileAt(e));
+    }
+  });
+
+  image.addEventListener("mouseleave", function() {
+    if (!pinned) {
+      show(null);
+    }
+  });
+
+  image.addEventListener("click", function(e) {
+    var t = tileAt(e);
+    pinned = (t === pinned) ? null : t;
+    info.className = pinned ? "pinned" : "";
+    show(t);
+  });
+})();
+</script>
+</body>
+</html>
+`))
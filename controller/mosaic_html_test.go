@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMosaicHtml(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_html")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic html test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	mosaic := MosaicBuild(env, "best", macro.Id, 0, false)
+	if mosaic == nil {
+		t.Fatal("Failed to build mosaic")
+	}
+
+	err = MosaicHtml(env, mosaic.Id, dir, MosaicDrawOptions{Grout: -1})
+	if err != nil {
+		t.Fatalf("Error writing mosaic html: %s\n", err.Error())
+	}
+
+	views, err := findMosaicPartialViews(env, mosaic)
+	if err != nil {
+		t.Fatalf("Error finding mosaic partial views: %s\n", err.Error())
+	}
+
+	gidxIds := make(map[int64]bool)
+	for _, view := range views {
+		gidxIds[view.Gidx.Id] = true
+	}
+
+	expect := []string{
+		"Drawing 4 mosaic partials...",
+		fmt.Sprintf("Writing %d thumbnails...", len(gidxIds)),
+		"Wrote mosaic html: " + filepath.Join(dir, "index.html"),
+	}
+
+	testResultExpect(t, out.String(), expect)
+
+	_, err = os.Stat(filepath.Join(dir, "mosaic.jpg"))
+	if err != nil {
+		t.Errorf("Expected mosaic image: %s\n", err.Error())
+	}
+
+	thumbs, err := filepath.Glob(filepath.Join(dir, "thumbs", "*.jpg"))
+	if err != nil || len(thumbs) != len(gidxIds) {
+		t.Errorf("Expected %d thumbnails, got %d\n", len(gidxIds), len(thumbs))
+	}
+
+	html, err := ioutil.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatalf("Error reading mosaic html: %s\n", err.Error())
+	}
+
+	for _, view := range views {
+		if !strings.Contains(string(html), filepath.Base(view.Gidx.Path)) {
+			t.Errorf("Expected html to contain index image %s\n", view.Gidx.Path)
+		}
+	}
+}
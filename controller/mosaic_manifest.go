@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"io"
+	"strconv"
+)
+
+// MosaicManifestFormats are the formats a mosaic manifest can be exported to
+var MosaicManifestFormats = []string{"csv", "json"}
+
+// mosaicManifestColumns are the csv header, and the names of the json fields
+var mosaicManifestColumns = []string{
+	"mosaic_partial_id",
+	"x1", "y1", "x2", "y2",
+	"gidx_id", "path", "md5sum",
+	"width", "height", "orientation",
+	"dist",
+}
+
+// mosaicManifestTile is a tile of the mosaic, and the index image placed in it.
+// Dist is nil if the comparison of the tile has been deleted.
+type mosaicManifestTile struct {
+	MosaicPartialId int64    `json:"mosaic_partial_id"`
+	X1              int      `json:"x1"`
+	Y1              int      `json:"y1"`
+	X2              int      `json:"x2"`
+	Y2              int      `json:"y2"`
+	GidxId          int64    `json:"gidx_id"`
+	Path            string   `json:"path"`
+	Md5sum          string   `json:"md5sum"`
+	Width           int      `json:"width"`
+	Height          int      `json:"height"`
+	Orientation     int      `json:"orientation"`
+	Dist            *float64 `json:"dist"`
+}
+
+func newMosaicManifestTile(view *model.MosaicPartialView) *mosaicManifestTile {
+	tile := &mosaicManifestTile{
+		MosaicPartialId: view.MosaicPartialId,
+		X1:              view.CoverPartial.X1,
+		Y1:              view.CoverPartial.Y1,
+		X2:              view.CoverPartial.X2,
+		Y2:              view.CoverPartial.Y2,
+		GidxId:          view.Gidx.Id,
+		Path:            view.Gidx.Path,
+		Md5sum:          view.Gidx.Md5sum,
+		Width:           view.Gidx.Width,
+		Height:          view.Gidx.Height,
+		Orientation:     view.Gidx.Orientation,
+	}
+	if view.Dist >= 0 {
+		dist := view.Dist
+		tile.Dist = &dist
+	}
+	return tile
+}
+
+func (tile *mosaicManifestTile) record() []string {
+	dist := ""
+	if tile.Dist != nil {
+		dist = strconv.FormatFloat(*tile.Dist, 'f', -1, 64)
+	}
+
+	return []string{
+		strconv.FormatInt(tile.MosaicPartialId, 10),
+		strconv.Itoa(tile.X1),
+		strconv.Itoa(tile.Y1),
+		strconv.Itoa(tile.X2),
+		strconv.Itoa(tile.Y2),
+		strconv.FormatInt(tile.GidxId, 10),
+		tile.Path,
+		tile.Md5sum,
+		strconv.Itoa(tile.Width),
+		strconv.Itoa(tile.Height),
+		strconv.Itoa(tile.Orientation),
+		dist,
+	}
+}
+
+// MosaicManifest writes every tile of a mosaic, with the index image placed
+// in it, to w. Tiles are read and written in batches, so that manifests of
+// very large mosaics are not held in memory.
+func MosaicManifest(env environment.Environment, mosaicId int64, format string, w io.Writer) error {
+	mosaicService := env.ServiceFactory().MustMosaicService()
+	mosaicPartialService := env.ServiceFactory().MustMosaicPartialService()
+
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("Invalid mosaic manifest format: %s", format)
+	}
+
+	mosaic, err := mosaicService.Get(mosaicId)
+	if err != nil {
+		return err
+	} else if mosaic == nil {
+		return errors.New("Mosaic not found")
+	}
+
+	var cw *csv.Writer
+	if format == "csv" {
+		cw = csv.NewWriter(w)
+		err = cw.Write(mosaicManifestColumns)
+	} else {
+		_, err = io.WriteString(w, "[")
+	}
+	if err != nil {
+		return err
+	}
+
+	batchSize := 1000
+	num := 0
+
+	for {
+		if env.Cancel() {
+			return errors.New("Cancelled")
+		}
+
+		views, err := mosaicPartialService.FindAllPartialViews(mosaic, "mosaic_partials.id asc", batchSize, num)
+		if err != nil {
+			return err
+		}
+
+		if len(views) == 0 {
+			break
+		}
+
+		for _, view := range views {
+			tile := newMosaicManifestTile(view)
+
+			if format == "csv" {
+				err = cw.Write(tile.record())
+			} else {
+				err = writeMosaicManifestJson(w, tile, num == 0)
+			}
+			if err != nil {
+				return err
+			}
+
+			num++
+		}
+
+		if format == "csv" {
+			cw.Flush()
+			err = cw.Error()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if format == "csv" {
+		cw.Flush()
+		return cw.Error()
+	}
+
+	if num > 0 {
+		_, err = io.WriteString(w, "\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+// writeMosaicManifestJson writes tile as an element of a json array,
+// on its own line
+func writeMosaicManifestJson(w io.Writer, tile *mosaicManifestTile, first bool) error {
+	b, err := json.Marshal(tile)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n  "
+	if first {
+		sep = "\n  "
+	}
+
+	_, err = io.WriteString(w, sep+string(b))
+	return err
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+)
+
+func TestMosaicManifest(t *testing.T) {
+	env, _, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	mosaic := MosaicBuild(env, "best", macro.Id, 0, false)
+	if mosaic == nil {
+		t.Fatal("Failed to build mosaic")
+	}
+
+	views, err := findMosaicPartialViews(env, mosaic)
+	if err != nil {
+		t.Fatalf("Error finding mosaic partial views: %s\n", err.Error())
+	}
+
+	var buf bytes.Buffer
+	err = MosaicManifest(env, mosaic.Id, "csv", &buf)
+	if err != nil {
+		t.Fatalf("Error exporting csv manifest: %s\n", err.Error())
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Error reading csv manifest: %s\n", err.Error())
+	}
+
+	if len(records) != len(views)+1 {
+		t.Fatalf("Expected %d csv records, got %d\n", len(views)+1, len(records))
+	}
+
+	for i, column := range mosaicManifestColumns {
+		if records[0][i] != column {
+			t.Errorf("Expected csv column %d to be %s, got %s\n", i, column, records[0][i])
+		}
+	}
+
+	for _, record := range records[1:] {
+		if record[6] == "" || record[7] == "" || record[11] == "" {
+			t.Errorf("Expected csv record to have path, md5sum and dist, got %v\n", record)
+		}
+	}
+
+	buf.Reset()
+	err = MosaicManifest(env, mosaic.Id, "json", &buf)
+	if err != nil {
+		t.Fatalf("Error exporting json manifest: %s\n", err.Error())
+	}
+
+	var tiles []mosaicManifestTile
+	err = json.Unmarshal(buf.Bytes(), &tiles)
+	if err != nil {
+		t.Fatalf("Error reading json manifest: %s\n", err.Error())
+	}
+
+	if len(tiles) != len(views) {
+		t.Fatalf("Expected %d json tiles, got %d\n", len(views), len(tiles))
+	}
+
+	for _, tile := range tiles {
+		if tile.X2 <= tile.X1 || tile.Y2 <= tile.Y1 || tile.Path == "" || tile.Dist == nil {
+			t.Errorf("Unexpected json tile %+v\n", tile)
+		}
+	}
+
+	err = MosaicManifest(env, mosaic.Id, "xml", &buf)
+	if err == nil {
+		t.Errorf("Expected error exporting xml manifest\n")
+	}
+}
@@ -0,0 +1,604 @@
+package controller
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+
+	"gopkg.in/cheggaaa/pb.v1"
+)
+
+const (
+	// memoryPartialBytes is about the memory used by a partial in memory
+	memoryPartialBytes = int64(util.DATA_SIZE*util.DATA_SIZE*3*8 + 64)
+	// memoryCandidateBytes is the memory used by a candidate of a macro partial
+	memoryCandidateBytes = int64(16)
+)
+
+// memoryPartial is a macro or index partial,
+// with its pixels flattened to l, a, b triples
+type memoryPartial struct {
+	id     int64
+	gidxId int64
+	lab    []float64
+}
+
+func newMemoryPartial(id, gidxId int64, pixels []*model.Lab) *memoryPartial {
+	lab := make([]float64, 3*len(pixels))
+	for i, p := range pixels {
+		lab[3*i] = p.L
+		lab[3*i+1] = p.A
+		lab[3*i+2] = p.B
+	}
+	return &memoryPartial{id: id, gidxId: gidxId, lab: lab}
+}
+
+// dist is the distance between the pixels of two partials,
+// the same as model.PixelDist
+func (p *memoryPartial) dist(other *memoryPartial) float64 {
+	dist := float64(0.0)
+	for i := 0; i < len(p.lab); i += 3 {
+		l := p.lab[i] - other.lab[i]
+		a := p.lab[i+1] - other.lab[i+1]
+		b := p.lab[i+2] - other.lab[i+2]
+		dist += math.Sqrt(l*l + a*a + b*b)
+	}
+	return dist
+}
+
+// memoryAspect is the macro partials of a mosaic with an aspect,
+// and the index partials they are compared with
+type memoryAspect struct {
+	id           int64
+	cells        []*memoryCell
+	numGidx      int64
+	gidxPartials []*memoryPartial
+}
+
+// memoryCandidate is an index partial of an aspect compared with a macro partial
+type memoryCandidate struct {
+	index int32
+	dist  float64
+}
+
+// memoryCandidates is a max heap of candidates by distance,
+// used to keep the closest candidates of a macro partial
+type memoryCandidates []memoryCandidate
+
+func (h memoryCandidates) Len() int            { return len(h) }
+func (h memoryCandidates) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h memoryCandidates) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *memoryCandidates) Push(x interface{}) { *h = append(*h, x.(memoryCandidate)) }
+
+func (h *memoryCandidates) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// memoryCell is a macro partial that is missing from the mosaic,
+// with its closest candidates in order of distance
+type memoryCell struct {
+	partial    *memoryPartial
+	aspect     *memoryAspect
+	candidates []memoryCandidate
+	// next is the first candidate that may not have been used too many times
+	next int
+	// restored is set when the cell has been compared with every index partial
+	restored bool
+	// key is the distance of the cell in the queue of the best fill
+	key float64
+}
+
+// compare compares the cell with each index partial of its aspect,
+// keeping the keep closest, or all of them when keep is 0
+func (c *memoryCell) compare(keep int) {
+	gidxPartials := c.aspect.gidxPartials
+	if keep <= 0 || keep > len(gidxPartials) {
+		keep = len(gidxPartials)
+	}
+
+	h := make(memoryCandidates, 0, keep)
+	for i, gp := range gidxPartials {
+		dist := c.partial.dist(gp)
+		if len(h) < keep {
+			heap.Push(&h, memoryCandidate{index: int32(i), dist: dist})
+		} else if dist < h[0].dist {
+			h[0] = memoryCandidate{index: int32(i), dist: dist}
+			heap.Fix(&h, 0)
+		}
+	}
+	sort.Sort(sort.Reverse(h))
+
+	c.candidates = h
+	c.next = 0
+	c.restored = keep == len(gidxPartials)
+}
+
+// available returns the closest candidate of the cell whose index image
+// has been used fewer than maxRepeats times. When every kept candidate
+// has been, the cell is compared with every index partial again.
+func (c *memoryCell) available(used map[int64]int, maxRepeats int) (*memoryCandidate, error) {
+	for {
+		for ; c.next < len(c.candidates); c.next++ {
+			candidate := &c.candidates[c.next]
+			if maxRepeats == 0 || used[c.aspect.gidxPartials[candidate.index].gidxId] < maxRepeats {
+				return candidate, nil
+			}
+		}
+
+		if c.restored {
+			return nil, fmt.Errorf("No index image available for macro partial %d", c.partial.id)
+		}
+
+		c.compare(0)
+	}
+}
+
+// memoryCellQueue is a min heap of cells by key
+type memoryCellQueue []*memoryCell
+
+func (q memoryCellQueue) Len() int            { return len(q) }
+func (q memoryCellQueue) Less(i, j int) bool  { return q[i].key < q[j].key }
+func (q memoryCellQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *memoryCellQueue) Push(x interface{}) { *q = append(*q, x.(*memoryCell)) }
+
+func (q *memoryCellQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	*q = old[:n-1]
+	return x
+}
+
+// MosaicBuildMemory builds a mosaic as MosaicBuild does, but compares
+// the macro partials with the index partials in memory, and only stores
+// the mosaic partials. When keepTop is greater than zero, only the keepTop
+// closest index partials of each macro partial are kept. It fails when the
+// comparisons would need more memory than the memory limit of env.
+func MosaicBuildMemory(env environment.Environment, fillType string, macroId int64, maxRepeats, keepTop int) *model.Mosaic {
+	if fillType != "random" && fillType != "best" {
+		env.Printf("Invalid mosaic type: %s\n", fillType)
+		return nil
+	}
+
+	mosaic, maxRepeats := mosaicBuildSetup(env, macroId, maxRepeats)
+	if mosaic == nil {
+		return nil
+	}
+
+	err := doMosaicBuildMemory(env, mosaic, fillType, maxRepeats, keepTop)
+	if err != nil {
+		env.Printf("Error building mosaic: %s\n", err.Error())
+		return nil
+	}
+
+	return mosaic
+}
+
+func doMosaicBuildMemory(env environment.Environment, mosaic *model.Mosaic, fillType string, maxRepeats, keepTop int) error {
+	used, filled, err := memoryFindUsed(env, mosaic)
+	if err != nil {
+		return err
+	}
+
+	aspects, cells, err := memoryFindCells(env, mosaic, filled)
+	if err != nil {
+		return err
+	}
+
+	if len(cells) == 0 {
+		return nil
+	}
+
+	size, err := memoryEstimate(env, aspects, len(cells), keepTop)
+	if err != nil {
+		return err
+	}
+
+	if limit := env.MemoryLimit(); limit > 0 && size > limit {
+		return fmt.Errorf("Comparing in memory needs about %s, more than the memory limit of %s. Keep fewer comparisons, or raise the limit.",
+			formatBytes(size), formatBytes(limit))
+	}
+
+	macro, err := env.ServiceFactory().MustMacroService().Get(mosaic.MacroId)
+	if err != nil {
+		return err
+	} else if macro == nil {
+		return fmt.Errorf("Macro id %d not found", mosaic.MacroId)
+	}
+
+	err = memoryCompare(env, aspects, len(cells), keepTop, macro.Oriented)
+	if err != nil {
+		return err
+	}
+
+	env.Printf("Building %d mosaic partials...\n", len(cells))
+	bar := pb.StartNew(len(cells))
+
+	switch fillType {
+	case "random":
+		err = memoryFillRandom(env, mosaic, cells, used, maxRepeats, bar)
+	case "best":
+		err = memoryFillBest(env, mosaic, cells, used, maxRepeats, bar)
+	}
+	if err != nil {
+		bar.Finish()
+		return err
+	}
+
+	bar.Finish()
+	return nil
+}
+
+// memoryFindCells loads the macro partials missing from mosaic,
+// those with a cover partial that is not filled, grouped by aspect
+func memoryFindCells(env environment.Environment, mosaic *model.Mosaic, filled map[int64]bool) ([]*memoryAspect, []*memoryCell, error) {
+	macroPartialService := env.ServiceFactory().MustMacroPartialService()
+
+	aspects := make([]*memoryAspect, 0)
+	byId := make(map[int64]*memoryAspect)
+	cells := make([]*memoryCell, 0)
+
+	batchSize := 1000
+	for offset := 0; ; offset += batchSize {
+		if env.Cancel() {
+			return nil, nil, errors.New("Cancelled")
+		}
+
+		macroPartials, err := macroPartialService.FindAll("macro_partials.id asc", batchSize, offset, "macro_partials.macro_id = ?", mosaic.MacroId)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, mp := range macroPartials {
+			if filled[mp.CoverPartialId] {
+				continue
+			}
+
+			aspect, ok := byId[mp.AspectId]
+			if !ok {
+				aspect = &memoryAspect{id: mp.AspectId}
+				byId[mp.AspectId] = aspect
+				aspects = append(aspects, aspect)
+			}
+
+			cell := &memoryCell{
+				partial: newMemoryPartial(mp.Id, int64(0), mp.Pixels),
+				aspect:  aspect,
+			}
+			aspect.cells = append(aspect.cells, cell)
+			cells = append(cells, cell)
+		}
+
+		if len(macroPartials) < batchSize {
+			break
+		}
+	}
+
+	return aspects, cells, nil
+}
+
+// memoryEstimate counts the index partials of each aspect, and returns
+// about how many bytes comparing the cells with them in memory needs
+func memoryEstimate(env environment.Environment, aspects []*memoryAspect, numCells, keepTop int) (int64, error) {
+	gidxPartialService := env.ServiceFactory().MustGidxPartialService()
+
+	size := int64(numCells) * memoryPartialBytes
+	for _, aspect := range aspects {
+		var err error
+		aspect.numGidx, err = gidxPartialService.CountBy("aspect_id = ?", aspect.id)
+		if err != nil {
+			return int64(0), err
+		}
+
+		keep := aspect.numGidx
+		if keepTop > 0 && int64(keepTop) < keep {
+			keep = int64(keepTop)
+		}
+
+		size += aspect.numGidx*memoryPartialBytes + int64(len(aspect.cells))*keep*memoryCandidateBytes
+	}
+
+	return size, nil
+}
+
+// memoryFindUsed counts the number of times each index image
+// is already used by mosaic, and finds the cover partials it has filled
+func memoryFindUsed(env environment.Environment, mosaic *model.Mosaic) (map[int64]int, map[int64]bool, error) {
+	mosaicPartialService := env.ServiceFactory().MustMosaicPartialService()
+
+	used := make(map[int64]int)
+	filled := make(map[int64]bool)
+
+	batchSize := 1000
+	for offset := 0; ; offset += batchSize {
+		views, err := mosaicPartialService.FindAllPartialViews(mosaic, "mosaic_partials.id asc", batchSize, offset)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, view := range views {
+			used[view.Gidx.Id]++
+			filled[view.CoverPartial.Id] = true
+		}
+
+		if len(views) < batchSize {
+			break
+		}
+	}
+
+	return used, filled, nil
+}
+
+// memoryCompare loads the index partials of each aspect, and compares
+// each cell with them, using the workers of env. When oriented, only
+// index partials of index images with the same orientation as the
+// aspect are loaded.
+func memoryCompare(env environment.Environment, aspects []*memoryAspect, numCells, keepTop int, oriented bool) error {
+	numGidx := int64(0)
+	for _, aspect := range aspects {
+		numGidx += aspect.numGidx
+	}
+
+	env.Printf("Comparing %d mosaic partials with %d index partials in memory...\n", numCells, numGidx)
+	bar := pb.StartNew(numCells)
+
+	workers := env.Workers()
+	if workers < 1 {
+		workers = 1
+	}
+
+	var shapes map[int64]*model.Gidx
+	if oriented {
+		var err error
+		shapes, err = memoryGidxShapes(env)
+		if err != nil {
+			bar.Finish()
+			return err
+		}
+	}
+
+	for _, aspect := range aspects {
+		err := memoryCompareAspect(env, aspect, keepTop, shapes, workers, bar)
+		if err != nil {
+			bar.Finish()
+			return err
+		}
+	}
+
+	bar.Finish()
+	return nil
+}
+
+// memoryCompareAspect loads the index partials of aspect, and compares
+// each of its cells with them, using workers goroutines. When shapes is
+// set, only index partials of index images with the same orientation as
+// the aspect are loaded.
+func memoryCompareAspect(env environment.Environment, aspect *memoryAspect, keepTop int, shapes map[int64]*model.Gidx, workers int, bar *pb.ProgressBar) error {
+	gidxPartialService := env.ServiceFactory().MustGidxPartialService()
+
+	aspect.gidxPartials = make([]*memoryPartial, 0, aspect.numGidx)
+
+	var sameOrientation func(gidxId int64) (bool, error)
+	if shapes != nil {
+		var err error
+		sameOrientation, err = memorySameOrientation(env, aspect.id, shapes)
+		if err != nil {
+			return err
+		}
+	}
+
+	batchSize := 1000
+	for offset := 0; ; offset += batchSize {
+		if env.Cancel() {
+			return errors.New("Cancelled")
+		}
+
+		gidxPartials, err := gidxPartialService.FindAll("gidx_partials.id asc", batchSize, offset, "gidx_partials.aspect_id = ?", aspect.id)
+		if err != nil {
+			return err
+		}
+
+		for _, gp := range gidxPartials {
+			if sameOrientation != nil {
+				ok, err := sameOrientation(gp.GidxId)
+				if err != nil {
+					return err
+				} else if !ok {
+					continue
+				}
+			}
+
+			p := newMemoryPartial(gp.Id, gp.GidxId, gp.Pixels)
+			if len(p.lab) != len(aspect.cells[0].partial.lab) {
+				return errors.New("Pixel slice not the same length")
+			}
+			aspect.gidxPartials = append(aspect.gidxPartials, p)
+		}
+
+		if len(gidxPartials) < batchSize {
+			break
+		}
+	}
+
+	var wg sync.WaitGroup
+	cellCh := make(chan *memoryCell)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cell := range cellCh {
+				cell.compare(keepTop)
+				bar.Increment()
+			}
+		}()
+	}
+
+	for _, cell := range aspect.cells {
+		if env.Cancel() {
+			break
+		}
+		cellCh <- cell
+	}
+
+	close(cellCh)
+	wg.Wait()
+
+	if env.Cancel() {
+		return errors.New("Cancelled")
+	}
+
+	return nil
+}
+
+// memoryGidxShapes loads the width and height of each index image, so
+// the orientation of index partials is found without a query for each
+func memoryGidxShapes(env environment.Environment) (map[int64]*model.Gidx, error) {
+	gidxService := env.ServiceFactory().MustGidxService()
+
+	shapes := make(map[int64]*model.Gidx)
+
+	batchSize := 1000
+	for offset := 0; ; offset += batchSize {
+		if env.Cancel() {
+			return nil, errors.New("Cancelled")
+		}
+
+		gidxs, err := gidxService.FindAll("gidx.id asc", batchSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, gidx := range gidxs {
+			shapes[gidx.Id] = &model.Gidx{Id: gidx.Id, Width: gidx.Width, Height: gidx.Height}
+		}
+
+		if len(gidxs) < batchSize {
+			break
+		}
+	}
+
+	return shapes, nil
+}
+
+// memorySameOrientation returns a function that is true when the index
+// image with gidxId has the same orientation as the aspect with aspectId
+func memorySameOrientation(env environment.Environment, aspectId int64, shapes map[int64]*model.Gidx) (func(int64) (bool, error), error) {
+	aspect, err := env.ServiceFactory().MustAspectService().Get(aspectId)
+	if err != nil {
+		return nil, err
+	} else if aspect == nil {
+		return nil, fmt.Errorf("Aspect id %d not found", aspectId)
+	}
+
+	return func(gidxId int64) (bool, error) {
+		gidx, ok := shapes[gidxId]
+		if !ok {
+			return false, fmt.Errorf("Index image id %d not found", gidxId)
+		}
+		return gidx.SameOrientation(aspect), nil
+	}, nil
+}
+
+// memoryFillRandom fills the cells in random order,
+// each with its closest available index partial
+func memoryFillRandom(env environment.Environment, mosaic *model.Mosaic, cells []*memoryCell, used map[int64]int, maxRepeats int, bar *pb.ProgressBar) error {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	for _, i := range r.Perm(len(cells)) {
+		if env.Cancel() {
+			return errors.New("Cancelled")
+		}
+
+		cell := cells[i]
+		candidate, err := cell.available(used, maxRepeats)
+		if err != nil {
+			return err
+		}
+
+		err = memoryFill(env, mosaic, cell, candidate, used)
+		if err != nil {
+			return err
+		}
+
+		bar.Increment()
+	}
+
+	return nil
+}
+
+// memoryFillBest fills the cell with the closest available
+// index partial of all cells, until every cell is filled
+func memoryFillBest(env environment.Environment, mosaic *model.Mosaic, cells []*memoryCell, used map[int64]int, maxRepeats int, bar *pb.ProgressBar) error {
+	queue := make(memoryCellQueue, 0, len(cells))
+	for _, cell := range cells {
+		candidate, err := cell.available(used, maxRepeats)
+		if err != nil {
+			return err
+		}
+		cell.key = candidate.dist
+		queue = append(queue, cell)
+	}
+	heap.Init(&queue)
+
+	for queue.Len() > 0 {
+		if env.Cancel() {
+			return errors.New("Cancelled")
+		}
+
+		cell := heap.Pop(&queue).(*memoryCell)
+		candidate, err := cell.available(used, maxRepeats)
+		if err != nil {
+			return err
+		}
+
+		// the closest candidate of the cell has been used up since it was
+		// queued, so it is queued again with its next closest
+		if candidate.dist > cell.key {
+			cell.key = candidate.dist
+			heap.Push(&queue, cell)
+			continue
+		}
+
+		err = memoryFill(env, mosaic, cell, candidate, used)
+		if err != nil {
+			return err
+		}
+
+		bar.Increment()
+	}
+
+	return nil
+}
+
+// memoryFill stores the mosaic partial of a cell filled with candidate
+func memoryFill(env environment.Environment, mosaic *model.Mosaic, cell *memoryCell, candidate *memoryCandidate, used map[int64]int) error {
+	gidxPartial := cell.aspect.gidxPartials[candidate.index]
+
+	mosaicPartial := model.MosaicPartial{
+		MosaicId:       mosaic.Id,
+		MacroPartialId: cell.partial.id,
+		GidxPartialId:  gidxPartial.id,
+		Dist:           candidate.dist,
+	}
+
+	err := env.ServiceFactory().MustMosaicPartialService().Insert(&mosaicPartial)
+	if err != nil {
+		return err
+	}
+
+	used[gidxPartial.gidxId]++
+	return nil
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+)
+
+func setupMosaicMemoryTest(t *testing.T, env environment.Environment) *model.Macro {
+	err := Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 1000, 1000, 2, 3, 10, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	return macro
+}
+
+func TestMosaicBuildMemory(t *testing.T) {
+	for _, fillType := range []string{"random", "best"} {
+		for _, keepTop := range []int{0, 1} {
+			env, out, err := setupControllerTest()
+			if err != nil {
+				t.Fatalf("Error getting test environment: %s\n", err.Error())
+			}
+
+			macro := setupMosaicMemoryTest(t, env)
+
+			mosaic := MosaicBuildMemory(env, fillType, macro.Id, -1, keepTop)
+			if mosaic == nil {
+				t.Fatalf("Failed to build %s mosaic keeping %d:\n%s\n", fillType, keepTop, out.String())
+			}
+
+			missing, err := env.ServiceFactory().MustMosaicPartialService().CountMissing(mosaic)
+			if err != nil {
+				t.Fatalf("Error counting missing mosaic partials: %s\n", err.Error())
+			}
+
+			if missing != int64(0) {
+				t.Fatalf("Expected %s mosaic to be filled, %d partials missing\n", fillType, missing)
+			}
+
+			num, err := env.ServiceFactory().MustPartialComparisonService().Count()
+			if err != nil {
+				t.Fatalf("Error counting comparisons: %s\n", err.Error())
+			}
+
+			if num != int64(0) {
+				t.Fatalf("Expected no comparisons to be stored, got %d\n", num)
+			}
+
+			// without comparisons, the distances are those stored
+			// with the mosaic partials
+			views, err := env.ServiceFactory().MustMosaicPartialService().FindAllPartialViews(mosaic, "dist desc", 200, 0)
+			if err != nil {
+				t.Fatalf("Error finding mosaic partial views: %s\n", err.Error())
+			}
+
+			if len(views) != 150 {
+				t.Fatalf("Expected 150 mosaic partial views, got %d\n", len(views))
+			}
+
+			for i, view := range views {
+				if view.Dist < 0 {
+					t.Fatalf("Expected mosaic partial %d to have a distance, got %f\n", view.MosaicPartialId, view.Dist)
+				}
+				if i > 0 && view.Dist > views[i-1].Dist {
+					t.Fatalf("Expected mosaic partial views in order of distance\n")
+				}
+			}
+
+			expect := []string{
+				"Comparing 150 mosaic partials with",
+				"Building 150 mosaic partials...",
+			}
+
+			testResultExpect(t, out.String(), expect)
+			env.Close()
+		}
+	}
+}
+
+func TestMosaicBuildMemoryClosest(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	macro := setupMosaicMemoryTest(t, env)
+
+	mosaic := MosaicBuildMemory(env, "best", macro.Id, 0, 1)
+	if mosaic == nil {
+		t.Fatalf("Failed to build mosaic:\n%s\n", out.String())
+	}
+
+	// the db engine keeps only the closest comparison of each macro partial,
+	// which must be the index partial the memory engine placed
+	err = Compare(env, macro.Id, 1)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	views, err := env.ServiceFactory().MustMosaicPartialService().FindAllPartialViews(mosaic, "mosaic_partials.id asc", 1000, 0)
+	if err != nil {
+		t.Fatalf("Error finding mosaic partials: %s\n", err.Error())
+	}
+
+	if len(views) != 150 {
+		t.Fatalf("Expected 150 mosaic partials, got %d\n", len(views))
+	}
+
+	for _, view := range views {
+		if view.Dist < 0 {
+			t.Fatalf("Mosaic partial %d is not filled with its closest index image\n", view.MosaicPartialId)
+		}
+	}
+}
+
+func TestMosaicBuildMemoryLimit(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	macro := setupMosaicMemoryTest(t, env)
+
+	env.SetMemoryLimit(int64(1024))
+	mosaic := MosaicBuildMemory(env, "random", macro.Id, -1, 0)
+	if mosaic != nil {
+		t.Fatal("Expected mosaic to exceed the memory limit")
+	}
+
+	if !strings.Contains(out.String(), "more than the memory limit of 1.0 KB") {
+		t.Fatalf("Expected memory limit error, got:\n%s\n", out.String())
+	}
+}
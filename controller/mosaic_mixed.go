@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+)
+
+func MosaicMixed(env environment.Environment,
+	inPath, name, onExists, fillType string,
+	coverWidth, coverHeight int,
+	aspects []*model.Aspect,
+	size, maxRepeats, keepTop int,
+	threashold float64,
+	coverOutfile, macroOutfile, mosaicOutfile string,
+	drawOpts MosaicDrawOptions,
+	cleanup, destructive bool) *model.Mosaic {
+
+	params := &projectParams{
+		Type:        "mixed",
+		Width:       coverWidth,
+		Height:      coverHeight,
+		Aspects:     formatProjectAspects(aspects),
+		Size:        size,
+		Grout:       drawOpts.Grout,
+		Bleed:       drawOpts.Bleed,
+		Threashold:  threashold,
+		FillType:    fillType,
+		MaxRepeats:  maxRepeats,
+		KeepTop:     keepTop,
+		Destructive: destructive,
+	}
+
+	project, err := findOrCreateProject(env, inPath, name, onExists, params, coverOutfile, macroOutfile, mosaicOutfile)
+	if err != nil {
+		env.Println(err.Error())
+		return nil
+	}
+	env.SetProjectId(project.Id)
+
+	cover, macro := MacroMixed(env, project.Path, coverWidth, coverHeight, aspects, size, drawOpts.Grout, drawOpts.Bleed, project.CoverPath, project.MacroPath)
+	if cover == nil || macro == nil {
+		return nil
+	}
+
+	err = PartialAspectOriented(env, macro.Id, threashold)
+	if err != nil {
+		return nil
+	}
+
+	mosaic := mosaicCompareBuild(env, fillType, macro.Id, maxRepeats, keepTop, destructive)
+	if mosaic == nil {
+		return nil
+	}
+
+	err = MosaicDraw(env, mosaic.Id, project.MosaicPath, drawOpts)
+	if err != nil {
+		return nil
+	}
+
+	err = projectComplete(env, project)
+	if err != nil {
+		return nil
+	}
+
+	if cleanup {
+		err = projectCleanup(env, macro)
+		if err != nil {
+			return nil
+		}
+	}
+
+	return mosaic
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+)
+
+func TestMosaicMixed(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_mixed")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic mixed test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	Index(env, []string{"testdata", "../service/testdata"})
+
+	aspects := []*model.Aspect{
+		&model.Aspect{Columns: 2, Rows: 3},
+		&model.Aspect{Columns: 3, Rows: 2},
+	}
+
+	mosaic := MosaicMixed(
+		env,
+		"testdata/jumping_bunny.jpg",
+		"Jumping Bunny",
+		"abort",
+		"best",
+		600, 600,
+		aspects,
+		6, 0, 0,
+		-1.0,
+		filepath.Join(dir, "jumping_bunny_cover.png"),
+		filepath.Join(dir, "jumping_bunny_macro.jpg"),
+		filepath.Join(dir, "jumping_bunny_mosaic.jpg"),
+		MosaicDrawOptions{},
+		false,
+		false,
+	)
+	if mosaic == nil {
+		t.Fatal("Failed to create mosaic")
+	}
+
+	expect := []string{
+		"Indexing 4 images...",
+		"Mixing partial aspects 2x3",
+		"Wrote mosaic image",
+	}
+
+	testResultExpect(t, out.String(), expect)
+}
+
+func TestMosaicMixedOrientation(t *testing.T) {
+	for _, engine := range []string{"db", "memory"} {
+		env, _, err := setupControllerTest()
+		if err != nil {
+			t.Fatalf("Error getting test environment: %s\n", err.Error())
+		}
+		defer env.Close()
+		env.SetEngine(engine)
+
+		err = Index(env, []string{"testdata", "../service/testdata"})
+		if err != nil {
+			t.Fatalf("Error indexing images: %s\n", err.Error())
+		}
+
+		aspects := []*model.Aspect{
+			&model.Aspect{Columns: 2, Rows: 3},
+			&model.Aspect{Columns: 3, Rows: 2},
+		}
+
+		cover, macro := MacroMixed(env, "testdata/jumping_bunny.jpg", 600, 600, aspects, 6, 0, 0, "", "")
+		if cover == nil || macro == nil {
+			t.Fatal("Failed to create cover or macro")
+		}
+
+		// index partials of both orientations, as an earlier
+		// aspect or quad mosaic would have built them
+		err = PartialAspect(env, macro.Id, -1.0)
+		if err != nil {
+			t.Fatalf("Error building index partials: %s\n", err.Error())
+		}
+
+		mosaic := mosaicCompareBuild(env, "best", macro.Id, 0, 0, false)
+		if mosaic == nil {
+			t.Fatalf("Failed to build %s engine mosaic", engine)
+		}
+
+		views, err := env.ServiceFactory().MustMosaicPartialService().FindAllPartialViews(mosaic, "mosaic_partials.id asc", 1000, 0)
+		if err != nil {
+			t.Fatalf("Error getting mosaic partials: %s\n", err.Error())
+		}
+
+		if len(views) == 0 {
+			t.Fatalf("Expected %s engine mosaic to have partials", engine)
+		}
+
+		for _, view := range views {
+			aspect, err := env.ServiceFactory().MustAspectService().Get(view.CoverPartial.AspectId)
+			if err != nil {
+				t.Fatalf("Error getting aspect: %s\n", err.Error())
+			}
+
+			if !view.Gidx.SameOrientation(aspect) {
+				t.Fatalf("Expected %s engine to fill %dx%d partial with an image of the same orientation, got %dx%d %s\n",
+					engine, aspect.Columns, aspect.Rows, view.Gidx.Width, view.Gidx.Height, view.Gidx.Path)
+			}
+		}
+	}
+}
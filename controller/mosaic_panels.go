@@ -0,0 +1,362 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/util"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/cheggaaa/pb.v1"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// MosaicPanelFormats are the formats mosaic panels can be written in.
+// Pdf writes a document with a page for each panel, and jpg writes
+// an image for each panel to a directory.
+var MosaicPanelFormats = []string{"pdf", "jpg"}
+
+const (
+	// inches, the length of the arms of alignment marks
+	mosaicPanelMarkSize = 0.25
+	// inches, the height of the strip that a panel label is printed in
+	mosaicPanelLabelHeight = 0.5
+)
+
+// MosaicPanelOptions control how a mosaic is split into panels,
+// to be printed on separate sheets or canvases
+type MosaicPanelOptions struct {
+	// Cols and Rows are the size of the grid of panels
+	Cols int
+	Rows int
+	// Overlap is the pixel width of the edge that neighbouring panels share
+	Overlap int
+	// Format is one of MosaicPanelFormats, defaults to pdf
+	Format string
+	// Marks draws alignment marks in the middle of each overlap
+	Marks bool
+	// Labels adds a strip below each panel, labelled with its position
+	Labels bool
+}
+
+// mosaicPanel is a region of the mosaic, at row and col of the grid
+type mosaicPanel struct {
+	Row  int
+	Col  int
+	Rect image.Rectangle
+}
+
+// MosaicPanels splits a mosaic into a grid of panels, and writes them to
+// out, either as a pdf file or a directory of jpg images. Each panel is
+// drawn from the index images one band at a time, at the dpi of opts.
+func MosaicPanels(env environment.Environment, mosaicId int64, out string, panelOpts MosaicPanelOptions, opts MosaicDrawOptions) error {
+	mosaic, macro, cover, err := findMosaicMacroCover(env, mosaicId)
+	if err != nil {
+		env.Println(err.Error())
+		return err
+	}
+
+	if panelOpts.Format == "" {
+		panelOpts.Format = "pdf"
+	}
+	if !util.SliceContainsString(MosaicPanelFormats, panelOpts.Format) {
+		err = fmt.Errorf("Invalid mosaic panel format: %s", panelOpts.Format)
+		env.Println(err.Error())
+		return err
+	}
+
+	if opts.Grout < 0 {
+		opts.Grout = macro.Grout
+	}
+	opts.Bleed = macro.Bleed
+
+	meta, err := util.NewMetadata(macro.Path, opts.Metadata, opts.DPI)
+	if err != nil {
+		env.Printf("Error reading metadata: %s\n", err.Error())
+		return err
+	}
+	opts.DPI = meta.DPI
+
+	border := util.MaxInt(opts.OuterBorder, 0)
+	bounds := image.Rect(0, 0, cover.Width+2*border, cover.Height+2*border)
+
+	panels, marks, err := getMosaicPanels(bounds, panelOpts)
+	if err != nil {
+		env.Printf("Error splitting mosaic into panels: %s\n", err.Error())
+		return err
+	}
+
+	views, err := findMosaicPartialViews(env, mosaic)
+	if err != nil {
+		env.Printf("Error finding mosaic partials: %s\n", err.Error())
+		return err
+	}
+
+	// panels are streamed to the encoder, so they must be opaque
+	var bg color.Color = color.Black
+	if opts.decorated() {
+		bg = opts.groutColor()
+	}
+
+	env.Printf("Drawing %d mosaic panels...\n", len(panels))
+	bar := pb.StartNew(len(panels))
+
+	newImage := func(panel mosaicPanel) *mosaicPanelImage {
+		renderer := newMosaicBandRenderer(env, views, cover, 1, opts, bg, nil)
+		renderer.marks = marks
+		renderer.markSize = util.InchesToPixels(mosaicPanelMarkSize, opts.DPI)
+
+		img := &mosaicPanelImage{
+			mosaicBandImage: &mosaicBandImage{
+				env:      env,
+				renderer: renderer,
+				rect:     panel.Rect,
+			},
+		}
+		if panelOpts.Labels {
+			img.label = drawMosaicPanelLabel(panel, panelOpts, opts.DPI)
+		}
+		return img
+	}
+
+	if panelOpts.Format == "pdf" {
+		err = writeMosaicPanelsPdf(panels, out, opts.DPI, newImage, bar)
+	} else {
+		err = writeMosaicPanelsJpg(panels, out, meta, newImage, bar)
+	}
+	if err != nil {
+		env.Printf("Error drawing mosaic panels: %s\n", err.Error())
+		return err
+	}
+
+	bar.Finish()
+	env.Printf("Wrote mosaic panels: %s\n", out)
+
+	return nil
+}
+
+func writeMosaicPanelsPdf(panels []mosaicPanel, out string, dpi int, newImage func(mosaicPanel) *mosaicPanelImage, bar *pb.ProgressBar) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdf, err := util.NewPdfWriter(f)
+	if err != nil {
+		return err
+	}
+
+	for _, panel := range panels {
+		img := newImage(panel)
+		size := img.Bounds().Size()
+
+		// 72 points per inch
+		width := float64(size.X) / float64(dpi) * 72
+		height := float64(size.Y) / float64(dpi) * 72
+		err = pdf.AddJpegPage(width, height, size.X, size.Y, func(w io.Writer) error {
+			return imaging.Encode(w, img, imaging.JPEG)
+		})
+		if img.err != nil {
+			return img.err
+		}
+		if err != nil {
+			return err
+		}
+		bar.Increment()
+	}
+
+	return pdf.Close()
+}
+
+func writeMosaicPanelsJpg(panels []mosaicPanel, out string, meta *util.Metadata, newImage func(mosaicPanel) *mosaicPanelImage, bar *pb.ProgressBar) error {
+	err := os.MkdirAll(out, 0755)
+	if err != nil {
+		return err
+	}
+
+	for _, panel := range panels {
+		img := newImage(panel)
+		path := filepath.Join(out, fmt.Sprintf("panel_%d_%d.jpg", panel.Row+1, panel.Col+1))
+		err = saveStreamed(img, path, meta)
+		if img.err != nil {
+			return img.err
+		}
+		if err != nil {
+			return err
+		}
+		bar.Increment()
+	}
+
+	return nil
+}
+
+// getMosaicPanels splits bounds into the grid of panels of opts, from left
+// to right and top to bottom. It also returns the centers of the alignment
+// marks of the panels, if opts has marks.
+func getMosaicPanels(bounds image.Rectangle, opts MosaicPanelOptions) ([]mosaicPanel, []image.Point, error) {
+	if opts.Cols <= 0 || opts.Rows <= 0 {
+		return nil, nil, errors.New("Grid must have at least one column and row")
+	}
+
+	if opts.Overlap < 0 {
+		return nil, nil, errors.New("Overlap cannot be negative")
+	}
+
+	cols, err := getMosaicPanelSpans(bounds.Dx(), opts.Cols, opts.Overlap)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := getMosaicPanelSpans(bounds.Dy(), opts.Rows, opts.Overlap)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	panels := make([]mosaicPanel, 0, opts.Cols*opts.Rows)
+	for r, row := range rows {
+		for c, col := range cols {
+			panels = append(panels, mosaicPanel{
+				Row:  r,
+				Col:  c,
+				Rect: image.Rect(col[0], row[0], col[1], row[1]).Add(bounds.Min),
+			})
+		}
+	}
+
+	marks := []image.Point{}
+	if !opts.Marks {
+		return panels, marks, nil
+	}
+
+	// a quarter, half and three quarters of the way along each overlap,
+	// so that they are printed in the same place on both panels
+	for i := 1; i < len(cols); i++ {
+		x := (cols[i][0] + cols[i-1][1]) / 2
+		for _, row := range rows {
+			for q := 1; q <= 3; q++ {
+				marks = append(marks, image.Pt(x, row[0]+(row[1]-row[0])*q/4).Add(bounds.Min))
+			}
+		}
+	}
+
+	for i := 1; i < len(rows); i++ {
+		y := (rows[i][0] + rows[i-1][1]) / 2
+		for _, col := range cols {
+			for q := 1; q <= 3; q++ {
+				marks = append(marks, image.Pt(col[0]+(col[1]-col[0])*q/4, y).Add(bounds.Min))
+			}
+		}
+	}
+
+	return panels, marks, nil
+}
+
+// getMosaicPanelSpans splits length into n spans of equal size, where each
+// span shares at least overlap with its neighbours. The first span starts
+// at zero, and the last ends at length.
+func getMosaicPanelSpans(length, n, overlap int) ([][2]int, error) {
+	if n > length {
+		return nil, fmt.Errorf("Cannot split %d pixels into %d panels", length, n)
+	}
+
+	if n > 1 && overlap >= length {
+		return nil, errors.New("Overlap must be less than the size of the mosaic")
+	}
+
+	size := (length + (n-1)*overlap + n - 1) / n
+
+	spans := make([][2]int, n)
+	for i := range spans {
+		start := 0
+		if n > 1 {
+			start = i * (length - size) / (n - 1)
+		}
+		spans[i] = [2]int{start, start + size}
+	}
+
+	return spans, nil
+}
+
+// drawAlignmentMark draws a cross at center, with arms of length size and
+// lines of width w, in black outlined in white so that it shows on any tile
+func drawAlignmentMark(dst *image.NRGBA, center image.Point, size, w int) {
+	for _, line := range []struct {
+		c color.Color
+		w int
+	}{
+		{color.White, 3 * w},
+		{color.Black, w},
+	} {
+		src := image.NewUniform(line.c)
+		horizontal := image.Rect(center.X-size, center.Y-line.w/2, center.X+size, center.Y-line.w/2+line.w)
+		vertical := image.Rect(center.X-line.w/2, center.Y-size, center.X-line.w/2+line.w, center.Y+size)
+		for _, rect := range []image.Rectangle{horizontal, vertical} {
+			draw.Draw(dst, rect.Intersect(dst.Bounds()), src, image.Point{}, draw.Src)
+		}
+	}
+}
+
+// drawMosaicPanelLabel returns a white strip below panel,
+// with the position of the panel written on it
+func drawMosaicPanelLabel(panel mosaicPanel, opts MosaicPanelOptions, dpi int) *image.NRGBA {
+	height := util.MaxInt(util.InchesToPixels(mosaicPanelLabelHeight, dpi), 1)
+	rect := image.Rect(panel.Rect.Min.X, panel.Rect.Max.Y, panel.Rect.Max.X, panel.Rect.Max.Y+height)
+	label := image.NewNRGBA(rect)
+	draw.Draw(label, rect, image.White, image.Point{}, draw.Src)
+
+	text := fmt.Sprintf("Panel %d of %d: row %d of %d, column %d of %d",
+		panel.Row*opts.Cols+panel.Col+1, opts.Cols*opts.Rows,
+		panel.Row+1, opts.Rows, panel.Col+1, opts.Cols)
+
+	face := basicfont.Face7x13
+	d := &font.Drawer{Face: face}
+	textWidth := d.MeasureString(text).Ceil()
+
+	// the font is tiny at print resolution, so the text is drawn
+	// at its natural size, and scaled up to half the strip height
+	small := image.NewNRGBA(image.Rect(0, 0, textWidth, face.Height))
+	draw.Draw(small, small.Bounds(), image.White, image.Point{}, draw.Src)
+	d.Dst = small
+	d.Src = image.Black
+	d.Dot = fixed.P(0, face.Ascent)
+	d.DrawString(text)
+
+	scale := util.MaxInt(height/2/face.Height, 1)
+	scaled := imaging.Resize(small, textWidth*scale, face.Height*scale, imaging.NearestNeighbor)
+
+	pt := rect.Min.Add(image.Pt(height/4, (height-face.Height*scale)/2))
+	draw.Draw(label, scaled.Bounds().Add(pt).Intersect(rect), scaled, image.Point{}, draw.Src)
+
+	return label
+}
+
+// mosaicPanelImage is a panel of the mosaic, drawn one band at a time,
+// with an optional label strip below it
+type mosaicPanelImage struct {
+	*mosaicBandImage
+	label *image.NRGBA
+}
+
+func (m *mosaicPanelImage) Bounds() image.Rectangle {
+	if m.label == nil {
+		return m.rect
+	}
+	return m.rect.Union(m.label.Rect)
+}
+
+func (m *mosaicPanelImage) At(x, y int) color.Color {
+	if m.label != nil && image.Pt(x, y).In(m.label.Rect) {
+		return m.label.NRGBAAt(x, y)
+	}
+	return m.mosaicBandImage.At(x, y)
+}
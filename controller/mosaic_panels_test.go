@@ -0,0 +1,167 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestGetMosaicPanels(t *testing.T) {
+	bounds := image.Rect(0, 0, 300, 200)
+
+	panels, marks, err := getMosaicPanels(bounds, MosaicPanelOptions{Cols: 3, Rows: 2, Overlap: 30, Marks: true})
+	if err != nil {
+		t.Fatalf("Error getting mosaic panels: %s\n", err.Error())
+	}
+
+	expect := []image.Rectangle{
+		image.Rect(0, 0, 120, 115),
+		image.Rect(90, 0, 210, 115),
+		image.Rect(180, 0, 300, 115),
+		image.Rect(0, 85, 120, 200),
+		image.Rect(90, 85, 210, 200),
+		image.Rect(180, 85, 300, 200),
+	}
+
+	if len(panels) != len(expect) {
+		t.Fatalf("Expected %d panels, got %d\n", len(expect), len(panels))
+	}
+
+	for i, panel := range panels {
+		if panel.Rect != expect[i] {
+			t.Errorf("Expected panel %d to be %v, got %v\n", i, expect[i], panel.Rect)
+		}
+		if panel.Row != i/3 || panel.Col != i%3 {
+			t.Errorf("Expected panel %d at row %d col %d, got row %d col %d\n", i, i/3, i%3, panel.Row, panel.Col)
+		}
+	}
+
+	// 2 column overlaps in 2 rows, and 1 row overlap in 3 columns
+	if len(marks) != 2*2*3+1*3*3 {
+		t.Fatalf("Expected %d marks, got %d\n", 2*2*3+1*3*3, len(marks))
+	}
+
+	if marks[0] != image.Pt(105, 28) {
+		t.Errorf("Expected first mark at (105,28), got %v\n", marks[0])
+	}
+
+	for _, mark := range marks {
+		in := 0
+		for _, panel := range panels {
+			if mark.In(panel.Rect) {
+				in++
+			}
+		}
+		if in < 2 {
+			t.Errorf("Expected mark %v to be in at least 2 panels\n", mark)
+		}
+	}
+
+	for _, opts := range []MosaicPanelOptions{
+		{Cols: 0, Rows: 1},
+		{Cols: 2, Rows: 2, Overlap: -1},
+		{Cols: 2, Rows: 2, Overlap: 300},
+		{Cols: 301, Rows: 1},
+	} {
+		_, _, err = getMosaicPanels(bounds, opts)
+		if err == nil {
+			t.Errorf("Expected error getting panels %+v\n", opts)
+		}
+	}
+}
+
+func TestMosaicPanels(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_panels")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic panels test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	err = PartialAspect(env, macro.Id, -1.0)
+	if err != nil {
+		t.Fatalf("Error building partial aspects: %s\n", err.Error())
+	}
+
+	err = Compare(env, macro.Id, 0)
+	if err != nil {
+		t.Fatalf("Comparing images: %s\n", err.Error())
+	}
+
+	mosaic := MosaicBuild(env, "best", macro.Id, 0, false)
+	if mosaic == nil {
+		t.Fatal("Failed to build mosaic")
+	}
+
+	panelOpts := MosaicPanelOptions{Cols: 2, Rows: 2, Overlap: 10, Format: "jpg", Marks: true, Labels: true}
+	drawOpts := MosaicDrawOptions{DPI: 100}
+
+	jpgDir := filepath.Join(dir, "panels")
+	err = MosaicPanels(env, mosaic.Id, jpgDir, panelOpts, drawOpts)
+	if err != nil {
+		t.Fatalf("Error drawing jpg panels: %s\n", err.Error())
+	}
+
+	for row := 1; row <= 2; row++ {
+		for col := 1; col <= 2; col++ {
+			path := filepath.Join(jpgDir, fmt.Sprintf("panel_%d_%d.jpg", row, col))
+			img, err := imaging.Open(path)
+			if err != nil {
+				t.Fatalf("Error opening panel %s: %s\n", path, err.Error())
+			}
+
+			// 105 pixel panels, with a half inch label strip
+			if img.Bounds().Dx() != 105 || img.Bounds().Dy() != 155 {
+				t.Errorf("Expected panel %s to be 105x155, got %v\n", path, img.Bounds())
+			}
+		}
+	}
+
+	panelOpts.Format = "pdf"
+	pdfPath := filepath.Join(dir, "panels.pdf")
+	err = MosaicPanels(env, mosaic.Id, pdfPath, panelOpts, drawOpts)
+	if err != nil {
+		t.Fatalf("Error drawing pdf panels: %s\n", err.Error())
+	}
+
+	b, err := ioutil.ReadFile(pdfPath)
+	if err != nil {
+		t.Fatalf("Error reading pdf panels: %s\n", err.Error())
+	}
+
+	// 105x155 pixels at 100 dpi, in points
+	for _, s := range []string{"%PDF-1.4", "/Count 4", "/MediaBox [0 0 75.60 111.60]"} {
+		if !bytes.Contains(b, []byte(s)) {
+			t.Errorf("Expected pdf to contain %q\n", s)
+		}
+	}
+
+	expect := []string{
+		"Drawing 4 mosaic panels...",
+		"Wrote mosaic panels: " + jpgDir,
+		"Wrote mosaic panels: " + pdfPath,
+	}
+
+	testResultExpect(t, out.String(), expect)
+}
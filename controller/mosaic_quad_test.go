@@ -26,12 +26,14 @@ func TestMosaicQuad(t *testing.T) {
 		env,
 		"testdata/jumping_bunny.jpg",
 		"Jumping Bunny",
+		"abort",
 		"random",
-		200, 200, 10, -1, 2, 50, -1, -1,
+		200, 200, 10, -1, 2, 50, -1, -1, 0,
 		-1.0,
 		filepath.Join(dir, "jumping_bunny_cover.png"),
 		filepath.Join(dir, "jumping_bunny_macro.jpg"),
 		filepath.Join(dir, "jumping_bunny_mosaic.jpg"),
+		MosaicDrawOptions{},
 		true,
 		false,
 	)
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+)
+
+func MosaicSplit(env environment.Environment,
+	inPath, name, onExists, fillType, mode string,
+	coverWidth, coverHeight, size, minDepth, maxDepth, minArea, maxArea, maxRepeats, keepTop int,
+	threashold float64,
+	coverOutfile, macroOutfile, mosaicOutfile string,
+	drawOpts MosaicDrawOptions,
+	cleanup, destructive bool) *model.Mosaic {
+
+	params := &projectParams{
+		Type:        mode,
+		Width:       coverWidth,
+		Height:      coverHeight,
+		Size:        size,
+		MinDepth:    minDepth,
+		MaxDepth:    maxDepth,
+		MinArea:     minArea,
+		MaxArea:     maxArea,
+		Grout:       drawOpts.Grout,
+		Bleed:       drawOpts.Bleed,
+		Threashold:  threashold,
+		FillType:    fillType,
+		MaxRepeats:  maxRepeats,
+		KeepTop:     keepTop,
+		Destructive: destructive,
+	}
+
+	project, err := findOrCreateProject(env, inPath, name, onExists, params, coverOutfile, macroOutfile, mosaicOutfile)
+	if err != nil {
+		env.Println(err.Error())
+		return nil
+	}
+	env.SetProjectId(project.Id)
+
+	cover, macro := MacroSplit(env, project.Path, mode, coverWidth, coverHeight, size, minDepth, maxDepth, minArea, maxArea, drawOpts.Grout, drawOpts.Bleed, project.CoverPath, project.MacroPath)
+	if cover == nil || macro == nil {
+		return nil
+	}
+
+	err = PartialAspect(env, macro.Id, threashold)
+	if err != nil {
+		return nil
+	}
+
+	mosaic := mosaicCompareBuild(env, fillType, macro.Id, maxRepeats, keepTop, destructive)
+	if mosaic == nil {
+		return nil
+	}
+
+	err = MosaicDraw(env, mosaic.Id, project.MosaicPath, drawOpts)
+	if err != nil {
+		return nil
+	}
+
+	err = projectComplete(env, project)
+	if err != nil {
+		return nil
+	}
+
+	if cleanup {
+		err = projectCleanup(env, macro)
+		if err != nil {
+			return nil
+		}
+	}
+
+	return mosaic
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMosaicSplit(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_mosaic_split")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for mosaic split test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	Index(env, []string{"testdata", "../service/testdata"})
+
+	mosaic := MosaicSplit(
+		env,
+		"testdata/jumping_bunny.jpg",
+		"Jumping Bunny",
+		"abort",
+		"random",
+		"guillotine",
+		200, 200, 10, -1, 4, 50, -1, -1, 0,
+		-1.0,
+		filepath.Join(dir, "jumping_bunny_cover.png"),
+		filepath.Join(dir, "jumping_bunny_macro.jpg"),
+		filepath.Join(dir, "jumping_bunny_mosaic.jpg"),
+		MosaicDrawOptions{},
+		true,
+		false,
+	)
+	if mosaic == nil {
+		t.Fatal("Failed to create mosaic")
+	}
+
+	expect := []string{
+		"Indexing 4 images...",
+		"Building macro guillotine split with 10 splits, 12 partials, max depth 4, min area 50...",
+		"Building 12 mosaic partials...",
+		"Drawing 12 mosaic partials...",
+	}
+
+	testResultExpect(t, out.String(), expect)
+}
@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+	"image"
+	"math"
+	"sync"
+
+	"github.com/disintegration/imaging"
+)
+
+// mosaicTileLoader loads the index images of mosaic tiles, resized to fill
+// their tiles. Tiles are read from the tile cache when they can be, and
+// written to it when they cannot. Within a single draw, each size of an
+// index image is only resized once, and an index image that is needed at
+// several sizes is only decoded once.
+// It is safe for concurrent use by multiple goroutines.
+type mosaicTileLoader struct {
+	cache   *util.TileCache
+	mu      sync.Mutex
+	tiles   map[mosaicTileKey]*mosaicTileEntry
+	sources map[int64]*mosaicTileSource
+}
+
+type mosaicTileKey struct {
+	md5sum string
+	width  int
+	height int
+}
+
+// mosaicTileEntry is a resized index image that is used by more than one tile
+type mosaicTileEntry struct {
+	once sync.Once
+	img  image.Image
+	err  error
+	// uses is how many more tiles will load it
+	uses int
+}
+
+// mosaicTileSource is a decoded index image that is needed at several sizes,
+// shrunk to the largest of them
+type mosaicTileSource struct {
+	once  sync.Once
+	img   image.Image
+	err   error
+	sizes []image.Point
+}
+
+// newMosaicTileLoader returns a loader of the tiles of views, where tile
+// returns the area of the mosaic that the index image of a view fills
+func newMosaicTileLoader(cache *util.TileCache, views []*model.MosaicPartialView, tile func(*model.MosaicPartialView) *model.CoverPartial) *mosaicTileLoader {
+	l := &mosaicTileLoader{
+		cache:   cache,
+		tiles:   make(map[mosaicTileKey]*mosaicTileEntry),
+		sources: make(map[int64]*mosaicTileSource),
+	}
+
+	sizes := make(map[int64]map[image.Point]bool)
+	for _, view := range views {
+		cp := tile(view)
+		key := mosaicTileKey{view.Gidx.Md5sum, cp.Width(), cp.Height()}
+		if _, ok := l.tiles[key]; !ok {
+			l.tiles[key] = &mosaicTileEntry{}
+		}
+		l.tiles[key].uses++
+
+		if _, ok := sizes[view.Gidx.Id]; !ok {
+			sizes[view.Gidx.Id] = make(map[image.Point]bool)
+		}
+		sizes[view.Gidx.Id][image.Pt(cp.Width(), cp.Height())] = true
+	}
+
+	for gidxId, gidxSizes := range sizes {
+		if len(gidxSizes) < 2 {
+			continue
+		}
+
+		source := &mosaicTileSource{}
+		for size := range gidxSizes {
+			source.sizes = append(source.sizes, size)
+		}
+		l.sources[gidxId] = source
+	}
+
+	return l
+}
+
+// load returns the index image of gidx, resized to fill width by height
+func (l *mosaicTileLoader) load(gidx *model.Gidx, width, height int) (image.Image, error) {
+	key := mosaicTileKey{gidx.Md5sum, width, height}
+
+	l.mu.Lock()
+	entry, ok := l.tiles[key]
+	if !ok {
+		// not a tile of the views, or all of its uses are done
+		entry = &mosaicTileEntry{}
+	}
+	l.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.img, entry.err = l.resize(gidx, width, height)
+	})
+
+	if ok {
+		l.mu.Lock()
+		entry.uses--
+		if entry.uses <= 0 {
+			delete(l.tiles, key)
+		}
+		l.mu.Unlock()
+	}
+
+	return entry.img, entry.err
+}
+
+func (l *mosaicTileLoader) resize(gidx *model.Gidx, width, height int) (image.Image, error) {
+	if width <= 0 || height <= 0 {
+		return image.NewNRGBA(image.Rect(0, 0, 0, 0)), nil
+	}
+
+	if img, ok := l.cache.Get(gidx.Md5sum, width, height); ok {
+		return img, nil
+	}
+
+	src, err := l.source(gidx)
+	if err != nil {
+		return nil, err
+	}
+
+	img := imaging.Fill(src, width, height, imaging.Center, imaging.Lanczos)
+
+	// the cache only speeds up later draws, so a tile that
+	// cannot be written to it is still drawn
+	l.cache.Put(gidx.Md5sum, img)
+
+	return img, nil
+}
+
+// source returns the decoded index image of gidx, with its orientation fixed
+func (l *mosaicTileLoader) source(gidx *model.Gidx) (image.Image, error) {
+	l.mu.Lock()
+	source, ok := l.sources[gidx.Id]
+	l.mu.Unlock()
+
+	if !ok {
+		return openMosaicTileSource(gidx)
+	}
+
+	source.once.Do(func() {
+		img, err := openMosaicTileSource(gidx)
+		if err != nil {
+			source.err = err
+			return
+		}
+		source.img = shrinkMosaicTileSource(img, source.sizes)
+	})
+
+	return source.img, source.err
+}
+
+func openMosaicTileSource(gidx *model.Gidx) (image.Image, error) {
+	img, err := util.OpenImg(gidx)
+	if err != nil {
+		return nil, err
+	}
+
+	if gidx.GetOrientation() != 1 {
+		err = util.FixOrientation(img, gidx.GetOrientation())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return *img, nil
+}
+
+// shrinkMosaicTileSource returns img scaled down to the smallest size
+// that each of sizes can still be filled from without enlarging it
+func shrinkMosaicTileSource(img image.Image, sizes []image.Point) image.Image {
+	bounds := img.Bounds()
+	scale := 0.0
+	for _, size := range sizes {
+		scale = math.Max(scale, math.Max(
+			float64(size.X)/float64(bounds.Dx()),
+			float64(size.Y)/float64(bounds.Dy()),
+		))
+	}
+
+	if scale >= 1 {
+		return img
+	}
+
+	width := int(math.Ceil(float64(bounds.Dx()) * scale))
+	height := int(math.Ceil(float64(bounds.Dy()) * scale))
+	return imaging.Resize(img, width, height, imaging.Lanczos)
+}
@@ -11,6 +11,18 @@ import (
 )
 
 func PartialAspect(env environment.Environment, macroId int64, threashold float64) error {
+	return partialAspect(env, macroId, threashold, false)
+}
+
+// PartialAspectOriented is like PartialAspect, but only builds index
+// partials for aspects with the same orientation as the index image,
+// so portrait partials are filled with portrait images and
+// landscape partials with landscape images.
+func PartialAspectOriented(env environment.Environment, macroId int64, threashold float64) error {
+	return partialAspect(env, macroId, threashold, true)
+}
+
+func partialAspect(env environment.Environment, macroId int64, threashold float64, oriented bool) error {
 	aspectService := env.ServiceFactory().MustAspectService()
 	macroService := env.ServiceFactory().MustMacroService()
 	macroPartialService := env.ServiceFactory().MustMacroPartialService()
@@ -37,7 +49,7 @@ func PartialAspect(env environment.Environment, macroId int64, threashold float6
 		return err
 	}
 
-	err = createPartialGidxIndexes(env, aspects, threashold, env.Workers())
+	err = createPartialGidxIndexes(env, aspects, threashold, oriented, env.Workers())
 	if err != nil {
 		env.Printf("Error creating index aspects: %s\n", err.Error())
 		return err
@@ -46,7 +58,7 @@ func PartialAspect(env environment.Environment, macroId int64, threashold float6
 	return nil
 }
 
-func createPartialGidxIndexes(env environment.Environment, aspects []*model.Aspect, threashold float64, workers int) error {
+func createPartialGidxIndexes(env environment.Environment, aspects []*model.Aspect, threashold float64, oriented bool, workers int) error {
 	gidxService := env.ServiceFactory().MustGidxService()
 	gidxPartialService := env.ServiceFactory().MustGidxPartialService()
 
@@ -78,7 +90,7 @@ func createPartialGidxIndexes(env environment.Environment, aspects []*model.Aspe
 				return errors.New("Cancelled")
 			}
 
-			gidxPartials, err := buildGidxPartials(env, gidx, aspects, threashold, workers)
+			gidxPartials, err := buildGidxPartials(env, gidx, aspects, threashold, oriented, workers)
 			if err != nil {
 				return err
 			}
@@ -96,7 +108,7 @@ func createPartialGidxIndexes(env environment.Environment, aspects []*model.Aspe
 	return nil
 }
 
-func buildGidxPartials(env environment.Environment, gidx *model.Gidx, aspects []*model.Aspect, threashold float64, workers int) ([]*model.GidxPartial, error) {
+func buildGidxPartials(env environment.Environment, gidx *model.Gidx, aspects []*model.Aspect, threashold float64, oriented bool, workers int) ([]*model.GidxPartial, error) {
 	gidxPartialService := env.ServiceFactory().MustGidxPartialService()
 
 	var gidxPartials []*model.GidxPartial
@@ -112,6 +124,16 @@ func buildGidxPartials(env environment.Environment, gidx *model.Gidx, aspects []
 		}
 	}
 
+	if oriented {
+		oAspects := []*model.Aspect{}
+		for _, aspect := range pAspects {
+			if gidx.SameOrientation(aspect) {
+				oAspects = append(oAspects, aspect)
+			}
+		}
+		pAspects = oAspects
+	}
+
 	if len(pAspects) == 0 {
 		return gidxPartials, nil
 	}
@@ -16,7 +16,7 @@ func TestPartialAspect(t *testing.T) {
 		t.Fatalf("Error indexing images: %s\n", err.Error())
 	}
 
-	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 594, 554, 2, 3, 10, "", "")
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 594, 554, 2, 3, 10, 0, 0, "grid", "", "")
 	if cover == nil || macro == nil {
 		t.Fatal("Failed to create cover or macro")
 	}
@@ -56,7 +56,7 @@ func TestPartialAspectWithin(t *testing.T) {
 		t.Fatalf("Error indexing images: %s\n", err.Error())
 	}
 
-	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 594, 554, 2, 3, 10, "", "")
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 594, 554, 2, 3, 10, 0, 0, "grid", "", "")
 	if cover == nil || macro == nil {
 		t.Fatal("Failed to create cover or macro")
 	}
@@ -0,0 +1,498 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"io"
+	"strconv"
+)
+
+// projectStages counts the progress of each stage of building a project
+type projectStages struct {
+	CoverPartials        int64
+	MacroPartials        int64
+	MacroPartialsMissing int64
+	ComparisonsMissing   int64
+	MosaicPartials       int64
+	MosaicMissing        int64
+}
+
+// findProject finds a project by name, or by id if no project has that name
+func findProject(env environment.Environment, nameOrId string) (*model.Project, error) {
+	projectService := env.ServiceFactory().MustProjectService()
+
+	project, err := projectService.GetOneBy("name = ?", nameOrId)
+	if err != nil {
+		return nil, err
+	}
+
+	if project == nil {
+		if id, err := strconv.ParseInt(nameOrId, 10, 64); err == nil {
+			project, err = projectService.Get(id)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if project == nil {
+		return nil, fmt.Errorf("Project %s not found", nameOrId)
+	}
+
+	return project, nil
+}
+
+// getProjectStages counts how far each stage of project has progressed.
+// Stages that the project has not reached are left at zero.
+func getProjectStages(env environment.Environment, project *model.Project) (projectStages, error) {
+	stages := projectStages{}
+
+	if project.CoverId != int64(0) {
+		cover, err := env.ServiceFactory().MustCoverService().Get(project.CoverId)
+		if err != nil {
+			return stages, err
+		}
+		if cover != nil {
+			stages.CoverPartials, err = env.ServiceFactory().MustCoverPartialService().Count(cover)
+			if err != nil {
+				return stages, err
+			}
+		}
+	}
+
+	if project.MacroId != int64(0) {
+		macro, err := env.ServiceFactory().MustMacroService().Get(project.MacroId)
+		if err != nil {
+			return stages, err
+		}
+		if macro != nil {
+			macroPartialService := env.ServiceFactory().MustMacroPartialService()
+			stages.MacroPartials, err = macroPartialService.Count(macro)
+			if err != nil {
+				return stages, err
+			}
+			stages.MacroPartialsMissing, err = macroPartialService.CountMissing(macro)
+			if err != nil {
+				return stages, err
+			}
+			stages.ComparisonsMissing, err = env.ServiceFactory().MustPartialComparisonService().CountMissing(macro)
+			if err != nil {
+				return stages, err
+			}
+		}
+	}
+
+	if project.MosaicId != int64(0) {
+		mosaic, err := env.ServiceFactory().MustMosaicService().Get(project.MosaicId)
+		if err != nil {
+			return stages, err
+		}
+		if mosaic != nil {
+			mosaicPartialService := env.ServiceFactory().MustMosaicPartialService()
+			stages.MosaicPartials, err = mosaicPartialService.Count(mosaic)
+			if err != nil {
+				return stages, err
+			}
+			stages.MosaicMissing, err = mosaicPartialService.CountMissing(mosaic)
+			if err != nil {
+				return stages, err
+			}
+		}
+	}
+
+	return stages, nil
+}
+
+func projectStatus(project *model.Project) string {
+	if project.IsComplete {
+		return "complete"
+	}
+	return "incomplete"
+}
+
+// ProjectList writes a line for each project to w, newest first
+func ProjectList(env environment.Environment, w io.Writer) error {
+	projectService := env.ServiceFactory().MustProjectService()
+
+	projects, err := projectService.FindAll("projects.id desc")
+	if err != nil {
+		env.Printf("Error finding projects: %s\n", err.Error())
+		return err
+	}
+
+	for _, project := range projects {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+			project.Id,
+			project.Name,
+			projectStatus(project),
+			project.CreatedAt.Format("2006-01-02 15:04:05"),
+			project.Path,
+		)
+	}
+
+	return nil
+}
+
+// ProjectShow writes the paths of a project, and the state of each of its stages, to w
+func ProjectShow(env environment.Environment, nameOrId string, w io.Writer) error {
+	project, err := findProject(env, nameOrId)
+	if err != nil {
+		env.Println(err.Error())
+		return err
+	}
+
+	stages, err := getProjectStages(env, project)
+	if err != nil {
+		env.Printf("Error getting project stages: %s\n", err.Error())
+		return err
+	}
+
+	fmt.Fprintf(w, "Id: %d\n", project.Id)
+	fmt.Fprintf(w, "Name: %s\n", project.Name)
+	fmt.Fprintf(w, "Status: %s\n", projectStatus(project))
+	fmt.Fprintf(w, "Created: %s\n", project.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "Path: %s\n", project.Path)
+	fmt.Fprintf(w, "Cover path: %s\n", project.CoverPath)
+	fmt.Fprintf(w, "Macro path: %s\n", project.MacroPath)
+	fmt.Fprintf(w, "Mosaic path: %s\n", project.MosaicPath)
+	if project.Params != "" {
+		fmt.Fprintf(w, "Parameters: %s\n", project.Params)
+	}
+	fmt.Fprintf(w, "Cover partials: %d\n", stages.CoverPartials)
+	fmt.Fprintf(w, "Macro partials: %d of %d\n", stages.MacroPartials, stages.MacroPartials+stages.MacroPartialsMissing)
+	fmt.Fprintf(w, "Comparisons remaining: %d\n", stages.ComparisonsMissing)
+	fmt.Fprintf(w, "Mosaic partials placed: %d of %d\n", stages.MosaicPartials, stages.MosaicPartials+stages.MosaicMissing)
+
+	return nil
+}
+
+// ProjectRm removes a project, along with its cover, macro and mosaic
+// when no other project or entry uses them. Image files are not removed.
+func ProjectRm(env environment.Environment, nameOrId string) error {
+	projectService := env.ServiceFactory().MustProjectService()
+
+	project, err := findProject(env, nameOrId)
+	if err != nil {
+		env.Println(err.Error())
+		return err
+	}
+
+	err = resetProjectStages(env, project, projectStageCover)
+	if err != nil {
+		env.Printf("Error removing project stages: %s\n", err.Error())
+		return err
+	}
+
+	err = projectService.Delete(project)
+	if err != nil {
+		env.Printf("Error removing project: %s\n", err.Error())
+		return err
+	}
+
+	env.Printf("Removed project %s\n", project.Name)
+
+	return nil
+}
+
+// resetProjectStages removes stage and each later stage of project when
+// no other project or entry uses them, and clears them from the project,
+// so that they are built again
+func resetProjectStages(env environment.Environment, project *model.Project, stage int) error {
+	projectService := env.ServiceFactory().MustProjectService()
+	coverService := env.ServiceFactory().MustCoverService()
+	macroService := env.ServiceFactory().MustMacroService()
+	mosaicService := env.ServiceFactory().MustMosaicService()
+
+	if project.MosaicId != int64(0) {
+		shared, err := projectService.ExistsBy("mosaic_id = ? AND id != ?", project.MosaicId, project.Id)
+		if err != nil {
+			return err
+		}
+
+		if !shared {
+			mosaic, err := mosaicService.Get(project.MosaicId)
+			if err == nil && mosaic != nil {
+				err = mosaicService.Delete(mosaic)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if stage <= projectStageMacro && project.MacroId != int64(0) {
+		shared, err := projectService.ExistsBy("macro_id = ? AND id != ?", project.MacroId, project.Id)
+		if err == nil && !shared {
+			shared, err = mosaicService.ExistsBy("macro_id = ?", project.MacroId)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !shared {
+			macro, err := macroService.Get(project.MacroId)
+			if err == nil && macro != nil {
+				err = macroService.Delete(macro)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if stage <= projectStageCover && project.CoverId != int64(0) {
+		shared, err := projectService.ExistsBy("cover_id = ? AND id != ?", project.CoverId, project.Id)
+		if err == nil && !shared {
+			shared, err = macroService.ExistsBy("cover_id = ?", project.CoverId)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !shared {
+			cover, err := coverService.Get(project.CoverId)
+			if err == nil && cover != nil {
+				err = coverService.Delete(cover)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if stage <= projectStageCover {
+		project.CoverId = int64(0)
+	}
+	if stage <= projectStageMacro {
+		project.MacroId = int64(0)
+	}
+	project.MosaicId = int64(0)
+	project.IsComplete = false
+	_, err := projectService.Update(project)
+	return err
+}
+
+// nextProjectName returns name followed by the lowest number
+// that no project has, starting from 2
+func nextProjectName(env environment.Environment, name string) (string, error) {
+	projectService := env.ServiceFactory().MustProjectService()
+
+	for i := 2; ; i++ {
+		next := fmt.Sprintf("%s-%d", name, i)
+		exists, err := projectService.ExistsBy("name = ?", next)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return next, nil
+		}
+	}
+}
+
+// ProjectRename changes the name of a project
+func ProjectRename(env environment.Environment, nameOrId, newName string) error {
+	projectService := env.ServiceFactory().MustProjectService()
+
+	if newName == "" {
+		err := errors.New("Project name cannot be empty")
+		env.Println(err.Error())
+		return err
+	}
+
+	project, err := findProject(env, nameOrId)
+	if err != nil {
+		env.Println(err.Error())
+		return err
+	}
+
+	exists, err := projectService.ExistsBy("name = ? AND id != ?", newName, project.Id)
+	if err != nil {
+		env.Printf("Error finding project name: %s\n", err.Error())
+		return err
+	}
+
+	if exists {
+		err = fmt.Errorf("Project with name '%s' already exists", newName)
+		env.Println(err.Error())
+		return err
+	}
+
+	oldName := project.Name
+	project.Name = newName
+	_, err = projectService.Update(project)
+	if err != nil {
+		env.Printf("Error renaming project: %s\n", err.Error())
+		return err
+	}
+
+	env.Printf("Renamed project %s to %s\n", oldName, newName)
+
+	return nil
+}
+
+// ProjectResumeOptions override the parameters stored with a project
+// when it is resumed. Nil options keep the stored parameters.
+type ProjectResumeOptions struct {
+	FillType    *string
+	MaxRepeats  *int
+	KeepTop     *int
+	Threashold  *float64
+	Engine      *string
+	Destructive *bool
+}
+
+// merge returns a copy of params with opts applied. Projects built before
+// their parameters were stored are resumed with the defaults of the
+// mosaic commands, and the engine of env.
+func (opts ProjectResumeOptions) merge(env environment.Environment, params *projectParams) *projectParams {
+	merged := projectParams{FillType: "random", MaxRepeats: -1, Threashold: -1.0, Engine: env.Engine()}
+	if params != nil {
+		merged = *params
+		merged.Engine = params.engine()
+	}
+
+	if opts.FillType != nil {
+		merged.FillType = *opts.FillType
+	}
+	if opts.MaxRepeats != nil {
+		merged.MaxRepeats = *opts.MaxRepeats
+	}
+	if opts.KeepTop != nil {
+		merged.KeepTop = *opts.KeepTop
+	}
+	if opts.Threashold != nil {
+		merged.Threashold = *opts.Threashold
+	}
+	if opts.Engine != nil {
+		merged.Engine = *opts.Engine
+	}
+	if opts.Destructive != nil {
+		merged.Destructive = *opts.Destructive
+	}
+
+	return &merged
+}
+
+// ProjectResume continues building a project from its first unfinished
+// stage, and draws the mosaic to the mosaic path of the project.
+// The cover and macro of the project must already exist. It is built with
+// the parameters stored with the project, overridden by opts, which are
+// stored in turn. Index partials of mixed projects are matched by
+// orientation, as mosaic mixed does.
+func ProjectResume(env environment.Environment, nameOrId string, opts ProjectResumeOptions, drawOpts MosaicDrawOptions) *model.Mosaic {
+	project, err := findProject(env, nameOrId)
+	if err != nil {
+		env.Println(err.Error())
+		return nil
+	}
+	env.SetProjectId(project.Id)
+
+	stored, err := getProjectParams(project)
+	if err != nil {
+		env.Printf("Error reading project parameters: %s\n", err.Error())
+		return nil
+	}
+
+	params := opts.merge(env, stored)
+	env.SetEngine(params.engine())
+
+	// projects built before their parameters were stored
+	// only store the parameters of a mosaic command
+	if stored != nil {
+		err = resetChangedProjectStages(env, project, params, false)
+		if err != nil {
+			env.Printf("Error resetting project: %s\n", err.Error())
+			return nil
+		}
+
+		err = setProjectParams(project, params)
+		if err != nil {
+			env.Printf("Error storing project parameters: %s\n", err.Error())
+			return nil
+		}
+
+		_, err = env.ServiceFactory().MustProjectService().Update(project)
+		if err != nil {
+			env.Printf("Error updating project: %s\n", err.Error())
+			return nil
+		}
+	}
+
+	oriented := params.Type == "mixed"
+
+	cover, err := envCover(env)
+	if err != nil {
+		env.Printf("Error getting cover from project environment: %s\n", err.Error())
+		return nil
+	}
+
+	macro, err := envMacro(env)
+	if err != nil {
+		env.Printf("Error getting macro from project environment: %s\n", err.Error())
+		return nil
+	}
+
+	if cover == nil || macro == nil {
+		env.Printf("Project %s has no cover or macro, it must be re-created with a mosaic command\n", project.Name)
+		return nil
+	}
+
+	missing, err := env.ServiceFactory().MustMacroPartialService().CountMissing(macro)
+	if err != nil {
+		env.Printf("Error counting missing macro partials: %s\n", err.Error())
+		return nil
+	}
+
+	if missing > 0 {
+		found, img, err := findOrCreateMacro(env, cover, project.Path, macro.Grout, macro.Bleed, "")
+		if err != nil {
+			env.Printf("Error opening macro: %s\n", err.Error())
+			return nil
+		}
+
+		if found.Id != macro.Id {
+			env.Printf("Project image %s has changed since the macro was created\n", project.Path)
+			return nil
+		}
+
+		err = buildMacroPartials(env, img, macro, env.Workers())
+		if err != nil {
+			env.Printf("Error creating macro partials: %s\n", err.Error())
+			return nil
+		}
+	}
+
+	if oriented {
+		err = orientMacro(env, macro)
+		if err != nil {
+			env.Printf("Error orienting macro: %s\n", err.Error())
+			return nil
+		}
+
+		err = PartialAspectOriented(env, macro.Id, params.Threashold)
+	} else {
+		err = PartialAspect(env, macro.Id, params.Threashold)
+	}
+	if err != nil {
+		return nil
+	}
+
+	mosaic := mosaicCompareBuild(env, params.FillType, macro.Id, params.MaxRepeats, params.KeepTop, params.Destructive)
+	if mosaic == nil {
+		return nil
+	}
+
+	err = MosaicDraw(env, mosaic.Id, project.MosaicPath, drawOpts)
+	if err != nil {
+		return nil
+	}
+
+	err = projectComplete(env, project)
+	if err != nil {
+		return nil
+	}
+
+	return mosaic
+}
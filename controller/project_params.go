@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/atongen/gosaic/model"
+	"strings"
+)
+
+// The stages of a project, in the order they are built.
+// Changing a parameter of a stage invalidates it and every later stage.
+const (
+	projectStageCover = iota
+	projectStageMacro
+	projectStageMosaic
+	projectStageNone
+)
+
+var projectStageNames = []string{"cover", "macro", "mosaic"}
+
+// projectParams are the parameters a project was built with
+type projectParams struct {
+	// Type is aspect, mixed, or the mode of a split mosaic
+	Type          string   `json:"type"`
+	Path          string   `json:"path"`
+	Width         int      `json:"width"`
+	Height        int      `json:"height"`
+	PartialWidth  int      `json:"partial_width,omitempty"`
+	PartialHeight int      `json:"partial_height,omitempty"`
+	Aspects       []string `json:"aspects,omitempty"`
+	Size          int      `json:"size"`
+	Layout        string   `json:"layout,omitempty"`
+	MinDepth      int      `json:"min_depth,omitempty"`
+	MaxDepth      int      `json:"max_depth,omitempty"`
+	MinArea       int      `json:"min_area,omitempty"`
+	MaxArea       int      `json:"max_area,omitempty"`
+
+	Grout int `json:"grout"`
+	Bleed int `json:"bleed"`
+
+	Threashold  float64 `json:"threashold"`
+	FillType    string  `json:"fill_type"`
+	MaxRepeats  int     `json:"max_repeats"`
+	KeepTop     int     `json:"keep_top"`
+	Destructive bool    `json:"destructive"`
+	// Engine is empty for projects built before it was stored,
+	// which were all built with the db engine
+	Engine string `json:"engine,omitempty"`
+}
+
+// projectParamChange is a parameter that differs between two builds of a project
+type projectParamChange struct {
+	Name  string
+	Stage int
+}
+
+func formatProjectAspects(aspects []*model.Aspect) []string {
+	s := make([]string, len(aspects))
+	for i, aspect := range aspects {
+		s[i] = fmt.Sprintf("%dx%d", aspect.Columns, aspect.Rows)
+	}
+	return s
+}
+
+// getProjectParams returns the parameters stored with project,
+// or nil if it was built before parameters were stored
+func getProjectParams(project *model.Project) (*projectParams, error) {
+	if project.Params == "" {
+		return nil, nil
+	}
+
+	params := &projectParams{}
+	err := json.Unmarshal([]byte(project.Params), params)
+	if err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+func setProjectParams(project *model.Project, params *projectParams) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	project.Params = string(b)
+	return nil
+}
+
+// changes returns the parameters that differ from prev,
+// in the order of the stages they belong to
+func (p *projectParams) changes(prev *projectParams) []projectParamChange {
+	fields := []struct {
+		name  string
+		stage int
+		a, b  interface{}
+	}{
+		{"type", projectStageCover, p.Type, prev.Type},
+		{"path", projectStageCover, p.Path, prev.Path},
+		{"width", projectStageCover, p.Width, prev.Width},
+		{"height", projectStageCover, p.Height, prev.Height},
+		{"aspect", projectStageCover, [2]int{p.PartialWidth, p.PartialHeight}, [2]int{prev.PartialWidth, prev.PartialHeight}},
+		{"aspects", projectStageCover, strings.Join(p.Aspects, ","), strings.Join(prev.Aspects, ",")},
+		{"size", projectStageCover, p.Size, prev.Size},
+		{"layout", projectStageCover, p.Layout, prev.Layout},
+		{"min-depth", projectStageCover, p.MinDepth, prev.MinDepth},
+		{"max-depth", projectStageCover, p.MaxDepth, prev.MaxDepth},
+		{"min-area", projectStageCover, p.MinArea, prev.MinArea},
+		{"max-area", projectStageCover, p.MaxArea, prev.MaxArea},
+		{"grout", projectStageMacro, p.Grout, prev.Grout},
+		{"bleed", projectStageMacro, p.Bleed, prev.Bleed},
+		{"threashold", projectStageMosaic, p.Threashold, prev.Threashold},
+		{"fill-type", projectStageMosaic, p.FillType, prev.FillType},
+		{"max-repeats", projectStageMosaic, p.MaxRepeats, prev.MaxRepeats},
+		{"keep-top", projectStageMosaic, p.KeepTop, prev.KeepTop},
+		{"destructive", projectStageMosaic, p.Destructive, prev.Destructive},
+		{"engine", projectStageMosaic, p.engine(), prev.engine()},
+	}
+
+	changes := []projectParamChange{}
+	for _, f := range fields {
+		if f.a != f.b {
+			changes = append(changes, projectParamChange{f.name, f.stage})
+		}
+	}
+
+	return changes
+}
+
+// engine returns the engine the project was built with
+func (p *projectParams) engine() string {
+	if p.Engine == "" {
+		return "db"
+	}
+	return p.Engine
+}
+
+// invalidatedProjectStage returns the first stage invalidated by changes
+func invalidatedProjectStage(changes []projectParamChange) int {
+	stage := projectStageNone
+	for _, c := range changes {
+		if c.Stage < stage {
+			stage = c.Stage
+		}
+	}
+	return stage
+}
+
+// describeProjectChanges describes which stages of a project changes invalidate
+func describeProjectChanges(changes []projectParamChange) string {
+	names := make([]string, len(changes))
+	for i, c := range changes {
+		names[i] = c.Name
+	}
+
+	stage := invalidatedProjectStage(changes)
+	return fmt.Sprintf("Changed %s, rebuilding %s", strings.Join(names, ", "), strings.Join(projectStageNames[stage:], ", "))
+}
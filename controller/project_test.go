@@ -0,0 +1,364 @@
+package controller
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+)
+
+func TestProject(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_project")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for project test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	projectService := env.ServiceFactory().MustProjectService()
+	project := &model.Project{
+		Name:       "bunny",
+		Path:       "testdata/jumping_bunny.jpg",
+		MosaicPath: filepath.Join(dir, "bunny_mosaic.jpg"),
+	}
+	err = projectService.Insert(project)
+	if err != nil {
+		t.Fatalf("Error inserting project: %s\n", err.Error())
+	}
+	env.SetProjectId(project.Id)
+
+	// stop after the macro stage
+	cover, macro := MacroAspect(env, project.Path, 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	var show bytes.Buffer
+	err = ProjectShow(env, "bunny", &show)
+	if err != nil {
+		t.Fatalf("Error showing project: %s\n", err.Error())
+	}
+
+	testResultExpect(t, show.String(), []string{
+		"Status: incomplete",
+		"Cover partials: 4",
+		"Macro partials: 4 of 4",
+		"Mosaic partials placed: 0 of 0",
+	})
+
+	fillType, maxRepeats := "best", 0
+	mosaic := ProjectResume(env, "bunny", ProjectResumeOptions{FillType: &fillType, MaxRepeats: &maxRepeats}, MosaicDrawOptions{Grout: -1})
+	if mosaic == nil {
+		t.Fatalf("Failed to resume project:\n%s\n", out.String())
+	}
+
+	if _, err := os.Stat(project.MosaicPath); err != nil {
+		t.Fatalf("Mosaic not drawn: %s\n", err.Error())
+	}
+
+	show.Reset()
+	err = ProjectShow(env, "bunny", &show)
+	if err != nil {
+		t.Fatalf("Error showing project: %s\n", err.Error())
+	}
+
+	testResultExpect(t, show.String(), []string{
+		"Status: complete",
+		"Comparisons remaining: 0",
+		"Mosaic partials placed: 4 of 4",
+	})
+
+	err = ProjectRename(env, "bunny", "rabbit")
+	if err != nil {
+		t.Fatalf("Error renaming project: %s\n", err.Error())
+	}
+
+	var list bytes.Buffer
+	err = ProjectList(env, &list)
+	if err != nil {
+		t.Fatalf("Error listing projects: %s\n", err.Error())
+	}
+
+	testResultExpect(t, list.String(), []string{"rabbit\tcomplete"})
+
+	err = ProjectRm(env, "rabbit")
+	if err != nil {
+		t.Fatalf("Error removing project: %s\n", err.Error())
+	}
+
+	if p, err := projectService.Get(project.Id); err != nil || p != nil {
+		t.Fatalf("Project not removed")
+	}
+
+	if m, err := env.ServiceFactory().MustMosaicService().Get(mosaic.Id); err != nil || m != nil {
+		t.Fatalf("Mosaic not removed")
+	}
+
+	if m, err := env.ServiceFactory().MustMacroService().Get(macro.Id); err != nil || m != nil {
+		t.Fatalf("Macro not removed")
+	}
+
+	if c, err := env.ServiceFactory().MustCoverService().Get(cover.Id); err != nil || c != nil {
+		t.Fatalf("Cover not removed")
+	}
+}
+
+func TestProjectRmShared(t *testing.T) {
+	env, _, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	cover, macro := MacroAspect(env, "testdata/jumping_bunny.jpg", 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	projectService := env.ServiceFactory().MustProjectService()
+	for _, name := range []string{"one", "two"} {
+		err = projectService.Insert(&model.Project{Name: name, CoverId: cover.Id, MacroId: macro.Id})
+		if err != nil {
+			t.Fatalf("Error inserting project: %s\n", err.Error())
+		}
+	}
+
+	err = ProjectRm(env, "one")
+	if err != nil {
+		t.Fatalf("Error removing project: %s\n", err.Error())
+	}
+
+	if m, err := env.ServiceFactory().MustMacroService().Get(macro.Id); err != nil || m == nil {
+		t.Fatalf("Macro of another project was removed")
+	}
+
+	if c, err := env.ServiceFactory().MustCoverService().Get(cover.Id); err != nil || c == nil {
+		t.Fatalf("Cover of another project was removed")
+	}
+
+	err = ProjectRename(env, "two", "two")
+	if err != nil {
+		t.Fatalf("Error renaming project to its own name: %s\n", err.Error())
+	}
+
+	if ProjectRm(env, "one") == nil {
+		t.Fatalf("Expected removing a missing project to fail")
+	}
+}
+
+func TestFindOrCreateProjectOnExists(t *testing.T) {
+	env, _, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_project_on_exists")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for project test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	find := func(onExists string, params *projectParams) (*model.Project, error) {
+		return findOrCreateProject(env, "testdata/jumping_bunny.jpg", "bunny", onExists, params,
+			filepath.Join(dir, "c.png"), filepath.Join(dir, "m.jpg"), filepath.Join(dir, "o.jpg"))
+	}
+
+	params := &projectParams{Type: "aspect", Width: 200, Height: 200, FillType: "best"}
+	project, err := find("abort", params)
+	if err != nil {
+		t.Fatalf("Error creating project: %s\n", err.Error())
+	}
+	env.SetProjectId(project.Id)
+
+	cover, macro := MacroAspect(env, project.Path, 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	_, err = find("abort", params)
+	if err == nil {
+		t.Fatal("Expected existing project to abort")
+	}
+
+	_, err = find("", params)
+	if err == nil {
+		t.Fatal("Expected existing project to abort without an action")
+	}
+
+	existing, err := ExistingProject(env, "testdata/jumping_bunny.jpg", "bunny")
+	if err != nil || existing == nil || existing.Id != project.Id {
+		t.Fatalf("Expected existing project to be found: %+v\n", existing)
+	}
+
+	resumed, err := find("resume", params)
+	if err != nil {
+		t.Fatalf("Error resuming project: %s\n", err.Error())
+	}
+
+	if resumed.Id != project.Id || resumed.CoverId != cover.Id || resumed.MacroId != macro.Id {
+		t.Fatalf("Expected resumed project to keep its cover and macro: %+v\n", resumed)
+	}
+
+	created, err := find("new", params)
+	if err != nil {
+		t.Fatalf("Error creating new project: %s\n", err.Error())
+	}
+
+	if created.Id == project.Id || created.Name != "bunny-2" || created.CoverId != int64(0) {
+		t.Fatalf("Expected a new project named bunny-2: %+v\n", created)
+	}
+
+	// only the mosaic depends on the fill type
+	rebuilt, err := find("rebuild", &projectParams{Type: "aspect", Width: 200, Height: 200, FillType: "random"})
+	if err != nil {
+		t.Fatalf("Error rebuilding project: %s\n", err.Error())
+	}
+
+	if rebuilt.Id != project.Id || rebuilt.CoverId != cover.Id || rebuilt.MacroId != macro.Id {
+		t.Fatalf("Expected rebuilt project to keep its cover and macro: %+v\n", rebuilt)
+	}
+
+	resumed, err = find("resume", &projectParams{Type: "aspect", Width: 300, Height: 200, FillType: "random"})
+	if err != nil {
+		t.Fatalf("Error resuming project: %s\n", err.Error())
+	}
+
+	if resumed.CoverId != int64(0) || resumed.MacroId != int64(0) {
+		t.Fatalf("Expected changed width to reset the cover and macro: %+v\n", resumed)
+	}
+
+	if c, err := env.ServiceFactory().MustCoverService().Get(cover.Id); err != nil || c != nil {
+		t.Fatalf("Cover of changed project not removed")
+	}
+
+	stored, err := getProjectParams(resumed)
+	if err != nil || stored == nil || stored.Width != 300 || stored.Engine != env.Engine() {
+		t.Fatalf("Expected project parameters to be stored: %+v\n", stored)
+	}
+}
+
+func TestProjectResumeParams(t *testing.T) {
+	env, out, err := setupControllerTest()
+	if err != nil {
+		t.Fatalf("Error getting test environment: %s\n", err.Error())
+	}
+	defer env.Close()
+
+	dir, err := ioutil.TempDir("", "gosaic_test_project_resume")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for project test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	err = Index(env, []string{"testdata", "../service/testdata"})
+	if err != nil {
+		t.Fatalf("Error indexing images: %s\n", err.Error())
+	}
+
+	env.SetEngine("memory")
+	project, err := findOrCreateProject(env, "testdata/jumping_bunny.jpg", "bunny", "abort",
+		&projectParams{Type: "aspect", Width: 200, Height: 200, FillType: "best", KeepTop: 1, Threashold: -1.0},
+		filepath.Join(dir, "c.png"), filepath.Join(dir, "m.jpg"), filepath.Join(dir, "o.jpg"))
+	if err != nil {
+		t.Fatalf("Error creating project: %s\n", err.Error())
+	}
+	env.SetProjectId(project.Id)
+
+	cover, macro := MacroAspect(env, project.Path, 200, 200, 1, 1, 2, 0, 0, "grid", "", "")
+	if cover == nil || macro == nil {
+		t.Fatal("Failed to create cover or macro")
+	}
+
+	// the stored engine is used, rather than that of env
+	env.SetEngine("db")
+	mosaic := ProjectResume(env, "bunny", ProjectResumeOptions{}, MosaicDrawOptions{Grout: -1})
+	if mosaic == nil {
+		t.Fatalf("Failed to resume project:\n%s\n", out.String())
+	}
+
+	if env.Engine() != "memory" {
+		t.Fatalf("Expected project to resume with the memory engine, got %s\n", env.Engine())
+	}
+
+	num, err := env.ServiceFactory().MustPartialComparisonService().Count()
+	if err != nil {
+		t.Fatalf("Error counting comparisons: %s\n", err.Error())
+	}
+
+	if num != int64(0) {
+		t.Fatalf("Expected no comparisons to be stored, got %d\n", num)
+	}
+
+	fillType := "random"
+	mosaic = ProjectResume(env, "bunny", ProjectResumeOptions{FillType: &fillType}, MosaicDrawOptions{Grout: -1})
+	if mosaic == nil {
+		t.Fatalf("Failed to resume project:\n%s\n", out.String())
+	}
+
+	project, err = findProject(env, "bunny")
+	if err != nil {
+		t.Fatalf("Error finding project: %s\n", err.Error())
+	}
+
+	stored, err := getProjectParams(project)
+	if err != nil || stored == nil {
+		t.Fatalf("Error reading project parameters: %v\n", err)
+	}
+
+	if stored.FillType != "random" || stored.KeepTop != 1 || stored.Engine != "memory" || stored.Width != 200 {
+		t.Fatalf("Expected overridden fill type to be stored with the other parameters: %+v\n", stored)
+	}
+
+	if project.MosaicId != mosaic.Id || !project.IsComplete {
+		t.Fatalf("Expected changed fill type to build a new mosaic: %+v\n", project)
+	}
+}
+
+func TestProjectParamsChanges(t *testing.T) {
+	prev := &projectParams{Type: "aspect", Width: 200, Grout: 2, FillType: "best", Aspects: []string{"1x1"}}
+
+	for _, tc := range []struct {
+		params *projectParams
+		names  []string
+		stage  int
+	}{
+		{&projectParams{Type: "aspect", Width: 200, Grout: 2, FillType: "best", Aspects: []string{"1x1"}}, []string{}, projectStageNone},
+		{&projectParams{Type: "aspect", Width: 200, Grout: 2, FillType: "random", Aspects: []string{"1x1"}}, []string{"fill-type"}, projectStageMosaic},
+		{&projectParams{Type: "aspect", Width: 200, Grout: 4, FillType: "random", Aspects: []string{"1x1"}}, []string{"grout", "fill-type"}, projectStageMacro},
+		{&projectParams{Type: "aspect", Width: 200, Grout: 2, FillType: "best", Aspects: []string{"1x1", "2x3"}}, []string{"aspects"}, projectStageCover},
+		{&projectParams{Type: "quad", Width: 100, Grout: 2, FillType: "best", Aspects: []string{"1x1"}}, []string{"type", "width"}, projectStageCover},
+		{&projectParams{Type: "aspect", Width: 200, Grout: 2, FillType: "best", Aspects: []string{"1x1"}, KeepTop: 5}, []string{"keep-top"}, projectStageMosaic},
+		{&projectParams{Type: "aspect", Width: 200, Grout: 2, FillType: "best", Aspects: []string{"1x1"}, Engine: "db"}, []string{}, projectStageNone},
+		{&projectParams{Type: "aspect", Width: 200, Grout: 2, FillType: "best", Aspects: []string{"1x1"}, Engine: "memory"}, []string{"engine"}, projectStageMosaic},
+		{&projectParams{Type: "aspect", Width: 200, Grout: 4, FillType: "best", Aspects: []string{"1x1"}, KeepTop: 1, Engine: "memory"}, []string{"grout", "keep-top", "engine"}, projectStageMacro},
+	} {
+		changes := tc.params.changes(prev)
+		names := make([]string, len(changes))
+		for i, c := range changes {
+			names[i] = c.Name
+		}
+
+		if strings.Join(names, ",") != strings.Join(tc.names, ",") {
+			t.Errorf("Expected changes %v, got %v\n", tc.names, names)
+		}
+
+		if stage := invalidatedProjectStage(changes); stage != tc.stage {
+			t.Errorf("Expected %v to invalidate stage %d, got %d\n", tc.names, tc.stage, stage)
+		}
+	}
+}
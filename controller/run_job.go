@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/atongen/gosaic/environment"
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+	"gopkg.in/yaml.v2"
+)
+
+// RunJobTypes are the mosaic sub-commands a job can run
+var RunJobTypes = []string{"aspect", "mixed", "quad", "split"}
+
+// RunJob is a mosaic described in a job file. Its keys are the
+// flags of the mosaic sub-command named by its type.
+type RunJob struct {
+	Type     string `yaml:"type"`
+	Path     string `yaml:"path"`
+	Name     string `yaml:"name"`
+	OnExists string `yaml:"on-exists"`
+	FillType string `yaml:"fill-type"`
+
+	Width     int    `yaml:"width"`
+	Height    int    `yaml:"height"`
+	PrintSize string `yaml:"print-size"`
+	Bleed     string `yaml:"bleed"`
+	Aspect    string `yaml:"aspect"`
+	Aspects   string `yaml:"aspects"`
+	Size      *int   `yaml:"size"`
+	Layout    string `yaml:"layout"`
+	Mode      string `yaml:"mode"`
+	MinDepth  int    `yaml:"min-depth"`
+	MaxDepth  int    `yaml:"max-depth"`
+	MinArea   int    `yaml:"min-area"`
+	MaxArea   int    `yaml:"max-area"`
+
+	MaxRepeats  int     `yaml:"max-repeats"`
+	KeepTop     int     `yaml:"keep-top"`
+	Threashold  float64 `yaml:"threashold"`
+	Cleanup     bool    `yaml:"cleanup"`
+	Destructive bool    `yaml:"destructive"`
+
+	Out      string `yaml:"out"`
+	CoverOut string `yaml:"cover-out"`
+	MacroOut string `yaml:"macro-out"`
+
+	Grout          int    `yaml:"grout"`
+	GroutColor     string `yaml:"grout-color"`
+	TileRadius     int    `yaml:"tile-radius"`
+	OuterBorder    int    `yaml:"outer-border"`
+	DeepZoom       string `yaml:"deepzoom"`
+	DeepZoomFormat string `yaml:"deepzoom-format"`
+	DeepZoomScale  int    `yaml:"deepzoom-scale"`
+	Metadata       string `yaml:"metadata"`
+	DPI            int    `yaml:"dpi"`
+	CropMarks      bool   `yaml:"crop-marks"`
+
+	// set by Prepare
+	aspects     []*model.Aspect
+	drawOpts    MosaicDrawOptions
+	coverWidth  int
+	coverHeight int
+}
+
+// NewRunJob returns a job with the defaults of the mosaic sub-command flags
+func NewRunJob() *RunJob {
+	return &RunJob{
+		Type:           "aspect",
+		FillType:       "random",
+		Aspects:        "2x3,3x2",
+		Layout:         "grid",
+		Mode:           "binary",
+		MinDepth:       -1,
+		MaxDepth:       -1,
+		MinArea:        -1,
+		MaxArea:        -1,
+		MaxRepeats:     -1,
+		Threashold:     -1.0,
+		GroutColor:     "#ffffff",
+		DeepZoomFormat: "dzi",
+		Metadata:       "safe",
+		DPI:            util.DefaultDPI,
+	}
+}
+
+// runJobFile is a job file, with a list of jobs,
+// and defaults shared by each of them
+type runJobFile struct {
+	Defaults yaml.MapSlice   `yaml:"defaults"`
+	Jobs     []yaml.MapSlice `yaml:"jobs"`
+}
+
+// LoadRunJobs reads the jobs in the job file at path. Relative paths
+// in the jobs are relative to the directory of the job file.
+func LoadRunJobs(path string) ([]*RunJob, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := runJobFile{}
+	err = yaml.Unmarshal(b, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(file.Jobs) == 0 {
+		return nil, errors.New("Job file has no jobs")
+	}
+
+	err = checkRunJobKeys(file.Defaults)
+	if err != nil {
+		return nil, fmt.Errorf("defaults: %s", err.Error())
+	}
+
+	dir := filepath.Dir(path)
+	jobs := make([]*RunJob, len(file.Jobs))
+	for i, keys := range file.Jobs {
+		err = checkRunJobKeys(keys)
+		if err != nil {
+			return nil, fmt.Errorf("job %d: %s", i+1, err.Error())
+		}
+
+		job := NewRunJob()
+		for _, m := range []yaml.MapSlice{file.Defaults, keys} {
+			b, err := yaml.Marshal(m)
+			if err != nil {
+				return nil, err
+			}
+
+			err = yaml.Unmarshal(b, job)
+			if err != nil {
+				return nil, fmt.Errorf("job %d: %s", i+1, err.Error())
+			}
+		}
+
+		for _, p := range []*string{&job.Path, &job.Out, &job.CoverOut, &job.MacroOut, &job.DeepZoom} {
+			if *p != "" && !filepath.IsAbs(*p) {
+				*p = filepath.Join(dir, *p)
+			}
+		}
+
+		jobs[i] = job
+	}
+
+	return jobs, nil
+}
+
+// checkRunJobKeys returns an error naming the first key of m
+// that is not a key of a job
+func checkRunJobKeys(m yaml.MapSlice) error {
+	t := reflect.TypeOf(RunJob{})
+	for _, item := range m {
+		key := fmt.Sprint(item.Key)
+		found := false
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).Tag.Get("yaml") == key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Unknown key: %s", key)
+		}
+	}
+	return nil
+}
+
+// Prepare validates the job as its mosaic sub-command validates its flags,
+// so that a job file with an invalid job can be rejected before any are run
+func (j *RunJob) Prepare() error {
+	if !util.SliceContainsString(RunJobTypes, j.Type) {
+		return fmt.Errorf("Invalid type: %s", j.Type)
+	}
+
+	if j.Path == "" {
+		return errors.New("Mosaic path is required")
+	}
+
+	if j.Width < 0 {
+		return errors.New("width must be greater than zero")
+	}
+
+	if j.Height < 0 {
+		return errors.New("height must be greater than zero")
+	}
+
+	if j.KeepTop < 0 {
+		return errors.New("keep-top cannot be negative")
+	}
+
+	if j.FillType != "best" && j.FillType != "random" {
+		return errors.New("Invalid fill-type")
+	}
+
+	if j.OnExists != "" && !util.SliceContainsString(ProjectOnExists, j.OnExists) {
+		return errors.New("Invalid on-exists")
+	}
+
+	if j.Size == nil {
+		size := 0
+		if j.Type == "quad" || j.Type == "split" {
+			size = -1
+		}
+		j.Size = &size
+	}
+
+	var err error
+	switch j.Type {
+	case "aspect":
+		if j.Aspect != "" {
+			j.aspects, err = ParseAspects(j.Aspect)
+			if err != nil {
+				return err
+			}
+			if len(j.aspects) != 1 {
+				return errors.New("aspect format must be CxR")
+			}
+		}
+
+		if !util.SliceContainsString(CoverAspectLayouts, j.Layout) {
+			return errors.New("Invalid layout")
+		}
+	case "mixed":
+		j.aspects, err = ParseAspects(j.Aspects)
+		if err != nil {
+			return err
+		}
+	case "split":
+		if !util.SliceContainsString(MacroSplitModes, j.Mode) {
+			return errors.New("Invalid mode")
+		}
+	}
+
+	j.drawOpts = MosaicDrawOptions{
+		Grout:       j.Grout,
+		TileRadius:  j.TileRadius,
+		OuterBorder: j.OuterBorder,
+
+		DeepZoom:       j.DeepZoom,
+		DeepZoomFormat: j.DeepZoomFormat,
+		DeepZoomScale:  j.DeepZoomScale,
+
+		Metadata:  j.Metadata,
+		DPI:       j.DPI,
+		CropMarks: j.CropMarks,
+	}
+
+	err = j.drawOpts.Validate()
+	if err != nil {
+		return err
+	}
+
+	if j.drawOpts.Grout < 0 {
+		return errors.New("grout cannot be negative")
+	}
+
+	j.drawOpts.GroutColor, err = util.ParseHexColor(j.GroutColor)
+	if err != nil {
+		return err
+	}
+
+	j.coverWidth, j.coverHeight, err = PrintCoverSize(j.Width, j.Height, j.PrintSize, j.Bleed, &j.drawOpts)
+	return err
+}
+
+// Run creates the mosaic of a prepared job, and returns an error if it fails.
+// The reason it failed has already been printed by the
+func (j *RunJob) Run(env environment.Environment, onExists string) error {
+	if j.OnExists != "" {
+		onExists = j.OnExists
+	}
+
+	var mosaic *model.Mosaic
+	switch j.Type {
+	case "aspect":
+		aw, ah := 0, 0
+		if len(j.aspects) == 1 {
+			aw, ah = j.aspects[0].Columns, j.aspects[0].Rows
+		}
+		mosaic = MosaicAspect(env, j.Path, j.Name, onExists, j.FillType,
+			j.coverWidth, j.coverHeight, aw, ah, *j.Size, j.MaxRepeats, j.KeepTop, j.Threashold, j.Layout,
+			j.CoverOut, j.MacroOut, j.Out, j.drawOpts, j.Cleanup, j.Destructive)
+	case "mixed":
+		mosaic = MosaicMixed(env, j.Path, j.Name, onExists, j.FillType,
+			j.coverWidth, j.coverHeight, j.aspects, *j.Size, j.MaxRepeats, j.KeepTop, j.Threashold,
+			j.CoverOut, j.MacroOut, j.Out, j.drawOpts, j.Cleanup, j.Destructive)
+	case "quad", "split":
+		mode := j.Mode
+		if j.Type == "quad" {
+			mode = "quad"
+		}
+		mosaic = MosaicSplit(env, j.Path, j.Name, onExists, j.FillType, mode,
+			j.coverWidth, j.coverHeight, *j.Size, j.MinDepth, j.MaxDepth, j.MinArea, j.MaxArea, j.MaxRepeats, j.KeepTop, j.Threashold,
+			j.CoverOut, j.MacroOut, j.Out, j.drawOpts, j.Cleanup, j.Destructive)
+	}
+
+	if mosaic == nil {
+		return errors.New("Failed to create mosaic")
+	}
+
+	return nil
+}
+
+// Describe names the job by its name, or the base name of its path
+func (j *RunJob) Describe() string {
+	if j.Name != "" {
+		return j.Name
+	}
+	return strings.TrimSuffix(filepath.Base(j.Path), filepath.Ext(j.Path))
+}
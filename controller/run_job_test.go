@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeRunJobFile writes data to a job file in dir, and returns its path
+func writeRunJobFile(t *testing.T, dir, data string) string {
+	path := filepath.Join(dir, "jobs.yml")
+	err := ioutil.WriteFile(path, []byte(data), 0644)
+	if err != nil {
+		t.Fatalf("Error writing job file: %s\n", err.Error())
+	}
+	return path
+}
+
+func TestLoadRunJobs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosaic_test_run_job")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for run job test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeRunJobFile(t, dir, `
+defaults:
+  fill-type: best
+  width: 400
+  grout: 2
+jobs:
+  - path: a.jpg
+  - type: mixed
+    path: /tmp/b.jpg
+    name: bee
+    width: 600
+    out: out/b-mosaic.jpg
+    cover-out: /tmp/b-cover.png
+    macro-out: b-macro.jpg
+    deepzoom: dz
+`)
+
+	jobs, err := LoadRunJobs(path)
+	if err != nil {
+		t.Fatalf("Error loading run jobs: %s\n", err.Error())
+	}
+
+	if len(jobs) != 2 {
+		t.Fatalf("Expected 2 jobs, got %d\n", len(jobs))
+	}
+
+	for i, tt := range []struct {
+		got, want string
+	}{
+		{jobs[0].Type, "aspect"},
+		{jobs[0].FillType, "best"},
+		{jobs[0].Path, filepath.Join(dir, "a.jpg")},
+		{jobs[0].Out, ""},
+		{jobs[0].Aspects, "2x3,3x2"},
+		{jobs[0].Describe(), "a"},
+		{jobs[1].Type, "mixed"},
+		{jobs[1].FillType, "best"},
+		{jobs[1].Path, "/tmp/b.jpg"},
+		{jobs[1].Out, filepath.Join(dir, "out", "b-mosaic.jpg")},
+		{jobs[1].CoverOut, "/tmp/b-cover.png"},
+		{jobs[1].MacroOut, filepath.Join(dir, "b-macro.jpg")},
+		{jobs[1].DeepZoom, filepath.Join(dir, "dz")},
+		{jobs[1].Describe(), "bee"},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%d: expected %q, got %q\n", i, tt.want, tt.got)
+		}
+	}
+
+	for i, tt := range []struct {
+		got, want int
+	}{
+		{jobs[0].Width, 400},
+		{jobs[0].Grout, 2},
+		{jobs[0].MaxRepeats, -1},
+		{jobs[1].Width, 600},
+		{jobs[1].Grout, 2},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%d: expected %d, got %d\n", i, tt.want, tt.got)
+		}
+	}
+
+	if jobs[0].Size != nil || jobs[1].Size != nil {
+		t.Error("Expected size to be unset before prepare")
+	}
+}
+
+func TestLoadRunJobsInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosaic_test_run_job")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for run job test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range []struct {
+		data string
+		err  string
+	}{
+		{"defaults:\n  width: 10\n", "Job file has no jobs"},
+		{"jobs: []\n", "Job file has no jobs"},
+		{"defaults:\n  colour: red\njobs:\n  - path: a.jpg\n", "defaults: Unknown key: colour"},
+		{"jobs:\n  - path: a.jpg\n  - path: b.jpg\n    sizes: 3\n", "job 2: Unknown key: sizes"},
+		{"jobs:\n  - path: a.jpg\n    width: wide\n", "job 1:"},
+	} {
+		_, err := LoadRunJobs(writeRunJobFile(t, dir, tt.data))
+		if err == nil {
+			t.Errorf("LoadRunJobs(%q) => nil, want %s", tt.data, tt.err)
+		} else if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("LoadRunJobs(%q) => %s, want %s", tt.data, err.Error(), tt.err)
+		}
+	}
+
+	_, err = LoadRunJobs(filepath.Join(dir, "missing.yml"))
+	if err == nil {
+		t.Error("Expected error loading missing job file")
+	}
+}
+
+func TestRunJobPrepareSize(t *testing.T) {
+	three := 3
+	for _, tt := range []struct {
+		typ  string
+		size *int
+		want int
+	}{
+		{"aspect", nil, 0},
+		{"mixed", nil, 0},
+		{"quad", nil, -1},
+		{"split", nil, -1},
+		{"aspect", &three, 3},
+		{"quad", &three, 3},
+	} {
+		job := NewRunJob()
+		job.Type = tt.typ
+		job.Path = "a.jpg"
+		job.Size = tt.size
+
+		err := job.Prepare()
+		if err != nil {
+			t.Fatalf("Error preparing %s job: %s\n", tt.typ, err.Error())
+		}
+
+		if job.Size == nil {
+			t.Errorf("%s job size is nil, want %d", tt.typ, tt.want)
+		} else if *job.Size != tt.want {
+			t.Errorf("%s job size is %d, want %d", tt.typ, *job.Size, tt.want)
+		}
+	}
+}
+
+func TestRunJobPrepareInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		set func(*RunJob)
+		err string
+	}{
+		{func(j *RunJob) { j.Type = "hex" }, "Invalid type: hex"},
+		{func(j *RunJob) { j.Path = "" }, "Mosaic path is required"},
+		{func(j *RunJob) { j.Width = -1 }, "width must be greater than zero"},
+		{func(j *RunJob) { j.KeepTop = -1 }, "keep-top cannot be negative"},
+		{func(j *RunJob) { j.FillType = "worst" }, "Invalid fill-type"},
+		{func(j *RunJob) { j.OnExists = "maybe" }, "Invalid on-exists"},
+		{func(j *RunJob) { j.Layout = "diagonal" }, "Invalid layout"},
+		{func(j *RunJob) { j.Aspect = "2x3,3x2" }, "aspect format must be CxR"},
+		{func(j *RunJob) { j.Type = "mixed"; j.Aspects = "2x" }, ""},
+		{func(j *RunJob) { j.Type = "split"; j.Mode = "diagonal" }, "Invalid mode"},
+		{func(j *RunJob) { j.Grout = -1 }, "grout cannot be negative"},
+		{func(j *RunJob) { j.TileRadius = -1 }, "tile-radius cannot be negative"},
+		{func(j *RunJob) { j.GroutColor = "white" }, ""},
+		{func(j *RunJob) { j.PrintSize = "8x10in"; j.Width = 100 }, "print-size cannot be used with width or height"},
+		{func(j *RunJob) { j.CropMarks = true }, "crop-marks requires bleed"},
+	} {
+		job := NewRunJob()
+		job.Path = "a.jpg"
+		tt.set(job)
+
+		err := job.Prepare()
+		if err == nil {
+			t.Errorf("Prepare() => nil, want %q", tt.err)
+		} else if !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Prepare() => %s, want %q", err.Error(), tt.err)
+		}
+	}
+}
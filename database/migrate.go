@@ -1,6 +1,9 @@
 package database
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
 
 type MigrationFunc func(db *sql.DB) error
 type Migrations []MigrationFunc
@@ -20,6 +23,14 @@ var (
 		createMosaicPartialTable,
 		createQuadDistTable,
 		createProjectTable,
+		addMacroGrout,
+		addMacroBleed,
+		addProjectParams,
+		addMacroPartialPrunedThrough,
+		addMacroOriented,
+		addMacroGroutBleedIndex,
+		addGidxPartialAutoincrement,
+		addMosaicPartialDist,
 	}
 )
 
@@ -399,3 +410,121 @@ func createProjectTable(db *sql.DB) error {
 	_, err = db.Exec(sql)
 	return err
 }
+
+func addMacroGrout(db *sql.DB) error {
+	sql := "alter table macros add column grout integer not null default 0;"
+	_, err := db.Exec(sql)
+	return err
+}
+
+func addMacroBleed(db *sql.DB) error {
+	sql := "alter table macros add column bleed integer not null default 0;"
+	_, err := db.Exec(sql)
+	return err
+}
+
+func addProjectParams(db *sql.DB) error {
+	sql := "alter table projects add column params text not null default '';"
+	_, err := db.Exec(sql)
+	return err
+}
+
+func addMacroPartialPrunedThrough(db *sql.DB) error {
+	sql := "alter table macro_partials add column pruned_through integer not null default 0;"
+	_, err := db.Exec(sql)
+	return err
+}
+
+func addMacroOriented(db *sql.DB) error {
+	sql := "alter table macros add column oriented integer not null default 0;"
+	_, err := db.Exec(sql)
+	return err
+}
+
+func addMacroGroutBleedIndex(db *sql.DB) error {
+	sql := "drop index idx_macro_cover_md5sum;"
+	_, err := db.Exec(sql)
+	if err != nil {
+		return err
+	}
+
+	sql = "create unique index idx_macro_cover_md5sum_grout_bleed on macros (cover_id,md5sum,grout,bleed);"
+	_, err = db.Exec(sql)
+	return err
+}
+
+// addGidxPartialAutoincrement rebuilds gidx_partials so that the ids of
+// deleted rows are never reused. Index partials are compared in order of
+// id, and macro_partials.pruned_through relies on new ones getting
+// higher ids than any that have been compared.
+func addGidxPartialAutoincrement(db *sql.DB) error {
+	ctx := context.Background()
+
+	// pragmas apply to a single connection, and dropping gidx_partials
+	// with foreign keys on would cascade to the rows that reference it
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var foreignKeys int
+	err = conn.QueryRowContext(ctx, "PRAGMA foreign_keys;").Scan(&foreignKeys)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF;")
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, sql := range []string{`
+    create table gidx_partials_autoincrement (
+      id integer not null primary key autoincrement,
+      gidx_id integer not null,
+      aspect_id integer not null,
+			data blob not null,
+			FOREIGN KEY(gidx_id) REFERENCES gidx(id) ON DELETE CASCADE,
+			FOREIGN KEY(aspect_id) REFERENCES aspects(id) ON DELETE RESTRICT,
+			CHECK(data <> '')
+    );
+  `,
+		"insert into gidx_partials_autoincrement (id, gidx_id, aspect_id, data) select id, gidx_id, aspect_id, data from gidx_partials;",
+		"drop table gidx_partials;",
+		"alter table gidx_partials_autoincrement rename to gidx_partials;",
+		"create unique index idx_gidx_partials on gidx_partials (gidx_id,aspect_id);",
+		// ids up to the highest pruned_through may already have been deleted
+		"delete from sqlite_sequence where name = 'gidx_partials';",
+		`insert into sqlite_sequence (name, seq) select 'gidx_partials', max(
+			coalesce((select max(id) from gidx_partials), 0),
+			coalesce((select max(pruned_through) from macro_partials), 0));`,
+	} {
+		_, err = tx.ExecContext(ctx, sql)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	if foreignKeys != 0 {
+		_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = ON;")
+	}
+	return err
+}
+
+func addMosaicPartialDist(db *sql.DB) error {
+	sql := "alter table mosaic_partials add column dist real not null default -1;"
+	_, err := db.Exec(sql)
+	return err
+}
@@ -1,7 +1,11 @@
+//go:build cgo
+// +build cgo
+
 package database
 
 import (
 	"database/sql"
+	"reflect"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -40,3 +44,75 @@ func TestGetVersion(t *testing.T) {
 		t.Fatalf("Incorrect version number returned: %d\n", version)
 	}
 }
+
+func TestAddGidxPartialAutoincrement(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Could not get test db: %s\n", err.Error())
+	}
+	defer db.Close()
+
+	last := 0
+	for idx, migFun := range migrations {
+		if reflect.ValueOf(migFun).Pointer() == reflect.ValueOf(MigrationFunc(addGidxPartialAutoincrement)).Pointer() {
+			last = idx
+		}
+	}
+
+	for idx, migFun := range migrations[:last] {
+		err = migFun(db)
+		if err != nil {
+			t.Fatalf("Failed to run migration %d: %s\n", idx+1, err.Error())
+		}
+	}
+
+	err = setVersion(db, last)
+	if err != nil {
+		t.Fatalf("Failed to set version: %s\n", err.Error())
+	}
+
+	for _, s := range []string{
+		"insert into gidx_partials (id, gidx_id, aspect_id, data) values (1, 1, 1, 'a'), (2, 1, 2, 'b'), (3, 2, 1, 'c');",
+		"insert into macro_partials (id, macro_id, cover_partial_id, aspect_id, data, pruned_through) values (1, 1, 1, 1, 'd', 5);",
+	} {
+		_, err = db.Exec(s)
+		if err != nil {
+			t.Fatalf("Failed to insert rows: %s\n", err.Error())
+		}
+	}
+
+	_, err = Migrate(db)
+	if err != nil {
+		t.Fatalf("Failed to migrate db: %s\n", err.Error())
+	}
+
+	var count int
+	err = db.QueryRow("select count(*) from gidx_partials").Scan(&count)
+	if err != nil {
+		t.Fatalf("Failed to count gidx partials: %s\n", err.Error())
+	}
+
+	if count != 3 {
+		t.Fatalf("Expected 3 gidx partials after migration, got %d\n", count)
+	}
+
+	// ids are never reused, and start after the highest pruned_through
+	_, err = db.Exec("delete from gidx_partials where id = 3;")
+	if err != nil {
+		t.Fatalf("Failed to delete gidx partial: %s\n", err.Error())
+	}
+
+	res, err := db.Exec("insert into gidx_partials (gidx_id, aspect_id, data) values (3, 1, 'e');")
+	if err != nil {
+		t.Fatalf("Failed to insert gidx partial: %s\n", err.Error())
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatalf("Failed to get gidx partial id: %s\n", err.Error())
+	}
+
+	if id != 6 {
+		t.Fatalf("Expected new gidx partial id to be 6, got %d\n", id)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"syscall"
 
 	"github.com/atongen/gosaic/service"
+	"github.com/atongen/gosaic/util"
 )
 
 var (
@@ -26,6 +27,12 @@ type Environment interface {
 	ServiceFactory() service.ServiceFactory
 	ProjectId() int64
 	SetProjectId(id int64)
+	TileCache() *util.TileCache
+	SetTileCache(cache *util.TileCache)
+	Engine() string
+	SetEngine(engine string)
+	MemoryLimit() int64
+	SetMemoryLimit(limit int64)
 	Printf(format string, a ...interface{})
 	Println(a ...interface{})
 	Fatalf(format string, a ...interface{})
@@ -35,6 +42,9 @@ type Environment interface {
 type environment struct {
 	workers        int
 	projectId      int64
+	tileCache      *util.TileCache
+	engine         string
+	memoryLimit    int64
 	log            *log.Logger
 	cancel         bool
 	cancelCh       chan os.Signal
@@ -47,6 +57,7 @@ func NewEnvironment(dsn string, out io.Writer, workers int) (Environment, error)
 	// setup the environment logger
 	env.log = log.New(out, "GOSAIC: ", log.Ldate|log.Ltime)
 	env.cancel = false
+	env.engine = "db"
 
 	serviceFactory, err := service.NewServiceFactory(dsn)
 	if err != nil {
@@ -63,8 +74,18 @@ func GetProdEnv(dsn string, workers int) (Environment, error) {
 	return NewEnvironment(dsn, os.Stdout, workers)
 }
 
+// TestDsn is the dsn of the database of test environments. It is set with
+// the GOSAIC_TEST_DSN environment variable, and is an in-memory sqlite3
+// database by default, or a mem database when built without cgo.
+func TestDsn() string {
+	if dsn := os.Getenv("GOSAIC_TEST_DSN"); dsn != "" {
+		return dsn
+	}
+	return defaultTestDsn
+}
+
 func GetTestEnv(out io.Writer) (Environment, error) {
-	return NewEnvironment("sqlite3://:memory:", out, 2)
+	return NewEnvironment(TestDsn(), out, 2)
 }
 
 func (env *environment) Init() error {
@@ -109,6 +130,34 @@ func (env *environment) SetProjectId(projectId int64) {
 	env.projectId = projectId
 }
 
+func (env *environment) TileCache() *util.TileCache {
+	return env.tileCache
+}
+
+func (env *environment) SetTileCache(cache *util.TileCache) {
+	env.tileCache = cache
+}
+
+// Engine is the engine used to compare and fill mosaics,
+// either "db" or "memory"
+func (env *environment) Engine() string {
+	return env.engine
+}
+
+func (env *environment) SetEngine(engine string) {
+	env.engine = engine
+}
+
+// MemoryLimit is the number of bytes the memory engine may use,
+// 0 is unlimited
+func (env *environment) MemoryLimit() int64 {
+	return env.memoryLimit
+}
+
+func (env *environment) SetMemoryLimit(limit int64) {
+	env.memoryLimit = limit
+}
+
 func (env *environment) Fatalln(v ...interface{}) {
 	env.log.Fatalln(v...)
 }
@@ -0,0 +1,6 @@
+//go:build cgo
+// +build cgo
+
+package environment
+
+const defaultTestDsn = "sqlite3://:memory:"
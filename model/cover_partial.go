@@ -31,3 +31,35 @@ func (cp *CoverPartial) Height() int {
 func (cp *CoverPartial) Area() int {
 	return cp.Width() * cp.Height()
 }
+
+// Inset returns a copy of the cover partial shrunk by grout pixels,
+// split between the top-left and bottom-right edges, such that
+// neighboring partials are separated by a gap of grout pixels.
+// The result is never smaller than a single pixel.
+func (cp *CoverPartial) Inset(grout int) *CoverPartial {
+	inset := *cp
+	if grout <= 0 {
+		return &inset
+	}
+
+	lo := grout / 2
+	hi := grout - lo
+
+	if cp.Width() > grout {
+		inset.X1 += lo
+		inset.X2 -= hi
+	} else {
+		inset.X1 += cp.Width() / 2
+		inset.X2 = inset.X1 + 1
+	}
+
+	if cp.Height() > grout {
+		inset.Y1 += lo
+		inset.Y2 -= hi
+	} else {
+		inset.Y1 += cp.Height() / 2
+		inset.Y2 = inset.Y1 + 1
+	}
+
+	return &inset
+}
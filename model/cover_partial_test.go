@@ -0,0 +1,22 @@
+package model
+
+import "testing"
+
+func TestCoverPartialInset(t *testing.T) {
+	for _, tt := range []struct {
+		cp    CoverPartial
+		grout int
+		r     [4]int
+	}{
+		{CoverPartial{X1: 0, Y1: 0, X2: 10, Y2: 10}, 0, [4]int{0, 0, 10, 10}},
+		{CoverPartial{X1: 0, Y1: 0, X2: 10, Y2: 10}, 2, [4]int{1, 1, 9, 9}},
+		{CoverPartial{X1: 10, Y1: 20, X2: 30, Y2: 40}, 3, [4]int{11, 21, 28, 38}},
+		{CoverPartial{X1: 0, Y1: 0, X2: 4, Y2: 10}, 4, [4]int{2, 2, 3, 8}},
+	} {
+		r := tt.cp.Inset(tt.grout)
+		if r.X1 != tt.r[0] || r.Y1 != tt.r[1] || r.X2 != tt.r[2] || r.Y2 != tt.r[3] {
+			t.Errorf("%v.Inset(%d) => (%d, %d, %d, %d), want %v",
+				tt.cp, tt.grout, r.X1, r.Y1, r.X2, r.Y2, tt.r)
+		}
+	}
+}
@@ -23,6 +23,16 @@ func (gidx *Gidx) Within(threashold float64, aspect *Aspect) bool {
 	return math.Abs(gRatio-aRatio) <= threashold
 }
 
+// SameOrientation is true when gidx and aspect are both portrait or
+// both landscape. Square images and square aspects match either.
+func (gidx *Gidx) SameOrientation(aspect *Aspect) bool {
+	if gidx.Width == gidx.Height || aspect.Columns == aspect.Rows {
+		return true
+	}
+
+	return (gidx.Width < gidx.Height) == (aspect.Columns < aspect.Rows)
+}
+
 // implement Image interface
 
 func (g *Gidx) GetPath() string {
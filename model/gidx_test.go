@@ -55,3 +55,27 @@ func TestGidxWithin(t *testing.T) {
 		}
 	}
 }
+
+func TestGidxSameOrientation(t *testing.T) {
+	for _, tt := range []struct {
+		g *Gidx
+		a *Aspect
+		r bool
+	}{
+		{gidxWithinTest(500, 500), NewAspect(1, 1), true},
+		{gidxWithinTest(500, 500), NewAspect(2, 3), true},
+		{gidxWithinTest(500, 500), NewAspect(3, 2), true},
+		{gidxWithinTest(400, 600), NewAspect(1, 1), true},
+		{gidxWithinTest(400, 600), NewAspect(2, 3), true},
+		{gidxWithinTest(400, 600), NewAspect(3, 2), false},
+		{gidxWithinTest(600, 400), NewAspect(1, 1), true},
+		{gidxWithinTest(600, 400), NewAspect(2, 3), false},
+		{gidxWithinTest(600, 400), NewAspect(3, 2), true},
+	} {
+		r := tt.g.SameOrientation(tt.a)
+		if r != tt.r {
+			t.Errorf("(%dx%d) same orientation as (%dx%d) = %v, want %v",
+				tt.g.Width, tt.g.Height, tt.a.Columns, tt.a.Rows, r, tt.r)
+		}
+	}
+}
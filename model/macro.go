@@ -9,6 +9,11 @@ type Macro struct {
 	Width       int    `db:"width"`
 	Height      int    `db:"height"`
 	Orientation int    `db:"orientation"`
+	Grout       int    `db:"grout"`
+	Bleed       int    `db:"bleed"`
+	// Oriented macros are only compared with index
+	// images of the same orientation as each partial
+	Oriented bool `db:"oriented"`
 }
 
 // implement Image interface
@@ -6,7 +6,10 @@ type MacroPartial struct {
 	CoverPartialId int64  `db:"cover_partial_id"`
 	AspectId       int64  `db:"aspect_id"`
 	Data           []byte `db:"data"`
-	Pixels         []*Lab `db:"-"`
+	// PrunedThrough is the id of the last index partial compared before
+	// the comparisons of the partial were pruned to the closest
+	PrunedThrough int64  `db:"pruned_through"`
+	Pixels        []*Lab `db:"-"`
 }
 
 // implement Pixel interface
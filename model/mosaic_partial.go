@@ -5,4 +5,7 @@ type MosaicPartial struct {
 	MosaicId       int64 `db:"mosaic_id"`
 	MacroPartialId int64 `db:"macro_partial_id"`
 	GidxPartialId  int64 `db:"gidx_partial_id"`
+	// Dist is the distance between the macro partial and the index image
+	// when the mosaic partial was placed, or -1 if it was not known
+	Dist float64 `db:"dist"`
 }
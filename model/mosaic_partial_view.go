@@ -4,4 +4,7 @@ type MosaicPartialView struct {
 	MosaicPartialId int64
 	Gidx            *Gidx
 	CoverPartial    *CoverPartial
+	// Dist is the distance between the macro partial and the index image,
+	// or -1 if it was not stored and their comparison has been deleted.
+	Dist float64
 }
@@ -14,4 +14,5 @@ type Project struct {
 	MosaicId   int64     `db:"mosaic_id"`
 	IsComplete bool      `db:"is_complete"`
 	CreatedAt  time.Time `db:"created_at"`
+	Params     string    `db:"params"`
 }
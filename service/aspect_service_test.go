@@ -5,8 +5,6 @@ import (
 
 	"github.com/atongen/gosaic/model"
 	"github.com/atongen/gosaic/util"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 func setupAspectServiceTest() {
@@ -0,0 +1,455 @@
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/atongen/gosaic/service/mem"
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltDb is the backend of a store that keeps its rows in a bolt
+// database, with the transaction of the service method that has the
+// store locked. Reads begin a read-only transaction that lasts until
+// the store is unlocked, and each write is committed in its own
+// transaction.
+type boltDb struct {
+	db *bolt.DB
+	tx *bolt.Tx
+	// err is the first error of the reads and writes of the service
+	// method that has the store locked, which it returns when unlocked
+	err  error
+	rows map[string]*boltRows
+}
+
+// OpenStore returns a store that keeps its rows in the bolt
+// database at path, which is created if it does not exist.
+// Each table is a bucket of rows by id, with a bucket for each
+// of its unique indexes and indexes.
+func OpenStore(path string) (*mem.Store, error) {
+	b, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	return mem.NewBackendStore(b)
+}
+
+func open(path string) (*boltDb, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err == bolt.ErrTimeout {
+		return nil, fmt.Errorf("%s is in use by another process", path)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &boltDb{db: db, rows: make(map[string]*boltRows)}, nil
+}
+
+func (b *boltDb) Rows(t *mem.Table) mem.Rows {
+	r := &boltRows{b: b, t: t}
+	b.rows[t.Name()] = r
+	return r
+}
+
+// Init creates the buckets of tables. Unique indexes and indexes that
+// were added since the database was created are built from the rows of
+// their table, and buckets of those that were removed are deleted.
+func (b *boltDb) Init(tables []*mem.Table) error {
+	tx := b.current()
+	if tx == nil {
+		return b.err
+	}
+
+	buckets := make(map[string]bool)
+	for _, t := range tables {
+		for _, name := range tableBuckets(t) {
+			buckets[name] = true
+		}
+	}
+
+	err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		if !buckets[string(name)] {
+			buckets[string(name)] = false
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for name, keep := range buckets {
+		if !keep {
+			err = tx.DeleteBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, t := range tables {
+		_, err = tx.CreateBucketIfNotExists([]byte(t.Name()))
+		if err != nil {
+			return err
+		}
+
+		added := false
+		for _, name := range tableBuckets(t)[1:] {
+			if tx.Bucket([]byte(name)) != nil {
+				continue
+			}
+			_, err = tx.CreateBucket([]byte(name))
+			if err != nil {
+				return err
+			}
+			added = true
+		}
+
+		if added {
+			r := b.rows[t.Name()]
+			for _, id := range r.Ids() {
+				v, _ := r.Get(id)
+				r.Put(id, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *boltDb) Close() error {
+	return b.db.Close()
+}
+
+func (b *boltDb) Path() string {
+	return b.db.Path()
+}
+
+// current returns the transaction of the locked store, beginning
+// a read-only one if there is none. It returns nil, and keeps
+// the error, if the transaction cannot begin.
+func (b *boltDb) current() *bolt.Tx {
+	if b.tx == nil {
+		tx, err := b.db.Begin(false)
+		if err != nil {
+			b.fail(fmt.Errorf("Unable to read bolt database: %s", err.Error()))
+			return nil
+		}
+		b.tx = tx
+	}
+	return b.tx
+}
+
+// End ends the read-only transaction of the store, if any,
+// and returns the first error of its reads and writes
+func (b *boltDb) End() error {
+	if b.tx != nil {
+		b.tx.Rollback()
+		b.tx = nil
+	}
+	err := b.err
+	b.err = nil
+	return err
+}
+
+// Update calls fn in a writable transaction, which is committed when
+// fn and its writes succeed, and rolled back otherwise. Calls of Update
+// in fn use the same transaction.
+func (b *boltDb) Update(fn func() error) error {
+	if b.tx != nil && b.tx.Writable() {
+		return fn()
+	}
+
+	// rows read before the write may be missing
+	if b.err != nil {
+		return b.err
+	}
+
+	// a read-only transaction would keep the writable
+	// transaction from growing the database
+	b.End()
+
+	tx, err := b.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	b.tx = tx
+
+	err = fn()
+	if err == nil {
+		err = b.err
+	}
+	b.tx = nil
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// fail keeps the first error of the reads and writes of the locked store
+func (b *boltDb) fail(err error) {
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+}
+
+// boltRows keeps the rows of a table, and its indexes, in buckets of a
+// bolt database. Rows are keyed by id. Unique indexes map the values of
+// their columns to an id, and indexes are sets of keys of a value, the
+// value of their order column if any, and an id, so that the ids with a
+// value are a range of keys in order.
+type boltRows struct {
+	b *boltDb
+	t *mem.Table
+}
+
+// tableBuckets returns the names of the buckets of t
+func tableBuckets(t *mem.Table) []string {
+	names := []string{t.Name()}
+	for i := range t.Uniques() {
+		names = append(names, uniqueBucket(t, i))
+	}
+	for column := range t.Indexes() {
+		names = append(names, indexBucket(t, column))
+	}
+	return names
+}
+
+func uniqueBucket(t *mem.Table, i int) string {
+	return t.Name() + "/unique/" + strings.Join(t.Uniques()[i], ",")
+}
+
+func indexBucket(t *mem.Table, column string) string {
+	if order := t.Indexes()[column]; order != "" {
+		return t.Name() + "/index/" + column + "," + order
+	}
+	return t.Name() + "/index/" + column
+}
+
+// bucket returns the bucket with name, or nil, and keeps
+// the error, if the database cannot be read
+func (r *boltRows) bucket(name string) *bolt.Bucket {
+	tx := r.b.current()
+	if tx == nil {
+		return nil
+	}
+
+	b := tx.Bucket([]byte(name))
+	if b == nil {
+		r.b.fail(fmt.Errorf("Missing bolt bucket: %s", name))
+	}
+	return b
+}
+
+func (r *boltRows) Get(id int64) (reflect.Value, bool) {
+	rows := r.bucket(r.t.Name())
+	if rows == nil {
+		return reflect.Value{}, false
+	}
+
+	data := rows.Get(intKey(id))
+	if data == nil {
+		return reflect.Value{}, false
+	}
+
+	v, err := r.t.Decode(data)
+	if err != nil {
+		r.b.fail(fmt.Errorf("Invalid %s row %d: %s", r.t.Name(), id, err.Error()))
+		return reflect.Value{}, false
+	}
+	return v, true
+}
+
+func (r *boltRows) Ids() []int64 {
+	ids := make([]int64, 0)
+	rows := r.bucket(r.t.Name())
+	if rows == nil {
+		return ids
+	}
+
+	c := rows.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		ids = append(ids, keyInt(k))
+	}
+	return ids
+}
+
+func (r *boltRows) IdsAfter(id int64, limit int) []int64 {
+	ids := make([]int64, 0)
+	rows := r.bucket(r.t.Name())
+	if rows == nil {
+		return ids
+	}
+
+	c := rows.Cursor()
+	for k, _ := c.Seek(intKey(id + 1)); k != nil && len(ids) < limit; k, _ = c.Next() {
+		ids = append(ids, keyInt(k))
+	}
+	return ids
+}
+
+func (r *boltRows) Count() int64 {
+	rows := r.bucket(r.t.Name())
+	if rows == nil {
+		return int64(0)
+	}
+	return int64(rows.Stats().KeyN)
+}
+
+func (r *boltRows) LastId() int64 {
+	rows := r.bucket(r.t.Name())
+	if rows == nil {
+		return int64(0)
+	}
+	return int64(rows.Sequence())
+}
+
+// scan calls fn with the id of each key of the index of column
+// with value, in order
+func (r *boltRows) scan(column string, value int64, fn func(id int64)) {
+	index := r.bucket(indexBucket(r.t, column))
+	if index == nil {
+		return
+	}
+
+	prefix := intKey(value)
+	c := index.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		fn(keyInt(k[len(k)-8:]))
+	}
+}
+
+func (r *boltRows) IdsBy(column string, value int64) []int64 {
+	ids := make([]int64, 0)
+	r.scan(column, value, func(id int64) {
+		ids = append(ids, id)
+	})
+	return ids
+}
+
+func (r *boltRows) CountBy(column string, value int64) int64 {
+	var n int64
+	r.scan(column, value, func(int64) {
+		n++
+	})
+	return n
+}
+
+func (r *boltRows) GetBy(i int, key string) (int64, bool) {
+	unique := r.bucket(uniqueBucket(r.t, i))
+	if unique == nil {
+		return int64(0), false
+	}
+
+	data := unique.Get([]byte(key))
+	if data == nil {
+		return int64(0), false
+	}
+	return keyInt(data), true
+}
+
+// write calls fn with the bucket with name, and keeps its error.
+// Writes to a missing bucket are skipped, since the error
+// of the bucket rolls back the transaction.
+func (r *boltRows) write(name string, fn func(b *bolt.Bucket) error) {
+	if b := r.bucket(name); b != nil {
+		r.b.fail(fn(b))
+	}
+}
+
+func (r *boltRows) Put(id int64, row reflect.Value) {
+	if old, ok := r.Get(id); ok {
+		r.removeIndexes(id, old)
+	}
+
+	r.write(r.t.Name(), func(rows *bolt.Bucket) error {
+		err := rows.Put(intKey(id), r.t.Encode(row))
+		if err == nil && uint64(id) > rows.Sequence() {
+			err = rows.SetSequence(uint64(id))
+		}
+		return err
+	})
+
+	for i, columns := range r.t.Uniques() {
+		r.write(uniqueBucket(r.t, i), func(b *bolt.Bucket) error {
+			return b.Put([]byte(r.t.UniqueKey(row, columns)), intKey(id))
+		})
+	}
+	for column := range r.t.Indexes() {
+		r.write(indexBucket(r.t, column), func(b *bolt.Bucket) error {
+			return b.Put(r.indexKey(id, row, column), []byte{})
+		})
+	}
+}
+
+func (r *boltRows) Remove(id int64) {
+	old, ok := r.Get(id)
+	if !ok {
+		return
+	}
+
+	r.removeIndexes(id, old)
+	r.write(r.t.Name(), func(rows *bolt.Bucket) error {
+		return rows.Delete(intKey(id))
+	})
+}
+
+func (r *boltRows) removeIndexes(id int64, v reflect.Value) {
+	for i, columns := range r.t.Uniques() {
+		r.write(uniqueBucket(r.t, i), func(b *bolt.Bucket) error {
+			return b.Delete([]byte(r.t.UniqueKey(v, columns)))
+		})
+	}
+	for column := range r.t.Indexes() {
+		r.write(indexBucket(r.t, column), func(b *bolt.Bucket) error {
+			return b.Delete(r.indexKey(id, v, column))
+		})
+	}
+}
+
+// indexKey returns the key of row v with id in the index of column
+func (r *boltRows) indexKey(id int64, v reflect.Value, column string) []byte {
+	key := intKey(r.t.Int(v, column))
+	if order := r.t.Indexes()[column]; order != "" {
+		value, _ := r.t.Value(v, order)
+		switch val := value.(type) {
+		case int64:
+			key = append(key, intKey(val)...)
+		case float64:
+			key = append(key, floatKey(val)...)
+		}
+	}
+	return append(key, intKey(id)...)
+}
+
+// intKey returns the 8 byte key of i, with keys
+// in the same order as the integers
+func intKey(i int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(i)^(1<<63))
+	return key
+}
+
+func keyInt(key []byte) int64 {
+	return int64(binary.BigEndian.Uint64(key) ^ (1 << 63))
+}
+
+// floatKey returns the 8 byte key of f, with keys
+// in the same order as the floats
+func floatKey(f float64) []byte {
+	bits := math.Float64bits(f)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, bits)
+	return key
+}
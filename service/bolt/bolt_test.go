@@ -0,0 +1,134 @@
+package bolt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/service/mem"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTestStore opens a store in the bolt database at path,
+// and returns it with its backend
+func openTestStore(t *testing.T, path string) (*mem.Store, *boltDb) {
+	b, err := open(path)
+	if err != nil {
+		t.Fatalf("Error opening bolt database: %s\n", err.Error())
+	}
+
+	s, err := mem.NewBackendStore(b)
+	if err != nil {
+		t.Fatalf("Error opening bolt store: %s\n", err.Error())
+	}
+
+	return s, b
+}
+
+func TestOpenStoreIndexes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosaic_bolt_test")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gosaic.db")
+
+	s, b := openTestStore(t, path)
+
+	aspect, err := mem.NewAspectService(s).Create(3, 2)
+	if err != nil {
+		t.Fatalf("Error inserting aspect: %s\n", err.Error())
+	}
+
+	// a database from before the unique index of aspects
+	// was added, with a unique index that was since removed
+	unique := "aspects/unique/columns,rows"
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte(unique))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucket([]byte("aspects/unique/columns"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Error changing buckets: %s\n", err.Error())
+	}
+	s.Close()
+
+	s, b = openTestStore(t, path)
+	defer s.Close()
+
+	found, err := mem.NewAspectService(s).Find(3, 2)
+	if err != nil {
+		t.Fatalf("Error reading unique index: %s\n", err.Error())
+	}
+	if found == nil || found.Id != aspect.Id {
+		t.Fatalf("Expected unique index to find aspect %d, got %+v\n", aspect.Id, found)
+	}
+
+	err = b.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("aspects/unique/columns")) != nil {
+			t.Fatal("Expected bucket of removed unique index to be deleted")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Error reading buckets: %s\n", err.Error())
+	}
+}
+
+func TestStoreReadErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosaic_bolt_test")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	s, b := openTestStore(t, filepath.Join(dir, "gosaic.db"))
+	defer s.Close()
+
+	aspectService := mem.NewAspectService(s)
+	aspect := model.Aspect{Columns: 3, Rows: 2}
+	err = aspectService.Insert(&aspect)
+	if err != nil {
+		t.Fatalf("Error inserting aspect: %s\n", err.Error())
+	}
+
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte("aspects")).Put(intKey(aspect.Id), []byte{0x80})
+	})
+	if err != nil {
+		t.Fatalf("Error corrupting aspect: %s\n", err.Error())
+	}
+
+	_, err = aspectService.Get(aspect.Id)
+	if err == nil || !strings.Contains(err.Error(), "Invalid aspects row") {
+		t.Fatalf("Expected invalid row error getting aspect, got %v\n", err)
+	}
+
+	// the error of a read is not kept by the next service method
+	num, err := aspectService.Count()
+	if err != nil || num != int64(1) {
+		t.Fatalf("Expected 1 aspect, got %d, %v\n", num, err)
+	}
+
+	err = b.db.Close()
+	if err != nil {
+		t.Fatalf("Error closing bolt database: %s\n", err.Error())
+	}
+
+	_, err = aspectService.Count()
+	if err == nil || !strings.Contains(err.Error(), "Unable to read bolt database") {
+		t.Fatalf("Expected read error counting aspects of closed database, got %v\n", err)
+	}
+
+	err = aspectService.Insert(&model.Aspect{Columns: 1, Rows: 1})
+	if err == nil {
+		t.Fatal("Expected error inserting aspect into closed database")
+	}
+}
@@ -0,0 +1,11 @@
+package service
+
+// ExportService reads and writes the rows of every table with their ids,
+// to copy a database to another one
+type ExportService interface {
+	Service
+	Tables() []string
+	Count(string) (int64, error)
+	Export(string, int, func([]interface{}) error) error
+	Import(string, []interface{}) (int64, error)
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+)
+
+func TestExportServiceCopy(t *testing.T) {
+	setupMosaicPartialServiceTest()
+	exportService := serviceFactory.MustExportService()
+	defer exportService.Close()
+
+	mosaicPartial = model.MosaicPartial{
+		MosaicId:       mosaic.Id,
+		MacroPartialId: macroPartial.Id,
+		GidxPartialId:  gidxPartial.Id,
+	}
+	err := serviceFactory.MustMosaicPartialService().Insert(&mosaicPartial)
+	if err != nil {
+		t.Fatalf("Error inserting mosaic partial: %s\n", err.Error())
+	}
+
+	_, err = serviceFactory.MustPartialComparisonService().Create(&macroPartial, &gidxPartial)
+	if err != nil {
+		t.Fatalf("Error creating partial comparison: %s\n", err.Error())
+	}
+
+	err = serviceFactory.MustProjectService().Insert(&model.Project{Name: "test", MacroId: macro.Id})
+	if err != nil {
+		t.Fatalf("Error inserting project: %s\n", err.Error())
+	}
+
+	f, err := newTestServiceFactory()
+	if err != nil {
+		t.Fatalf("Error getting service factory: %s\n", err.Error())
+	}
+	defer f.Close()
+	dst := f.MustExportService()
+
+	for _, table := range exportService.Tables() {
+		err = exportService.Export(table, 2, func(rows []interface{}) error {
+			if len(rows) > 2 {
+				t.Fatalf("Expected at most 2 %s rows, got %d\n", table, len(rows))
+			}
+			num, err := dst.Import(table, rows)
+			if err == nil && num != int64(len(rows)) {
+				t.Fatalf("Expected %d %s rows imported, got %d\n", len(rows), table, num)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Error copying %s: %s\n", table, err.Error())
+		}
+
+		expected, err := exportService.Count(table)
+		if err != nil {
+			t.Fatalf("Error counting %s: %s\n", table, err.Error())
+		}
+
+		num, err := dst.Count(table)
+		if err != nil {
+			t.Fatalf("Error counting copied %s: %s\n", table, err.Error())
+		}
+
+		if num != expected {
+			t.Fatalf("Expected %d copied %s, got %d\n", expected, table, num)
+		}
+	}
+
+	g, err := f.MustGidxService().Get(gidx.Id)
+	if err != nil {
+		t.Fatalf("Error getting copied gidx: %s\n", err.Error())
+	} else if g == nil || g.Md5sum != gidx.Md5sum || g.Path != gidx.Path {
+		t.Fatalf("Expected copied gidx %+v, got %+v\n", gidx, g)
+	}
+
+	mp, err := f.MustMosaicPartialService().Get(mosaicPartial.Id)
+	if err != nil {
+		t.Fatalf("Error getting copied mosaic partial: %s\n", err.Error())
+	} else if mp == nil || *mp != mosaicPartial {
+		t.Fatalf("Expected copied mosaic partial %+v, got %+v\n", mosaicPartial, mp)
+	}
+
+	// rows keep their ids, which are already in use
+	_, err = dst.Import("aspects", []interface{}{&aspect})
+	if err == nil {
+		t.Fatal("Expected importing an existing id to fail")
+	}
+
+	_, err = dst.Count("versions")
+	if err == nil {
+		t.Fatal("Expected versions not to be an export table")
+	}
+}
@@ -0,0 +1,12 @@
+package service
+
+type GcService interface {
+	Service
+	Tables() []string
+	CountOrphans(string) (int64, error)
+	DeleteOrphans(string, int) (int64, error)
+	TableSize(string) (int64, int64, error)
+	FileSize() (int64, error)
+	Vacuum() error
+	Analyze() error
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/atongen/gosaic/model"
+)
+
+func TestGcServiceOrphans(t *testing.T) {
+	setupMacroServiceTest()
+	gcService := serviceFactory.MustGcService()
+	defer gcService.Close()
+
+	macro = model.Macro{
+		AspectId:    aspect.Id,
+		CoverId:     cover.Id,
+		Path:        "/path/to/my/macro_image.jpg",
+		Md5sum:      "68b329da9893e34099c7d8ad5cb9c940",
+		Width:       1,
+		Height:      1,
+		Orientation: 1,
+	}
+	err := serviceFactory.MustMacroService().Insert(&macro)
+	if err != nil {
+		t.Fatalf("Error inserting macro: %s\n", err.Error())
+	}
+
+	unused := model.Aspect{Columns: 2, Rows: 3}
+	err = serviceFactory.MustAspectService().Insert(&unused)
+	if err != nil {
+		t.Fatalf("Error inserting aspect: %s\n", err.Error())
+	}
+
+	for table, expected := range map[string]int64{"macros": 1, "covers": 1, "aspects": 1} {
+		num, err := gcService.CountOrphans(table)
+		if err != nil {
+			t.Fatalf("Error counting orphaned %s: %s\n", table, err.Error())
+		}
+		if num != expected {
+			t.Fatalf("Expected %d orphaned %s, got %d\n", expected, table, num)
+		}
+	}
+
+	err = serviceFactory.MustProjectService().Insert(&model.Project{Name: "test", MacroId: macro.Id})
+	if err != nil {
+		t.Fatalf("Error inserting project: %s\n", err.Error())
+	}
+
+	for _, table := range []string{"macros", "covers"} {
+		num, err := gcService.CountOrphans(table)
+		if err != nil {
+			t.Fatalf("Error counting orphaned %s: %s\n", table, err.Error())
+		}
+		if num != int64(0) {
+			t.Fatalf("Expected %s of a project not to be orphaned, got %d\n", table, num)
+		}
+	}
+
+	for _, table := range gcService.Tables() {
+		_, err := gcService.DeleteOrphans(table, 1)
+		if err != nil {
+			t.Fatalf("Error deleting orphaned %s: %s\n", table, err.Error())
+		}
+	}
+
+	if a, err := serviceFactory.MustAspectService().Get(unused.Id); err != nil || a != nil {
+		t.Fatalf("Unused aspect not deleted")
+	}
+
+	if m, err := serviceFactory.MustMacroService().Get(macro.Id); err != nil || m == nil {
+		t.Fatalf("Macro of a project deleted")
+	}
+
+	if _, err := gcService.CountOrphans("projects"); err == nil {
+		t.Fatalf("Expected projects not to be a gc table")
+	}
+}
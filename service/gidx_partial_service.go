@@ -16,6 +16,7 @@ type GidxPartialService interface {
 	Count() (int64, error)
 	CountBy(string, ...interface{}) (int64, error)
 	CountForMacro(*model.Macro) (int64, error)
+	FindAll(string, int, int, string, ...interface{}) ([]*model.GidxPartial, error)
 	Find(*model.Gidx, *model.Aspect) (*model.GidxPartial, error)
 	Create(*model.Gidx, *model.Aspect) (*model.GidxPartial, error)
 	FindOrCreate(*model.Gidx, *model.Aspect) (*model.GidxPartial, error)
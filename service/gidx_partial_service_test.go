@@ -4,8 +4,6 @@ import (
 	"testing"
 
 	"github.com/atongen/gosaic/model"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 func setupGidxPartialServiceTest() {
@@ -436,6 +434,47 @@ func TestGidxPartialServiceCountForMacro(t *testing.T) {
 	}
 }
 
+func TestGidxPartialServiceFindAll(t *testing.T) {
+	setupGidxPartialServiceTest()
+	gidxPartialService := serviceFactory.MustGidxPartialService()
+	defer gidxPartialService.Close()
+
+	mp := model.GidxPartial{
+		GidxId:   gidx.Id,
+		AspectId: aspect.Id,
+		Pixels: []*model.Lab{
+			&model.Lab{
+				L:     0.4,
+				A:     0.5,
+				B:     0.6,
+				Alpha: 0.0,
+			},
+		},
+	}
+
+	err := gidxPartialService.Insert(&mp)
+	if err != nil {
+		t.Fatalf("Error inserting gidx partial: %s\n", err.Error())
+	}
+
+	gps, err := gidxPartialService.FindAll("id DESC", 1000, 0, "aspect_id = ?", aspect.Id)
+	if err != nil {
+		t.Fatalf("Error finding all gidx partials: %s\n", err.Error())
+	}
+
+	if len(gps) != 1 {
+		t.Fatal("Inserted gidx partial not found by FindAll")
+	}
+
+	if gps[0].Id != mp.Id || gps[0].GidxId != mp.GidxId {
+		t.Fatal("Gidx partial found by FindAll does not match")
+	}
+
+	if len(gps[0].Pixels) != 1 || gps[0].Pixels[0].L != 0.4 {
+		t.Fatal("Gidx partial pixel data is not correct")
+	}
+}
+
 func TestGidxPartialServiceFindOrCreate(t *testing.T) {
 	setupGidxPartialServiceTest()
 	gidxPartialService := serviceFactory.MustGidxPartialService()
@@ -4,8 +4,6 @@ import (
 	"testing"
 
 	"github.com/atongen/gosaic/model"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 func setupMacroPartialServiceTest() {
@@ -0,0 +1,119 @@
+package mem
+
+import (
+	"github.com/atongen/gosaic/model"
+)
+
+type aspectServiceMem struct {
+	store *Store
+}
+
+func NewAspectService(store *Store) *aspectServiceMem {
+	return &aspectServiceMem{store: store}
+}
+
+func (s *aspectServiceMem) Register() error {
+	return nil
+}
+
+func (s *aspectServiceMem) Close() error {
+	return nil
+}
+
+func (s *aspectServiceMem) Insert(aspect *model.Aspect) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insert("aspects", aspect)
+}
+
+func (s *aspectServiceMem) Get(id int64) (_ *model.Aspect, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("aspects", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v.Interface().(*model.Aspect), nil
+}
+
+func (s *aspectServiceMem) Count() (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.tables["aspects"].count(), nil
+}
+
+func (s *aspectServiceMem) Find(width int, height int) (_ *model.Aspect, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.doFind(width, height)
+}
+
+func (s *aspectServiceMem) doFind(width int, height int) (*model.Aspect, error) {
+	aspect := model.NewAspect(width, height)
+
+	t := s.store.tables["aspects"]
+	id, ok := t.getBy(0, aspect.Columns, aspect.Rows)
+	if !ok {
+		return nil, nil
+	}
+
+	v, _ := t.get(id)
+	return t.copyRow(v).Interface().(*model.Aspect), nil
+}
+
+func (s *aspectServiceMem) Create(width int, height int) (_ *model.Aspect, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.doCreate(width, height)
+}
+
+func (s *aspectServiceMem) doCreate(width int, height int) (*model.Aspect, error) {
+	aspect := model.NewAspect(width, height)
+
+	err := s.store.insert("aspects", aspect)
+	if err != nil {
+		return nil, err
+	}
+
+	return aspect, nil
+}
+
+func (s *aspectServiceMem) FindOrCreate(width int, height int) (_ *model.Aspect, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	aspect, err := s.doFind(width, height)
+	if err != nil {
+		return nil, err
+	} else if aspect != nil {
+		return aspect, nil
+	}
+
+	return s.doCreate(width, height)
+}
+
+func (s *aspectServiceMem) FindIn(ids []int64) (_ []*model.Aspect, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	t := s.store.tables["aspects"]
+	found := make(map[int64]bool)
+	for _, id := range ids {
+		if _, ok := t.get(id); ok {
+			found[id] = true
+		}
+	}
+
+	aspects := make([]*model.Aspect, 0)
+	for _, id := range sortedIds(found) {
+		v, _ := t.get(id)
+		aspects = append(aspects, t.copyRow(v).Interface().(*model.Aspect))
+	}
+
+	return aspects, nil
+}
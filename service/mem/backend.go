@@ -0,0 +1,102 @@
+package mem
+
+import (
+	"reflect"
+)
+
+// Backend keeps the rows of the tables of a store outside of memory,
+// such as in a bolt database. Its rows are read and written by the
+// service method that has the store locked.
+type Backend interface {
+	// Rows returns the rows of table t
+	Rows(t *Table) Rows
+	// Init prepares the backend for tables, whose rows it returned,
+	// before the store is used. It is called by Update.
+	Init(tables []*Table) error
+	// Update calls fn, which changes rows, in a writable transaction,
+	// which is committed when fn and its writes succeed
+	Update(fn func() error) error
+	// End ends the reads of the service method that has the store
+	// locked, and returns the first error of its reads and writes
+	End() error
+	Close() error
+	// Path is the path of the file the rows are kept in
+	Path() string
+}
+
+// NewBackendStore returns a store with a table for each model,
+// that keeps its rows in backend
+func NewBackendStore(backend Backend) (*Store, error) {
+	s := newStore(backend.Rows)
+	s.backend = backend
+
+	tables := make([]*Table, 0, len(s.tables))
+	for _, t := range s.tables {
+		tables = append(tables, t)
+	}
+
+	err := backend.Update(func() error {
+		return backend.Init(tables)
+	})
+	backend.End()
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Close closes the backend of the store. It does
+// nothing when the rows are kept in memory.
+func (s *Store) Close() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.backend == nil {
+		return nil
+	}
+	s.backend.End()
+	return s.backend.Close()
+}
+
+// Path returns the path of the file the backend of the store
+// keeps its rows in, or "" when they are kept in memory
+func (s *Store) Path() string {
+	if s.backend == nil {
+		return ""
+	}
+	return s.backend.Path()
+}
+
+// Name is the name of the table
+func (t *Table) Name() string {
+	return t.name
+}
+
+// Uniques are the columns of each unique index of the table
+func (t *Table) Uniques() [][]string {
+	return t.uniques
+}
+
+// Indexes are the indexed columns of the table, each with the column
+// the ids with a value are ordered by, or "" when they are ordered by id
+func (t *Table) Indexes() map[string]string {
+	return t.indexes
+}
+
+// Int returns the value of integer column of row v
+func (t *Table) Int(v reflect.Value, column string) int64 {
+	return t.int(v, column)
+}
+
+// Value returns the value of column of row v, with integers as int64,
+// floats as float64 and blobs as strings
+func (t *Table) Value(v reflect.Value, column string) (interface{}, error) {
+	return t.value(v, column)
+}
+
+// UniqueKey returns the key of row v in the unique index of columns
+func (t *Table) UniqueKey(v reflect.Value, columns []string) string {
+	return t.uniqueKey(v, columns)
+}
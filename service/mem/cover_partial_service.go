@@ -0,0 +1,85 @@
+package mem
+
+import (
+	"github.com/atongen/gosaic/model"
+)
+
+type coverPartialServiceMem struct {
+	store *Store
+}
+
+func NewCoverPartialService(store *Store) *coverPartialServiceMem {
+	return &coverPartialServiceMem{store: store}
+}
+
+func (s *coverPartialServiceMem) Register() error {
+	return nil
+}
+
+func (s *coverPartialServiceMem) Close() error {
+	return nil
+}
+
+func (s *coverPartialServiceMem) Get(id int64) (_ *model.CoverPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("cover_partials", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v.Interface().(*model.CoverPartial), nil
+}
+
+func (s *coverPartialServiceMem) Insert(c *model.CoverPartial) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insert("cover_partials", c)
+}
+
+func (s *coverPartialServiceMem) BulkInsert(coverPartials []*model.CoverPartial) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insertAll("cover_partials", coverPartials)
+}
+
+func (s *coverPartialServiceMem) Count(c *model.Cover) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.tables["cover_partials"].countBy("cover_id", c.Id), nil
+}
+
+func (s *coverPartialServiceMem) Update(c *model.CoverPartial) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.update("cover_partials", c)
+	return err
+}
+
+func (s *coverPartialServiceMem) Delete(c *model.CoverPartial) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.deleteRow("cover_partials", c)
+	return err
+}
+
+func (s *coverPartialServiceMem) FindAll(coverId int64, order string) (_ []*model.CoverPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("cover_partials", "cover_id = ?", []interface{}{coverId}, order, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	coverPartials := make([]*model.CoverPartial, len(rows))
+	for i, v := range rows {
+		coverPartials[i] = v.Interface().(*model.CoverPartial)
+	}
+	return coverPartials, nil
+}
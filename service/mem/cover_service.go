@@ -0,0 +1,82 @@
+package mem
+
+import (
+	"github.com/atongen/gosaic/model"
+)
+
+type coverServiceMem struct {
+	store *Store
+}
+
+func NewCoverService(store *Store) *coverServiceMem {
+	return &coverServiceMem{store: store}
+}
+
+func (s *coverServiceMem) Register() error {
+	return nil
+}
+
+func (s *coverServiceMem) Close() error {
+	return nil
+}
+
+func (s *coverServiceMem) Get(id int64) (_ *model.Cover, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("covers", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v.Interface().(*model.Cover), nil
+}
+
+func (s *coverServiceMem) Insert(c *model.Cover) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insert("covers", c)
+}
+
+func (s *coverServiceMem) Update(c *model.Cover) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.update("covers", c)
+	return err
+}
+
+func (s *coverServiceMem) Delete(c *model.Cover) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.deleteRow("covers", c)
+	return err
+}
+
+func (s *coverServiceMem) GetOneBy(conditions string, params ...interface{}) (_ *model.Cover, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("covers", conditions, params, "", 1, 0)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0].Interface().(*model.Cover), nil
+}
+
+func (s *coverServiceMem) FindAll(order string) (_ []*model.Cover, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("covers", "", nil, order, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	covers := make([]*model.Cover, len(rows))
+	for i, v := range rows {
+		covers[i] = v.Interface().(*model.Cover)
+	}
+	return covers, nil
+}
@@ -0,0 +1,117 @@
+package mem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"time"
+)
+
+// Encode returns the values of the columns of row v, in order,
+// for backends that keep rows as bytes
+func (t *Table) Encode(v reflect.Value) []byte {
+	var b bytes.Buffer
+	buf := make([]byte, binary.MaxVarintLen64)
+	putBytes := func(data []byte) {
+		n := binary.PutUvarint(buf, uint64(len(data)))
+		b.Write(buf[:n])
+		b.Write(data)
+	}
+
+	for _, column := range t.columns {
+		f := v.Elem().Field(t.fields[column])
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n := binary.PutVarint(buf, f.Int())
+			b.Write(buf[:n])
+		case reflect.Float32, reflect.Float64:
+			binary.BigEndian.PutUint64(buf, math.Float64bits(f.Float()))
+			b.Write(buf[:8])
+		case reflect.Bool:
+			if f.Bool() {
+				b.WriteByte(1)
+			} else {
+				b.WriteByte(0)
+			}
+		case reflect.String:
+			putBytes([]byte(f.String()))
+		case reflect.Slice:
+			putBytes(f.Bytes())
+		case reflect.Struct:
+			data, _ := f.Interface().(time.Time).MarshalBinary()
+			putBytes(data)
+		}
+	}
+
+	return b.Bytes()
+}
+
+var errRowData = errors.New("invalid data")
+
+// Decode returns a pointer to the row encoded in data. Columns missing
+// from the end of data, which was encoded before they were added, are zero.
+func (t *Table) Decode(data []byte) (reflect.Value, error) {
+	v := reflect.New(t.typ)
+	r := bytes.NewReader(data)
+	getBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil || n > uint64(r.Len()) {
+			return nil, errRowData
+		}
+		b := make([]byte, n)
+		r.Read(b)
+		return b, nil
+	}
+
+	for _, column := range t.columns {
+		if r.Len() == 0 {
+			break
+		}
+
+		f := v.Elem().Field(t.fields[column])
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i, err := binary.ReadVarint(r)
+			if err != nil {
+				return v, errRowData
+			}
+			f.SetInt(i)
+		case reflect.Float32, reflect.Float64:
+			buf := make([]byte, 8)
+			if n, _ := r.Read(buf); n != 8 {
+				return v, errRowData
+			}
+			f.SetFloat(math.Float64frombits(binary.BigEndian.Uint64(buf)))
+		case reflect.Bool:
+			c, _ := r.ReadByte()
+			f.SetBool(c != 0)
+		case reflect.String:
+			b, err := getBytes()
+			if err != nil {
+				return v, err
+			}
+			f.SetString(string(b))
+		case reflect.Slice:
+			b, err := getBytes()
+			if err != nil {
+				return v, err
+			}
+			f.SetBytes(b)
+		case reflect.Struct:
+			b, err := getBytes()
+			if err != nil {
+				return v, err
+			}
+			var tm time.Time
+			err = tm.UnmarshalBinary(b)
+			if err != nil {
+				return v, err
+			}
+			f.Set(reflect.ValueOf(tm))
+		}
+	}
+
+	return v, nil
+}
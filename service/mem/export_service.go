@@ -0,0 +1,117 @@
+package mem
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// exportTables are the tables of the store, in an order where
+// each row comes after the rows it references
+var exportTables = []string{
+	"aspects",
+	"gidx",
+	"gidx_partials",
+	"covers",
+	"cover_partials",
+	"macros",
+	"macro_partials",
+	"partial_comparisons",
+	"mosaics",
+	"mosaic_partials",
+	"quad_dists",
+	"projects",
+}
+
+type exportServiceMem struct {
+	store *Store
+}
+
+func NewExportService(store *Store) *exportServiceMem {
+	return &exportServiceMem{store: store}
+}
+
+func (s *exportServiceMem) Register() error {
+	return nil
+}
+
+func (s *exportServiceMem) Close() error {
+	return nil
+}
+
+// Tables returns the tables of the store, in the order
+// they must be imported
+func (s *exportServiceMem) Tables() []string {
+	tables := make([]string, len(exportTables))
+	copy(tables, exportTables)
+	return tables
+}
+
+// Count returns the number of rows of table
+func (s *exportServiceMem) Count(table string) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	t, err := s.store.table(table)
+	if err != nil {
+		return int64(0), err
+	}
+	return t.count(), nil
+}
+
+// Export calls fn with the rows of table in order of id, up to
+// batchSize at a time, each a pointer to its model
+func (s *exportServiceMem) Export(table string, batchSize int, fn func([]interface{}) error) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("Batch size must be greater than zero")
+	}
+
+	var lastId int64
+	for {
+		rows, err := s.export(table, lastId, batchSize)
+		if err != nil {
+			return err
+		} else if len(rows) == 0 {
+			return nil
+		}
+
+		lastId = s.store.tables[table].id(reflect.ValueOf(rows[len(rows)-1]))
+
+		err = fn(rows)
+		if err != nil {
+			return err
+		}
+
+		if len(rows) < batchSize {
+			return nil
+		}
+	}
+}
+
+// export returns copies of up to limit rows of table
+// with ids after lastId, in order of id
+func (s *exportServiceMem) export(table string, lastId int64, limit int) (_ []interface{}, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	t, err := s.store.table(table)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]interface{}, 0)
+	for _, id := range t.rows.IdsAfter(lastId, limit) {
+		v, _ := t.get(id)
+		rows = append(rows, t.copyRow(v).Interface())
+	}
+
+	return rows, nil
+}
+
+// Import stores rows, pointers to the model of table, with their ids.
+// Either all rows are stored, or none.
+func (s *exportServiceMem) Import(table string, rows []interface{}) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.importRows(table, rows)
+}
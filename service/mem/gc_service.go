@@ -0,0 +1,225 @@
+package mem
+
+import (
+	"fmt"
+	"os"
+)
+
+// gcTables are the tables with rows that can be orphaned, in the order
+// they are deleted, like those of the sqlite3 gc service
+var gcTables = []string{
+	"partial_comparisons",
+	"quad_dists",
+	"macro_partials",
+	"macros",
+	"cover_partials",
+	"covers",
+	"gidx_partials",
+	"aspects",
+}
+
+type gcServiceMem struct {
+	store *Store
+}
+
+func NewGcService(store *Store) *gcServiceMem {
+	return &gcServiceMem{store: store}
+}
+
+func (s *gcServiceMem) Register() error {
+	return nil
+}
+
+func (s *gcServiceMem) Close() error {
+	return nil
+}
+
+// Tables returns the tables with rows that can be orphaned,
+// in the order their orphans must be deleted
+func (s *gcServiceMem) Tables() []string {
+	tables := make([]string, len(gcTables))
+	copy(tables, gcTables)
+	return tables
+}
+
+func checkGcTable(table string) error {
+	for _, name := range gcTables {
+		if name == table {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown gc table: %s", table)
+}
+
+// values returns the set of values of column of the rows of table,
+// or of the rows with a value of where in the set in, when where is set
+func (s *gcServiceMem) values(table, column, where string, in map[int64]bool) map[int64]bool {
+	t := s.store.tables[table]
+	values := make(map[int64]bool)
+	for _, id := range t.ids() {
+		v, _ := t.get(id)
+		if where != "" && !in[t.int(v, where)] {
+			continue
+		}
+		values[t.int(v, column)] = true
+	}
+	return values
+}
+
+// orphans returns the ids of the rows of table that are not reachable
+// from any project or mosaic, in ascending order
+func (s *gcServiceMem) orphans(table string) ([]int64, error) {
+	err := checkGcTable(table)
+	if err != nil {
+		return nil, err
+	}
+
+	// macros used by a project or mosaic
+	macros := s.values("projects", "macro_id", "", nil)
+	for id := range s.values("mosaics", "macro_id", "", nil) {
+		macros[id] = true
+	}
+
+	// covers used by a project, or by a macro in use
+	covers := s.values("projects", "cover_id", "", nil)
+	for id := range s.values("macros", "cover_id", "id", macros) {
+		covers[id] = true
+	}
+
+	// gidx partials with an aspect that no cover or macro in use needs,
+	// and that no mosaic has placed
+	gidxPartials := s.store.tables["gidx_partials"]
+	coverAspects := s.values("cover_partials", "aspect_id", "cover_id", covers)
+	macroAspects := s.values("macro_partials", "aspect_id", "macro_id", macros)
+	placed := s.values("mosaic_partials", "gidx_partial_id", "", nil)
+	orphanGidxPartial := func(id int64) bool {
+		v, _ := gidxPartials.get(id)
+		aspectId := gidxPartials.int(v, "aspect_id")
+		return !coverAspects[aspectId] && !macroAspects[aspectId] && !placed[id]
+	}
+
+	macroPartials := s.store.tables["macro_partials"]
+	orphanMacroPartial := func(id int64) bool {
+		v, _ := macroPartials.get(id)
+		return !macros[macroPartials.int(v, "macro_id")]
+	}
+
+	t := s.store.tables[table]
+	var orphan func(int64) bool
+	switch table {
+	case "partial_comparisons":
+		orphan = func(id int64) bool {
+			v, _ := t.get(id)
+			return orphanMacroPartial(t.int(v, "macro_partial_id")) || orphanGidxPartial(t.int(v, "gidx_partial_id"))
+		}
+	case "quad_dists":
+		orphan = func(id int64) bool {
+			v, _ := t.get(id)
+			return orphanMacroPartial(t.int(v, "macro_partial_id"))
+		}
+	case "macro_partials":
+		orphan = orphanMacroPartial
+	case "macros":
+		orphan = func(id int64) bool {
+			return !macros[id]
+		}
+	case "cover_partials":
+		orphan = func(id int64) bool {
+			v, _ := t.get(id)
+			return !covers[t.int(v, "cover_id")]
+		}
+	case "covers":
+		orphan = func(id int64) bool {
+			return !covers[id]
+		}
+	case "gidx_partials":
+		orphan = orphanGidxPartial
+	case "aspects":
+		aspects := make(map[int64]bool)
+		for _, name := range []string{"gidx", "gidx_partials", "covers", "cover_partials", "macros", "macro_partials"} {
+			for id := range s.values(name, "aspect_id", "", nil) {
+				aspects[id] = true
+			}
+		}
+		orphan = func(id int64) bool {
+			return !aspects[id]
+		}
+	}
+
+	ids := make([]int64, 0)
+	for _, id := range t.ids() {
+		if orphan(id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// CountOrphans returns the number of rows of table that
+// are not reachable from any project or mosaic
+func (s *gcServiceMem) CountOrphans(table string) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	ids, err := s.orphans(table)
+	if err != nil {
+		return int64(0), err
+	}
+	return int64(len(ids)), nil
+}
+
+// DeleteOrphans deletes up to limit orphaned rows of table,
+// and returns the number of rows deleted
+func (s *gcServiceMem) DeleteOrphans(table string, limit int) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	ids, err := s.orphans(table)
+	if err != nil {
+		return int64(0), err
+	}
+	if limit >= 0 && limit < len(ids) {
+		ids = ids[:limit]
+	}
+	return s.store.delete(table, ids...)
+}
+
+// TableSize returns the number of rows of table. Bytes is always -1,
+// since the size of the rows in memory is unknown.
+func (s *gcServiceMem) TableSize(table string) (_ int64, _ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	err = checkGcTable(table)
+	if err != nil {
+		return int64(0), int64(0), err
+	}
+	return s.store.tables[table].count(), int64(-1), nil
+}
+
+// FileSize returns the size of the bolt database file in bytes,
+// or 0 when the rows are kept in memory
+func (s *gcServiceMem) FileSize() (int64, error) {
+	path := s.store.Path()
+	if path == "" {
+		return int64(0), nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return int64(0), err
+	}
+	return fi.Size(), nil
+}
+
+// Vacuum does nothing. Deleted rows are freed by the garbage collector,
+// or their pages are reused by a bolt database.
+func (s *gcServiceMem) Vacuum() error {
+	return nil
+}
+
+// Analyze does nothing, there is no query planner
+func (s *gcServiceMem) Analyze() error {
+	return nil
+}
@@ -0,0 +1,290 @@
+package mem
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+)
+
+type gidxPartialServiceMem struct {
+	store *Store
+}
+
+func NewGidxPartialService(store *Store) *gidxPartialServiceMem {
+	return &gidxPartialServiceMem{store: store}
+}
+
+func (s *gidxPartialServiceMem) Register() error {
+	return nil
+}
+
+func (s *gidxPartialServiceMem) Close() error {
+	return nil
+}
+
+func (s *gidxPartialServiceMem) Insert(gidxPartial *model.GidxPartial) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	err = gidxPartial.EncodePixels()
+	if err != nil {
+		return err
+	}
+	return s.store.insert("gidx_partials", gidxPartial)
+}
+
+func (s *gidxPartialServiceMem) BulkInsert(gidxPartials []*model.GidxPartial) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insertAll("gidx_partials", gidxPartials)
+}
+
+func (s *gidxPartialServiceMem) Update(gidxPartial *model.GidxPartial) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	err = gidxPartial.EncodePixels()
+	if err != nil {
+		return err
+	}
+	_, err = s.store.update("gidx_partials", gidxPartial)
+	return err
+}
+
+func (s *gidxPartialServiceMem) Delete(gidxPartial *model.GidxPartial) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.deleteRow("gidx_partials", gidxPartial)
+	return err
+}
+
+func (s *gidxPartialServiceMem) Get(id int64) (_ *model.GidxPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("gidx_partials", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	gp := v.Interface().(*model.GidxPartial)
+	err = gp.DecodeData()
+	if err != nil {
+		return nil, err
+	}
+
+	return gp, nil
+}
+
+func (s *gidxPartialServiceMem) GetOneBy(column string, value interface{}) (_ *model.GidxPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	gidxPartials, err := s.find("", 1, 0, fmt.Sprintf("%s = ?", column), value)
+	if err != nil {
+		return nil, err
+	} else if len(gidxPartials) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return gidxPartials[0], nil
+}
+
+func (s *gidxPartialServiceMem) ExistsBy(conditions string, params ...interface{}) (_ bool, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("gidx_partials", conditions, params, "", 1, 0)
+	return len(rows) == 1, err
+}
+
+func (s *gidxPartialServiceMem) Count() (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.tables["gidx_partials"].count(), nil
+}
+
+func (s *gidxPartialServiceMem) CountBy(conditions string, params ...interface{}) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.countRows("gidx_partials", conditions, params)
+}
+
+func (s *gidxPartialServiceMem) CountForMacro(macro *model.Macro) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	gidxPartials := s.store.tables["gidx_partials"]
+	macroPartials := s.store.tables["macro_partials"]
+
+	aspectIds := make(map[int64]bool)
+	for _, id := range macroPartials.idsBy("macro_id", macro.Id) {
+		v, _ := macroPartials.get(id)
+		aspectIds[macroPartials.int(v, "aspect_id")] = true
+	}
+
+	gidxIds := make(map[int64]bool)
+	for aspectId := range aspectIds {
+		for _, id := range gidxPartials.idsBy("aspect_id", aspectId) {
+			v, _ := gidxPartials.get(id)
+			gidxIds[gidxPartials.int(v, "gidx_id")] = true
+		}
+	}
+
+	return int64(len(gidxIds)), nil
+}
+
+func (s *gidxPartialServiceMem) FindAll(order string, limit, offset int, conditions string, params ...interface{}) (_ []*model.GidxPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.find(order, limit, offset, conditions, params...)
+}
+
+func (s *gidxPartialServiceMem) find(order string, limit, offset int, conditions string, params ...interface{}) ([]*model.GidxPartial, error) {
+	rows, err := s.store.selectRows("gidx_partials", conditions, params, order, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	gidxPartials := make([]*model.GidxPartial, len(rows))
+	for i, v := range rows {
+		gp := v.Interface().(*model.GidxPartial)
+		err = gp.DecodeData()
+		if err != nil {
+			return nil, err
+		}
+		gidxPartials[i] = gp
+	}
+
+	return gidxPartials, nil
+}
+
+func (s *gidxPartialServiceMem) doFind(gidx *model.Gidx, aspect *model.Aspect) (*model.GidxPartial, error) {
+	t := s.store.tables["gidx_partials"]
+	id, ok := t.getBy(0, gidx.Id, aspect.Id)
+	if !ok {
+		return nil, nil
+	}
+
+	v, _ := t.get(id)
+	p := t.copyRow(v).Interface().(*model.GidxPartial)
+	err := p.DecodeData()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *gidxPartialServiceMem) Find(gidx *model.Gidx, aspect *model.Aspect) (_ *model.GidxPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.doFind(gidx, aspect)
+}
+
+func (s *gidxPartialServiceMem) doCreate(gidx *model.Gidx, aspect *model.Aspect) (*model.GidxPartial, error) {
+	p := model.GidxPartial{
+		GidxId:   gidx.Id,
+		AspectId: aspect.Id,
+	}
+
+	pixels, err := util.GetAspectLab(gidx, aspect)
+	if err != nil {
+		return nil, err
+	}
+	p.Pixels = pixels
+
+	err = p.EncodePixels()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.insert("gidx_partials", &p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (s *gidxPartialServiceMem) Create(gidx *model.Gidx, aspect *model.Aspect) (_ *model.GidxPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.doCreate(gidx, aspect)
+}
+
+func (s *gidxPartialServiceMem) FindOrCreate(gidx *model.Gidx, aspect *model.Aspect) (_ *model.GidxPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	p, err := s.doFind(gidx, aspect)
+	if err != nil {
+		return nil, err
+	} else if p != nil {
+		return p, nil
+	}
+
+	// or create
+	return s.doCreate(gidx, aspect)
+}
+
+// missing returns the ids of the gidx without a partial of aspect
+func (s *gidxPartialServiceMem) missing(aspect *model.Aspect) []int64 {
+	gidxPartials := s.store.tables["gidx_partials"]
+
+	ids := make([]int64, 0)
+	for _, id := range s.store.tables["gidx"].ids() {
+		if _, ok := gidxPartials.getBy(0, id, aspect.Id); !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func (s *gidxPartialServiceMem) FindMissing(aspect *model.Aspect, order string, limit, offset int) (_ []*model.Gidx, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	t := s.store.tables["gidx"]
+	rows := make([]getter, 0)
+	values := make([]*model.Gidx, 0)
+	for _, id := range s.missing(aspect) {
+		v, _ := t.get(id)
+		rows = append(rows, t.getter(v))
+		values = append(values, t.copyRow(v).Interface().(*model.Gidx))
+	}
+
+	perm, err := sortRows(order, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	gidxs := make([]*model.Gidx, 0)
+	for _, p := range page(perm, limit, offset) {
+		gidxs = append(gidxs, values[p])
+	}
+
+	return gidxs, nil
+}
+
+func (s *gidxPartialServiceMem) CountMissing(aspects []*model.Aspect) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	var count int64 = 0
+	for _, aspect := range aspects {
+		count += int64(len(s.missing(aspect)))
+	}
+
+	return count, nil
+}
@@ -0,0 +1,107 @@
+package mem
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/atongen/gosaic/model"
+)
+
+type gidxServiceMem struct {
+	store *Store
+}
+
+func NewGidxService(store *Store) *gidxServiceMem {
+	return &gidxServiceMem{store: store}
+}
+
+func (s *gidxServiceMem) Register() error {
+	return nil
+}
+
+func (s *gidxServiceMem) Close() error {
+	return nil
+}
+
+func (s *gidxServiceMem) Insert(gidx *model.Gidx) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insert("gidx", gidx)
+}
+
+func (s *gidxServiceMem) Update(gidx *model.Gidx) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.update("gidx", gidx)
+}
+
+func (s *gidxServiceMem) Delete(gidx *model.Gidx) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.deleteRow("gidx", gidx)
+}
+
+func (s *gidxServiceMem) Get(id int64) (_ *model.Gidx, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("gidx", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v.Interface().(*model.Gidx), nil
+}
+
+func (s *gidxServiceMem) GetOneBy(column string, value interface{}) (_ *model.Gidx, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("gidx", fmt.Sprintf("%s = ?", column), []interface{}{value}, "", 1, 0)
+	if err != nil {
+		return &model.Gidx{}, err
+	} else if len(rows) == 0 {
+		return &model.Gidx{}, sql.ErrNoRows
+	}
+	return rows[0].Interface().(*model.Gidx), nil
+}
+
+func (s *gidxServiceMem) ExistsBy(column string, value interface{}) (_ bool, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("gidx", fmt.Sprintf("%s = ?", column), []interface{}{value}, "", 1, 0)
+	return len(rows) == 1, err
+}
+
+func (s *gidxServiceMem) Count() (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.tables["gidx"].count(), nil
+}
+
+func (s *gidxServiceMem) CountBy(column string, value interface{}) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.countRows("gidx", fmt.Sprintf("%s = ?", column), []interface{}{value})
+}
+
+func (s *gidxServiceMem) FindAll(order string, limit, offset int) (_ []*model.Gidx, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("gidx", "", nil, order, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	gidxs := make([]*model.Gidx, len(rows))
+	for i, v := range rows {
+		gidxs[i] = v.Interface().(*model.Gidx)
+	}
+	return gidxs, nil
+}
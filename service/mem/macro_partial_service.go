@@ -0,0 +1,261 @@
+package mem
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/atongen/gosaic/model"
+	"github.com/atongen/gosaic/util"
+)
+
+type macroPartialServiceMem struct {
+	store *Store
+}
+
+func NewMacroPartialService(store *Store) *macroPartialServiceMem {
+	return &macroPartialServiceMem{store: store}
+}
+
+func (s *macroPartialServiceMem) Register() error {
+	return nil
+}
+
+func (s *macroPartialServiceMem) Close() error {
+	return nil
+}
+
+func (s *macroPartialServiceMem) Insert(macroPartial *model.MacroPartial) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	err = macroPartial.EncodePixels()
+	if err != nil {
+		return err
+	}
+	return s.store.insert("macro_partials", macroPartial)
+}
+
+func (s *macroPartialServiceMem) Update(macroPartial *model.MacroPartial) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	err = macroPartial.EncodePixels()
+	if err != nil {
+		return err
+	}
+	_, err = s.store.update("macro_partials", macroPartial)
+	return err
+}
+
+func (s *macroPartialServiceMem) Delete(macroPartial *model.MacroPartial) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.deleteRow("macro_partials", macroPartial)
+	return err
+}
+
+func (s *macroPartialServiceMem) Get(id int64) (_ *model.MacroPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("macro_partials", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	mp := v.Interface().(*model.MacroPartial)
+	err = mp.DecodeData()
+	if err != nil {
+		return nil, err
+	}
+
+	return mp, nil
+}
+
+func (s *macroPartialServiceMem) GetOneBy(column string, value interface{}) (_ *model.MacroPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	macroPartials, err := s.find("", 1, 0, fmt.Sprintf("%s = ?", column), value)
+	if err != nil {
+		return nil, err
+	} else if len(macroPartials) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return macroPartials[0], nil
+}
+
+func (s *macroPartialServiceMem) ExistsBy(column string, value interface{}) (_ bool, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("macro_partials", fmt.Sprintf("%s = ?", column), []interface{}{value}, "", 1, 0)
+	return len(rows) == 1, err
+}
+
+func (s *macroPartialServiceMem) Count(macro *model.Macro) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.tables["macro_partials"].countBy("macro_id", macro.Id), nil
+}
+
+func (s *macroPartialServiceMem) FindAll(order string, limit, offset int, conditions string, params ...interface{}) (_ []*model.MacroPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.find(order, limit, offset, conditions, params...)
+}
+
+func (s *macroPartialServiceMem) find(order string, limit, offset int, conditions string, params ...interface{}) ([]*model.MacroPartial, error) {
+	rows, err := s.store.selectRows("macro_partials", conditions, params, order, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	macroPartials := make([]*model.MacroPartial, len(rows))
+	for i, v := range rows {
+		mp := v.Interface().(*model.MacroPartial)
+		err = mp.DecodeData()
+		if err != nil {
+			return nil, err
+		}
+		macroPartials[i] = mp
+	}
+
+	return macroPartials, nil
+}
+
+func (s *macroPartialServiceMem) doFind(macro *model.Macro, coverPartial *model.CoverPartial) (*model.MacroPartial, error) {
+	t := s.store.tables["macro_partials"]
+	id, ok := t.getBy(0, macro.Id, coverPartial.Id)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	v, _ := t.get(id)
+	p := t.copyRow(v).Interface().(*model.MacroPartial)
+	err := p.DecodeData()
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *macroPartialServiceMem) Find(macro *model.Macro, coverPartial *model.CoverPartial) (_ *model.MacroPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.doFind(macro, coverPartial)
+}
+
+func (s *macroPartialServiceMem) doCreate(macro *model.Macro, coverPartial *model.CoverPartial) (*model.MacroPartial, error) {
+	p := model.MacroPartial{
+		MacroId:        macro.Id,
+		CoverPartialId: coverPartial.Id,
+		AspectId:       coverPartial.AspectId,
+	}
+
+	pixels, err := util.GetPartialLab(macro, coverPartial)
+	if err != nil {
+		return nil, err
+	}
+	p.Pixels = pixels
+
+	err = p.EncodePixels()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.store.insert("macro_partials", &p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (s *macroPartialServiceMem) Create(macro *model.Macro, coverPartial *model.CoverPartial) (_ *model.MacroPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.doCreate(macro, coverPartial)
+}
+
+func (s *macroPartialServiceMem) FindOrCreate(macro *model.Macro, coverPartial *model.CoverPartial) (_ *model.MacroPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	p, err := s.doFind(macro, coverPartial)
+	if err == nil {
+		return p, nil
+	}
+
+	// or create
+	return s.doCreate(macro, coverPartial)
+}
+
+// missing returns the ids of the cover partials of the cover of macro
+// without a macro partial
+func (s *macroPartialServiceMem) missing(macro *model.Macro) []int64 {
+	macroPartials := s.store.tables["macro_partials"]
+
+	ids := make([]int64, 0)
+	for _, id := range s.store.tables["cover_partials"].idsBy("cover_id", macro.CoverId) {
+		if _, ok := macroPartials.getBy(0, macro.Id, id); !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func (s *macroPartialServiceMem) CountMissing(macro *model.Macro) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return int64(len(s.missing(macro))), nil
+}
+
+func (s *macroPartialServiceMem) FindMissing(macro *model.Macro, order string, limit, offset int) (_ []*model.CoverPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	t := s.store.tables["cover_partials"]
+	rows := make([]getter, 0)
+	values := make([]*model.CoverPartial, 0)
+	for _, id := range s.missing(macro) {
+		v, _ := t.get(id)
+		rows = append(rows, t.getter(v))
+		values = append(values, t.copyRow(v).Interface().(*model.CoverPartial))
+	}
+
+	perm, err := sortRows(order, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	coverPartials := make([]*model.CoverPartial, 0)
+	for _, p := range page(perm, limit, offset) {
+		coverPartials = append(coverPartials, values[p])
+	}
+
+	return coverPartials, nil
+}
+
+func (s *macroPartialServiceMem) AspectIds(macroId int64) (_ []int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	t := s.store.tables["macro_partials"]
+	aspectIds := make(map[int64]bool)
+	for _, id := range t.idsBy("macro_id", macroId) {
+		v, _ := t.get(id)
+		aspectIds[t.int(v, "aspect_id")] = true
+	}
+
+	return sortedIds(aspectIds), nil
+}
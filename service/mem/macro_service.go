@@ -0,0 +1,90 @@
+package mem
+
+import (
+	"github.com/atongen/gosaic/model"
+)
+
+type macroServiceMem struct {
+	store *Store
+}
+
+func NewMacroService(store *Store) *macroServiceMem {
+	return &macroServiceMem{store: store}
+}
+
+func (s *macroServiceMem) Register() error {
+	return nil
+}
+
+func (s *macroServiceMem) Close() error {
+	return nil
+}
+
+func (s *macroServiceMem) Get(id int64) (_ *model.Macro, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("macros", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v.Interface().(*model.Macro), nil
+}
+
+func (s *macroServiceMem) Insert(c *model.Macro) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insert("macros", c)
+}
+
+func (s *macroServiceMem) Update(c *model.Macro) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.update("macros", c)
+	return err
+}
+
+func (s *macroServiceMem) Delete(c *model.Macro) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.deleteRow("macros", c)
+	return err
+}
+
+func (s *macroServiceMem) GetOneBy(conditions string, params ...interface{}) (_ *model.Macro, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("macros", conditions, params, "", 1, 0)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0].Interface().(*model.Macro), nil
+}
+
+func (s *macroServiceMem) ExistsBy(conditions string, params ...interface{}) (_ bool, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("macros", conditions, params, "", 1, 0)
+	return len(rows) == 1, err
+}
+
+func (s *macroServiceMem) FindAll(order string) (_ []*model.Macro, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("macros", "", nil, order, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	macros := make([]*model.Macro, len(rows))
+	for i, v := range rows {
+		macros[i] = v.Interface().(*model.Macro)
+	}
+	return macros, nil
+}
@@ -0,0 +1,199 @@
+package mem
+
+import (
+	"math/rand"
+	"reflect"
+
+	"github.com/atongen/gosaic/model"
+)
+
+type mosaicPartialServiceMem struct {
+	store *Store
+}
+
+func NewMosaicPartialService(store *Store) *mosaicPartialServiceMem {
+	return &mosaicPartialServiceMem{store: store}
+}
+
+func (s *mosaicPartialServiceMem) Register() error {
+	return nil
+}
+
+func (s *mosaicPartialServiceMem) Close() error {
+	return nil
+}
+
+func (s *mosaicPartialServiceMem) Get(id int64) (_ *model.MosaicPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("mosaic_partials", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v.Interface().(*model.MosaicPartial), nil
+}
+
+func (s *mosaicPartialServiceMem) Insert(mosaicPartial *model.MosaicPartial) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insert("mosaic_partials", mosaicPartial)
+}
+
+// missingMacroPartials returns the ids of the macro partials of the macro
+// of mosaic that are not in mosaic, in ascending order
+func (s *Store) missingMacroPartials(mosaic *model.Mosaic) []int64 {
+	mosaicPartials := s.tables["mosaic_partials"]
+
+	ids := make([]int64, 0)
+	for _, id := range s.tables["macro_partials"].idsBy("macro_id", mosaic.MacroId) {
+		if _, ok := mosaicPartials.getBy(0, mosaic.Id, id); !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func (s *mosaicPartialServiceMem) CountMissing(mosaic *model.Mosaic) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return int64(len(s.store.missingMacroPartials(mosaic))), nil
+}
+
+func (s *mosaicPartialServiceMem) Count(mosaic *model.Mosaic) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.tables["mosaic_partials"].countBy("mosaic_id", mosaic.Id), nil
+}
+
+func (s *mosaicPartialServiceMem) GetMissing(mosaic *model.Mosaic) (_ *model.MacroPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	ids := s.store.missingMacroPartials(mosaic)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	v, _, err := s.store.getRow("macro_partials", ids[0])
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface().(*model.MacroPartial), nil
+}
+
+func (s *mosaicPartialServiceMem) GetRandomMissing(mosaic *model.Mosaic) (_ *model.MacroPartial, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	ids := s.store.missingMacroPartials(mosaic)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	v, _, err := s.store.getRow("macro_partials", ids[rand.Intn(len(ids))])
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface().(*model.MacroPartial), nil
+}
+
+func (s *mosaicPartialServiceMem) FindAllPartialViews(mosaic *model.Mosaic, order string, limit, offset int) (_ []*model.MosaicPartialView, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	mosaicPartials := s.store.tables["mosaic_partials"]
+	gidxPartials := s.store.tables["gidx_partials"]
+	gidxs := s.store.tables["gidx"]
+	macroPartials := s.store.tables["macro_partials"]
+	coverPartials := s.store.tables["cover_partials"]
+	partialComparisons := s.store.tables["partial_comparisons"]
+
+	rows := make([]getter, 0)
+	views := make([]*model.MosaicPartialView, 0)
+	for _, id := range mosaicPartials.idsBy("mosaic_id", mosaic.Id) {
+		mop, _ := mosaicPartials.get(id)
+		gp, _ := gidxPartials.get(mosaicPartials.int(mop, "gidx_partial_id"))
+		g, _ := gidxs.get(gidxPartials.int(gp, "gidx_id"))
+		mp, _ := macroPartials.get(mosaicPartials.int(mop, "macro_partial_id"))
+		cp, _ := coverPartials.get(macroPartials.int(mp, "cover_partial_id"))
+
+		dist := mosaicPartials.float(mop, "dist")
+		if pcId, ok := partialComparisons.getBy(0, macroPartials.id(mp), gidxPartials.id(gp)); ok {
+			pc, _ := partialComparisons.get(pcId)
+			dist = partialComparisons.float(pc, "dist")
+		}
+
+		rows = append(rows, s.store.joinGetter(map[string]reflect.Value{
+			"mosaic_partials": mop,
+			"gidx_partials":   gp,
+			"gidx":            g,
+			"macro_partials":  mp,
+			"cover_partials":  cp,
+		}, map[string]interface{}{
+			"mosaic_partial_id": id,
+			"dist":              dist,
+		}))
+
+		views = append(views, &model.MosaicPartialView{
+			MosaicPartialId: id,
+			Gidx:            gidxs.copyRow(g).Interface().(*model.Gidx),
+			CoverPartial:    coverPartials.copyRow(cp).Interface().(*model.CoverPartial),
+			Dist:            dist,
+		})
+	}
+
+	perm, err := sortRows(order, rows)
+	if err != nil {
+		return nil, err
+	}
+
+	mosaicPartialViews := make([]*model.MosaicPartialView, 0)
+	for _, p := range page(perm, limit, offset) {
+		mosaicPartialViews = append(mosaicPartialViews, views[p])
+	}
+
+	return mosaicPartialViews, nil
+}
+
+// FindRepeats returns gidx_partial ids that have maxRepeats or more duplicats
+// used in mosaic
+func (s *mosaicPartialServiceMem) FindRepeats(mosaic *model.Mosaic, maxRepeats int) (_ []int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	mosaicPartials := s.store.tables["mosaic_partials"]
+	gidxPartials := s.store.tables["gidx_partials"]
+
+	// the number of uses and the first partial of each index image
+	uses := make(map[int64]int)
+	first := make(map[int64]int64)
+	for _, id := range mosaicPartials.idsBy("mosaic_id", mosaic.Id) {
+		mop, _ := mosaicPartials.get(id)
+		gidxPartialId := mosaicPartials.int(mop, "gidx_partial_id")
+		gp, _ := gidxPartials.get(gidxPartialId)
+		gidxId := gidxPartials.int(gp, "gidx_id")
+		uses[gidxId]++
+		if f, ok := first[gidxId]; !ok || gidxPartialId < f {
+			first[gidxId] = gidxPartialId
+		}
+	}
+
+	gidxIds := make(map[int64]bool)
+	for gidxId, n := range uses {
+		if n >= maxRepeats {
+			gidxIds[gidxId] = true
+		}
+	}
+
+	gidxPartialIds := make([]int64, 0)
+	for _, gidxId := range sortedIds(gidxIds) {
+		gidxPartialIds = append(gidxPartialIds, first[gidxId])
+	}
+
+	return gidxPartialIds, nil
+}
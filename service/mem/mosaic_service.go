@@ -0,0 +1,92 @@
+package mem
+
+import (
+	"github.com/atongen/gosaic/model"
+)
+
+type mosaicServiceMem struct {
+	store *Store
+}
+
+func NewMosaicService(store *Store) *mosaicServiceMem {
+	return &mosaicServiceMem{store: store}
+}
+
+func (s *mosaicServiceMem) Register() error {
+	return nil
+}
+
+func (s *mosaicServiceMem) Close() error {
+	return nil
+}
+
+func (s *mosaicServiceMem) Get(id int64) (_ *model.Mosaic, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("mosaics", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v.Interface().(*model.Mosaic), nil
+}
+
+func (s *mosaicServiceMem) Insert(mosaic *model.Mosaic) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insert("mosaics", mosaic)
+}
+
+func (s *mosaicServiceMem) Update(mosaic *model.Mosaic) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.update("mosaics", mosaic)
+}
+
+func (s *mosaicServiceMem) Delete(mosaic *model.Mosaic) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.deleteRow("mosaics", mosaic)
+	return err
+}
+
+func (s *mosaicServiceMem) GetOneBy(conditions string, params ...interface{}) (_ *model.Mosaic, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("mosaics", conditions, params, "", 1, 0)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0].Interface().(*model.Mosaic), nil
+}
+
+func (s *mosaicServiceMem) ExistsBy(conditions string, params ...interface{}) (_ bool, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("mosaics", conditions, params, "", 1, 0)
+	if err != nil {
+		return false, err
+	}
+	return len(rows) == 1, nil
+}
+
+func (s *mosaicServiceMem) FindAll(order string) (_ []*model.Mosaic, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("mosaics", "", nil, order, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	mosaics := make([]*model.Mosaic, len(rows))
+	for i, v := range rows {
+		mosaics[i] = v.Interface().(*model.Mosaic)
+	}
+	return mosaics, nil
+}
@@ -0,0 +1,528 @@
+package mem
+
+import (
+	"database/sql"
+	"reflect"
+
+	"github.com/atongen/gosaic/model"
+)
+
+type partialComparisonServiceMem struct {
+	store *Store
+}
+
+func NewPartialComparisonService(store *Store) *partialComparisonServiceMem {
+	return &partialComparisonServiceMem{store: store}
+}
+
+func (s *partialComparisonServiceMem) Register() error {
+	return nil
+}
+
+func (s *partialComparisonServiceMem) Close() error {
+	return nil
+}
+
+func (s *partialComparisonServiceMem) table() *Table {
+	return s.store.tables["partial_comparisons"]
+}
+
+func (s *partialComparisonServiceMem) Insert(pc *model.PartialComparison) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insert("partial_comparisons", pc)
+}
+
+func (s *partialComparisonServiceMem) BulkInsert(partialComparisons []*model.PartialComparison) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insertAll("partial_comparisons", partialComparisons)
+}
+
+func (s *partialComparisonServiceMem) Update(pc *model.PartialComparison) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.update("partial_comparisons", pc)
+	return err
+}
+
+func (s *partialComparisonServiceMem) Delete(pc *model.PartialComparison) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.deleteRow("partial_comparisons", pc)
+	return err
+}
+
+func (s *partialComparisonServiceMem) DeleteBy(conditions string, params ...interface{}) (err error) {
+	if conditions == "" || len(params) == 0 {
+		return nil
+	}
+
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("partial_comparisons", conditions, params, "", -1, 0)
+	if err != nil {
+		return err
+	}
+
+	t := s.table()
+	ids := make([]int64, len(rows))
+	for i, v := range rows {
+		ids[i] = t.id(v)
+	}
+
+	_, err = s.store.delete("partial_comparisons", ids...)
+	return err
+}
+
+func (s *partialComparisonServiceMem) DeleteFrom(macro *model.Macro) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	t := s.table()
+	ids := make([]int64, 0)
+	for _, macroPartialId := range s.store.tables["macro_partials"].idsBy("macro_id", macro.Id) {
+		for _, id := range t.idsBy("macro_partial_id", macroPartialId) {
+			ids = append(ids, id)
+		}
+	}
+
+	_, err = s.store.delete("partial_comparisons", ids...)
+	return err
+}
+
+func (s *partialComparisonServiceMem) Get(id int64) (_ *model.PartialComparison, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("partial_comparisons", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v.Interface().(*model.PartialComparison), nil
+}
+
+func (s *partialComparisonServiceMem) GetOneBy(conditions string, params ...interface{}) (_ *model.PartialComparison, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("partial_comparisons", conditions, params, "", 1, 0)
+	if err != nil {
+		return nil, err
+	} else if len(rows) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return rows[0].Interface().(*model.PartialComparison), nil
+}
+
+func (s *partialComparisonServiceMem) ExistsBy(conditions string, params ...interface{}) (_ bool, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("partial_comparisons", conditions, params, "", 1, 0)
+	return len(rows) == 1, err
+}
+
+func (s *partialComparisonServiceMem) Count() (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.table().count(), nil
+}
+
+func (s *partialComparisonServiceMem) CountBy(conditions string, params ...interface{}) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.countRows("partial_comparisons", conditions, params)
+}
+
+func (s *partialComparisonServiceMem) FindAll(order string, limit, offset int, conditions string, params ...interface{}) (_ []*model.PartialComparison, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("partial_comparisons", conditions, params, order, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	partialComparisons := make([]*model.PartialComparison, len(rows))
+	for i, v := range rows {
+		partialComparisons[i] = v.Interface().(*model.PartialComparison)
+	}
+	return partialComparisons, nil
+}
+
+func (s *partialComparisonServiceMem) doFind(macroPartial *model.MacroPartial, gidxPartial *model.GidxPartial) (*model.PartialComparison, error) {
+	t := s.table()
+	id, ok := t.getBy(0, macroPartial.Id, gidxPartial.Id)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	v, _ := t.get(id)
+	return t.copyRow(v).Interface().(*model.PartialComparison), nil
+}
+
+func (s *partialComparisonServiceMem) Find(macroPartial *model.MacroPartial, gidxPartial *model.GidxPartial) (_ *model.PartialComparison, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.doFind(macroPartial, gidxPartial)
+}
+
+func (s *partialComparisonServiceMem) doCreate(macroPartial *model.MacroPartial, gidxPartial *model.GidxPartial) (*model.PartialComparison, error) {
+	p := model.PartialComparison{
+		MacroPartialId: macroPartial.Id,
+		GidxPartialId:  gidxPartial.Id,
+	}
+
+	dist, err := model.PixelDist(macroPartial, gidxPartial)
+	if err != nil {
+		return nil, err
+	}
+	p.Dist = dist
+
+	err = s.store.insert("partial_comparisons", &p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (s *partialComparisonServiceMem) Create(macroPartial *model.MacroPartial, gidxPartial *model.GidxPartial) (_ *model.PartialComparison, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.doCreate(macroPartial, gidxPartial)
+}
+
+func (s *partialComparisonServiceMem) FindOrCreate(macroPartial *model.MacroPartial, gidxPartial *model.GidxPartial) (_ *model.PartialComparison, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	p, err := s.doFind(macroPartial, gidxPartial)
+	if err == nil {
+		return p, nil
+	}
+
+	// or create
+	return s.doCreate(macroPartial, gidxPartial)
+}
+
+// oriented is true when the macro with macroId is oriented
+func (s *partialComparisonServiceMem) oriented(macroId int64) bool {
+	m, ok := s.store.tables["macros"].get(macroId)
+	return ok && m.Interface().(*model.Macro).Oriented
+}
+
+// orientedIds returns the ids of the index partials of aspectId with
+// an index image of the same orientation as the aspect
+func (s *partialComparisonServiceMem) orientedIds(aspectId int64, ids []int64) []int64 {
+	gidxPartials := s.store.tables["gidx_partials"]
+
+	a, ok := s.store.tables["aspects"].get(aspectId)
+	if !ok {
+		return ids
+	}
+	aspect := a.Interface().(*model.Aspect)
+
+	oriented := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		gp, _ := gidxPartials.get(id)
+		g, ok := s.store.tables["gidx"].get(gidxPartials.int(gp, "gidx_id"))
+		if ok && g.Interface().(*model.Gidx).SameOrientation(aspect) {
+			oriented = append(oriented, id)
+		}
+	}
+	return oriented
+}
+
+// uncompared calls fn with the index partials of the aspect of macro
+// partial v that it has not been compared with, in order of id, for those
+// after its pruned_through when after is true, or through it otherwise.
+// When oriented, only index partials of index images with the same
+// orientation as the aspect are included. It stops when fn returns false.
+func (s *partialComparisonServiceMem) uncompared(v reflect.Value, after, oriented bool, gidxPartialIds map[int64][]int64, fn func(reflect.Value) bool) bool {
+	t := s.table()
+	macroPartials := s.store.tables["macro_partials"]
+	gidxPartials := s.store.tables["gidx_partials"]
+
+	id := macroPartials.id(v)
+	aspectId := macroPartials.int(v, "aspect_id")
+	prunedThrough := macroPartials.int(v, "pruned_through")
+
+	ids, ok := gidxPartialIds[aspectId]
+	if !ok {
+		ids = gidxPartials.idsBy("aspect_id", aspectId)
+		if oriented {
+			ids = s.orientedIds(aspectId, ids)
+		}
+		gidxPartialIds[aspectId] = ids
+	}
+
+	for _, gidxPartialId := range ids {
+		if (gidxPartialId > prunedThrough) != after {
+			continue
+		}
+		if _, ok := t.getBy(0, id, gidxPartialId); ok {
+			continue
+		}
+		gp, _ := gidxPartials.get(gidxPartialId)
+		if !fn(gp) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// macroGidxView returns a view of macro partial mp and index partial gp
+// with the columns that comparing them needs
+func (s *partialComparisonServiceMem) macroGidxView(mp, gp reflect.Value) *model.MacroGidxView {
+	macroPartial := mp.Elem().Interface().(model.MacroPartial)
+	gidxPartial := gp.Elem().Interface().(model.GidxPartial)
+
+	return &model.MacroGidxView{
+		MacroPartial: &model.MacroPartial{
+			Id:             macroPartial.Id,
+			MacroId:        macroPartial.MacroId,
+			CoverPartialId: macroPartial.CoverPartialId,
+			AspectId:       macroPartial.AspectId,
+			Data:           macroPartial.Data,
+		},
+		GidxPartial: &model.GidxPartial{
+			Id:       gidxPartial.Id,
+			GidxId:   gidxPartial.GidxId,
+			AspectId: macroPartial.AspectId,
+			Data:     gidxPartial.Data,
+		},
+	}
+}
+
+func (s *partialComparisonServiceMem) CountMissing(macro *model.Macro) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	macroPartials := s.store.tables["macro_partials"]
+	gidxPartialIds := make(map[int64][]int64)
+	oriented := s.oriented(macro.Id)
+
+	var count int64
+	for _, id := range macroPartials.idsBy("macro_id", macro.Id) {
+		mp, _ := macroPartials.get(id)
+		s.uncompared(mp, true, oriented, gidxPartialIds, func(reflect.Value) bool {
+			count++
+			return true
+		})
+	}
+
+	return count, nil
+}
+
+func (s *partialComparisonServiceMem) FindMissing(macro *model.Macro, limit int) (_ []*model.MacroGidxView, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	macroPartials := s.store.tables["macro_partials"]
+	gidxPartialIds := make(map[int64][]int64)
+	oriented := s.oriented(macro.Id)
+
+	macroGidxViews := make([]*model.MacroGidxView, 0)
+	if limit == 0 {
+		return macroGidxViews, nil
+	}
+
+	for _, id := range macroPartials.idsBy("macro_id", macro.Id) {
+		mp, _ := macroPartials.get(id)
+		more := s.uncompared(mp, true, oriented, gidxPartialIds, func(gp reflect.Value) bool {
+			macroGidxViews = append(macroGidxViews, s.macroGidxView(mp, gp))
+			return limit < 0 || len(macroGidxViews) < limit
+		})
+		if !more {
+			break
+		}
+	}
+
+	return macroGidxViews, nil
+}
+
+func (s *partialComparisonServiceMem) FindPruned(macroPartial *model.MacroPartial) (_ []*model.MacroGidxView, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	macroGidxViews := make([]*model.MacroGidxView, 0)
+	mp, ok := s.store.tables["macro_partials"].get(macroPartial.Id)
+	if !ok {
+		return macroGidxViews, nil
+	}
+
+	oriented := s.oriented(s.store.tables["macro_partials"].int(mp, "macro_id"))
+	s.uncompared(mp, false, oriented, make(map[int64][]int64), func(gp reflect.Value) bool {
+		macroGidxViews = append(macroGidxViews, s.macroGidxView(mp, gp))
+		return true
+	})
+
+	return macroGidxViews, nil
+}
+
+// closest returns the ids of the comparisons of macro partial id,
+// by ascending distance
+func (s *partialComparisonServiceMem) closest(id int64) []int64 {
+	return s.table().idsBy("macro_partial_id", id)
+}
+
+func (s *partialComparisonServiceMem) KeepTop(macroPartial *model.MacroPartial, keep int) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	t := s.table()
+	macroPartials := s.store.tables["macro_partials"]
+
+	mp, ok := macroPartials.get(macroPartial.Id)
+	if !ok {
+		return int64(0), nil
+	}
+
+	// comparisons are made in order of index partial id, so every
+	// index partial up to the last one compared has been compared
+	prunedThrough := macroPartials.int(mp, "pruned_through")
+	for _, id := range t.idsBy("macro_partial_id", macroPartial.Id) {
+		v, _ := t.get(id)
+		if gidxPartialId := t.int(v, "gidx_partial_id"); gidxPartialId > prunedThrough {
+			prunedThrough = gidxPartialId
+		}
+	}
+	if prunedThrough != macroPartials.int(mp, "pruned_through") {
+		mp = macroPartials.copyRow(mp)
+		macroPartials.setInt(mp, "pruned_through", prunedThrough)
+		_, err := s.store.update("macro_partials", mp.Interface())
+		if err != nil {
+			return int64(0), err
+		}
+	}
+
+	ids := s.closest(macroPartial.Id)
+	if keep >= 0 && keep < len(ids) {
+		return s.store.delete("partial_comparisons", ids[keep:]...)
+	}
+
+	return int64(0), nil
+}
+
+func (s *partialComparisonServiceMem) CreateFromView(view *model.MacroGidxView) (_ *model.PartialComparison, err error) {
+	pc, err := view.PartialComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	err = s.store.insert("partial_comparisons", pc)
+	if err != nil {
+		return nil, err
+	}
+
+	return pc, nil
+}
+
+// uses returns the number of times each index partial is used by mosaic
+func (s *partialComparisonServiceMem) uses(mosaicId int64) map[int64]int {
+	mosaicPartials := s.store.tables["mosaic_partials"]
+
+	uses := make(map[int64]int)
+	for _, id := range mosaicPartials.idsBy("mosaic_id", mosaicId) {
+		v, _ := mosaicPartials.get(id)
+		uses[mosaicPartials.int(v, "gidx_partial_id")]++
+	}
+
+	return uses
+}
+
+// best returns the closest comparison of the macro partials with ids.
+// When limit is true, the index partial of the comparison must be used
+// by mosaic fewer than maxRepeats times, or not at all.
+func (s *partialComparisonServiceMem) best(ids []int64, mosaicId int64, limit bool, maxRepeats int) (reflect.Value, bool) {
+	t := s.table()
+
+	var uses map[int64]int
+	if limit {
+		uses = s.uses(mosaicId)
+	}
+
+	var best reflect.Value
+	var bestDist float64
+	var bestId int64
+	found := false
+	for _, macroPartialId := range ids {
+		// the first comparison that can be used is the
+		// closest of the macro partial
+		for _, id := range s.closest(macroPartialId) {
+			v, _ := t.get(id)
+			if n := uses[t.int(v, "gidx_partial_id")]; n > 0 && n >= maxRepeats {
+				continue
+			}
+			dist := t.float(v, "dist")
+			if !found || dist < bestDist || (dist == bestDist && id < bestId) {
+				best, bestDist, bestId, found = v, dist, id, true
+			}
+			break
+		}
+	}
+
+	if !found {
+		return reflect.Value{}, false
+	}
+	return t.copyRow(best), true
+}
+
+func (s *partialComparisonServiceMem) GetClosest(macroPartial *model.MacroPartial) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok := s.best([]int64{macroPartial.Id}, int64(0), false, 0)
+	if !ok {
+		return 0, nil
+	}
+	return s.table().int(v, "gidx_partial_id"), nil
+}
+
+func (s *partialComparisonServiceMem) GetClosestMax(macroPartial *model.MacroPartial, mosaic *model.Mosaic, maxRepeats int) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok := s.best([]int64{macroPartial.Id}, mosaic.Id, true, maxRepeats)
+	if !ok {
+		return 0, nil
+	}
+	return s.table().int(v, "gidx_partial_id"), nil
+}
+
+func (s *partialComparisonServiceMem) GetBestAvailable(mosaic *model.Mosaic) (_ *model.PartialComparison, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok := s.best(s.store.missingMacroPartials(mosaic), mosaic.Id, false, 0)
+	if !ok {
+		return nil, nil
+	}
+	return v.Interface().(*model.PartialComparison), nil
+}
+
+func (s *partialComparisonServiceMem) GetBestAvailableMax(mosaic *model.Mosaic, maxRepeats int) (_ *model.PartialComparison, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok := s.best(s.store.missingMacroPartials(mosaic), mosaic.Id, true, maxRepeats)
+	if !ok {
+		return nil, nil
+	}
+	return v.Interface().(*model.PartialComparison), nil
+}
@@ -0,0 +1,97 @@
+package mem
+
+import (
+	"time"
+
+	"github.com/atongen/gosaic/model"
+)
+
+type projectServiceMem struct {
+	store *Store
+}
+
+func NewProjectService(store *Store) *projectServiceMem {
+	return &projectServiceMem{store: store}
+}
+
+func (s *projectServiceMem) Register() error {
+	return nil
+}
+
+func (s *projectServiceMem) Close() error {
+	return nil
+}
+
+func (s *projectServiceMem) Get(id int64) (_ *model.Project, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("projects", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v.Interface().(*model.Project), nil
+}
+
+func (s *projectServiceMem) Insert(project *model.Project) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	if project.CreatedAt.IsZero() {
+		project.CreatedAt = time.Now()
+	}
+	return s.store.insert("projects", project)
+}
+
+func (s *projectServiceMem) Update(project *model.Project) (_ int64, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.update("projects", project)
+}
+
+func (s *projectServiceMem) Delete(project *model.Project) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	_, err = s.store.deleteRow("projects", project)
+	return err
+}
+
+func (s *projectServiceMem) GetOneBy(conditions string, params ...interface{}) (_ *model.Project, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("projects", conditions, params, "", 1, 0)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+	return rows[0].Interface().(*model.Project), nil
+}
+
+func (s *projectServiceMem) ExistsBy(conditions string, params ...interface{}) (_ bool, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("projects", conditions, params, "", 1, 0)
+	if err != nil {
+		return false, err
+	}
+	return len(rows) == 1, nil
+}
+
+func (s *projectServiceMem) FindAll(order string) (_ []*model.Project, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	rows, err := s.store.selectRows("projects", "", nil, order, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := make([]*model.Project, len(rows))
+	for i, v := range rows {
+		projects[i] = v.Interface().(*model.Project)
+	}
+	return projects, nil
+}
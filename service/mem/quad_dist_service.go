@@ -0,0 +1,78 @@
+package mem
+
+import (
+	"github.com/atongen/gosaic/model"
+)
+
+type quadDistServiceMem struct {
+	store *Store
+}
+
+func NewQuadDistService(store *Store) *quadDistServiceMem {
+	return &quadDistServiceMem{store: store}
+}
+
+func (s *quadDistServiceMem) Register() error {
+	return nil
+}
+
+func (s *quadDistServiceMem) Close() error {
+	return nil
+}
+
+func (s *quadDistServiceMem) Get(id int64) (_ *model.QuadDist, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	v, ok, err := s.store.getRow("quad_dists", id)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return v.Interface().(*model.QuadDist), nil
+}
+
+func (s *quadDistServiceMem) Insert(pc *model.QuadDist) (err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	return s.store.insert("quad_dists", pc)
+}
+
+func (s *quadDistServiceMem) GetWorst(macro *model.Macro, depth, area int) (_ *model.CoverPartialQuadView, err error) {
+	s.store.lock()
+	defer s.store.unlock(&err)
+
+	quadDists := s.store.tables["quad_dists"]
+	macroPartials := s.store.tables["macro_partials"]
+	coverPartials := s.store.tables["cover_partials"]
+
+	var worst *model.CoverPartialQuadView
+	for _, id := range macroPartials.idsBy("macro_id", macro.Id) {
+		mp, _ := macroPartials.get(id)
+		qdId, ok := quadDists.getBy(0, id)
+		if !ok {
+			continue
+		}
+
+		qd, _ := quadDists.get(qdId)
+		quadDist := quadDists.copyRow(qd).Interface().(*model.QuadDist)
+		if depth > 0 && quadDist.Depth > depth {
+			continue
+		}
+		if area > 0 && quadDist.Area < area {
+			continue
+		}
+		if worst != nil && (quadDist.Dist < worst.QuadDist.Dist ||
+			(quadDist.Dist == worst.QuadDist.Dist && quadDist.Id > worst.QuadDist.Id)) {
+			continue
+		}
+
+		cp, _ := coverPartials.get(macroPartials.int(mp, "cover_partial_id"))
+		worst = &model.CoverPartialQuadView{
+			CoverPartial: coverPartials.copyRow(cp).Interface().(*model.CoverPartial),
+			QuadDist:     quadDist,
+		}
+	}
+
+	return worst, nil
+}
@@ -0,0 +1,142 @@
+package mem
+
+import (
+	"reflect"
+	"sort"
+)
+
+// memRows keeps the rows of a table, and its indexes, in maps
+type memRows struct {
+	t       *Table
+	rows    map[int64]reflect.Value
+	last    int64
+	unique  []map[string]int64
+	indexes map[string]map[int64]map[int64]bool
+}
+
+func newMemRows(t *Table) Rows {
+	r := &memRows{
+		t:       t,
+		rows:    make(map[int64]reflect.Value),
+		unique:  make([]map[string]int64, len(t.uniques)),
+		indexes: make(map[string]map[int64]map[int64]bool),
+	}
+
+	for i := range t.uniques {
+		r.unique[i] = make(map[string]int64)
+	}
+
+	for column := range t.indexes {
+		r.indexes[column] = make(map[int64]map[int64]bool)
+	}
+
+	return r
+}
+
+func (r *memRows) Get(id int64) (reflect.Value, bool) {
+	v, ok := r.rows[id]
+	return v, ok
+}
+
+func (r *memRows) Ids() []int64 {
+	ids := make([]int64, 0, len(r.rows))
+	for id := range r.rows {
+		ids = append(ids, id)
+	}
+	sortIds(ids)
+	return ids
+}
+
+func (r *memRows) IdsAfter(id int64, limit int) []int64 {
+	ids := make([]int64, 0)
+	for rowId := range r.rows {
+		if rowId > id {
+			ids = append(ids, rowId)
+		}
+	}
+	sortIds(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+func (r *memRows) Count() int64 {
+	return int64(len(r.rows))
+}
+
+func (r *memRows) LastId() int64 {
+	return r.last
+}
+
+func (r *memRows) IdsBy(column string, value int64) []int64 {
+	ids := sortedIds(r.indexes[column][value])
+
+	order := r.t.indexes[column]
+	if order == "" {
+		return ids
+	}
+
+	keys := make(map[int64]interface{}, len(ids))
+	for _, id := range ids {
+		keys[id], _ = r.t.value(r.rows[id], order)
+	}
+	sort.SliceStable(ids, func(i, j int) bool {
+		return compare(keys[ids[i]], keys[ids[j]]) < 0
+	})
+
+	return ids
+}
+
+func (r *memRows) CountBy(column string, value int64) int64 {
+	return int64(len(r.indexes[column][value]))
+}
+
+func (r *memRows) GetBy(i int, key string) (int64, bool) {
+	id, ok := r.unique[i][key]
+	return id, ok
+}
+
+func (r *memRows) Put(id int64, row reflect.Value) {
+	if old, ok := r.rows[id]; ok {
+		r.removeIndexes(id, old)
+	}
+
+	r.rows[id] = row
+	if id > r.last {
+		r.last = id
+	}
+
+	for i, columns := range r.t.uniques {
+		r.unique[i][r.t.uniqueKey(row, columns)] = id
+	}
+	for column, index := range r.indexes {
+		value := r.t.int(row, column)
+		set, ok := index[value]
+		if !ok {
+			set = make(map[int64]bool)
+			index[value] = set
+		}
+		set[id] = true
+	}
+}
+
+func (r *memRows) Remove(id int64) {
+	if v, ok := r.rows[id]; ok {
+		r.removeIndexes(id, v)
+		delete(r.rows, id)
+	}
+}
+
+func (r *memRows) removeIndexes(id int64, v reflect.Value) {
+	for i, columns := range r.t.uniques {
+		delete(r.unique[i], r.t.uniqueKey(v, columns))
+	}
+	for column, index := range r.indexes {
+		value := r.t.int(v, column)
+		delete(index[value], id)
+		if len(index[value]) == 0 {
+			delete(index, value)
+		}
+	}
+}
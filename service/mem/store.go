@@ -0,0 +1,761 @@
+package mem
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/atongen/gosaic/model"
+)
+
+var (
+	errForeignKey = errors.New("FOREIGN KEY constraint failed")
+)
+
+// Store holds the tables of a database, shared by the services of a mem
+// or bolt service factory. Its tables follow the sqlite3 schema: rows are
+// copies of the models, unique indexes and checks are enforced, and
+// deleting a row cascades along its foreign keys. Unlike sqlite3, ids are
+// never reused after a row is deleted.
+type Store struct {
+	m      sync.Mutex
+	tables map[string]*Table
+	// backend is where the rows are stored,
+	// or nil when they are kept in memory
+	backend Backend
+}
+
+type foreignKey struct {
+	column  string
+	table   string
+	cascade bool
+}
+
+// Table is a table of a store, which a backend keeps the rows of
+type Table struct {
+	name    string
+	typ     reflect.Type
+	fields  map[string]int
+	columns []string
+	refs    []foreignKey
+	uniques [][]string
+	// indexes are the indexed columns, each with the column the ids
+	// with a value are ordered by, or "" when they are ordered by id
+	indexes map[string]string
+	check   func(reflect.Value) bool
+	rows    Rows
+}
+
+// Rows stores the rows of a table, with its unique indexes and indexes.
+// Rows returned by Get must not be modified.
+type Rows interface {
+	Get(id int64) (reflect.Value, bool)
+	// Ids returns the ids of the rows, in ascending order
+	Ids() []int64
+	// IdsAfter returns up to limit ids greater than id, in ascending order
+	IdsAfter(id int64, limit int) []int64
+	Count() int64
+	// LastId returns the greatest id that a row has ever had
+	LastId() int64
+	// IdsBy returns the ids of the rows with value in the indexed
+	// column, in the order of the index
+	IdsBy(column string, value int64) []int64
+	CountBy(column string, value int64) int64
+	// GetBy returns the id of the row with key in unique index i
+	GetBy(i int, key string) (int64, bool)
+	Put(id int64, row reflect.Value)
+	Remove(id int64)
+}
+
+// NewStore returns an empty store with a table for each model,
+// that keeps its rows in memory
+func NewStore() *Store {
+	return newStore(newMemRows)
+}
+
+func newStore(newRows func(*Table) Rows) *Store {
+	s := &Store{tables: make(map[string]*Table)}
+
+	s.addTable("aspects", model.Aspect{}, nil,
+		[][]string{{"columns", "rows"}},
+		func(v reflect.Value) bool {
+			return v.FieldByName("Columns").Int() > 0 && v.FieldByName("Rows").Int() > 0
+		})
+	s.addTable("gidx", model.Gidx{},
+		[]foreignKey{{"aspect_id", "aspects", false}},
+		[][]string{{"md5sum"}},
+		checkImage)
+	s.addTable("gidx_partials", model.GidxPartial{},
+		[]foreignKey{{"gidx_id", "gidx", true}, {"aspect_id", "aspects", false}},
+		[][]string{{"gidx_id", "aspect_id"}},
+		checkData)
+	s.addTable("covers", model.Cover{},
+		[]foreignKey{{"aspect_id", "aspects", false}},
+		nil, nil)
+	s.addTable("cover_partials", model.CoverPartial{},
+		[]foreignKey{{"cover_id", "covers", true}, {"aspect_id", "aspects", false}},
+		nil, nil)
+	s.addTable("macros", model.Macro{},
+		[]foreignKey{{"aspect_id", "aspects", false}, {"cover_id", "covers", true}},
+		[][]string{{"cover_id", "md5sum", "grout", "bleed"}},
+		checkImage)
+	s.addTable("macro_partials", model.MacroPartial{},
+		[]foreignKey{{"macro_id", "macros", true}, {"cover_partial_id", "cover_partials", true}, {"aspect_id", "aspects", false}},
+		[][]string{{"macro_id", "cover_partial_id"}},
+		checkData)
+	s.addTable("partial_comparisons", model.PartialComparison{},
+		[]foreignKey{{"macro_partial_id", "macro_partials", true}, {"gidx_partial_id", "gidx_partials", true}},
+		[][]string{{"macro_partial_id", "gidx_partial_id"}},
+		nil)
+	s.addTable("mosaics", model.Mosaic{},
+		[]foreignKey{{"macro_id", "macros", true}},
+		nil, nil)
+	s.addTable("mosaic_partials", model.MosaicPartial{},
+		[]foreignKey{{"mosaic_id", "mosaics", true}, {"macro_partial_id", "macro_partials", true}, {"gidx_partial_id", "gidx_partials", true}},
+		[][]string{{"mosaic_id", "macro_partial_id"}},
+		nil)
+	s.addTable("quad_dists", model.QuadDist{},
+		[]foreignKey{{"macro_partial_id", "macro_partials", true}},
+		[][]string{{"macro_partial_id"}},
+		nil)
+	s.addTable("projects", model.Project{}, nil,
+		[][]string{{"name"}},
+		nil)
+
+	// comparisons of a macro partial are found closest first
+	s.tables["partial_comparisons"].indexes["macro_partial_id"] = "dist"
+
+	for _, t := range s.tables {
+		t.rows = newRows(t)
+	}
+
+	return s
+}
+
+func checkImage(v reflect.Value) bool {
+	return v.FieldByName("Path").String() != "" && len(v.FieldByName("Md5sum").String()) == 32
+}
+
+func checkData(v reflect.Value) bool {
+	return v.FieldByName("Data").Len() > 0
+}
+
+func (s *Store) addTable(name string, row interface{}, refs []foreignKey, uniques [][]string, check func(reflect.Value) bool) {
+	t := &Table{
+		name:    name,
+		typ:     reflect.TypeOf(row),
+		fields:  make(map[string]int),
+		refs:    refs,
+		uniques: uniques,
+		indexes: make(map[string]string),
+		check:   check,
+	}
+
+	for i := 0; i < t.typ.NumField(); i++ {
+		column := t.typ.Field(i).Tag.Get("db")
+		if column == "" || column == "-" {
+			continue
+		}
+		t.fields[column] = i
+		t.columns = append(t.columns, column)
+	}
+
+	// every foreign key is indexed, to find the rows
+	// a delete cascades to
+	for _, ref := range refs {
+		t.indexes[ref.column] = ""
+	}
+
+	s.tables[name] = t
+}
+
+func (s *Store) table(name string) (*Table, error) {
+	t, ok := s.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("no such table: %s", name)
+	}
+	return t, nil
+}
+
+// copyRow returns a pointer to a copy of the struct v points to,
+// without the fields that are not stored
+func (t *Table) copyRow(v reflect.Value) reflect.Value {
+	c := reflect.New(t.typ)
+	c.Elem().Set(v.Elem())
+	for i := 0; i < t.typ.NumField(); i++ {
+		if t.typ.Field(i).Tag.Get("db") == "-" {
+			f := c.Elem().Field(i)
+			f.Set(reflect.Zero(f.Type()))
+		}
+	}
+	if i, ok := t.fields["data"]; ok {
+		f := c.Elem().Field(i)
+		f.SetBytes(append([]byte(nil), f.Bytes()...))
+	}
+	return c
+}
+
+func (t *Table) id(v reflect.Value) int64 {
+	return v.Elem().Field(t.fields["id"]).Int()
+}
+
+func (t *Table) int(v reflect.Value, column string) int64 {
+	return v.Elem().Field(t.fields[column]).Int()
+}
+
+func (t *Table) float(v reflect.Value, column string) float64 {
+	return v.Elem().Field(t.fields[column]).Float()
+}
+
+func (t *Table) setInt(v reflect.Value, column string, value int64) {
+	v.Elem().Field(t.fields[column]).SetInt(value)
+}
+
+// value returns the value of column of row v, with integers as int64,
+// floats as float64 and blobs as strings
+func (t *Table) value(v reflect.Value, column string) (interface{}, error) {
+	i, ok := t.fields[column]
+	if !ok {
+		return nil, fmt.Errorf("no such column: %s", column)
+	}
+	return normalize(v.Elem().Field(i).Interface()), nil
+}
+
+func (t *Table) uniqueKey(v reflect.Value, columns []string) string {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i], _ = t.value(v, column)
+	}
+	return uniqueKey(values...)
+}
+
+// uniqueKey returns the key of values in a unique index
+func uniqueKey(values ...interface{}) string {
+	var b bytes.Buffer
+	for _, val := range values {
+		fmt.Fprintf(&b, "%v\x00", normalize(val))
+	}
+	return b.String()
+}
+
+// get returns the row of table with id, or false if there is none
+func (t *Table) get(id int64) (reflect.Value, bool) {
+	return t.rows.Get(id)
+}
+
+// getBy returns the id of the row with values of the columns of
+// unique index i, or false if there is none
+func (t *Table) getBy(i int, values ...interface{}) (int64, bool) {
+	return t.rows.GetBy(i, uniqueKey(values...))
+}
+
+// ids returns the ids of the rows of table, in ascending order
+func (t *Table) ids() []int64 {
+	return t.rows.Ids()
+}
+
+// idsBy returns the ids of the rows with value in the indexed column,
+// in the order of the index
+func (t *Table) idsBy(column string, value int64) []int64 {
+	return t.rows.IdsBy(column, value)
+}
+
+// candidates returns the ids of the rows that can have the values of
+// equals, using the unique indexes and indexes of t when possible,
+// in ascending order
+func (t *Table) candidates(equals map[string]interface{}) []int64 {
+	if value, ok := equals["id"].(int64); ok {
+		if _, ok := t.get(value); ok {
+			return []int64{value}
+		}
+		return []int64{}
+	}
+
+	for i, columns := range t.uniques {
+		values := make([]interface{}, 0, len(columns))
+		for _, column := range columns {
+			if value, ok := equals[column]; ok {
+				values = append(values, value)
+			}
+		}
+		if len(values) == len(columns) {
+			if id, ok := t.getBy(i, values...); ok {
+				return []int64{id}
+			}
+			return []int64{}
+		}
+	}
+
+	for column, order := range t.indexes {
+		if value, ok := equals[column].(int64); ok {
+			ids := t.idsBy(column, value)
+			if order != "" {
+				sortIds(ids)
+			}
+			return ids
+		}
+	}
+
+	return t.ids()
+}
+
+// count returns the number of rows of table
+func (t *Table) count() int64 {
+	return t.rows.Count()
+}
+
+func (t *Table) countBy(column string, value int64) int64 {
+	return t.rows.CountBy(column, value)
+}
+
+// sortedIds returns the ids of set in ascending order
+func sortedIds(set map[int64]bool) []int64 {
+	ids := make([]int64, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sortIds(ids)
+	return ids
+}
+
+func sortIds(ids []int64) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
+
+// validate checks that row v with id can be stored in table
+func (s *Store) validate(t *Table, id int64, v reflect.Value) error {
+	if t.check != nil && !t.check(v.Elem()) {
+		return fmt.Errorf("CHECK constraint failed: %s", t.name)
+	}
+
+	for i, columns := range t.uniques {
+		if other, ok := t.rows.GetBy(i, t.uniqueKey(v, columns)); ok && other != id {
+			qualified := make([]string, len(columns))
+			for j, column := range columns {
+				qualified[j] = t.name + "." + column
+			}
+			return fmt.Errorf("UNIQUE constraint failed: %s", strings.Join(qualified, ", "))
+		}
+	}
+
+	for _, ref := range t.refs {
+		if _, ok := s.tables[ref.table].get(t.int(v, ref.column)); !ok {
+			return errForeignKey
+		}
+	}
+
+	return nil
+}
+
+// lock locks the store for a service method, which must unlock it
+func (s *Store) lock() {
+	s.m.Lock()
+}
+
+// unlock ends the transaction of the backend of the store, and unlocks
+// the store. It sets err, the error of the service method that locked the
+// store, to the first error reading or writing the backend, unless the
+// method is already returning an error.
+func (s *Store) unlock(err *error) {
+	if s.backend != nil {
+		if e := s.backend.End(); e != nil && *err == nil {
+			*err = e
+		}
+	}
+	s.m.Unlock()
+}
+
+// write calls fn, which changes rows, in a writable transaction of the
+// backend of the store. The changes are committed when fn returns nil.
+func (s *Store) write(fn func() error) error {
+	if s.backend == nil {
+		return fn()
+	}
+	return s.backend.Update(fn)
+}
+
+// rowValue returns the value of ptr, a pointer to a row of table
+func (t *Table) rowValue(ptr interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.Elem().Type() != t.typ {
+		return reflect.Value{}, fmt.Errorf("Invalid %s row: %T", t.name, ptr)
+	}
+	return v, nil
+}
+
+// insert stores a copy of the row that ptr points to with the next id
+// of table, and sets the id of the row
+func (s *Store) insert(name string, ptr interface{}) error {
+	t, err := s.table(name)
+	if err != nil {
+		return err
+	}
+
+	v, err := t.rowValue(ptr)
+	if err != nil {
+		return err
+	}
+
+	return s.write(func() error {
+		id := t.rows.LastId() + 1
+		err := s.validate(t, id, v)
+		if err != nil {
+			return err
+		}
+
+		v.Elem().Field(t.fields["id"]).SetInt(id)
+		t.rows.Put(id, t.copyRow(v))
+		return nil
+	})
+}
+
+// insertAll stores copies of the rows of the slice of pointers,
+// leaving their ids unset. Either all rows are inserted, or none.
+func (s *Store) insertAll(name string, slice interface{}) (int64, error) {
+	t, err := s.table(name)
+	if err != nil {
+		return int64(0), err
+	}
+
+	rows := reflect.ValueOf(slice)
+	err = s.write(func() error {
+		inserted := make([]int64, 0, rows.Len())
+		for i := 0; i < rows.Len(); i++ {
+			row := t.copyRow(rows.Index(i))
+			err := s.insert(name, row.Interface())
+			if err != nil {
+				for _, id := range inserted {
+					t.rows.Remove(id)
+				}
+				return err
+			}
+			inserted = append(inserted, t.id(row))
+		}
+		return nil
+	})
+	if err != nil {
+		return int64(0), err
+	}
+
+	return int64(rows.Len()), nil
+}
+
+// importRows stores copies of the rows of the slice of pointers with
+// their ids, which must not be in use. Either all rows are stored, or none.
+func (s *Store) importRows(name string, slice interface{}) (int64, error) {
+	t, err := s.table(name)
+	if err != nil {
+		return int64(0), err
+	}
+
+	rows := reflect.ValueOf(slice)
+	err = s.write(func() error {
+		imported := make([]int64, 0, rows.Len())
+		for i := 0; i < rows.Len(); i++ {
+			v, err := t.rowValue(rows.Index(i).Interface())
+			if err == nil {
+				err = s.importRow(t, v)
+			}
+			if err != nil {
+				for _, id := range imported {
+					t.rows.Remove(id)
+				}
+				return err
+			}
+			imported = append(imported, t.id(v))
+		}
+		return nil
+	})
+	if err != nil {
+		return int64(0), err
+	}
+
+	return int64(rows.Len()), nil
+}
+
+func (s *Store) importRow(t *Table, v reflect.Value) error {
+	id := t.id(v)
+	if id <= 0 {
+		return fmt.Errorf("Invalid %s id: %d", t.name, id)
+	}
+	if _, ok := t.get(id); ok {
+		return fmt.Errorf("UNIQUE constraint failed: %s.id", t.name)
+	}
+
+	err := s.validate(t, id, v)
+	if err != nil {
+		return err
+	}
+
+	t.rows.Put(id, t.copyRow(v))
+	return nil
+}
+
+// update replaces the row of table with the id of the row ptr points to,
+// and returns the number of rows updated
+func (s *Store) update(name string, ptr interface{}) (int64, error) {
+	t, err := s.table(name)
+	if err != nil {
+		return int64(0), err
+	}
+
+	v, err := t.rowValue(ptr)
+	if err != nil {
+		return int64(0), err
+	}
+
+	id := t.id(v)
+	if _, ok := t.get(id); !ok {
+		return int64(0), nil
+	}
+
+	err = s.write(func() error {
+		err := s.validate(t, id, v)
+		if err != nil {
+			return err
+		}
+
+		t.rows.Put(id, t.copyRow(v))
+		return nil
+	})
+	if err != nil {
+		return int64(0), err
+	}
+
+	return int64(1), nil
+}
+
+// delete deletes the rows of table with ids, and the rows that reference
+// them with a cascading foreign key. Nothing is deleted if a row is
+// referenced with a restricting foreign key. It returns the number of rows
+// of table deleted.
+func (s *Store) delete(name string, ids ...int64) (int64, error) {
+	t, err := s.table(name)
+	if err != nil {
+		return int64(0), err
+	}
+
+	deleting := make(map[string]map[int64]bool)
+	var num int64
+	for _, id := range ids {
+		if _, ok := t.get(id); ok {
+			if !deleting[name][id] {
+				num++
+			}
+			s.cascade(name, id, deleting)
+		}
+	}
+
+	for refName, refIds := range deleting {
+		for _, ref := range s.refsTo(refName) {
+			if ref.cascade {
+				continue
+			}
+			refTable := s.tables[ref.table]
+			for id := range refIds {
+				for _, refId := range refTable.idsBy(ref.column, id) {
+					if !deleting[ref.table][refId] {
+						return int64(0), errForeignKey
+					}
+				}
+			}
+		}
+	}
+
+	if len(deleting) == 0 {
+		return num, nil
+	}
+
+	err = s.write(func() error {
+		for delName, delIds := range deleting {
+			delTable := s.tables[delName]
+			for id := range delIds {
+				delTable.rows.Remove(id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return int64(0), err
+	}
+
+	return num, nil
+}
+
+// cascade adds the row of table with id, and the rows that reference it
+// with a cascading foreign key, to deleting
+func (s *Store) cascade(name string, id int64, deleting map[string]map[int64]bool) {
+	if deleting[name][id] {
+		return
+	}
+	if deleting[name] == nil {
+		deleting[name] = make(map[int64]bool)
+	}
+	deleting[name][id] = true
+
+	for _, ref := range s.refsTo(name) {
+		if !ref.cascade {
+			continue
+		}
+		for _, refId := range s.tables[ref.table].idsBy(ref.column, id) {
+			s.cascade(ref.table, refId, deleting)
+		}
+	}
+}
+
+// refsTo returns the foreign keys that reference table, with the table
+// of each set to the referencing table
+func (s *Store) refsTo(name string) []foreignKey {
+	refs := make([]foreignKey, 0)
+	for _, t := range s.tables {
+		for _, ref := range t.refs {
+			if ref.table == name {
+				refs = append(refs, foreignKey{ref.column, t.name, ref.cascade})
+			}
+		}
+	}
+	return refs
+}
+
+// deleteRow deletes the row of table with the id of the row ptr points to
+func (s *Store) deleteRow(name string, ptr interface{}) (int64, error) {
+	t, err := s.table(name)
+	if err != nil {
+		return int64(0), err
+	}
+	return s.delete(name, t.id(reflect.ValueOf(ptr)))
+}
+
+// selectRows returns the rows of table that match conditions, sorted by
+// order, with the offset and limit applied. A negative limit returns all rows.
+func (s *Store) selectRows(name, conditions string, params []interface{}, order string, limit, offset int) ([]reflect.Value, error) {
+	t, err := s.table(name)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := parseWhere(conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := t.candidates(w.equals(t, params))
+
+	// validate the conditions against a row, even when there are no rows
+	err = w.validate(t.getter(reflect.New(t.typ)), params)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]reflect.Value, 0)
+	for _, id := range ids {
+		v, _ := t.get(id)
+		ok, err := w.match(t.getter(v), params)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rows = append(rows, v)
+		}
+	}
+
+	getters := make([]getter, len(rows))
+	for i, v := range rows {
+		getters[i] = t.getter(v)
+	}
+	perm, err := sortRows(order, getters)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]reflect.Value, 0)
+	for _, p := range page(perm, limit, offset) {
+		result = append(result, t.copyRow(rows[p]))
+	}
+
+	return result, nil
+}
+
+// getter returns the values of the columns of row v
+func (t *Table) getter(v reflect.Value) getter {
+	return func(qualifier, column string) (interface{}, error) {
+		if qualifier != "" && qualifier != t.name {
+			return nil, fmt.Errorf("no such column: %s.%s", qualifier, column)
+		}
+		return t.value(v, column)
+	}
+}
+
+// joinGetter returns the values of the columns of a join of rows, by the
+// names of their tables, and of the aliases of the result columns
+func (s *Store) joinGetter(rows map[string]reflect.Value, aliases map[string]interface{}) getter {
+	return func(qualifier, column string) (interface{}, error) {
+		if qualifier != "" {
+			v, ok := rows[qualifier]
+			if !ok {
+				return nil, fmt.Errorf("no such column: %s.%s", qualifier, column)
+			}
+			return s.tables[qualifier].value(v, column)
+		}
+
+		if value, ok := aliases[column]; ok {
+			return value, nil
+		}
+
+		var value interface{}
+		found := false
+		for name, v := range rows {
+			if _, ok := s.tables[name].fields[column]; !ok {
+				continue
+			}
+			if found {
+				return nil, fmt.Errorf("ambiguous column name: %s", column)
+			}
+			value, _ = s.tables[name].value(v, column)
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("no such column: %s", column)
+		}
+		return value, nil
+	}
+}
+
+// page returns the items of perm after offset, up to limit of them.
+// A negative limit returns all of them.
+func page(perm []int, limit, offset int) []int {
+	if offset > len(perm) {
+		offset = len(perm)
+	}
+	perm = perm[offset:]
+	if limit >= 0 && limit < len(perm) {
+		perm = perm[:limit]
+	}
+	return perm
+}
+
+// countRows returns the number of rows of table that match conditions
+func (s *Store) countRows(name, conditions string, params []interface{}) (int64, error) {
+	rows, err := s.selectRows(name, conditions, params, "", -1, 0)
+	if err != nil {
+		return int64(0), err
+	}
+	return int64(len(rows)), nil
+}
+
+// getRow returns a copy of the row of table with id, or nil
+func (s *Store) getRow(name string, id int64) (reflect.Value, bool, error) {
+	t, err := s.table(name)
+	if err != nil {
+		return reflect.Value{}, false, err
+	}
+	v, ok := t.get(id)
+	if !ok {
+		return reflect.Value{}, false, nil
+	}
+	return t.copyRow(v), true, nil
+}
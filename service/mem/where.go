@@ -0,0 +1,345 @@
+package mem
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getter returns the value of a column of a row, optionally
+// qualified by the name of its table
+type getter func(qualifier, column string) (interface{}, error)
+
+// term compares a column with a parameter, or tests that the column
+// is not zero when op is empty
+type term struct {
+	qualifier string
+	column    string
+	op        string
+	param     int
+}
+
+// where is a parsed sql condition, in the form the services accept:
+// comparisons of columns with ? parameters, joined by and and or
+type where struct {
+	// terms of each group are joined by and, and groups by or
+	groups  [][]term
+	nParams int
+}
+
+var (
+	whereTermRe = regexp.MustCompile(`^(?:(\w+)\.)?(\w+)(?:\s*(=|==|!=|<>|<=|>=|<|>)\s*\?)?$`)
+	whereJoinRe = regexp.MustCompile(`(?i)\s+(and|or)\s+`)
+	orderTermRe = regexp.MustCompile(`(?i)^(?:(\w+)\.)?(\w+)(?:\s+(asc|desc))?$`)
+)
+
+// parseWhere parses conditions, which match all rows when empty
+func parseWhere(conditions string) (*where, error) {
+	w := &where{}
+	conditions = strings.TrimSpace(conditions)
+	if conditions == "" {
+		return w, nil
+	}
+
+	joins := whereJoinRe.FindAllStringSubmatchIndex(conditions, -1)
+	group := make([]term, 0)
+	start := 0
+	for i := 0; i <= len(joins); i++ {
+		end := len(conditions)
+		if i < len(joins) {
+			end = joins[i][0]
+		}
+
+		str := strings.TrimSpace(conditions[start:end])
+		m := whereTermRe.FindStringSubmatch(str)
+		if m == nil {
+			return nil, fmt.Errorf("Unsupported condition: %s", str)
+		}
+
+		t := term{qualifier: m[1], column: m[2], op: m[3], param: -1}
+		if t.op != "" {
+			t.param = w.nParams
+			w.nParams++
+		}
+		group = append(group, t)
+
+		if i == len(joins) || strings.EqualFold(conditions[joins[i][2]:joins[i][3]], "or") {
+			w.groups = append(w.groups, group)
+			group = make([]term, 0)
+		}
+		if i < len(joins) {
+			start = joins[i][1]
+		}
+	}
+
+	return w, nil
+}
+
+// equals returns the parameters that columns of t must equal,
+// for every row the conditions match
+func (w *where) equals(t *Table, params []interface{}) map[string]interface{} {
+	equals := make(map[string]interface{})
+	if len(w.groups) != 1 || len(params) < w.nParams {
+		return equals
+	}
+
+	for _, term := range w.groups[0] {
+		if term.op != "=" && term.op != "==" {
+			continue
+		}
+		if term.qualifier != "" && term.qualifier != t.name {
+			continue
+		}
+		equals[term.column] = normalize(params[term.param])
+	}
+
+	return equals
+}
+
+// validate checks that the columns of the conditions exist, and that
+// there is a parameter for each placeholder
+func (w *where) validate(get getter, params []interface{}) error {
+	if len(params) < w.nParams {
+		return fmt.Errorf("sql: expected %d arguments, got %d", w.nParams, len(params))
+	}
+	for _, group := range w.groups {
+		for _, term := range group {
+			_, err := get(term.qualifier, term.column)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *where) match(get getter, params []interface{}) (bool, error) {
+	if len(w.groups) == 0 {
+		return true, nil
+	}
+
+	for _, group := range w.groups {
+		ok := true
+		for _, term := range group {
+			value, err := get(term.qualifier, term.column)
+			if err != nil {
+				return false, err
+			}
+
+			if term.op == "" {
+				ok = compare(value, int64(0)) != 0
+			} else {
+				c := compare(value, normalize(params[term.param]))
+				switch term.op {
+				case "=", "==":
+					ok = c == 0
+				case "!=", "<>":
+					ok = c != 0
+				case "<":
+					ok = c < 0
+				case "<=":
+					ok = c <= 0
+				case ">":
+					ok = c > 0
+				case ">=":
+					ok = c >= 0
+				}
+			}
+
+			if !ok {
+				break
+			}
+		}
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+type orderTerm struct {
+	qualifier string
+	column    string
+	desc      bool
+}
+
+func parseOrder(order string) ([]orderTerm, error) {
+	terms := make([]orderTerm, 0)
+	order = strings.TrimSpace(order)
+	if order == "" {
+		return terms, nil
+	}
+
+	for _, str := range strings.Split(order, ",") {
+		str = strings.TrimSpace(str)
+		m := orderTermRe.FindStringSubmatch(str)
+		if m == nil {
+			return nil, fmt.Errorf("Unsupported order: %s", str)
+		}
+		terms = append(terms, orderTerm{
+			qualifier: m[1],
+			column:    m[2],
+			desc:      strings.EqualFold(m[3], "desc"),
+		})
+	}
+
+	return terms, nil
+}
+
+// sortRows returns the indexes of rows sorted by order. Rows that are
+// equal by order keep their position.
+func sortRows(order string, rows []getter) ([]int, error) {
+	terms, err := parseOrder(order)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([][]interface{}, len(rows))
+	perm := make([]int, len(rows))
+	for i, get := range rows {
+		perm[i] = i
+		keys[i] = make([]interface{}, len(terms))
+		for j, term := range terms {
+			keys[i][j], err = get(term.qualifier, term.column)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sort.SliceStable(perm, func(a, b int) bool {
+		for j, term := range terms {
+			c := compare(keys[perm[a]][j], keys[perm[b]][j])
+			if c == 0 {
+				continue
+			}
+			if term.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+
+	return perm, nil
+}
+
+// normalize converts integers to int64, floats to float64,
+// booleans to 0 or 1 and blobs to strings, like sqlite stores them
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return int64(v)
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	case []byte:
+		return string(v)
+	}
+	return value
+}
+
+// compare compares normalized values the way sqlite does,
+// with null before numbers, and numbers before text
+func compare(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		// text that looks like a number compares as one
+		if fa, ok := numeric(a); ok {
+			if fb, ok := numeric(b); ok {
+				return compareFloat(fa, fb)
+			}
+		}
+		return ra - rb
+	}
+
+	switch va := a.(type) {
+	case int64:
+		switch vb := b.(type) {
+		case int64:
+			if va < vb {
+				return -1
+			} else if va > vb {
+				return 1
+			}
+			return 0
+		case float64:
+			return compareFloat(float64(va), vb)
+		}
+	case float64:
+		fb, _ := numeric(b)
+		return compareFloat(va, fb)
+	case string:
+		if vb, ok := b.(string); ok {
+			return strings.Compare(va, vb)
+		}
+	case time.Time:
+		vb, _ := b.(time.Time)
+		if va.Before(vb) {
+			return -1
+		} else if va.After(vb) {
+			return 1
+		}
+		return 0
+	}
+	return 0
+}
+
+func rank(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case int64, float64:
+		return 1
+	case time.Time:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func numeric(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func compareFloat(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,121 @@
+package mem
+
+import (
+	"fmt"
+	"testing"
+)
+
+func whereTestGetter(row map[string]interface{}) getter {
+	return func(qualifier, column string) (interface{}, error) {
+		if qualifier != "" && qualifier != "t" {
+			return nil, fmt.Errorf("no such column: %s.%s", qualifier, column)
+		}
+		value, ok := row[column]
+		if !ok {
+			return nil, fmt.Errorf("no such column: %s", column)
+		}
+		return normalize(value), nil
+	}
+}
+
+func TestWhereMatch(t *testing.T) {
+	row := whereTestGetter(map[string]interface{}{
+		"id":   int64(3),
+		"name": "test",
+		"dist": 1.5,
+	})
+
+	for _, tt := range []struct {
+		conditions string
+		params     []interface{}
+		r          bool
+	}{
+		{"", nil, true},
+		{"id = ?", []interface{}{3}, true},
+		{"t.id = ?", []interface{}{int64(3)}, true},
+		{"id != ?", []interface{}{3}, false},
+		{"id <> ?", []interface{}{4}, true},
+		{"id", nil, true},
+		{"dist < ?", []interface{}{2}, true},
+		{"dist >= ?", []interface{}{1.5}, true},
+		{"id > ?", []interface{}{"2"}, true},
+		{"name = ? and id = ?", []interface{}{"test", 4}, false},
+		{"name = ? AND id = ?", []interface{}{"test", 3}, true},
+		{"name = ? and id = ? or dist = ?", []interface{}{"test", 4, 1.5}, true},
+		{"name = ? OR id = ? and dist = ?", []interface{}{"other", 3, 2}, false},
+	} {
+		w, err := parseWhere(tt.conditions)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %s\n", tt.conditions, err.Error())
+		}
+
+		err = w.validate(row, tt.params)
+		if err != nil {
+			t.Fatalf("Error validating %q: %s\n", tt.conditions, err.Error())
+		}
+
+		r, err := w.match(row, tt.params)
+		if err != nil {
+			t.Fatalf("Error matching %q: %s\n", tt.conditions, err.Error())
+		}
+
+		if r != tt.r {
+			t.Errorf("%q with %v => %v, want %v", tt.conditions, tt.params, r, tt.r)
+		}
+	}
+}
+
+func TestWhereErrors(t *testing.T) {
+	row := whereTestGetter(map[string]interface{}{"id": int64(1)})
+
+	for _, tt := range []struct {
+		conditions string
+		params     []interface{}
+	}{
+		{"id in (?)", []interface{}{1}},
+		{"id = 1", nil},
+		{"name = ?", []interface{}{"test"}},
+		{"other.id = ?", []interface{}{1}},
+		{"id = ? and id = ?", []interface{}{1}},
+	} {
+		w, err := parseWhere(tt.conditions)
+		if err == nil {
+			err = w.validate(row, tt.params)
+		}
+		if err == nil {
+			t.Errorf("Expected %q with %v to fail", tt.conditions, tt.params)
+		}
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	rows := []getter{
+		whereTestGetter(map[string]interface{}{"id": 1, "dist": 2.0}),
+		whereTestGetter(map[string]interface{}{"id": 2, "dist": 1.0}),
+		whereTestGetter(map[string]interface{}{"id": 3, "dist": 2.0}),
+	}
+
+	for _, tt := range []struct {
+		order string
+		r     []int
+	}{
+		{"", []int{0, 1, 2}},
+		{"id desc", []int{2, 1, 0}},
+		{"t.dist ASC", []int{1, 0, 2}},
+		{"dist asc, id desc", []int{1, 2, 0}},
+	} {
+		perm, err := sortRows(tt.order, rows)
+		if err != nil {
+			t.Fatalf("Error sorting by %q: %s\n", tt.order, err.Error())
+		}
+
+		if fmt.Sprint(perm) != fmt.Sprint(tt.r) {
+			t.Errorf("Sorting by %q => %v, want %v", tt.order, perm, tt.r)
+		}
+	}
+
+	_, err := sortRows("random()", rows)
+	if err == nil {
+		t.Error("Expected sorting by random() to fail")
+	}
+}
@@ -308,11 +308,13 @@ func TestMosaicPartialServiceFindAllPartialViews(t *testing.T) {
 	setupMosaicPartialServiceTest()
 	mosaicPartialService := serviceFactory.MustMosaicPartialService()
 	defer mosaicPartialService.Close()
+	partialComparisonService := serviceFactory.MustPartialComparisonService()
 
 	c1 := model.MosaicPartial{
 		MosaicId:       mosaic.Id,
 		MacroPartialId: macroPartial.Id,
 		GidxPartialId:  gidxPartial.Id,
+		Dist:           0.25,
 	}
 
 	err := mosaicPartialService.Insert(&c1)
@@ -357,6 +359,30 @@ func TestMosaicPartialServiceFindAllPartialViews(t *testing.T) {
 		coverPartial.Y2 != view.CoverPartial.Y2 {
 		t.Fatalf("Inserted cover partial (%+v) does not match: %+v\n", view.CoverPartial, coverPartial)
 	}
+
+	if view.Dist != 0.25 {
+		t.Fatalf("Expected stored dist 0.25 without a partial comparison, got: %f\n", view.Dist)
+	}
+
+	pc := model.PartialComparison{
+		MacroPartialId: macroPartial.Id,
+		GidxPartialId:  gidxPartial.Id,
+		Dist:           0.5,
+	}
+
+	err = partialComparisonService.Insert(&pc)
+	if err != nil {
+		t.Fatalf("Error inserting partial comparison: %s\n", err.Error())
+	}
+
+	views, err = mosaicPartialService.FindAllPartialViews(&mosaic, "mosaic_partials.id asc", 1000, 0)
+	if err != nil {
+		t.Fatalf("Error finding all mosaic partial views: %s\n", err.Error())
+	}
+
+	if len(views) != 1 || views[0].Dist != 0.5 {
+		t.Fatalf("Expected 1 mosaic partial view with dist 0.5, got: %+v\n", views)
+	}
 }
 
 func TestMosaicPartialServiceFindRepeats(t *testing.T) {
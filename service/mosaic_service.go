@@ -9,6 +9,7 @@ type MosaicService interface {
 	Get(int64) (*model.Mosaic, error)
 	Insert(*model.Mosaic) error
 	Update(*model.Mosaic) (int64, error)
+	Delete(*model.Mosaic) error
 	GetOneBy(string, ...interface{}) (*model.Mosaic, error)
 	ExistsBy(string, ...interface{}) (bool, error)
 	FindAll(string) ([]*model.Mosaic, error)
@@ -70,6 +70,33 @@ func TestMosaicServiceInsert(t *testing.T) {
 	}
 }
 
+func TestMosaicServiceDelete(t *testing.T) {
+	setupMosaicServiceTest()
+	mosaicService := serviceFactory.MustMosaicService()
+	defer mosaicService.Close()
+
+	c1 := model.Mosaic{
+		MacroId: macro.Id,
+	}
+
+	err := mosaicService.Insert(&c1)
+	if err != nil {
+		t.Fatalf("Error inserting mosaic: %s\n", err.Error())
+	}
+
+	err = mosaicService.Delete(&c1)
+	if err != nil {
+		t.Fatalf("Error deleting mosaic: %s\n", err.Error())
+	}
+
+	c2, err := mosaicService.Get(c1.Id)
+	if err != nil {
+		t.Fatalf("Error getting mosaic: %s\n", err.Error())
+	} else if c2 != nil {
+		t.Fatalf("Mosaic not deleted")
+	}
+}
+
 func TestMosaicServiceGetOneBy(t *testing.T) {
 	setupMosaicServiceTest()
 	mosaicService := serviceFactory.MustMosaicService()
@@ -23,6 +23,8 @@ type PartialComparisonService interface {
 	FindOrCreate(*model.MacroPartial, *model.GidxPartial) (*model.PartialComparison, error)
 	CountMissing(macro *model.Macro) (int64, error)
 	FindMissing(*model.Macro, int) ([]*model.MacroGidxView, error)
+	FindPruned(*model.MacroPartial) ([]*model.MacroGidxView, error)
+	KeepTop(*model.MacroPartial, int) (int64, error)
 	CreateFromView(*model.MacroGidxView) (*model.PartialComparison, error)
 	GetClosest(*model.MacroPartial) (int64, error)
 	GetClosestMax(*model.MacroPartial, *model.Mosaic, int) (int64, error)
@@ -4,8 +4,6 @@ import (
 	"testing"
 
 	"github.com/atongen/gosaic/model"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 func setupPartialComparisonServiceTest() {
@@ -531,6 +529,66 @@ func TestPartialComparisonServiceCountMissing(t *testing.T) {
 	}
 }
 
+func TestPartialComparisonServiceCountMissingOriented(t *testing.T) {
+	setupPartialComparisonServiceTest()
+	partialComparisonService := serviceFactory.MustPartialComparisonService()
+	defer partialComparisonService.Close()
+
+	portrait := model.Gidx{
+		AspectId:    aspect.Id,
+		Path:        "testdata/matterhorn.jpg",
+		Md5sum:      "fcaadee574094a3ae04c6badbbb9ee5e",
+		Width:       696,
+		Height:      1024,
+		Orientation: 1,
+	}
+	err := serviceFactory.MustGidxService().Insert(&portrait)
+	if err != nil {
+		t.Fatalf("Error inserting gidx: %s\n", err.Error())
+	}
+
+	_, err = serviceFactory.MustGidxPartialService().FindOrCreate(&portrait, &aspect)
+	if err != nil {
+		t.Fatalf("Error creating gidx partial: %s\n", err.Error())
+	}
+
+	num, err := partialComparisonService.CountMissing(&macro)
+	if err != nil {
+		t.Fatalf("Error counting missing partial comparisons: %s\n", err.Error())
+	}
+
+	if num != 15 {
+		t.Fatalf("Expected 15 missing partial comparisons, got %d\n", num)
+	}
+
+	macro.Oriented = true
+	err = serviceFactory.MustMacroService().Update(&macro)
+	if err != nil {
+		t.Fatalf("Error updating macro: %s\n", err.Error())
+	}
+
+	// the portrait index image does not match the landscape aspect
+	num, err = partialComparisonService.CountMissing(&macro)
+	if err != nil {
+		t.Fatalf("Error counting missing partial comparisons: %s\n", err.Error())
+	}
+
+	if num != 10 {
+		t.Fatalf("Expected 10 missing oriented partial comparisons, got %d\n", num)
+	}
+
+	macroGidxViews, err := partialComparisonService.FindMissing(&macro, 1000)
+	if err != nil {
+		t.Fatalf("Error finding missing partial comparisons: %s\n", err.Error())
+	}
+
+	for _, view := range macroGidxViews {
+		if view.GidxPartial.GidxId == portrait.Id {
+			t.Fatal("Expected oriented macro not to be compared with portrait index image")
+		}
+	}
+}
+
 func TestPartialComparisonServiceFindMissing(t *testing.T) {
 	setupPartialComparisonServiceTest()
 	partialComparisonService := serviceFactory.MustPartialComparisonService()
@@ -546,6 +604,65 @@ func TestPartialComparisonServiceFindMissing(t *testing.T) {
 	}
 }
 
+func TestPartialComparisonServiceKeepTop(t *testing.T) {
+	setupPartialComparisonServiceTest()
+	partialComparisonService := serviceFactory.MustPartialComparisonService()
+	defer partialComparisonService.Close()
+
+	macroGidxViews, err := partialComparisonService.FindMissing(&macro, 1000)
+	if err != nil {
+		t.Fatalf("Error finding missing partial comparisons: %s\n", err.Error())
+	}
+
+	for _, view := range macroGidxViews {
+		_, err = partialComparisonService.CreateFromView(view)
+		if err != nil {
+			t.Fatalf("Error creating partial comparison from view: %s\n", err.Error())
+		}
+	}
+
+	num, err := partialComparisonService.KeepTop(&macroPartial, 1)
+	if err != nil {
+		t.Fatalf("Error pruning partial comparisons: %s\n", err.Error())
+	}
+
+	if num != 1 {
+		t.Fatalf("Expected 1 partial comparison to be pruned, got %d\n", num)
+	}
+
+	num, err = partialComparisonService.CountMissing(&macro)
+	if err != nil {
+		t.Fatalf("Error counting missing partial comparisons: %s\n", err.Error())
+	}
+
+	if num != 0 {
+		t.Fatalf("Expected pruned partial comparisons not to be missing, got %d\n", num)
+	}
+
+	pruned, err := partialComparisonService.FindPruned(&macroPartial)
+	if err != nil {
+		t.Fatalf("Error finding pruned partial comparisons: %s\n", err.Error())
+	}
+
+	if len(pruned) != 1 {
+		t.Fatalf("Expected 1 pruned partial comparison, got %d\n", len(pruned))
+	}
+
+	_, err = partialComparisonService.CreateFromView(pruned[0])
+	if err != nil {
+		t.Fatalf("Error restoring pruned partial comparison: %s\n", err.Error())
+	}
+
+	num, err = partialComparisonService.Count()
+	if err != nil {
+		t.Fatalf("Error counting partial comparisons: %s\n", err.Error())
+	}
+
+	if num != 10 {
+		t.Fatalf("Expected 10 partial comparisons, got %d\n", num)
+	}
+}
+
 func TestPartialComparisonServiceCreateFromView(t *testing.T) {
 	setupPartialComparisonServiceTest()
 	partialComparisonService := serviceFactory.MustPartialComparisonService()
@@ -9,6 +9,7 @@ type ProjectService interface {
 	Get(int64) (*model.Project, error)
 	Insert(*model.Project) error
 	Update(*model.Project) (int64, error)
+	Delete(*model.Project) error
 	GetOneBy(string, ...interface{}) (*model.Project, error)
 	ExistsBy(string, ...interface{}) (bool, error)
 	FindAll(string) ([]*model.Project, error)
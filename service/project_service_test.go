@@ -160,6 +160,33 @@ func TestProjectServiceUpdate(t *testing.T) {
 	}
 }
 
+func TestProjectServiceDelete(t *testing.T) {
+	setTestServiceFactory()
+	projectService := serviceFactory.MustProjectService()
+	defer projectService.Close()
+
+	p1 := model.Project{
+		Name: "test1",
+	}
+
+	err := projectService.Insert(&p1)
+	if err != nil {
+		t.Fatalf("Error inserting project: %s\n", err.Error())
+	}
+
+	err = projectService.Delete(&p1)
+	if err != nil {
+		t.Fatalf("Error deleting project: %s\n", err.Error())
+	}
+
+	p2, err := projectService.Get(p1.Id)
+	if err != nil {
+		t.Fatalf("Error getting project: %s\n", err.Error())
+	} else if p2 != nil {
+		t.Fatalf("Project not deleted")
+	}
+}
+
 func TestProjectServiceFindAll(t *testing.T) {
 	setTestServiceFactory()
 	projectService := serviceFactory.MustProjectService()
@@ -1,14 +1,8 @@
 package service
 
 import (
-	"database/sql"
 	"fmt"
 	"net/url"
-
-	"github.com/atongen/gosaic/database"
-	gorp "gopkg.in/gorp.v1"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
 type ServiceName uint8
@@ -26,6 +20,8 @@ const (
 	MosaicPartialServiceName
 	QuadDistServiceName
 	ProjectServiceName
+	GcServiceName
+	ExportServiceName
 )
 
 type ServiceFactory interface {
@@ -43,6 +39,8 @@ type ServiceFactory interface {
 	MosaicPartialService() (MosaicPartialService, error)
 	QuadDistService() (QuadDistService, error)
 	ProjectService() (ProjectService, error)
+	GcService() (GcService, error)
+	ExportService() (ExportService, error)
 
 	MustGidxService() GidxService
 	MustAspectService() AspectService
@@ -56,6 +54,8 @@ type ServiceFactory interface {
 	MustMosaicPartialService() MosaicPartialService
 	MustQuadDistService() QuadDistService
 	MustProjectService() ProjectService
+	MustGcService() GcService
+	MustExportService() ExportService
 }
 
 func NewServiceFactory(dsn string) (ServiceFactory, error) {
@@ -69,37 +69,9 @@ func NewServiceFactory(dsn string) (ServiceFactory, error) {
 		return nil, fmt.Errorf("Unknown database type: %s", u.Scheme)
 	case "sqlite3":
 		return newServiceFactorySqlite3(u)
+	case "mem":
+		return newServiceFactoryMem(u)
+	case "bolt":
+		return newServiceFactoryBolt(u)
 	}
 }
-
-func newServiceFactorySqlite3(u *url.URL) (*serviceFactorySqlite3, error) {
-	f := serviceFactorySqlite3{
-		services: make(map[ServiceName]Service),
-	}
-
-	db, err := sql.Open("sqlite3", u.Path)
-	if err != nil {
-		return nil, err
-	}
-	f.dB = db
-
-	err = f.dB.Ping()
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = f.dB.Exec("PRAGMA foreign_keys = ON;")
-	if err != nil {
-		return nil, err
-	}
-
-	_, err = database.Migrate(f.dB)
-	if err != nil {
-		return nil, err
-	}
-
-	// setup orm
-	f.dbMap = &gorp.DbMap{Db: f.dB, Dialect: gorp.SqliteDialect{}}
-
-	return &f, nil
-}
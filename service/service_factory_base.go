@@ -0,0 +1,344 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+)
+
+// serviceFactoryBase implements the service getters of a ServiceFactory,
+// creating each service once with the newService function of its backend
+type serviceFactoryBase struct {
+	m          sync.Mutex
+	services   map[ServiceName]Service
+	newService func(ServiceName) (Service, error)
+}
+
+func (f *serviceFactoryBase) getService(name ServiceName) (Service, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if s, ok := f.services[name]; ok {
+		return s, nil
+	}
+
+	s, err := f.newService(name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Register()
+	if err != nil {
+		return nil, err
+	}
+
+	f.services[name] = s
+	return s, nil
+}
+
+func (f *serviceFactoryBase) GidxService() (GidxService, error) {
+	s, err := f.getService(GidxServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	gidxService, ok := s.(GidxService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid gidx service")
+	}
+
+	return gidxService, nil
+}
+
+func (f *serviceFactoryBase) AspectService() (AspectService, error) {
+	s, err := f.getService(AspectServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	aspectService, ok := s.(AspectService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid aspect service")
+	}
+
+	return aspectService, nil
+}
+
+func (f *serviceFactoryBase) GidxPartialService() (GidxPartialService, error) {
+	s, err := f.getService(GidxPartialServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	gidxPartialService, ok := s.(GidxPartialService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid gidx_partial service")
+	}
+
+	return gidxPartialService, nil
+}
+
+func (f *serviceFactoryBase) CoverService() (CoverService, error) {
+	s, err := f.getService(CoverServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	coverService, ok := s.(CoverService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid cover service")
+	}
+
+	return coverService, nil
+}
+
+func (f *serviceFactoryBase) CoverPartialService() (CoverPartialService, error) {
+	s, err := f.getService(CoverPartialServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	coverPartialService, ok := s.(CoverPartialService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid cover_partial service")
+	}
+
+	return coverPartialService, nil
+}
+
+func (f *serviceFactoryBase) MacroService() (MacroService, error) {
+	s, err := f.getService(MacroServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	macroService, ok := s.(MacroService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid macro service")
+	}
+
+	return macroService, nil
+}
+
+func (f *serviceFactoryBase) MacroPartialService() (MacroPartialService, error) {
+	s, err := f.getService(MacroPartialServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	macroPartialService, ok := s.(MacroPartialService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid macro partial service")
+	}
+
+	return macroPartialService, nil
+}
+
+func (f *serviceFactoryBase) PartialComparisonService() (PartialComparisonService, error) {
+	s, err := f.getService(PartialComparisonServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	partialComparisonService, ok := s.(PartialComparisonService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid partial comparison service")
+	}
+
+	return partialComparisonService, nil
+}
+
+func (f *serviceFactoryBase) MosaicService() (MosaicService, error) {
+	s, err := f.getService(MosaicServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	mosaicService, ok := s.(MosaicService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid mosaic service")
+	}
+
+	return mosaicService, nil
+}
+
+func (f *serviceFactoryBase) MosaicPartialService() (MosaicPartialService, error) {
+	s, err := f.getService(MosaicPartialServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	mosaicPartialService, ok := s.(MosaicPartialService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid mosaic partial service")
+	}
+
+	return mosaicPartialService, nil
+}
+
+func (f *serviceFactoryBase) QuadDistService() (QuadDistService, error) {
+	s, err := f.getService(QuadDistServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	quadDistService, ok := s.(QuadDistService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid quad dist service")
+	}
+
+	return quadDistService, nil
+}
+
+func (f *serviceFactoryBase) ProjectService() (ProjectService, error) {
+	s, err := f.getService(ProjectServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	projectService, ok := s.(ProjectService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid project service")
+	}
+
+	return projectService, nil
+}
+
+func (f *serviceFactoryBase) GcService() (GcService, error) {
+	s, err := f.getService(GcServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	gcService, ok := s.(GcService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid gc service")
+	}
+
+	return gcService, nil
+}
+
+func (f *serviceFactoryBase) ExportService() (ExportService, error) {
+	s, err := f.getService(ExportServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	exportService, ok := s.(ExportService)
+	if !ok {
+		return nil, fmt.Errorf("Invalid export service")
+	}
+
+	return exportService, nil
+}
+
+func (f *serviceFactoryBase) MustGidxService() GidxService {
+	s, err := f.GidxService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustAspectService() AspectService {
+	s, err := f.AspectService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustGidxPartialService() GidxPartialService {
+	s, err := f.GidxPartialService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustCoverService() CoverService {
+	s, err := f.CoverService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustCoverPartialService() CoverPartialService {
+	s, err := f.CoverPartialService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustMacroService() MacroService {
+	s, err := f.MacroService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustMacroPartialService() MacroPartialService {
+	s, err := f.MacroPartialService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustPartialComparisonService() PartialComparisonService {
+	s, err := f.PartialComparisonService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustMosaicService() MosaicService {
+	s, err := f.MosaicService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustMosaicPartialService() MosaicPartialService {
+	s, err := f.MosaicPartialService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustQuadDistService() QuadDistService {
+	s, err := f.QuadDistService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustProjectService() ProjectService {
+	s, err := f.ProjectService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustGcService() GcService {
+	s, err := f.GcService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
+
+func (f *serviceFactoryBase) MustExportService() ExportService {
+	s, err := f.ExportService()
+	if err != nil {
+		panic(err.Error())
+	}
+	return s
+}
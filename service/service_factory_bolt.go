@@ -0,0 +1,23 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/atongen/gosaic/service/bolt"
+)
+
+// newServiceFactoryBolt returns a mem service factory with a store
+// kept in the bolt database at the path of u, which needs no cgo
+func newServiceFactoryBolt(u *url.URL) (ServiceFactory, error) {
+	if u.Path == "" {
+		return nil, errors.New("bolt database path is empty")
+	}
+
+	store, err := bolt.OpenStore(u.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return newServiceFactoryStore(store), nil
+}
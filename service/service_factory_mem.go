@@ -0,0 +1,66 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/atongen/gosaic/service/mem"
+)
+
+// serviceFactoryMem keeps all data in a mem store, either in memory,
+// where it is lost when the process exits, or in a bolt database
+type serviceFactoryMem struct {
+	serviceFactoryBase
+	store *mem.Store
+}
+
+func newServiceFactoryMem(u *url.URL) (ServiceFactory, error) {
+	return newServiceFactoryStore(mem.NewStore()), nil
+}
+
+func newServiceFactoryStore(store *mem.Store) ServiceFactory {
+	f := serviceFactoryMem{store: store}
+	f.services = make(map[ServiceName]Service)
+	f.newService = f.newMemService
+
+	return &f
+}
+
+func (f *serviceFactoryMem) newMemService(name ServiceName) (Service, error) {
+	switch name {
+	default:
+		return nil, fmt.Errorf("Service not found")
+	case GidxServiceName:
+		return mem.NewGidxService(f.store), nil
+	case AspectServiceName:
+		return mem.NewAspectService(f.store), nil
+	case GidxPartialServiceName:
+		return mem.NewGidxPartialService(f.store), nil
+	case CoverServiceName:
+		return mem.NewCoverService(f.store), nil
+	case CoverPartialServiceName:
+		return mem.NewCoverPartialService(f.store), nil
+	case MacroServiceName:
+		return mem.NewMacroService(f.store), nil
+	case MacroPartialServiceName:
+		return mem.NewMacroPartialService(f.store), nil
+	case PartialComparisonServiceName:
+		return mem.NewPartialComparisonService(f.store), nil
+	case MosaicServiceName:
+		return mem.NewMosaicService(f.store), nil
+	case MosaicPartialServiceName:
+		return mem.NewMosaicPartialService(f.store), nil
+	case QuadDistServiceName:
+		return mem.NewQuadDistService(f.store), nil
+	case ProjectServiceName:
+		return mem.NewProjectService(f.store), nil
+	case GcServiceName:
+		return mem.NewGcService(f.store), nil
+	case ExportServiceName:
+		return mem.NewExportService(f.store), nil
+	}
+}
+
+func (f *serviceFactoryMem) Close() error {
+	return f.store.Close()
+}
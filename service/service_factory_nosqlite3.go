@@ -0,0 +1,13 @@
+//go:build !cgo
+// +build !cgo
+
+package service
+
+import (
+	"errors"
+	"net/url"
+)
+
+func newServiceFactorySqlite3(u *url.URL) (ServiceFactory, error) {
+	return nil, errors.New("sqlite3 is not available, gosaic was built without cgo")
+}
@@ -0,0 +1,8 @@
+//go:build cgo
+// +build cgo
+
+package service
+
+func init() {
+	testDsns = append([]string{"sqlite3://:memory:"}, testDsns...)
+}
@@ -5,7 +5,7 @@ import (
 )
 
 func getServiceFactory() (ServiceFactory, error) {
-	return NewServiceFactory("sqlite3://:memory:")
+	return newTestServiceFactory()
 }
 
 func TestServices(t *testing.T) {
@@ -74,6 +74,16 @@ func TestServices(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Error getting projectService: %s\n", err.Error())
 	}
+
+	_, err = f.GcService()
+	if err != nil {
+		t.Fatalf("Error getting gcService: %s\n", err.Error())
+	}
+
+	_, err = f.ExportService()
+	if err != nil {
+		t.Fatalf("Error getting exportService: %s\n", err.Error())
+	}
 }
 
 func TestMustServices(t *testing.T) {
@@ -95,4 +105,6 @@ func TestMustServices(t *testing.T) {
 	f.MustMosaicPartialService()
 	f.MustQuadDistService()
 	f.MustProjectService()
+	f.MustGcService()
+	f.MustExportService()
 }
@@ -1,10 +1,24 @@
 package service
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
 	"github.com/atongen/gosaic/model"
 )
 
 var (
+	// testDsns are the databases the tests run against,
+	// sqlite3 is added when it is available. Bolt databases
+	// are created in a temporary directory.
+	testDsns = []string{"mem://", "bolt://"}
+	testDsn  string
+	testDbs  int
+
 	serviceFactory ServiceFactory
 
 	aspect        model.Aspect
@@ -18,9 +32,42 @@ var (
 	mosaicPartial model.MosaicPartial
 )
 
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "gosaic_service_test")
+	if err != nil {
+		panic(err)
+	}
+
+	code := 0
+	for _, dsn := range testDsns {
+		if dsn == "bolt://" {
+			dsn += dir
+		}
+		testDsn = dsn
+		code = m.Run()
+		if code != 0 {
+			fmt.Printf("Tests failed with %s\n", dsn)
+			break
+		}
+	}
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestServiceFactory returns a service factory with an empty database
+func newTestServiceFactory() (ServiceFactory, error) {
+	dsn := testDsn
+	if strings.HasPrefix(dsn, "bolt://") {
+		testDbs++
+		dsn = "bolt://" + filepath.Join(strings.TrimPrefix(dsn, "bolt://"), fmt.Sprintf("%d.db", testDbs))
+	}
+	return NewServiceFactory(dsn)
+}
+
 func setTestServiceFactory() {
 	var err error
-	serviceFactory, err = NewServiceFactory("sqlite3://:memory:")
+	serviceFactory, err = newTestServiceFactory()
 	if err != nil {
 		panic(err)
 	}
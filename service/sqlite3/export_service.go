@@ -0,0 +1,184 @@
+package sqlite3
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/atongen/gosaic/model"
+
+	"gopkg.in/gorp.v1"
+)
+
+// exportTables are the tables of the database with their models,
+// in an order where each row comes after the rows it references
+var exportTables = []struct {
+	name string
+	row  interface{}
+}{
+	{"aspects", model.Aspect{}},
+	{"gidx", model.Gidx{}},
+	{"gidx_partials", model.GidxPartial{}},
+	{"covers", model.Cover{}},
+	{"cover_partials", model.CoverPartial{}},
+	{"macros", model.Macro{}},
+	{"macro_partials", model.MacroPartial{}},
+	{"partial_comparisons", model.PartialComparison{}},
+	{"mosaics", model.Mosaic{}},
+	{"mosaic_partials", model.MosaicPartial{}},
+	{"quad_dists", model.QuadDist{}},
+	{"projects", model.Project{}},
+}
+
+type exportServiceSqlite3 struct {
+	dbMap *gorp.DbMap
+	m     sync.Mutex
+}
+
+func NewExportService(dbMap *gorp.DbMap) *exportServiceSqlite3 {
+	return &exportServiceSqlite3{dbMap: dbMap}
+}
+
+func (s *exportServiceSqlite3) Register() error {
+	return nil
+}
+
+func (s *exportServiceSqlite3) Close() error {
+	return s.dbMap.Db.Close()
+}
+
+// Tables returns the tables of the database, in the order
+// they must be imported
+func (s *exportServiceSqlite3) Tables() []string {
+	tables := make([]string, len(exportTables))
+	for i, t := range exportTables {
+		tables[i] = t.name
+	}
+	return tables
+}
+
+func (s *exportServiceSqlite3) rowType(table string) (reflect.Type, error) {
+	for _, t := range exportTables {
+		if t.name == table {
+			return reflect.TypeOf(t.row), nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown export table: %s", table)
+}
+
+// Count returns the number of rows of table
+func (s *exportServiceSqlite3) Count(table string) (int64, error) {
+	_, err := s.rowType(table)
+	if err != nil {
+		return int64(0), err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.dbMap.SelectInt(fmt.Sprintf("select count(*) from %s", table))
+}
+
+// Export calls fn with the rows of table in order of id, up to
+// batchSize at a time, each a pointer to its model
+func (s *exportServiceSqlite3) Export(table string, batchSize int, fn func([]interface{}) error) error {
+	typ, err := s.rowType(table)
+	if err != nil {
+		return err
+	}
+
+	if batchSize <= 0 {
+		return fmt.Errorf("Batch size must be greater than zero")
+	}
+
+	sqlStr := fmt.Sprintf("select * from %s where id > ? order by id asc limit ?", table)
+	var lastId int64
+	for {
+		slice := reflect.New(reflect.SliceOf(reflect.PtrTo(typ)))
+
+		s.m.Lock()
+		_, err = s.dbMap.Select(slice.Interface(), sqlStr, lastId, batchSize)
+		s.m.Unlock()
+		if err != nil {
+			return err
+		}
+
+		n := slice.Elem().Len()
+		if n == 0 {
+			return nil
+		}
+
+		rows := make([]interface{}, n)
+		for i := 0; i < n; i++ {
+			rows[i] = slice.Elem().Index(i).Interface()
+		}
+		lastId = slice.Elem().Index(n - 1).Elem().FieldByName("Id").Int()
+
+		err = fn(rows)
+		if err != nil {
+			return err
+		}
+
+		if n < batchSize {
+			return nil
+		}
+	}
+}
+
+// Import inserts rows, pointers to the model of table, with their ids.
+// Either all rows are inserted, or none.
+func (s *exportServiceSqlite3) Import(table string, rows []interface{}) (int64, error) {
+	typ, err := s.rowType(table)
+	if err != nil {
+		return int64(0), err
+	}
+
+	columns := make([]string, 0)
+	fields := make([]int, 0)
+	for i := 0; i < typ.NumField(); i++ {
+		column := typ.Field(i).Tag.Get("db")
+		if column == "" || column == "-" {
+			continue
+		}
+		columns = append(columns, column)
+		fields = append(fields, i)
+	}
+
+	sqlStr := fmt.Sprintf("insert into %s (%s) values (?%s)", table,
+		strings.Join(columns, ", "), strings.Repeat(", ?", len(columns)-1))
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	tx, err := s.dbMap.Begin()
+	if err != nil {
+		return int64(0), err
+	}
+
+	for _, row := range rows {
+		v := reflect.ValueOf(row)
+		if v.Kind() != reflect.Ptr || v.Elem().Type() != typ {
+			tx.Rollback()
+			return int64(0), fmt.Errorf("Invalid %s row: %T", table, row)
+		}
+
+		values := make([]interface{}, len(fields))
+		for i, field := range fields {
+			values[i] = v.Elem().Field(field).Interface()
+		}
+
+		_, err = tx.Exec(sqlStr, values...)
+		if err != nil {
+			tx.Rollback()
+			return int64(0), err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return int64(0), err
+	}
+
+	return int64(len(rows)), nil
+}
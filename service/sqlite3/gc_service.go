@@ -0,0 +1,179 @@
+package sqlite3
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"gopkg.in/gorp.v1"
+)
+
+const (
+	// macros used by a project or mosaic
+	gcMacros = "select macro_id from projects where macro_id is not null union select macro_id from mosaics"
+	// covers used by a project, or by a macro in use
+	gcCovers = "select cover_id from projects where cover_id is not null union select cover_id from macros where id in (" + gcMacros + ")"
+	// gidx partials with an aspect that no cover or macro in use needs,
+	// and that no mosaic has placed
+	gcGidxPartials = "aspect_id not in (select aspect_id from cover_partials where cover_id in (" + gcCovers + "))" +
+		" and aspect_id not in (select aspect_id from macro_partials where macro_id in (" + gcMacros + "))" +
+		" and id not in (select gidx_partial_id from mosaic_partials)"
+)
+
+// gcTables are the tables with rows that can be orphaned, in the order
+// they are deleted, each with the condition of its orphaned rows.
+// Rows are deleted before the rows they reference, so that deleting
+// a row never cascades to more rows than a single batch.
+var gcTables = []struct {
+	name       string
+	conditions string
+}{
+	{"partial_comparisons", "macro_partial_id in (select id from macro_partials where macro_id not in (" + gcMacros + "))" +
+		" or gidx_partial_id in (select id from gidx_partials where " + gcGidxPartials + ")"},
+	{"quad_dists", "macro_partial_id in (select id from macro_partials where macro_id not in (" + gcMacros + "))"},
+	{"macro_partials", "macro_id not in (" + gcMacros + ")"},
+	{"macros", "id not in (" + gcMacros + ")"},
+	{"cover_partials", "cover_id not in (" + gcCovers + ")"},
+	{"covers", "id not in (" + gcCovers + ")"},
+	{"gidx_partials", gcGidxPartials},
+	{"aspects", "id not in (select aspect_id from gidx)" +
+		" and id not in (select aspect_id from gidx_partials)" +
+		" and id not in (select aspect_id from covers)" +
+		" and id not in (select aspect_id from cover_partials)" +
+		" and id not in (select aspect_id from macros)" +
+		" and id not in (select aspect_id from macro_partials)"},
+}
+
+type gcServiceSqlite3 struct {
+	dbMap *gorp.DbMap
+	m     sync.Mutex
+}
+
+func NewGcService(dbMap *gorp.DbMap) *gcServiceSqlite3 {
+	return &gcServiceSqlite3{dbMap: dbMap}
+}
+
+func (s *gcServiceSqlite3) Register() error {
+	return nil
+}
+
+func (s *gcServiceSqlite3) Close() error {
+	return s.dbMap.Db.Close()
+}
+
+// Tables returns the tables with rows that can be orphaned,
+// in the order their orphans must be deleted
+func (s *gcServiceSqlite3) Tables() []string {
+	tables := make([]string, len(gcTables))
+	for i, t := range gcTables {
+		tables[i] = t.name
+	}
+	return tables
+}
+
+func (s *gcServiceSqlite3) conditions(table string) (string, error) {
+	for _, t := range gcTables {
+		if t.name == table {
+			return t.conditions, nil
+		}
+	}
+	return "", fmt.Errorf("Unknown gc table: %s", table)
+}
+
+// CountOrphans returns the number of rows of table that
+// are not reachable from any project or mosaic
+func (s *gcServiceSqlite3) CountOrphans(table string) (int64, error) {
+	conditions, err := s.conditions(table)
+	if err != nil {
+		return int64(0), err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.dbMap.SelectInt(fmt.Sprintf("select count(*) from %s where %s", table, conditions))
+}
+
+// DeleteOrphans deletes up to limit orphaned rows of table,
+// and returns the number of rows deleted
+func (s *gcServiceSqlite3) DeleteOrphans(table string, limit int) (int64, error) {
+	conditions, err := s.conditions(table)
+	if err != nil {
+		return int64(0), err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	res, err := s.dbMap.Exec(fmt.Sprintf("delete from %s where id in (select id from %s where %s limit ?)", table, table, conditions), limit)
+	if err != nil {
+		return int64(0), err
+	}
+
+	return res.RowsAffected()
+}
+
+// TableSize returns the number of rows of table, and the bytes used by it
+// and its indexes. Bytes is -1 when sqlite was built without dbstat.
+func (s *gcServiceSqlite3) TableSize(table string) (int64, int64, error) {
+	_, err := s.conditions(table)
+	if err != nil {
+		return int64(0), int64(0), err
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	rows, err := s.dbMap.SelectInt(fmt.Sprintf("select count(*) from %s", table))
+	if err != nil {
+		return int64(0), int64(0), err
+	}
+
+	size, err := s.dbMap.SelectNullInt("select sum(pgsize) from dbstat where name in (select name from sqlite_master where tbl_name = ?)", table)
+	if err != nil {
+		return rows, int64(-1), nil
+	}
+
+	if !size.Valid {
+		return rows, int64(0), nil
+	}
+
+	return rows, size.Int64, nil
+}
+
+// FileSize returns the size of the database in bytes
+func (s *gcServiceSqlite3) FileSize() (int64, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var pageCount, pageSize sql.NullInt64
+	err := s.dbMap.Db.QueryRow("pragma page_count").Scan(&pageCount)
+	if err != nil {
+		return int64(0), err
+	}
+
+	err = s.dbMap.Db.QueryRow("pragma page_size").Scan(&pageSize)
+	if err != nil {
+		return int64(0), err
+	}
+
+	return pageCount.Int64 * pageSize.Int64, nil
+}
+
+// Vacuum rebuilds the database file, returning free pages to the file system
+func (s *gcServiceSqlite3) Vacuum() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	_, err := s.dbMap.Exec("vacuum")
+	return err
+}
+
+// Analyze updates the statistics the query planner uses to choose indexes
+func (s *gcServiceSqlite3) Analyze() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	_, err := s.dbMap.Exec("analyze")
+	return err
+}
@@ -189,6 +189,30 @@ func (s *gidxPartialServiceSqlite3) CountForMacro(macro *model.Macro) (int64, er
 	return s.dbMap.SelectInt(sql, macro.Id)
 }
 
+func (s *gidxPartialServiceSqlite3) FindAll(order string, limit, offset int, conditions string, params ...interface{}) ([]*model.GidxPartial, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var gidxPartials []*model.GidxPartial
+
+	sql := fmt.Sprintf("select * from gidx_partials where %s order by %s limit %d offset %d",
+		conditions, order, limit, offset)
+
+	_, err := s.dbMap.Select(&gidxPartials, sql, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, gp := range gidxPartials {
+		err = gp.DecodeData()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return gidxPartials, nil
+}
+
 func (s *gidxPartialServiceSqlite3) doFind(gidx *model.Gidx, aspect *model.Aspect) (*model.GidxPartial, error) {
 	p := model.GidxPartial{
 		GidxId:   gidx.Id,
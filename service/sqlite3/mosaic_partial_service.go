@@ -167,7 +167,8 @@ func (s *mosaicPartialServiceSqlite3) FindAllPartialViews(mosaic *model.Mosaic,
 			cover_partials.x1 as cover_partial_x1,
 			cover_partials.y1 as cover_partial_y1,
 			cover_partials.x2 as cover_partial_x2,
-			cover_partials.y2 as cover_partial_y2
+			cover_partials.y2 as cover_partial_y2,
+			coalesce(partial_comparisons.dist, mosaic_partials.dist) as dist
 		from mosaic_partials
 		inner join gidx_partials
 			on mosaic_partials.gidx_partial_id = gidx_partials.id
@@ -177,6 +178,9 @@ func (s *mosaicPartialServiceSqlite3) FindAllPartialViews(mosaic *model.Mosaic,
 			on mosaic_partials.macro_partial_id = macro_partials.id
 		inner join cover_partials
 			on macro_partials.cover_partial_id = cover_partials.id
+		left join partial_comparisons
+			on partial_comparisons.macro_partial_id = mosaic_partials.macro_partial_id
+			and partial_comparisons.gidx_partial_id = mosaic_partials.gidx_partial_id
 		where mosaic_partials.mosaic_id = ?
 		order by %s
 		limit %d
@@ -211,6 +215,7 @@ func (s *mosaicPartialServiceSqlite3) FindAllPartialViews(mosaic *model.Mosaic,
 			&r.CoverPartial.Y1,
 			&r.CoverPartial.X2,
 			&r.CoverPartial.Y2,
+			&r.Dist,
 		)
 		if err != nil {
 			return nil, err
@@ -68,6 +68,14 @@ func (s *mosaicServiceSqlite3) Update(mosaic *model.Mosaic) (int64, error) {
 	return s.dbMap.Update(mosaic)
 }
 
+func (s *mosaicServiceSqlite3) Delete(mosaic *model.Mosaic) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	_, err := s.dbMap.Delete(mosaic)
+	return err
+}
+
 func (s *mosaicServiceSqlite3) GetOneBy(conditions string, params ...interface{}) (*model.Mosaic, error) {
 	s.m.Lock()
 	defer s.m.Unlock()
@@ -270,6 +270,25 @@ func (s *partialComparisonServiceSqlite3) FindOrCreate(macroPartial *model.Macro
 	return s.doCreate(macroPartial, gidxPartial)
 }
 
+// sameOrientation is the condition of a query of macro_partials and
+// gidx_partials that the index image of the index partial has the same
+// orientation as the aspect of the macro partial, when its macro is
+// oriented, as model.Gidx.SameOrientation
+const sameOrientation = `(
+	not exists (
+		select 1 from macros
+		where macros.id = macro_partials.macro_id
+		and macros.oriented = 1
+	) or exists (
+		select 1 from gidx, aspects
+		where gidx.id = gidx_partials.gidx_id
+		and aspects.id = macro_partials.aspect_id
+		and (gidx.width = gidx.height
+			or aspects.columns = aspects.rows
+			or (gidx.width < gidx.height) = (aspects.columns < aspects.rows))
+	)
+)`
+
 func (s *partialComparisonServiceSqlite3) CountMissing(macro *model.Macro) (int64, error) {
 	s.m.Lock()
 	defer s.m.Unlock()
@@ -279,6 +298,8 @@ select count(*)
 from macro_partials, gidx_partials
 where macro_partials.macro_id = ?
 and macro_partials.aspect_id = gidx_partials.aspect_id
+and gidx_partials.id > macro_partials.pruned_through
+and ` + sameOrientation + `
 and not exists (
 	select 1 from partial_comparisons
 	where partial_comparisons.macro_partial_id = macro_partials.id
@@ -305,6 +326,8 @@ select macro_partials.id as macro_partial_id,
 from macro_partials join gidx_partials
 where macro_partials.macro_id = ?
 and macro_partials.aspect_id = gidx_partials.aspect_id
+and gidx_partials.id > macro_partials.pruned_through
+and %s
 and not exists (
 	select 1 from partial_comparisons
 	where partial_comparisons.macro_partial_id = macro_partials.id
@@ -313,7 +336,7 @@ and not exists (
 order by macro_partials.id asc,
 	gidx_partials.id asc
 limit %d
-`, limit)
+`, sameOrientation, limit)
 
 	var macroGidxViews []*model.MacroGidxView
 	rows, err := s.dbMap.Db.Query(sql, macro.Id)
@@ -346,6 +369,101 @@ limit %d
 	return macroGidxViews, nil
 }
 
+func (s *partialComparisonServiceSqlite3) FindPruned(macroPartial *model.MacroPartial) ([]*model.MacroGidxView, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	sql := `
+select macro_partials.id as macro_partial_id,
+	macro_partials.macro_id,
+	macro_partials.cover_partial_id,
+	macro_partials.aspect_id,
+	macro_partials.data as macro_partial_data,
+	gidx_partials.id as gidx_partial_id,
+	gidx_partials.gidx_id,
+	gidx_partials.data as gidx_partial_data
+from macro_partials join gidx_partials
+where macro_partials.id = ?
+and macro_partials.aspect_id = gidx_partials.aspect_id
+and gidx_partials.id <= macro_partials.pruned_through
+and ` + sameOrientation + `
+and not exists (
+	select 1 from partial_comparisons
+	where partial_comparisons.macro_partial_id = macro_partials.id
+	and partial_comparisons.gidx_partial_id = gidx_partials.id
+)
+order by gidx_partials.id asc
+`
+
+	var macroGidxViews []*model.MacroGidxView
+	rows, err := s.dbMap.Db.Query(sql, macroPartial.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		r := model.MacroGidxView{
+			MacroPartial: &model.MacroPartial{},
+			GidxPartial:  &model.GidxPartial{},
+		}
+		err = rows.Scan(
+			&r.MacroPartial.Id,
+			&r.MacroPartial.MacroId,
+			&r.MacroPartial.CoverPartialId,
+			&r.MacroPartial.AspectId,
+			&r.MacroPartial.Data,
+			&r.GidxPartial.Id,
+			&r.GidxPartial.GidxId,
+			&r.GidxPartial.Data,
+		)
+		if err != nil {
+			return nil, err
+		}
+		r.GidxPartial.AspectId = r.MacroPartial.AspectId
+		macroGidxViews = append(macroGidxViews, &r)
+	}
+
+	return macroGidxViews, rows.Err()
+}
+
+func (s *partialComparisonServiceSqlite3) KeepTop(macroPartial *model.MacroPartial, keep int) (int64, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// comparisons are made in order of index partial id, so every
+	// index partial up to the last one compared has been compared
+	_, err := s.dbMap.Db.Exec(`
+		update macro_partials
+		set pruned_through = max(pruned_through, coalesce((
+			select max(gidx_partial_id)
+			from partial_comparisons
+			where macro_partial_id = ?
+		), 0))
+		where id = ?
+	`, macroPartial.Id, macroPartial.Id)
+	if err != nil {
+		return int64(0), err
+	}
+
+	res, err := s.dbMap.Db.Exec(`
+		delete from partial_comparisons
+		where macro_partial_id = ?
+		and id not in (
+			select id
+			from partial_comparisons
+			where macro_partial_id = ?
+			order by dist asc, id asc
+			limit ?
+		)
+	`, macroPartial.Id, macroPartial.Id, keep)
+	if err != nil {
+		return int64(0), err
+	}
+
+	return res.RowsAffected()
+}
+
 func (s *partialComparisonServiceSqlite3) CreateFromView(view *model.MacroGidxView) (*model.PartialComparison, error) {
 	s.m.Lock()
 	defer s.m.Unlock()
@@ -72,6 +72,14 @@ func (s *projectServiceSqlite3) Update(project *model.Project) (int64, error) {
 	return s.dbMap.Update(project)
 }
 
+func (s *projectServiceSqlite3) Delete(project *model.Project) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	_, err := s.dbMap.Delete(project)
+	return err
+}
+
 func (s *projectServiceSqlite3) GetOneBy(conditions string, params ...interface{}) (*model.Project, error) {
 	s.m.Lock()
 	defer s.m.Unlock()
@@ -1,72 +1,334 @@
 package util
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"sort"
 	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
 )
 
-var (
-	exiftoolName = "exiftool"
+// MetadataPolicies are the ways that the exif metadata of the source image
+// can be copied to a mosaic. All copies every tag, safe copies every tag
+// except gps location and personal tags, such as the camera owner and serial
+// numbers, and none copies nothing.
+var MetadataPolicies = []string{"all", "safe", "none"}
+
+const (
+	exifTagOrientation     = 0x0112
+	exifTagStripOffsets    = 0x0111
+	exifTagStripByteCounts = 0x0117
+	exifTagXResolution     = 0x011a
+	exifTagYResolution     = 0x011b
+	exifTagResolutionUnit  = 0x0128
+	exifTagSoftware        = 0x0131
+	exifTagDateTime        = 0x0132
+	exifTagExifIFD         = 0x8769
+	exifTagGPSIFD          = 0x8825
+	exifTagInteropIFD      = 0xa005
+	exifTagPixelXDimension = 0xa002
+	exifTagPixelYDimension = 0xa003
+	exifTagICCProfile      = 0x8773
+	exifSoftware           = "https://github.com/atongen/gosaic"
+	exifResolutionUnitInch = 2
+	exifDateTimeFormat     = "2006:01:02 15:04:05"
 )
 
-func ExiftoolPath(toolPath string) (myPath string, err error) {
-	if toolPath == "" {
-		// provided path is empty, search PATH, it is not an error
-		// if exiftool isn't found
-		myPath, _ = ExiftoolPathFind()
-	} else {
-		myPath, err = ExiftoolPathValidate(toolPath)
-	}
-	return
+// exifSubIFDs are the tags that point to nested directories
+var exifSubIFDs = []uint16{exifTagExifIFD, exifTagGPSIFD, exifTagInteropIFD}
+
+// exifDropped are tags that describe the source image file,
+// rather than the photo, so are never copied to the mosaic
+var exifDropped = []uint16{
+	exifTagOrientation,
+	exifTagStripOffsets,
+	exifTagStripByteCounts,
+	exifTagPixelXDimension,
+	exifTagPixelYDimension,
 }
 
-// ExiftoolPathValidate validates the provided path to exiftool exists,
-// and if so, returns the absolute path to it.
-func ExiftoolPathValidate(toolPath string) (myPath string, err error) {
-	_, err = os.Stat(toolPath)
+// exifPersonal are the tags that the safe policy does not copy
+var exifPersonal = []uint16{
+	exifTagGPSIFD,
+	0x013b, // Artist
+	0x013c, // HostComputer
+	0x927c, // MakerNote
+	0x9286, // UserComment
+	0x9c9c, // XPComment
+	0x9c9d, // XPAuthor
+	0x9c9e, // XPKeywords
+	0x9c9f, // XPSubject
+	0xa420, // ImageUniqueID
+	0xa430, // CameraOwnerName
+	0xa431, // BodySerialNumber
+	0xa435, // LensSerialNumber
+}
+
+// exifDir is a tiff image file directory, with its nested directories
+type exifDir struct {
+	tags []*tiff.Tag
+	subs map[uint16]*exifDir
+}
+
+// ReadExif returns the tiff encoded exif data of the image at path,
+// or nil if it has none.
+func ReadExif(path string) ([]byte, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return
+		return nil, err
 	}
-	myPath, err = filepath.Abs(toolPath)
-	return
+	defer f.Close()
+
+	x, err := exif.Decode(f)
+	// no exif data
+	if err != nil || x.Raw == nil {
+		return nil, nil
+	}
+
+	return x.Raw, nil
 }
 
-// ExiftoolPathFind searches PATH for exiftool and returns the absolute
-// path to it if found.
-func ExiftoolPathFind() (myPath string, err error) {
-	myPath, err = exec.LookPath(exiftoolName)
+// FilterExif returns a copy of the tiff encoded exif data in raw, containing
+// the tags allowed by policy, with the resolution set to dpi. The thumbnail,
+// orientation and image dimensions of the source image are never copied.
+// It returns nil if policy is none or raw is empty.
+func FilterExif(raw []byte, policy string, dpi int) ([]byte, error) {
+	if policy == "none" || len(raw) == 0 {
+		return nil, nil
+	}
+	if !SliceContainsString(MetadataPolicies, policy) {
+		return nil, fmt.Errorf("Invalid metadata policy: %s", policy)
+	}
+
+	tif, err := tiff.Decode(bytes.NewReader(raw))
 	if err != nil {
+		return nil, err
+	}
+	if len(tif.Dirs) == 0 {
+		return nil, nil
+	}
+
+	dropped := exifDropped
+	if policy == "safe" {
+		dropped = append(append([]uint16{}, exifDropped...), exifPersonal...)
+	}
+
+	dir, err := readExifDir(raw, tif.Order, tif.Dirs[0], dropped)
+	if err != nil {
+		return nil, err
+	}
+
+	dir.set(exifTag(exifTagXResolution, tiff.DTRational, 1, exifRational(tif.Order, uint32(dpi), 1)))
+	dir.set(exifTag(exifTagYResolution, tiff.DTRational, 1, exifRational(tif.Order, uint32(dpi), 1)))
+	dir.set(exifTag(exifTagResolutionUnit, tiff.DTShort, 1, exifShort(tif.Order, exifResolutionUnitInch)))
+	dir.set(exifASCII(exifTagSoftware, exifSoftware))
+	dir.set(exifASCII(exifTagDateTime, time.Now().Format(exifDateTimeFormat)))
+
+	return encodeExif(tif.Order, dir), nil
+}
+
+// readExifDir copies the tags of d that are not dropped,
+// reading nested directories from raw
+func readExifDir(raw []byte, order binary.ByteOrder, d *tiff.Dir, dropped []uint16) (*exifDir, error) {
+	dir := &exifDir{subs: make(map[uint16]*exifDir)}
+
+	for _, tag := range d.Tags {
+		if exifContains(dropped, tag.Id) {
+			continue
+		}
+
+		if !exifContains(exifSubIFDs, tag.Id) {
+			dir.tags = append(dir.tags, tag)
+			continue
+		}
+
+		offset, err := tag.Int64(0)
+		if err != nil || offset <= 0 || offset >= int64(len(raw)) {
+			continue
+		}
+
+		r := bytes.NewReader(raw)
+		_, err = r.Seek(offset, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+
+		subDir, _, err := tiff.DecodeDir(r, order)
+		if err != nil {
+			// skip unreadable nested directories, rather than the whole exif
+			continue
+		}
+
+		sub, err := readExifDir(raw, order, subDir, dropped)
+		if err != nil {
+			return nil, err
+		}
+
+		dir.tags = append(dir.tags, tag)
+		dir.subs[tag.Id] = sub
+	}
+
+	return dir, nil
+}
+
+// set adds tag to dir, replacing any tag with the same id
+func (dir *exifDir) set(tag *tiff.Tag) {
+	for i, t := range dir.tags {
+		if t.Id == tag.Id {
+			dir.tags[i] = tag
+			return
+		}
+	}
+	dir.tags = append(dir.tags, tag)
+}
+
+// has is true when dir has a tag with id
+func (dir *exifDir) has(id uint16) bool {
+	for _, t := range dir.tags {
+		if t.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// convert changes the values of the tags of dir, and of its nested
+// directories, from byte order from to byte order to
+func (dir *exifDir) convert(from, to binary.ByteOrder) {
+	if from == to {
 		return
 	}
-	myPath, err = filepath.Abs(myPath)
-	return
+
+	for i, tag := range dir.tags {
+		var size int
+		switch tag.Type {
+		case tiff.DTShort, tiff.DTSShort:
+			size = 2
+		// rationals are pairs of longs
+		case tiff.DTLong, tiff.DTSLong, tiff.DTFloat, tiff.DTRational, tiff.DTSRational:
+			size = 4
+		case tiff.DTDouble:
+			size = 8
+		default:
+			continue
+		}
+
+		val := append([]byte{}, tag.Val...)
+		for j := 0; j+size <= len(val); j += size {
+			for a, b := j, j+size-1; a < b; a, b = a+1, b-1 {
+				val[a], val[b] = val[b], val[a]
+			}
+		}
+		dir.tags[i] = exifTag(tag.Id, tag.Type, tag.Count, val)
+	}
+
+	for _, sub := range dir.subs {
+		sub.convert(from, to)
+	}
 }
 
-// YYYY:MM:DD HH:MM:SS
-func ExifTags() map[string]string {
-	now := time.Now()
-	return map[string]string{
-		"ResolutionUnit": "inches",
-		"XResolution":    "300",
-		"YResolution":    "300",
-		"Software":       "https://github.com/atongen/gosaic",
-		"DateTime":       now.Format("2006:01:02 15:04:05"),
+// encodeExif writes dir as the only image file directory of a tiff structure
+func encodeExif(order binary.ByteOrder, dir *exifDir) []byte {
+	header := make([]byte, 8)
+	if order == binary.LittleEndian {
+		copy(header, "II")
+	} else {
+		copy(header, "MM")
+	}
+	order.PutUint16(header[2:], 42)
+	order.PutUint32(header[4:], 8)
+
+	return append(header, dir.encode(order, 8)...)
+}
+
+// encode writes dir at offset, followed by the values that do not fit in
+// its entries, and then its nested directories
+func (dir *exifDir) encode(order binary.ByteOrder, offset uint32) []byte {
+	sort.Sort(exifTagsById(dir.tags))
+
+	size := uint32(2 + 12*len(dir.tags) + 4)
+	entries := make([]byte, size)
+	data := []byte{}
+
+	order.PutUint16(entries, uint16(len(dir.tags)))
+	for i, tag := range dir.tags {
+		entry := entries[2+12*i : 2+12*(i+1)]
+		order.PutUint16(entry, tag.Id)
+
+		if _, ok := dir.subs[tag.Id]; ok {
+			// filled in once the values are written
+			order.PutUint16(entry[2:], uint16(tiff.DTLong))
+			order.PutUint32(entry[4:], 1)
+			continue
+		}
+
+		order.PutUint16(entry[2:], uint16(tag.Type))
+		order.PutUint32(entry[4:], tag.Count)
+		if len(tag.Val) <= 4 {
+			copy(entry[8:], tag.Val)
+			continue
+		}
+
+		order.PutUint32(entry[8:], offset+size+uint32(len(data)))
+		data = append(data, tag.Val...)
+		// values must start on a word boundary
+		if len(data)%2 == 1 {
+			data = append(data, 0)
+		}
 	}
+
+	for i, tag := range dir.tags {
+		sub, ok := dir.subs[tag.Id]
+		if !ok {
+			continue
+		}
+
+		subOffset := offset + size + uint32(len(data))
+		order.PutUint32(entries[2+12*i+8:], subOffset)
+		data = append(data, sub.encode(order, subOffset)...)
+	}
+
+	return append(entries, data...)
+}
+
+type exifTagsById []*tiff.Tag
+
+func (t exifTagsById) Len() int           { return len(t) }
+func (t exifTagsById) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t exifTagsById) Less(i, j int) bool { return t[i].Id < t[j].Id }
+
+func exifTag(id uint16, typ tiff.DataType, count uint32, val []byte) *tiff.Tag {
+	return &tiff.Tag{Id: id, Type: typ, Count: count, Val: val}
+}
+
+func exifASCII(id uint16, s string) *tiff.Tag {
+	val := append([]byte(s), 0)
+	return exifTag(id, tiff.DTAscii, uint32(len(val)), val)
+}
+
+func exifShort(order binary.ByteOrder, n uint16) []byte {
+	val := make([]byte, 2)
+	order.PutUint16(val, n)
+	return val
+}
+
+func exifRational(order binary.ByteOrder, num, den uint32) []byte {
+	val := make([]byte, 8)
+	order.PutUint32(val, num)
+	order.PutUint32(val[4:], den)
+	return val
 }
 
-// ExifCp copies exif data from src image to dst image.
-// It excludes Orientation tag because the orientation has been
-// normalized in the processed images.
-func ExifCp(toolPath string, src, dst string) (string, error) {
-	args := []string{"-overwrite_original_in_place", "-tagsFromFile", src, "-x", "Orientation"}
-	for key, value := range ExifTags() {
-		args = append(args, fmt.Sprintf("-%s=\"%s\"", key, value))
+func exifContains(ids []uint16, id uint16) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
 	}
-	args = append(args, dst)
-	cmd := exec.Command(toolPath, args...)
-	out, err := cmd.CombinedOutput()
-	return string(out), err
+	return false
 }
@@ -0,0 +1,121 @@
+package util
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// iccCurveSize is the number of entries in the sRGB tone curve
+const iccCurveSize = 1024
+
+// SRGBProfile returns a version 2 ICC profile of the sRGB color space,
+// with D50 adapted primaries and a sampled sRGB tone curve,
+// for embedding in mosaic images.
+func SRGBProfile() []byte {
+	type iccTag struct {
+		sig  string
+		data []byte
+	}
+
+	trc := iccCurve()
+	tags := []iccTag{
+		{"desc", iccDesc("sRGB")},
+		{"cprt", iccText("No copyright, use freely")},
+		{"wtpt", iccXYZ(0.9642, 1.0, 0.8249)},
+		{"rXYZ", iccXYZ(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", iccXYZ(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", iccXYZ(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	headerSize := 128
+	tableSize := 4 + 12*len(tags)
+
+	table := make([]byte, tableSize)
+	binary.BigEndian.PutUint32(table, uint32(len(tags)))
+
+	data := []byte{}
+	offsets := make(map[*byte]int)
+	for i, tag := range tags {
+		// tags with the same data share it
+		offset, ok := offsets[&tag.data[0]]
+		if !ok {
+			offset = headerSize + tableSize + len(data)
+			offsets[&tag.data[0]] = offset
+			data = append(data, tag.data...)
+			// tag data must start on a four byte boundary
+			for len(data)%4 != 0 {
+				data = append(data, 0)
+			}
+		}
+
+		entry := table[4+12*i:]
+		copy(entry, tag.sig)
+		binary.BigEndian.PutUint32(entry[4:], uint32(offset))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(tag.data)))
+	}
+
+	size := headerSize + tableSize + len(data)
+	header := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(header, uint32(size))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000)
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	// date and time of creation
+	for i, n := range []uint16{2017, 1, 1, 0, 0, 0} {
+		binary.BigEndian.PutUint16(header[24+2*i:], n)
+	}
+	copy(header[36:], "acsp")
+	// D50 illuminant of the profile connection space
+	copy(header[68:], iccXYZ(0.9642, 1.0, 0.8249)[8:])
+
+	profile := append(header, table...)
+	return append(profile, data...)
+}
+
+// iccCurve samples the sRGB transfer function
+func iccCurve() []byte {
+	curve := make([]byte, 12+2*iccCurveSize)
+	copy(curve, "curv")
+	binary.BigEndian.PutUint32(curve[8:], iccCurveSize)
+
+	for i := 0; i < iccCurveSize; i++ {
+		v := float64(i) / float64(iccCurveSize-1)
+		if v <= 0.04045 {
+			v = v / 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.BigEndian.PutUint16(curve[12+2*i:], uint16(Round(v*65535)))
+	}
+
+	return curve
+}
+
+func iccXYZ(x, y, z float64) []byte {
+	xyz := make([]byte, 20)
+	copy(xyz, "XYZ ")
+	for i, v := range []float64{x, y, z} {
+		binary.BigEndian.PutUint32(xyz[8+4*i:], uint32(int32(Round(v*65536))))
+	}
+	return xyz
+}
+
+func iccText(s string) []byte {
+	text := make([]byte, 8+len(s)+1)
+	copy(text, "text")
+	copy(text[8:], s)
+	return text
+}
+
+func iccDesc(s string) []byte {
+	// ascii description, followed by empty unicode and scriptcode descriptions
+	desc := make([]byte, 12+len(s)+1+4+4+2+1+67)
+	copy(desc, "desc")
+	binary.BigEndian.PutUint32(desc[8:], uint32(len(s)+1))
+	copy(desc[12:], s)
+	return desc
+}
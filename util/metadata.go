@@ -0,0 +1,283 @@
+package util
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/rwcarlsen/goexif/tiff"
+)
+
+// DefaultDPI is the print resolution recorded in mosaic images
+const DefaultDPI = 300
+
+const (
+	// the most bytes that fit in a jpeg segment, after its length
+	jpegMaxSegment = 65533
+	// jpeg start of image marker
+	jpegHeaderSize = 2
+	// png signature and IHDR chunk
+	pngHeaderSize = 8 + 12 + 13
+)
+
+// Metadata is written to mosaic images as they are encoded
+type Metadata struct {
+	// DPI is the print resolution, in dots per inch
+	DPI int
+	// Exif is tiff encoded exif data, or nil
+	Exif []byte
+	// ICC is an embedded color profile, or nil
+	ICC []byte
+}
+
+// NewMetadata returns the metadata of a mosaic drawn from the image at src.
+// The exif data of src is copied according to policy, and an sRGB color
+// profile is always embedded.
+func NewMetadata(src, policy string, dpi int) (*Metadata, error) {
+	if policy == "" {
+		policy = "safe"
+	}
+	if !SliceContainsString(MetadataPolicies, policy) {
+		return nil, errors.New("Invalid metadata policy: " + policy)
+	}
+
+	if dpi <= 0 {
+		dpi = DefaultDPI
+	}
+
+	meta := &Metadata{
+		DPI: dpi,
+		ICC: SRGBProfile(),
+	}
+
+	if policy == "none" || src == "" {
+		return meta, nil
+	}
+
+	raw, err := ReadExif(src)
+	if err != nil {
+		return nil, err
+	}
+
+	meta.Exif, err = FilterExif(raw, policy, dpi)
+	if err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// JpegSegments returns the JFIF, exif and ICC profile application segments
+// of a jpeg, which follow its start of image marker
+func (m *Metadata) JpegSegments() []byte {
+	buf := &bytes.Buffer{}
+
+	// JFIF version 1.01, with density in dots per inch and no thumbnail
+	jfif := []byte("JFIF\x00\x01\x01\x01\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint16(jfif[8:], uint16(m.DPI))
+	binary.BigEndian.PutUint16(jfif[10:], uint16(m.DPI))
+	writeJpegSegment(buf, 0xe0, jfif)
+
+	if m.Exif != nil {
+		writeJpegSegment(buf, 0xe1, append([]byte("Exif\x00\x00"), m.Exif...))
+	}
+
+	if m.ICC != nil {
+		// a single chunk, numbered 1 of 1
+		writeJpegSegment(buf, 0xe2, append([]byte("ICC_PROFILE\x00\x01\x01"), m.ICC...))
+	}
+
+	return buf.Bytes()
+}
+
+// writeJpegSegment writes the segment to w, skipping it if it is too large
+func writeJpegSegment(w *bytes.Buffer, marker byte, data []byte) {
+	if len(data) > jpegMaxSegment {
+		return
+	}
+
+	w.Write([]byte{0xff, marker})
+	binary.Write(w, binary.BigEndian, uint16(len(data)+2))
+	w.Write(data)
+}
+
+// PngChunks returns the ICC profile, physical pixel dimensions and exif
+// chunks of a png, which follow its IHDR chunk
+func (m *Metadata) PngChunks() []byte {
+	buf := &bytes.Buffer{}
+
+	if m.ICC != nil {
+		iccp := &bytes.Buffer{}
+		iccp.WriteString("sRGB\x00\x00")
+		zw := zlib.NewWriter(iccp)
+		zw.Write(m.ICC)
+		zw.Close()
+		writePngChunk(buf, "iCCP", iccp.Bytes())
+	}
+
+	// pixels per metre, with a unit of metres
+	ppm := uint32(Round(float64(m.DPI) / 0.0254))
+	phys := make([]byte, 9)
+	binary.BigEndian.PutUint32(phys, ppm)
+	binary.BigEndian.PutUint32(phys[4:], ppm)
+	phys[8] = 1
+	writePngChunk(buf, "pHYs", phys)
+
+	if m.Exif != nil {
+		writePngChunk(buf, "eXIf", m.Exif)
+	}
+
+	return buf.Bytes()
+}
+
+func writePngChunk(w *bytes.Buffer, name string, data []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(data)))
+	crc := crc32.NewIEEE()
+	io.WriteString(crc, name)
+	crc.Write(data)
+	w.WriteString(name)
+	w.Write(data)
+	binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+// metadataWriter inserts metadata into an encoded image,
+// after its first n bytes
+type metadataWriter struct {
+	w        io.Writer
+	n        int
+	head     []byte
+	metadata []byte
+	done     bool
+}
+
+// NewJpegMetadataWriter returns a writer that adds m to the jpeg written to w
+func NewJpegMetadataWriter(w io.Writer, m *Metadata) io.Writer {
+	return &metadataWriter{w: w, n: jpegHeaderSize, metadata: m.JpegSegments()}
+}
+
+// NewPngMetadataWriter returns a writer that adds m to the png written to w
+func NewPngMetadataWriter(w io.Writer, m *Metadata) io.Writer {
+	return &metadataWriter{w: w, n: pngHeaderSize, metadata: m.PngChunks()}
+}
+
+func (mw *metadataWriter) Write(p []byte) (int, error) {
+	if mw.done {
+		return mw.w.Write(p)
+	}
+
+	mw.head = append(mw.head, p...)
+	if len(mw.head) < mw.n {
+		return len(p), nil
+	}
+
+	mw.done = true
+	for _, b := range [][]byte{mw.head[:mw.n], mw.metadata, mw.head[mw.n:]} {
+		_, err := mw.w.Write(b)
+		if err != nil {
+			return 0, err
+		}
+	}
+	mw.head = nil
+
+	return len(p), nil
+}
+
+// TiffFile is an encoded tiff that metadata can be added to
+type TiffFile interface {
+	io.ReadWriteSeeker
+	io.ReaderAt
+}
+
+// WriteTiffMetadata adds m to the first image of the tiff file f: its
+// resolution, color profile and exif tags. The directory of the image is
+// written again, with the tags of m, at the end of f, since they do not fit
+// in the one the tiff encoder wrote. Exif tags that the image has, which
+// describe how it is encoded, are not replaced.
+func WriteTiffMetadata(f TiffFile, m *Metadata) error {
+	header := make([]byte, 8)
+	_, err := f.ReadAt(header, 0)
+	if err != nil {
+		return err
+	}
+
+	var order binary.ByteOrder
+	switch string(header[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return errors.New("Invalid tiff byte order")
+	}
+
+	_, err = f.Seek(int64(order.Uint32(header[4:])), io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	d, _, err := tiff.DecodeDir(f, order)
+	if err != nil {
+		return err
+	}
+	dir := &exifDir{tags: d.Tags, subs: make(map[uint16]*exifDir)}
+
+	if m.Exif != nil {
+		x, err := tiff.Decode(bytes.NewReader(m.Exif))
+		if err != nil {
+			return err
+		}
+
+		if len(x.Dirs) > 0 {
+			exif, err := readExifDir(m.Exif, x.Order, x.Dirs[0], exifDropped)
+			if err != nil {
+				return err
+			}
+			exif.convert(x.Order, order)
+
+			for _, tag := range exif.tags {
+				if dir.has(tag.Id) {
+					continue
+				}
+				dir.tags = append(dir.tags, tag)
+				if sub, ok := exif.subs[tag.Id]; ok {
+					dir.subs[tag.Id] = sub
+				}
+			}
+		}
+	}
+
+	dir.set(exifTag(exifTagXResolution, tiff.DTRational, 1, exifRational(order, uint32(m.DPI), 1)))
+	dir.set(exifTag(exifTagYResolution, tiff.DTRational, 1, exifRational(order, uint32(m.DPI), 1)))
+	dir.set(exifTag(exifTagResolutionUnit, tiff.DTShort, 1, exifShort(order, exifResolutionUnitInch)))
+	if m.ICC != nil {
+		dir.set(exifTag(exifTagICCProfile, tiff.DTUndefined, uint32(len(m.ICC)), m.ICC))
+	}
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	// directories must start on a word boundary
+	if end%2 == 1 {
+		_, err = f.Write([]byte{0})
+		if err != nil {
+			return err
+		}
+		end++
+	}
+
+	_, err = f.Write(dir.encode(order, uint32(end)))
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Seek(4, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	return binary.Write(f, order, uint32(end))
+}
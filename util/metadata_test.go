@@ -0,0 +1,281 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"github.com/rwcarlsen/goexif/tiff"
+	xtiff "golang.org/x/image/tiff"
+)
+
+func testExif() []byte {
+	order := binary.LittleEndian
+	gps := &exifDir{tags: []*tiff.Tag{exifASCII(0x0001, "N")}}
+	sub := &exifDir{tags: []*tiff.Tag{
+		exifTag(0x829a, tiff.DTRational, 1, exifRational(order, 1, 250)),
+		exifASCII(0xa420, "12345"),
+	}}
+	ifd0 := &exifDir{
+		tags: []*tiff.Tag{
+			exifASCII(0x010f, "Canon"),
+			exifTag(exifTagOrientation, tiff.DTShort, 1, exifShort(order, 6)),
+			exifASCII(0x013b, "Somebody"),
+			exifTag(exifTagExifIFD, tiff.DTLong, 1, nil),
+			exifTag(exifTagGPSIFD, tiff.DTLong, 1, nil),
+		},
+		subs: map[uint16]*exifDir{
+			exifTagExifIFD: sub,
+			exifTagGPSIFD:  gps,
+		},
+	}
+	return encodeExif(order, ifd0)
+}
+
+func TestFilterExif(t *testing.T) {
+	for _, tt := range []struct {
+		policy  string
+		present []exif.FieldName
+		missing []exif.FieldName
+	}{
+		{
+			"all",
+			[]exif.FieldName{exif.Make, exif.Artist, exif.ExposureTime, exif.ImageUniqueID, exif.GPSLatitudeRef, exif.XResolution},
+			[]exif.FieldName{exif.Orientation},
+		},
+		{
+			"safe",
+			[]exif.FieldName{exif.Make, exif.ExposureTime, exif.XResolution, exif.Software},
+			[]exif.FieldName{exif.Orientation, exif.Artist, exif.ImageUniqueID, exif.GPSLatitudeRef},
+		},
+	} {
+		raw, err := FilterExif(testExif(), tt.policy, 150)
+		if err != nil {
+			t.Fatalf("FilterExif(%s) error: %s\n", tt.policy, err.Error())
+		}
+
+		x, err := exif.Decode(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("Error decoding filtered exif for policy %s: %s\n", tt.policy, err.Error())
+		}
+
+		for _, name := range tt.present {
+			if _, err := x.Get(name); err != nil {
+				t.Errorf("FilterExif(%s) missing tag %s\n", tt.policy, name)
+			}
+		}
+
+		for _, name := range tt.missing {
+			if _, err := x.Get(name); err == nil {
+				t.Errorf("FilterExif(%s) has tag %s\n", tt.policy, name)
+			}
+		}
+
+		res, err := x.Get(exif.XResolution)
+		if err == nil {
+			num, den, _ := res.Rat2(0)
+			if num != 150 || den != 1 {
+				t.Errorf("FilterExif(%s) resolution => %d/%d, want 150/1\n", tt.policy, num, den)
+			}
+		}
+	}
+
+	raw, err := FilterExif(testExif(), "none", 150)
+	if err != nil || raw != nil {
+		t.Errorf("FilterExif(none) => %v, %v, want nil\n", raw, err)
+	}
+
+	_, err = FilterExif(testExif(), "bogus", 150)
+	if err == nil {
+		t.Errorf("FilterExif(bogus) expected error\n")
+	}
+}
+
+func TestSRGBProfile(t *testing.T) {
+	profile := SRGBProfile()
+
+	if int(binary.BigEndian.Uint32(profile)) != len(profile) {
+		t.Errorf("Expected profile size %d, got %d\n", len(profile), binary.BigEndian.Uint32(profile))
+	}
+
+	if string(profile[36:40]) != "acsp" {
+		t.Errorf("Expected profile signature acsp, got %s\n", profile[36:40])
+	}
+
+	if n := binary.BigEndian.Uint32(profile[128:]); n != 9 {
+		t.Errorf("Expected 9 profile tags, got %d\n", n)
+	}
+}
+
+func TestJpegMetadataWriter(t *testing.T) {
+	meta := &Metadata{DPI: 300, Exif: testExif(), ICC: SRGBProfile()}
+
+	buf := &bytes.Buffer{}
+	err := jpeg.Encode(NewJpegMetadataWriter(buf, meta), image.NewGray(image.Rect(0, 0, 10, 10)), nil)
+	if err != nil {
+		t.Fatalf("Error encoding jpeg: %s\n", err.Error())
+	}
+
+	b := buf.Bytes()
+	if !bytes.HasPrefix(b, []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00\x01\x01\x01\x01\x2c\x01\x2c")) {
+		t.Errorf("Expected JFIF segment with 300 dpi, got % x\n", b[:20])
+	}
+
+	for _, s := range []string{"Exif\x00\x00", "ICC_PROFILE\x00"} {
+		if !bytes.Contains(b, []byte(s)) {
+			t.Errorf("Expected jpeg to contain %q segment\n", s)
+		}
+	}
+
+	_, err = jpeg.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Errorf("Error decoding jpeg with metadata: %s\n", err.Error())
+	}
+}
+
+func TestPngMetadataWriter(t *testing.T) {
+	meta := &Metadata{DPI: 254, ICC: SRGBProfile()}
+
+	buf := &bytes.Buffer{}
+	err := png.Encode(NewPngMetadataWriter(buf, meta), image.NewGray(image.Rect(0, 0, 10, 10)))
+	if err != nil {
+		t.Fatalf("Error encoding png: %s\n", err.Error())
+	}
+
+	b := buf.Bytes()
+	if string(b[pngHeaderSize+4:pngHeaderSize+8]) != "iCCP" {
+		t.Errorf("Expected iCCP chunk after IHDR, got %q\n", b[pngHeaderSize+4:pngHeaderSize+8])
+	}
+
+	// 254 dpi is 10000 pixels per metre
+	if !bytes.Contains(b, []byte("pHYs\x00\x00\x27\x10\x00\x00\x27\x10\x01")) {
+		t.Errorf("Expected pHYs chunk with 10000 pixels per metre\n")
+	}
+
+	if bytes.Contains(b, []byte("eXIf")) {
+		t.Errorf("Expected no eXIf chunk without exif\n")
+	}
+
+	_, err = png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Errorf("Error decoding png with metadata: %s\n", err.Error())
+	}
+}
+
+func TestWriteTiffMetadata(t *testing.T) {
+	f, err := ioutil.TempFile("", "gosaic_test_tiff_metadata")
+	if err != nil {
+		t.Fatalf("Error creating temp file: %s\n", err.Error())
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	err = xtiff.Encode(f, image.NewGray(image.Rect(0, 0, 10, 10)), nil)
+	if err != nil {
+		t.Fatalf("Error encoding tiff: %s\n", err.Error())
+	}
+
+	// exif in the other byte order than the little endian tiff
+	order := binary.BigEndian
+	raw := encodeExif(order, &exifDir{
+		tags: []*tiff.Tag{
+			exifASCII(0x010f, "Canon"),
+			exifTag(0x0100, tiff.DTShort, 1, exifShort(order, 4000)),
+			exifTag(exifTagExifIFD, tiff.DTLong, 1, nil),
+		},
+		subs: map[uint16]*exifDir{
+			exifTagExifIFD: {tags: []*tiff.Tag{
+				exifTag(0x829a, tiff.DTRational, 1, exifRational(order, 1, 250)),
+			}},
+		},
+	})
+
+	m := &Metadata{DPI: 600, Exif: raw, ICC: SRGBProfile()}
+	err = WriteTiffMetadata(f, m)
+	if err != nil {
+		t.Fatalf("Error writing tiff metadata: %s\n", err.Error())
+	}
+
+	_, err = f.Seek(0, 0)
+	if err != nil {
+		t.Fatalf("Error seeking tiff: %s\n", err.Error())
+	}
+
+	x, err := exif.Decode(f)
+	if err != nil {
+		t.Fatalf("Error decoding tiff: %s\n", err.Error())
+	}
+
+	for _, name := range []exif.FieldName{exif.XResolution, exif.YResolution} {
+		tag, err := x.Get(name)
+		if err != nil {
+			t.Fatalf("Error getting tiff %s: %s\n", name, err.Error())
+		}
+
+		num, den, _ := tag.Rat2(0)
+		if num != 600 || den != 1 {
+			t.Errorf("Expected tiff %s 600/1, got %d/%d\n", name, num, den)
+		}
+	}
+
+	make, err := x.Get(exif.Make)
+	if err != nil {
+		t.Errorf("Expected tiff to have exif make\n")
+	} else if s, _ := make.StringVal(); s != "Canon" {
+		t.Errorf("Expected tiff make Canon, got %s\n", s)
+	}
+
+	exposure, err := x.Get(exif.ExposureTime)
+	if err != nil {
+		t.Errorf("Expected tiff to have exif exposure time\n")
+	} else if num, den, _ := exposure.Rat2(0); num != 1 || den != 250 {
+		t.Errorf("Expected tiff exposure time 1/250, got %d/%d\n", num, den)
+	}
+
+	width, err := x.Get(exif.ImageWidth)
+	if err != nil {
+		t.Fatalf("Error getting tiff width: %s\n", err.Error())
+	}
+	if n, _ := width.Int(0); n != 10 {
+		t.Errorf("Expected tiff width 10, got %d\n", n)
+	}
+
+	_, err = f.Seek(0, 0)
+	if err != nil {
+		t.Fatalf("Error seeking tiff: %s\n", err.Error())
+	}
+
+	d, err := tiff.Decode(f)
+	if err != nil {
+		t.Fatalf("Error decoding tiff directories: %s\n", err.Error())
+	}
+
+	var icc []byte
+	for _, tag := range d.Dirs[0].Tags {
+		if tag.Id == exifTagICCProfile {
+			icc = tag.Val
+		}
+	}
+	if !bytes.Equal(icc, m.ICC) {
+		t.Errorf("Expected tiff to have the icc profile\n")
+	}
+
+	_, err = f.Seek(0, 0)
+	if err != nil {
+		t.Fatalf("Error seeking tiff: %s\n", err.Error())
+	}
+
+	img, err := xtiff.Decode(f)
+	if err != nil {
+		t.Fatalf("Error decoding tiff image: %s\n", err.Error())
+	}
+	if img.Bounds().Dx() != 10 || img.Bounds().Dy() != 10 {
+		t.Errorf("Expected 10x10 tiff image, got %v\n", img.Bounds())
+	}
+}
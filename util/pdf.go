@@ -0,0 +1,164 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// PdfWriter writes a pdf document with a single jpeg image filling each page.
+// Images are streamed straight into the document as they are encoded.
+type PdfWriter struct {
+	w       *countingWriter
+	offsets []int64
+	pages   []int
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// the catalog and page tree are the first two objects,
+// but they are written last, once all pages are known
+const (
+	pdfCatalogObj = 1
+	pdfPagesObj   = 2
+)
+
+// NewPdfWriter returns a writer of a pdf document to w,
+// and writes the pdf header
+func NewPdfWriter(w io.Writer) (*PdfWriter, error) {
+	p := &PdfWriter{
+		w:       &countingWriter{w: w},
+		offsets: make([]int64, pdfPagesObj),
+	}
+
+	_, err := io.WriteString(p.w, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// nextObj reserves the number of a new object
+func (p *PdfWriter) nextObj() int {
+	p.offsets = append(p.offsets, 0)
+	return len(p.offsets)
+}
+
+// startObj records the offset of object num, and writes its header
+func (p *PdfWriter) startObj(num int) error {
+	p.offsets[num-1] = p.w.n
+	_, err := fmt.Fprintf(p.w, "%d 0 obj\n", num)
+	return err
+}
+
+func (p *PdfWriter) writeObj(num int, body string) error {
+	err := p.startObj(num)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(p.w, "%s\nendobj\n", body)
+	return err
+}
+
+// AddJpegPage adds a page of width by height points, filled with a jpeg image
+// of imgWidth by imgHeight pixels. The image is written by encode, which
+// must write an RGB jpeg.
+func (p *PdfWriter) AddJpegPage(width, height float64, imgWidth, imgHeight int, encode func(io.Writer) error) error {
+	img := p.nextObj()
+	imgLen := p.nextObj()
+	content := p.nextObj()
+	page := p.nextObj()
+
+	err := p.startObj(img)
+	if err != nil {
+		return err
+	}
+
+	// the length of the stream is not known until it has been
+	// encoded, so it is written to an object after the stream
+	_, err = fmt.Fprintf(p.w, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d 0 R >>\nstream\n", imgWidth, imgHeight, imgLen)
+	if err != nil {
+		return err
+	}
+
+	start := p.w.n
+	err = encode(p.w)
+	if err != nil {
+		return err
+	}
+	length := p.w.n - start
+
+	_, err = io.WriteString(p.w, "\nendstream\nendobj\n")
+	if err != nil {
+		return err
+	}
+
+	err = p.writeObj(imgLen, fmt.Sprintf("%d", length))
+	if err != nil {
+		return err
+	}
+
+	ops := fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", width, height)
+	err = p.writeObj(content, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(ops), ops))
+	if err != nil {
+		return err
+	}
+
+	err = p.writeObj(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>", pdfPagesObj, width, height, img, content))
+	if err != nil {
+		return err
+	}
+
+	p.pages = append(p.pages, page)
+
+	return nil
+}
+
+// Close writes the page tree, catalog and cross-reference table of the
+// document. It does not close the underlying writer.
+func (p *PdfWriter) Close() error {
+	kids := &bytes.Buffer{}
+	for i, page := range p.pages {
+		if i > 0 {
+			kids.WriteString(" ")
+		}
+		fmt.Fprintf(kids, "%d 0 R", page)
+	}
+
+	err := p.writeObj(pdfPagesObj, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(p.pages)))
+	if err != nil {
+		return err
+	}
+
+	err = p.writeObj(pdfCatalogObj, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObj))
+	if err != nil {
+		return err
+	}
+
+	xref := p.w.n
+	_, err = fmt.Fprintf(p.w, "xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	if err != nil {
+		return err
+	}
+
+	for _, offset := range p.offsets {
+		_, err = fmt.Fprintf(p.w, "%010d 00000 n \n", offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(p.w, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, pdfCatalogObj, xref)
+	return err
+}
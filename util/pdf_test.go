@@ -0,0 +1,81 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestPdfWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf, err := NewPdfWriter(buf)
+	if err != nil {
+		t.Fatalf("Error creating pdf: %s\n", err.Error())
+	}
+
+	for i := 0; i < 2; i++ {
+		img := image.NewNRGBA(image.Rect(0, 0, 30, 20))
+		err = pdf.AddJpegPage(72, 48, 30, 20, func(w io.Writer) error {
+			return jpeg.Encode(w, img, nil)
+		})
+		if err != nil {
+			t.Fatalf("Error adding pdf page: %s\n", err.Error())
+		}
+	}
+
+	err = pdf.Close()
+	if err != nil {
+		t.Fatalf("Error closing pdf: %s\n", err.Error())
+	}
+
+	b := buf.Bytes()
+	if !bytes.HasPrefix(b, []byte("%PDF-1.4\n")) {
+		t.Errorf("Expected pdf header, got %q\n", b[:9])
+	}
+
+	if !bytes.Contains(b, []byte("/Count 2")) {
+		t.Errorf("Expected pdf with 2 pages\n")
+	}
+
+	if !bytes.HasSuffix(b, []byte("%%EOF\n")) {
+		t.Errorf("Expected pdf to end with %%%%EOF\n")
+	}
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(b)
+	if m == nil {
+		t.Fatalf("Expected pdf startxref\n")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(b[xref:], []byte("xref\n0 11\n")) {
+		t.Fatalf("Expected xref table with 11 entries at %d\n", xref)
+	}
+
+	// every object must start at the offset in the xref table
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(b[xref:], -1)
+	if len(entries) != 10 {
+		t.Fatalf("Expected 10 xref entries, got %d\n", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		obj := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !bytes.HasPrefix(b[offset:], []byte(obj)) {
+			t.Errorf("Expected object %d at offset %d\n", i+1, offset)
+		}
+	}
+
+	// the first image stream, and the object that holds its length
+	loc := regexp.MustCompile(`/Length (\d+) 0 R >>\nstream\n`).FindSubmatchIndex(b)
+	if loc == nil {
+		t.Fatalf("Expected image stream with indirect length\n")
+	}
+	length := bytes.Index(b[loc[1]:], []byte("\nendstream"))
+	obj := fmt.Sprintf("%s 0 obj\n%d\nendobj\n", b[loc[2]:loc[3]], length)
+	if !bytes.Contains(b, []byte(obj)) {
+		t.Errorf("Expected image stream length object %q\n", obj)
+	}
+}
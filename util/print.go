@@ -0,0 +1,149 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrintPresets are named print sizes, as width x height.
+// They are all portrait, add the landscape suffix to swap
+// their dimensions, for example "a2-landscape".
+var PrintPresets = map[string]string{
+	"letter":    "8.5x11in",
+	"legal":     "8.5x14in",
+	"tabloid":   "11x17in",
+	"a4":        "210x297mm",
+	"a3":        "297x420mm",
+	"a2":        "420x594mm",
+	"a1":        "594x841mm",
+	"a0":        "841x1189mm",
+	"poster-s":  "11x17in",
+	"poster-m":  "18x24in",
+	"poster-l":  "24x36in",
+	"poster-xl": "27x40in",
+	"canvas-s":  "12x16in",
+	"canvas-m":  "16x20in",
+	"canvas-l":  "24x30in",
+	"canvas-xl": "30x40in",
+}
+
+const landscapeSuffix = "-landscape"
+
+// lengthUnits are the number of inches in each supported unit of length
+var lengthUnits = map[string]float64{
+	"in": 1.0,
+	"cm": 1.0 / 2.54,
+	"mm": 1.0 / 25.4,
+}
+
+// PrintPresetNames returns the sorted names of the print presets
+func PrintPresetNames() []string {
+	names := make([]string, 0, len(PrintPresets))
+	for name := range PrintPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseLength parses a physical length, such as "0.125in", "3mm" or "1.5cm",
+// and returns it in inches. A length without a unit is in inches.
+func ParseLength(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	factor := 1.0
+	for unit, f := range lengthUnits {
+		if strings.HasSuffix(s, unit) {
+			s = strings.TrimSuffix(s, unit)
+			factor = f
+			break
+		}
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid length: %s", s)
+	}
+	if v < 0 {
+		return 0, errors.New("Length cannot be negative")
+	}
+
+	return v * factor, nil
+}
+
+// ParsePrintSize parses a print size, either the name of a preset, or
+// width x height with a unit, such as "24x36in" or "50x70cm".
+// It returns the width and height in inches.
+func ParsePrintSize(s string) (float64, float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	landscape := strings.HasSuffix(s, landscapeSuffix)
+	if preset, ok := PrintPresets[strings.TrimSuffix(s, landscapeSuffix)]; ok {
+		w, h, err := ParsePrintSize(preset)
+		if landscape {
+			w, h = h, w
+		}
+		return w, h, err
+	}
+
+	unit := ""
+	for u := range lengthUnits {
+		if strings.HasSuffix(s, u) {
+			unit = u
+			s = strings.TrimSuffix(s, u)
+			break
+		}
+	}
+
+	dims := strings.Split(s, "x")
+	if len(dims) != 2 {
+		return 0, 0, fmt.Errorf("Invalid print size: %s", s)
+	}
+
+	w, err := ParseLength(dims[0] + unit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	h, err := ParseLength(dims[1] + unit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if w == 0 || h == 0 {
+		return 0, 0, errors.New("Print size must be greater than zero")
+	}
+
+	return w, h, nil
+}
+
+// ParseGrid parses a grid of columns x rows, such as "3x2"
+func ParseGrid(s string) (int, int, error) {
+	dims := strings.Split(strings.ToLower(strings.TrimSpace(s)), "x")
+	if len(dims) != 2 {
+		return 0, 0, fmt.Errorf("Invalid grid: %s", s)
+	}
+
+	cols, err := strconv.Atoi(dims[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid grid: %s", s)
+	}
+
+	rows, err := strconv.Atoi(dims[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid grid: %s", s)
+	}
+
+	if cols <= 0 || rows <= 0 {
+		return 0, 0, errors.New("Grid must have at least one column and row")
+	}
+
+	return cols, rows, nil
+}
+
+// InchesToPixels returns the number of pixels that inches spans at dpi
+func InchesToPixels(inches float64, dpi int) int {
+	return Round(inches * float64(dpi))
+}
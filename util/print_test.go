@@ -0,0 +1,78 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseLength(t *testing.T) {
+	for _, tt := range []struct {
+		s   string
+		in  float64
+		err bool
+	}{
+		{"0.125in", 0.125, false},
+		{"2", 2.0, false},
+		{"2.54cm", 1.0, false},
+		{"3mm", 3.0 / 25.4, false},
+		{"-1in", 0, true},
+		{"abc", 0, true},
+	} {
+		in, err := ParseLength(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseLength(%s) error => %v, want error %t", tt.s, err, tt.err)
+		} else if math.Abs(in-tt.in) > 1e-9 {
+			t.Errorf("ParseLength(%s) => %f, want %f", tt.s, in, tt.in)
+		}
+	}
+}
+
+func TestParsePrintSize(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		w, h float64
+		err  bool
+	}{
+		{"24x36in", 24, 36, false},
+		{"50.8x76.2cm", 20, 30, false},
+		{"8.5x11", 8.5, 11, false},
+		{"letter", 8.5, 11, false},
+		{"A4", 210 / 25.4, 297 / 25.4, false},
+		{"poster-l-landscape", 36, 24, false},
+		{"24in", 0, 0, true},
+		{"0x10in", 0, 0, true},
+		{"bogus", 0, 0, true},
+	} {
+		w, h, err := ParsePrintSize(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("ParsePrintSize(%s) error => %v, want error %t", tt.s, err, tt.err)
+		} else if math.Abs(w-tt.w) > 1e-9 || math.Abs(h-tt.h) > 1e-9 {
+			t.Errorf("ParsePrintSize(%s) => %fx%f, want %fx%f", tt.s, w, h, tt.w, tt.h)
+		}
+	}
+
+	if px := InchesToPixels(0.125, 300); px != 38 {
+		t.Errorf("InchesToPixels(0.125, 300) => %d, want 38", px)
+	}
+}
+
+func TestParseGrid(t *testing.T) {
+	for _, tt := range []struct {
+		s          string
+		cols, rows int
+		err        bool
+	}{
+		{"3x2", 3, 2, false},
+		{" 1X4 ", 1, 4, false},
+		{"0x2", 0, 0, true},
+		{"3", 0, 0, true},
+		{"ax2", 0, 0, true},
+	} {
+		cols, rows, err := ParseGrid(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseGrid(%s) error => %v, want error %t", tt.s, err, tt.err)
+		} else if cols != tt.cols || rows != tt.rows {
+			t.Errorf("ParseGrid(%s) => %dx%d, want %dx%d", tt.s, cols, rows, tt.cols, tt.rows)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package util
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+)
+
+const tileCacheExt = ".png"
+
+// TileCache is an on-disk cache of index images resized to tiles,
+// keyed by the md5sum of the index image and the size of the tile.
+// Tiles are stored losslessly, so a cached tile is identical to the
+// tile it replaces. A nil *TileCache is a cache that is always empty.
+type TileCache struct {
+	// Dir is the directory the tiles are stored in
+	Dir string
+	// MaxSize is the most bytes the cache holds once pruned,
+	// the least recently used tiles are pruned first
+	MaxSize int64
+}
+
+// TileCacheStats describes the contents of a tile cache
+type TileCacheStats struct {
+	Tiles int
+	Size  int64
+}
+
+// NewTileCache returns a tile cache in dir, which is pruned to maxSize bytes.
+// It returns nil if dir is empty or maxSize is not positive, which disables caching.
+func NewTileCache(dir string, maxSize int64) *TileCache {
+	if dir == "" || maxSize <= 0 {
+		return nil
+	}
+	return &TileCache{Dir: dir, MaxSize: maxSize}
+}
+
+func (c *TileCache) path(md5sum string, width, height int) string {
+	prefix := md5sum
+	if len(prefix) > 2 {
+		prefix = prefix[:2]
+	}
+	return filepath.Join(c.Dir, prefix, fmt.Sprintf("%s_%dx%d%s", md5sum, width, height, tileCacheExt))
+}
+
+// Get returns the cached tile of width by height of the index image with
+// md5sum, and whether it was found. Found tiles are marked as recently used.
+func (c *TileCache) Get(md5sum string, width, height int) (image.Image, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	path := c.path(md5sum, width, height)
+	img, err := imaging.Open(path)
+	if err != nil {
+		return nil, false
+	}
+
+	if img.Bounds().Dx() != width || img.Bounds().Dy() != height {
+		return nil, false
+	}
+
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	return img, true
+}
+
+// Put stores img as the tile of its size of the index image with md5sum.
+// The tile is written to a temporary file and then renamed, so concurrent
+// readers never see a partial tile.
+func (c *TileCache) Put(md5sum string, img image.Image) error {
+	if c == nil {
+		return nil
+	}
+
+	path := c.path(md5sum, img.Bounds().Dx(), img.Bounds().Dy())
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "tile")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+
+	err = imaging.Encode(f, img, imaging.PNG)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+type tileCacheFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// tileCacheFilesByTime sorts tiles from the least recently used
+type tileCacheFilesByTime []tileCacheFile
+
+func (f tileCacheFilesByTime) Len() int      { return len(f) }
+func (f tileCacheFilesByTime) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f tileCacheFilesByTime) Less(i, j int) bool {
+	return f[i].modTime.Before(f[j].modTime)
+}
+
+// files returns the tiles in the cache
+func (c *TileCache) files() ([]tileCacheFile, error) {
+	files := []tileCacheFile{}
+	err := filepath.Walk(c.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if info.Mode().IsRegular() && strings.HasSuffix(path, tileCacheExt) {
+			files = append(files, tileCacheFile{path, info.Size(), info.ModTime()})
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// Stats returns the number of tiles in the cache, and their total size
+func (c *TileCache) Stats() (TileCacheStats, error) {
+	stats := TileCacheStats{}
+	if c == nil {
+		return stats, nil
+	}
+
+	files, err := c.files()
+	if err != nil {
+		return stats, err
+	}
+
+	for _, f := range files {
+		stats.Tiles++
+		stats.Size += f.size
+	}
+
+	return stats, nil
+}
+
+// Prune removes the least recently used tiles from the cache until its
+// total size is at most maxSize bytes, and returns what was removed
+func (c *TileCache) Prune(maxSize int64) (TileCacheStats, error) {
+	removed := TileCacheStats{}
+	if c == nil {
+		return removed, nil
+	}
+
+	files, err := c.files()
+	if err != nil {
+		return removed, err
+	}
+
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+
+	sort.Sort(tileCacheFilesByTime(files))
+
+	for _, f := range files {
+		if total <= maxSize {
+			break
+		}
+
+		err = os.Remove(f.path)
+		if err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+
+		total -= f.size
+		removed.Tiles++
+		removed.Size += f.size
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/disintegration/imaging"
+)
+
+func TestTileCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosaic_test_tile_cache")
+	if err != nil {
+		t.Fatalf("Error getting temp dir for tile cache test: %s\n", err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	if NewTileCache(dir, 0) != nil || NewTileCache("", 100) != nil {
+		t.Errorf("Expected tile cache without dir or size to be nil\n")
+	}
+
+	var disabled *TileCache
+	if _, ok := disabled.Get("abc", 10, 10); ok {
+		t.Errorf("Expected nil tile cache to be empty\n")
+	}
+
+	cache := NewTileCache(filepath.Join(dir, "tiles"), 1<<20)
+
+	stats, err := cache.Stats()
+	if err != nil || stats.Tiles != 0 {
+		t.Errorf("Expected new tile cache to be empty, got %+v, %v\n", stats, err)
+	}
+
+	for i, md5sum := range []string{"aaaa", "bbbb", "cccc"} {
+		img := imaging.New(20, 10, color.NRGBA{uint8(i * 100), 50, 200, 255})
+		err = cache.Put(md5sum, img)
+		if err != nil {
+			t.Fatalf("Error putting tile: %s\n", err.Error())
+		}
+
+		// distinct times, oldest first
+		then := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(cache.path(md5sum, 20, 10), then, then)
+	}
+
+	img, ok := cache.Get("bbbb", 20, 10)
+	if !ok {
+		t.Fatalf("Expected tile to be cached\n")
+	}
+	if img.Bounds() != image.Rect(0, 0, 20, 10) {
+		t.Errorf("Expected cached tile to be 20x10, got %v\n", img.Bounds())
+	}
+	if c := color.NRGBAModel.Convert(img.At(5, 5)); c != (color.NRGBA{100, 50, 200, 255}) {
+		t.Errorf("Expected cached tile color {100 50 200 255}, got %v\n", c)
+	}
+
+	if _, ok := cache.Get("bbbb", 10, 20); ok {
+		t.Errorf("Expected tile of another size not to be cached\n")
+	}
+
+	stats, err = cache.Stats()
+	if err != nil || stats.Tiles != 3 || stats.Size <= 0 {
+		t.Fatalf("Expected 3 cached tiles, got %+v, %v\n", stats, err)
+	}
+
+	// leaves room for one tile, and bbbb was used most recently
+	tileSize := stats.Size / 3
+	removed, err := cache.Prune(tileSize + tileSize/2)
+	if err != nil {
+		t.Fatalf("Error pruning tile cache: %s\n", err.Error())
+	}
+	if removed.Tiles != 2 {
+		t.Errorf("Expected to prune 2 tiles, pruned %d\n", removed.Tiles)
+	}
+
+	if _, ok := cache.Get("bbbb", 20, 10); !ok {
+		t.Errorf("Expected most recently used tile to remain cached\n")
+	}
+
+	for _, md5sum := range []string{"aaaa", "cccc"} {
+		if _, ok := cache.Get(md5sum, 20, 10); ok {
+			t.Errorf("Expected tile %s to be pruned\n", md5sum)
+		}
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"github.com/atongen/gosaic/model"
 	"image"
+	"image/color"
 	_ "image/jpeg"
 	"io"
 	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/disintegration/imaging"
@@ -196,15 +198,21 @@ func GetPartialLab(i model.Image, coverPartial *model.CoverPartial) ([]*model.La
 }
 
 func GetImgPartialLab(img *image.Image, coverPartial *model.CoverPartial) []*model.Lab {
+	return GetImgPartialLabSize(img, coverPartial, DATA_SIZE)
+}
+
+// GetImgPartialLabSize samples the cover partial area of img
+// into a size by size grid of lab values, in row order.
+func GetImgPartialLabSize(img *image.Image, coverPartial *model.CoverPartial, size int) []*model.Lab {
 	cropImg := imaging.Crop((*img), coverPartial.Rectangle())
-	dataImg := imaging.Resize(cropImg, DATA_SIZE, DATA_SIZE, imaging.Lanczos)
+	dataImg := imaging.Resize(cropImg, size, size, imaging.Lanczos)
 
-	labs := make([]*model.Lab, DATA_SIZE*DATA_SIZE)
+	labs := make([]*model.Lab, size*size)
 
-	for y := 0; y < DATA_SIZE; y++ {
-		for x := 0; x < DATA_SIZE; x++ {
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
 			lab := model.RgbaToLab(dataImg.At(x, y))
-			labs[y*DATA_SIZE+x] = lab
+			labs[y*size+x] = lab
 		}
 	}
 
@@ -284,6 +292,47 @@ func Round(f float64) int {
 	return int(r)
 }
 
+func MinInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func MaxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// ParseHexColor parses a color in the form #rgb, #rrggbb or #rrggbbaa.
+// The leading # is optional.
+func ParseHexColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("Invalid color: %s", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("Invalid color: %s", s)
+	}
+
+	return color.NRGBA{
+		R: uint8(v >> 24),
+		G: uint8(v >> 16),
+		B: uint8(v >> 8),
+		A: uint8(v),
+	}, nil
+}
+
 var (
 	cleanStr1Re = regexp.MustCompile("[^0-9a-z-]+")
 	cleanStr2Re = regexp.MustCompile("(?:^_|_$)")
@@ -1,6 +1,9 @@
 package util
 
-import "testing"
+import (
+	"image/color"
+	"testing"
+)
 
 func TestRound(t *testing.T) {
 	for _, tt := range []struct {
@@ -43,3 +46,25 @@ func TestCleanStr(t *testing.T) {
 		}
 	}
 }
+
+func TestParseHexColor(t *testing.T) {
+	for _, tt := range []struct {
+		s   string
+		c   color.NRGBA
+		err bool
+	}{
+		{"#ffffff", color.NRGBA{255, 255, 255, 255}, false},
+		{"000", color.NRGBA{0, 0, 0, 255}, false},
+		{"#f80", color.NRGBA{255, 136, 0, 255}, false},
+		{"#10203040", color.NRGBA{16, 32, 48, 64}, false},
+		{"#ff", color.NRGBA{}, true},
+		{"#gggggg", color.NRGBA{}, true},
+	} {
+		c, err := ParseHexColor(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseHexColor(%s) error => %v, want error %t", tt.s, err, tt.err)
+		} else if c != tt.c {
+			t.Errorf("ParseHexColor(%s) => %v, want %v", tt.s, c, tt.c)
+		}
+	}
+}